@@ -0,0 +1,1546 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: executor/v1/executor.proto
+
+// 调度中心与执行器之间的gRPC协议
+// 执行器请求调度中心时需在metadata中携带任务组访问令牌签名:
+//   x-executor-app       任务组应用名称
+//   x-executor-timestamp 当前Unix时间戳(秒)
+//   x-executor-signature hex(HMAC-SHA256(访问令牌, 应用名称 + "\n" + 时间戳 + "\n" + 完整方法名))
+// 完整方法名如 /executor.v1.SchedulerService/Register
+
+package executorv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// InstanceState 实例在执行器上的状态
+type InstanceState int32
+
+const (
+	InstanceState_INSTANCE_STATE_UNSPECIFIED InstanceState = 0
+	// 执行器上没有该实例(未接收或已结束)
+	InstanceState_INSTANCE_STATE_NOT_FOUND InstanceState = 1
+	InstanceState_INSTANCE_STATE_RUNNING   InstanceState = 2
+)
+
+// Enum value maps for InstanceState.
+var (
+	InstanceState_name = map[int32]string{
+		0: "INSTANCE_STATE_UNSPECIFIED",
+		1: "INSTANCE_STATE_NOT_FOUND",
+		2: "INSTANCE_STATE_RUNNING",
+	}
+	InstanceState_value = map[string]int32{
+		"INSTANCE_STATE_UNSPECIFIED": 0,
+		"INSTANCE_STATE_NOT_FOUND":   1,
+		"INSTANCE_STATE_RUNNING":     2,
+	}
+)
+
+func (x InstanceState) Enum() *InstanceState {
+	p := new(InstanceState)
+	*p = x
+	return p
+}
+
+func (x InstanceState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (InstanceState) Descriptor() protoreflect.EnumDescriptor {
+	return file_executor_v1_executor_proto_enumTypes[0].Descriptor()
+}
+
+func (InstanceState) Type() protoreflect.EnumType {
+	return &file_executor_v1_executor_proto_enumTypes[0]
+}
+
+func (x InstanceState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use InstanceState.Descriptor instead.
+func (InstanceState) EnumDescriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{0}
+}
+
+// DrainStatus 摘流状态
+type DrainStatus int32
+
+const (
+	// 正常(未摘流)
+	DrainStatus_DRAIN_STATUS_UNSPECIFIED DrainStatus = 0
+	// 摘流中: 不再分配新任务, 等待执行中的任务完成
+	DrainStatus_DRAIN_STATUS_DRAINING DrainStatus = 1
+	// 摘流完成: 执行中的任务已全部完成, 可以停止
+	DrainStatus_DRAIN_STATUS_DRAINED DrainStatus = 2
+)
+
+// Enum value maps for DrainStatus.
+var (
+	DrainStatus_name = map[int32]string{
+		0: "DRAIN_STATUS_UNSPECIFIED",
+		1: "DRAIN_STATUS_DRAINING",
+		2: "DRAIN_STATUS_DRAINED",
+	}
+	DrainStatus_value = map[string]int32{
+		"DRAIN_STATUS_UNSPECIFIED": 0,
+		"DRAIN_STATUS_DRAINING":    1,
+		"DRAIN_STATUS_DRAINED":     2,
+	}
+)
+
+func (x DrainStatus) Enum() *DrainStatus {
+	p := new(DrainStatus)
+	*p = x
+	return p
+}
+
+func (x DrainStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DrainStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_executor_v1_executor_proto_enumTypes[1].Descriptor()
+}
+
+func (DrainStatus) Type() protoreflect.EnumType {
+	return &file_executor_v1_executor_proto_enumTypes[1]
+}
+
+func (x DrainStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DrainStatus.Descriptor instead.
+func (DrainStatus) EnumDescriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{1}
+}
+
+// Task 任务参数
+type Task struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	InstanceId      uint64                 `protobuf:"varint,1,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	TaskId          uint64                 `protobuf:"varint,2,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	ExecutorHandler string                 `protobuf:"bytes,3,opt,name=executor_handler,json=executorHandler,proto3" json:"executor_handler,omitempty"`
+	ExecutorParam   string                 `protobuf:"bytes,4,opt,name=executor_param,json=executorParam,proto3" json:"executor_param,omitempty"`
+	ShardIndex      uint32                 `protobuf:"varint,5,opt,name=shard_index,json=shardIndex,proto3" json:"shard_index,omitempty"`
+	ShardTotal      uint32                 `protobuf:"varint,6,opt,name=shard_total,json=shardTotal,proto3" json:"shard_total,omitempty"`
+	// 超时时间(秒), 0表示不限制
+	Timeout uint32 `protobuf:"varint,7,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	// 派发令牌, 同一实例的重复下发令牌相同, 重新分发时递增
+	DispatchToken uint64 `protobuf:"varint,8,opt,name=dispatch_token,json=dispatchToken,proto3" json:"dispatch_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Task) Reset() {
+	*x = Task{}
+	mi := &file_executor_v1_executor_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Task) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Task) ProtoMessage() {}
+
+func (x *Task) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Task.ProtoReflect.Descriptor instead.
+func (*Task) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Task) GetInstanceId() uint64 {
+	if x != nil {
+		return x.InstanceId
+	}
+	return 0
+}
+
+func (x *Task) GetTaskId() uint64 {
+	if x != nil {
+		return x.TaskId
+	}
+	return 0
+}
+
+func (x *Task) GetExecutorHandler() string {
+	if x != nil {
+		return x.ExecutorHandler
+	}
+	return ""
+}
+
+func (x *Task) GetExecutorParam() string {
+	if x != nil {
+		return x.ExecutorParam
+	}
+	return ""
+}
+
+func (x *Task) GetShardIndex() uint32 {
+	if x != nil {
+		return x.ShardIndex
+	}
+	return 0
+}
+
+func (x *Task) GetShardTotal() uint32 {
+	if x != nil {
+		return x.ShardTotal
+	}
+	return 0
+}
+
+func (x *Task) GetTimeout() uint32 {
+	if x != nil {
+		return x.Timeout
+	}
+	return 0
+}
+
+func (x *Task) GetDispatchToken() uint64 {
+	if x != nil {
+		return x.DispatchToken
+	}
+	return 0
+}
+
+type RunRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Task          *Task                  `protobuf:"bytes,1,opt,name=task,proto3" json:"task,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunRequest) Reset() {
+	*x = RunRequest{}
+	mi := &file_executor_v1_executor_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunRequest) ProtoMessage() {}
+
+func (x *RunRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunRequest.ProtoReflect.Descriptor instead.
+func (*RunRequest) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RunRequest) GetTask() *Task {
+	if x != nil {
+		return x.Task
+	}
+	return nil
+}
+
+type RunResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunResponse) Reset() {
+	*x = RunResponse{}
+	mi := &file_executor_v1_executor_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunResponse) ProtoMessage() {}
+
+func (x *RunResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunResponse.ProtoReflect.Descriptor instead.
+func (*RunResponse) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{2}
+}
+
+type KillRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	InstanceId uint64                 `protobuf:"varint,1,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	// 要终止的派发令牌, 与执行中的令牌不一致时忽略; 为0时终止实例当前的执行
+	DispatchToken uint64 `protobuf:"varint,2,opt,name=dispatch_token,json=dispatchToken,proto3" json:"dispatch_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KillRequest) Reset() {
+	*x = KillRequest{}
+	mi := &file_executor_v1_executor_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KillRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KillRequest) ProtoMessage() {}
+
+func (x *KillRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KillRequest.ProtoReflect.Descriptor instead.
+func (*KillRequest) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *KillRequest) GetInstanceId() uint64 {
+	if x != nil {
+		return x.InstanceId
+	}
+	return 0
+}
+
+func (x *KillRequest) GetDispatchToken() uint64 {
+	if x != nil {
+		return x.DispatchToken
+	}
+	return 0
+}
+
+type KillResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 实例是否在执行中并已终止
+	Killed        bool `protobuf:"varint,1,opt,name=killed,proto3" json:"killed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KillResponse) Reset() {
+	*x = KillResponse{}
+	mi := &file_executor_v1_executor_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KillResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KillResponse) ProtoMessage() {}
+
+func (x *KillResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KillResponse.ProtoReflect.Descriptor instead.
+func (*KillResponse) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *KillResponse) GetKilled() bool {
+	if x != nil {
+		return x.Killed
+	}
+	return false
+}
+
+type StatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	InstanceId    uint64                 `protobuf:"varint,1,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusRequest) Reset() {
+	*x = StatusRequest{}
+	mi := &file_executor_v1_executor_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusRequest) ProtoMessage() {}
+
+func (x *StatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusRequest.ProtoReflect.Descriptor instead.
+func (*StatusRequest) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StatusRequest) GetInstanceId() uint64 {
+	if x != nil {
+		return x.InstanceId
+	}
+	return 0
+}
+
+type StatusResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	State InstanceState          `protobuf:"varint,1,opt,name=state,proto3,enum=executor.v1.InstanceState" json:"state,omitempty"`
+	// 开始执行时间(Unix毫秒)
+	StartTime int64 `protobuf:"varint,2,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	// 执行器当前执行中的任务数
+	CurrentLoad   uint32 `protobuf:"varint,3,opt,name=current_load,json=currentLoad,proto3" json:"current_load,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusResponse) Reset() {
+	*x = StatusResponse{}
+	mi := &file_executor_v1_executor_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusResponse) ProtoMessage() {}
+
+func (x *StatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
+func (*StatusResponse) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *StatusResponse) GetState() InstanceState {
+	if x != nil {
+		return x.State
+	}
+	return InstanceState_INSTANCE_STATE_UNSPECIFIED
+}
+
+func (x *StatusResponse) GetStartTime() int64 {
+	if x != nil {
+		return x.StartTime
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetCurrentLoad() uint32 {
+	if x != nil {
+		return x.CurrentLoad
+	}
+	return 0
+}
+
+type BeatRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Idle          bool                   `protobuf:"varint,1,opt,name=idle,proto3" json:"idle,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BeatRequest) Reset() {
+	*x = BeatRequest{}
+	mi := &file_executor_v1_executor_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BeatRequest) ProtoMessage() {}
+
+func (x *BeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BeatRequest.ProtoReflect.Descriptor instead.
+func (*BeatRequest) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BeatRequest) GetIdle() bool {
+	if x != nil {
+		return x.Idle
+	}
+	return false
+}
+
+type BeatResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Busy          bool                   `protobuf:"varint,1,opt,name=busy,proto3" json:"busy,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BeatResponse) Reset() {
+	*x = BeatResponse{}
+	mi := &file_executor_v1_executor_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BeatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BeatResponse) ProtoMessage() {}
+
+func (x *BeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BeatResponse.ProtoReflect.Descriptor instead.
+func (*BeatResponse) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *BeatResponse) GetBusy() bool {
+	if x != nil {
+		return x.Busy
+	}
+	return false
+}
+
+func (x *BeatResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type RegisterRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AppName       string                 `protobuf:"bytes,1,opt,name=app_name,json=appName,proto3" json:"app_name,omitempty"`
+	InstanceId    string                 `protobuf:"bytes,2,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	Host          string                 `protobuf:"bytes,3,opt,name=host,proto3" json:"host,omitempty"`
+	Port          uint32                 `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
+	MaxConcurrent uint32                 `protobuf:"varint,5,opt,name=max_concurrent,json=maxConcurrent,proto3" json:"max_concurrent,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,6,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterRequest) Reset() {
+	*x = RegisterRequest{}
+	mi := &file_executor_v1_executor_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterRequest) ProtoMessage() {}
+
+func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterRequest.ProtoReflect.Descriptor instead.
+func (*RegisterRequest) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *RegisterRequest) GetAppName() string {
+	if x != nil {
+		return x.AppName
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetPort() uint32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetMaxConcurrent() uint32 {
+	if x != nil {
+		return x.MaxConcurrent
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type RegisterResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ExecutorId    string                 `protobuf:"bytes,1,opt,name=executor_id,json=executorId,proto3" json:"executor_id,omitempty"`
+	GroupId       uint64                 `protobuf:"varint,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterResponse) Reset() {
+	*x = RegisterResponse{}
+	mi := &file_executor_v1_executor_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterResponse) ProtoMessage() {}
+
+func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterResponse.ProtoReflect.Descriptor instead.
+func (*RegisterResponse) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RegisterResponse) GetExecutorId() string {
+	if x != nil {
+		return x.ExecutorId
+	}
+	return ""
+}
+
+func (x *RegisterResponse) GetGroupId() uint64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+type UnregisterRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ExecutorId    string                 `protobuf:"bytes,1,opt,name=executor_id,json=executorId,proto3" json:"executor_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnregisterRequest) Reset() {
+	*x = UnregisterRequest{}
+	mi := &file_executor_v1_executor_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnregisterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnregisterRequest) ProtoMessage() {}
+
+func (x *UnregisterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnregisterRequest.ProtoReflect.Descriptor instead.
+func (*UnregisterRequest) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *UnregisterRequest) GetExecutorId() string {
+	if x != nil {
+		return x.ExecutorId
+	}
+	return ""
+}
+
+type UnregisterResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnregisterResponse) Reset() {
+	*x = UnregisterResponse{}
+	mi := &file_executor_v1_executor_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnregisterResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnregisterResponse) ProtoMessage() {}
+
+func (x *UnregisterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnregisterResponse.ProtoReflect.Descriptor instead.
+func (*UnregisterResponse) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{12}
+}
+
+type HeartbeatRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	ExecutorId  string                 `protobuf:"bytes,1,opt,name=executor_id,json=executorId,proto3" json:"executor_id,omitempty"`
+	InstanceId  string                 `protobuf:"bytes,2,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	AppName     string                 `protobuf:"bytes,3,opt,name=app_name,json=appName,proto3" json:"app_name,omitempty"`
+	Host        string                 `protobuf:"bytes,4,opt,name=host,proto3" json:"host,omitempty"`
+	Port        uint32                 `protobuf:"varint,5,opt,name=port,proto3" json:"port,omitempty"`
+	CurrentLoad uint32                 `protobuf:"varint,6,opt,name=current_load,json=currentLoad,proto3" json:"current_load,omitempty"`
+	CpuUsage    float64                `protobuf:"fixed64,7,opt,name=cpu_usage,json=cpuUsage,proto3" json:"cpu_usage,omitempty"`
+	MemoryUsage float64                `protobuf:"fixed64,8,opt,name=memory_usage,json=memoryUsage,proto3" json:"memory_usage,omitempty"`
+	// 为空时不更新标签
+	Labels map[string]string `protobuf:"bytes,9,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// 摘流中的执行器已没有执行中的任务
+	ReadyToStop   bool `protobuf:"varint,10,opt,name=ready_to_stop,json=readyToStop,proto3" json:"ready_to_stop,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatRequest) Reset() {
+	*x = HeartbeatRequest{}
+	mi := &file_executor_v1_executor_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatRequest) ProtoMessage() {}
+
+func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *HeartbeatRequest) GetExecutorId() string {
+	if x != nil {
+		return x.ExecutorId
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetAppName() string {
+	if x != nil {
+		return x.AppName
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetPort() uint32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetCurrentLoad() uint32 {
+	if x != nil {
+		return x.CurrentLoad
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetCpuUsage() float64 {
+	if x != nil {
+		return x.CpuUsage
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetMemoryUsage() float64 {
+	if x != nil {
+		return x.MemoryUsage
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *HeartbeatRequest) GetReadyToStop() bool {
+	if x != nil {
+		return x.ReadyToStop
+	}
+	return false
+}
+
+type HeartbeatResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ExecutorId    string                 `protobuf:"bytes,1,opt,name=executor_id,json=executorId,proto3" json:"executor_id,omitempty"`
+	DrainStatus   DrainStatus            `protobuf:"varint,2,opt,name=drain_status,json=drainStatus,proto3,enum=executor.v1.DrainStatus" json:"drain_status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatResponse) Reset() {
+	*x = HeartbeatResponse{}
+	mi := &file_executor_v1_executor_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatResponse) ProtoMessage() {}
+
+func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *HeartbeatResponse) GetExecutorId() string {
+	if x != nil {
+		return x.ExecutorId
+	}
+	return ""
+}
+
+func (x *HeartbeatResponse) GetDrainStatus() DrainStatus {
+	if x != nil {
+		return x.DrainStatus
+	}
+	return DrainStatus_DRAIN_STATUS_UNSPECIFIED
+}
+
+type DrainRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ExecutorId    string                 `protobuf:"bytes,1,opt,name=executor_id,json=executorId,proto3" json:"executor_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrainRequest) Reset() {
+	*x = DrainRequest{}
+	mi := &file_executor_v1_executor_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainRequest) ProtoMessage() {}
+
+func (x *DrainRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainRequest.ProtoReflect.Descriptor instead.
+func (*DrainRequest) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DrainRequest) GetExecutorId() string {
+	if x != nil {
+		return x.ExecutorId
+	}
+	return ""
+}
+
+type DrainResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DrainStatus   DrainStatus            `protobuf:"varint,1,opt,name=drain_status,json=drainStatus,proto3,enum=executor.v1.DrainStatus" json:"drain_status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrainResponse) Reset() {
+	*x = DrainResponse{}
+	mi := &file_executor_v1_executor_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainResponse) ProtoMessage() {}
+
+func (x *DrainResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainResponse.ProtoReflect.Descriptor instead.
+func (*DrainResponse) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *DrainResponse) GetDrainStatus() DrainStatus {
+	if x != nil {
+		return x.DrainStatus
+	}
+	return DrainStatus_DRAIN_STATUS_UNSPECIFIED
+}
+
+type CallbackRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	InstanceId uint64                 `protobuf:"varint,1,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	// 0表示成功
+	Code    int32  `protobuf:"varint,2,opt,name=code,proto3" json:"code,omitempty"`
+	Message string `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	// Handler发生panic时的调用栈
+	Stack string `protobuf:"bytes,4,opt,name=stack,proto3" json:"stack,omitempty"`
+	// 执行的派发令牌, 与实例当前令牌不一致的结果被拒绝; 为0时不校验
+	DispatchToken uint64 `protobuf:"varint,5,opt,name=dispatch_token,json=dispatchToken,proto3" json:"dispatch_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CallbackRequest) Reset() {
+	*x = CallbackRequest{}
+	mi := &file_executor_v1_executor_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CallbackRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallbackRequest) ProtoMessage() {}
+
+func (x *CallbackRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallbackRequest.ProtoReflect.Descriptor instead.
+func (*CallbackRequest) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *CallbackRequest) GetInstanceId() uint64 {
+	if x != nil {
+		return x.InstanceId
+	}
+	return 0
+}
+
+func (x *CallbackRequest) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *CallbackRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *CallbackRequest) GetStack() string {
+	if x != nil {
+		return x.Stack
+	}
+	return ""
+}
+
+func (x *CallbackRequest) GetDispatchToken() uint64 {
+	if x != nil {
+		return x.DispatchToken
+	}
+	return 0
+}
+
+type CallbackResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CallbackResponse) Reset() {
+	*x = CallbackResponse{}
+	mi := &file_executor_v1_executor_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CallbackResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallbackResponse) ProtoMessage() {}
+
+func (x *CallbackResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallbackResponse.ProtoReflect.Descriptor instead.
+func (*CallbackResponse) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{18}
+}
+
+// ReportLogsRequest 单条执行日志
+type ReportLogsRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	InstanceId uint64                 `protobuf:"varint,1,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	// 日志时间(Unix毫秒)
+	LogTime int64 `protobuf:"varint,2,opt,name=log_time,json=logTime,proto3" json:"log_time,omitempty"`
+	// DEBUG/INFO/WARN/ERROR
+	LogLevel      string `protobuf:"bytes,3,opt,name=log_level,json=logLevel,proto3" json:"log_level,omitempty"`
+	LogContent    string `protobuf:"bytes,4,opt,name=log_content,json=logContent,proto3" json:"log_content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportLogsRequest) Reset() {
+	*x = ReportLogsRequest{}
+	mi := &file_executor_v1_executor_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportLogsRequest) ProtoMessage() {}
+
+func (x *ReportLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportLogsRequest.ProtoReflect.Descriptor instead.
+func (*ReportLogsRequest) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ReportLogsRequest) GetInstanceId() uint64 {
+	if x != nil {
+		return x.InstanceId
+	}
+	return 0
+}
+
+func (x *ReportLogsRequest) GetLogTime() int64 {
+	if x != nil {
+		return x.LogTime
+	}
+	return 0
+}
+
+func (x *ReportLogsRequest) GetLogLevel() string {
+	if x != nil {
+		return x.LogLevel
+	}
+	return ""
+}
+
+func (x *ReportLogsRequest) GetLogContent() string {
+	if x != nil {
+		return x.LogContent
+	}
+	return ""
+}
+
+type ReportLogsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Saved         uint64                 `protobuf:"varint,1,opt,name=saved,proto3" json:"saved,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportLogsResponse) Reset() {
+	*x = ReportLogsResponse{}
+	mi := &file_executor_v1_executor_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportLogsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportLogsResponse) ProtoMessage() {}
+
+func (x *ReportLogsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_executor_v1_executor_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportLogsResponse.ProtoReflect.Descriptor instead.
+func (*ReportLogsResponse) Descriptor() ([]byte, []int) {
+	return file_executor_v1_executor_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ReportLogsResponse) GetSaved() uint64 {
+	if x != nil {
+		return x.Saved
+	}
+	return 0
+}
+
+var File_executor_v1_executor_proto protoreflect.FileDescriptor
+
+const file_executor_v1_executor_proto_rawDesc = "" +
+	"\n" +
+	"\x1aexecutor/v1/executor.proto\x12\vexecutor.v1\"\x95\x02\n" +
+	"\x04Task\x12\x1f\n" +
+	"\vinstance_id\x18\x01 \x01(\x04R\n" +
+	"instanceId\x12\x17\n" +
+	"\atask_id\x18\x02 \x01(\x04R\x06taskId\x12)\n" +
+	"\x10executor_handler\x18\x03 \x01(\tR\x0fexecutorHandler\x12%\n" +
+	"\x0eexecutor_param\x18\x04 \x01(\tR\rexecutorParam\x12\x1f\n" +
+	"\vshard_index\x18\x05 \x01(\rR\n" +
+	"shardIndex\x12\x1f\n" +
+	"\vshard_total\x18\x06 \x01(\rR\n" +
+	"shardTotal\x12\x18\n" +
+	"\atimeout\x18\a \x01(\rR\atimeout\x12%\n" +
+	"\x0edispatch_token\x18\b \x01(\x04R\rdispatchToken\"3\n" +
+	"\n" +
+	"RunRequest\x12%\n" +
+	"\x04task\x18\x01 \x01(\v2\x11.executor.v1.TaskR\x04task\"\r\n" +
+	"\vRunResponse\"U\n" +
+	"\vKillRequest\x12\x1f\n" +
+	"\vinstance_id\x18\x01 \x01(\x04R\n" +
+	"instanceId\x12%\n" +
+	"\x0edispatch_token\x18\x02 \x01(\x04R\rdispatchToken\"&\n" +
+	"\fKillResponse\x12\x16\n" +
+	"\x06killed\x18\x01 \x01(\bR\x06killed\"0\n" +
+	"\rStatusRequest\x12\x1f\n" +
+	"\vinstance_id\x18\x01 \x01(\x04R\n" +
+	"instanceId\"\x84\x01\n" +
+	"\x0eStatusResponse\x120\n" +
+	"\x05state\x18\x01 \x01(\x0e2\x1a.executor.v1.InstanceStateR\x05state\x12\x1d\n" +
+	"\n" +
+	"start_time\x18\x02 \x01(\x03R\tstartTime\x12!\n" +
+	"\fcurrent_load\x18\x03 \x01(\rR\vcurrentLoad\"!\n" +
+	"\vBeatRequest\x12\x12\n" +
+	"\x04idle\x18\x01 \x01(\bR\x04idle\"<\n" +
+	"\fBeatResponse\x12\x12\n" +
+	"\x04busy\x18\x01 \x01(\bR\x04busy\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\x99\x02\n" +
+	"\x0fRegisterRequest\x12\x19\n" +
+	"\bapp_name\x18\x01 \x01(\tR\aappName\x12\x1f\n" +
+	"\vinstance_id\x18\x02 \x01(\tR\n" +
+	"instanceId\x12\x12\n" +
+	"\x04host\x18\x03 \x01(\tR\x04host\x12\x12\n" +
+	"\x04port\x18\x04 \x01(\rR\x04port\x12%\n" +
+	"\x0emax_concurrent\x18\x05 \x01(\rR\rmaxConcurrent\x12@\n" +
+	"\x06labels\x18\x06 \x03(\v2(.executor.v1.RegisterRequest.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"N\n" +
+	"\x10RegisterResponse\x12\x1f\n" +
+	"\vexecutor_id\x18\x01 \x01(\tR\n" +
+	"executorId\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\x04R\agroupId\"4\n" +
+	"\x11UnregisterRequest\x12\x1f\n" +
+	"\vexecutor_id\x18\x01 \x01(\tR\n" +
+	"executorId\"\x14\n" +
+	"\x12UnregisterResponse\"\x9c\x03\n" +
+	"\x10HeartbeatRequest\x12\x1f\n" +
+	"\vexecutor_id\x18\x01 \x01(\tR\n" +
+	"executorId\x12\x1f\n" +
+	"\vinstance_id\x18\x02 \x01(\tR\n" +
+	"instanceId\x12\x19\n" +
+	"\bapp_name\x18\x03 \x01(\tR\aappName\x12\x12\n" +
+	"\x04host\x18\x04 \x01(\tR\x04host\x12\x12\n" +
+	"\x04port\x18\x05 \x01(\rR\x04port\x12!\n" +
+	"\fcurrent_load\x18\x06 \x01(\rR\vcurrentLoad\x12\x1b\n" +
+	"\tcpu_usage\x18\a \x01(\x01R\bcpuUsage\x12!\n" +
+	"\fmemory_usage\x18\b \x01(\x01R\vmemoryUsage\x12A\n" +
+	"\x06labels\x18\t \x03(\v2).executor.v1.HeartbeatRequest.LabelsEntryR\x06labels\x12\"\n" +
+	"\rready_to_stop\x18\n" +
+	" \x01(\bR\vreadyToStop\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"q\n" +
+	"\x11HeartbeatResponse\x12\x1f\n" +
+	"\vexecutor_id\x18\x01 \x01(\tR\n" +
+	"executorId\x12;\n" +
+	"\fdrain_status\x18\x02 \x01(\x0e2\x18.executor.v1.DrainStatusR\vdrainStatus\"/\n" +
+	"\fDrainRequest\x12\x1f\n" +
+	"\vexecutor_id\x18\x01 \x01(\tR\n" +
+	"executorId\"L\n" +
+	"\rDrainResponse\x12;\n" +
+	"\fdrain_status\x18\x01 \x01(\x0e2\x18.executor.v1.DrainStatusR\vdrainStatus\"\x9d\x01\n" +
+	"\x0fCallbackRequest\x12\x1f\n" +
+	"\vinstance_id\x18\x01 \x01(\x04R\n" +
+	"instanceId\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\x05R\x04code\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12\x14\n" +
+	"\x05stack\x18\x04 \x01(\tR\x05stack\x12%\n" +
+	"\x0edispatch_token\x18\x05 \x01(\x04R\rdispatchToken\"\x12\n" +
+	"\x10CallbackResponse\"\x8d\x01\n" +
+	"\x11ReportLogsRequest\x12\x1f\n" +
+	"\vinstance_id\x18\x01 \x01(\x04R\n" +
+	"instanceId\x12\x19\n" +
+	"\blog_time\x18\x02 \x01(\x03R\alogTime\x12\x1b\n" +
+	"\tlog_level\x18\x03 \x01(\tR\blogLevel\x12\x1f\n" +
+	"\vlog_content\x18\x04 \x01(\tR\n" +
+	"logContent\"*\n" +
+	"\x12ReportLogsResponse\x12\x14\n" +
+	"\x05saved\x18\x01 \x01(\x04R\x05saved*i\n" +
+	"\rInstanceState\x12\x1e\n" +
+	"\x1aINSTANCE_STATE_UNSPECIFIED\x10\x00\x12\x1c\n" +
+	"\x18INSTANCE_STATE_NOT_FOUND\x10\x01\x12\x1a\n" +
+	"\x16INSTANCE_STATE_RUNNING\x10\x02*`\n" +
+	"\vDrainStatus\x12\x1c\n" +
+	"\x18DRAIN_STATUS_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15DRAIN_STATUS_DRAINING\x10\x01\x12\x18\n" +
+	"\x14DRAIN_STATUS_DRAINED\x10\x022\x88\x02\n" +
+	"\x0fExecutorService\x128\n" +
+	"\x03Run\x12\x17.executor.v1.RunRequest\x1a\x18.executor.v1.RunResponse\x12;\n" +
+	"\x04Kill\x12\x18.executor.v1.KillRequest\x1a\x19.executor.v1.KillResponse\x12A\n" +
+	"\x06Status\x12\x1a.executor.v1.StatusRequest\x1a\x1b.executor.v1.StatusResponse\x12;\n" +
+	"\x04Beat\x12\x18.executor.v1.BeatRequest\x1a\x19.executor.v1.BeatResponse2\xd4\x03\n" +
+	"\x10SchedulerService\x12G\n" +
+	"\bRegister\x12\x1c.executor.v1.RegisterRequest\x1a\x1d.executor.v1.RegisterResponse\x12M\n" +
+	"\n" +
+	"Unregister\x12\x1e.executor.v1.UnregisterRequest\x1a\x1f.executor.v1.UnregisterResponse\x12N\n" +
+	"\tHeartbeat\x12\x1d.executor.v1.HeartbeatRequest\x1a\x1e.executor.v1.HeartbeatResponse(\x010\x01\x12>\n" +
+	"\x05Drain\x12\x19.executor.v1.DrainRequest\x1a\x1a.executor.v1.DrainResponse\x12G\n" +
+	"\bCallback\x12\x1c.executor.v1.CallbackRequest\x1a\x1d.executor.v1.CallbackResponse\x12O\n" +
+	"\n" +
+	"ReportLogs\x12\x1e.executor.v1.ReportLogsRequest\x1a\x1f.executor.v1.ReportLogsResponse(\x01Bi\n" +
+	"$com.distributedscheduler.executor.v1B\rExecutorProtoP\x01Z0distributed-scheduler/api/executor/v1;executorv1b\x06proto3"
+
+var (
+	file_executor_v1_executor_proto_rawDescOnce sync.Once
+	file_executor_v1_executor_proto_rawDescData []byte
+)
+
+func file_executor_v1_executor_proto_rawDescGZIP() []byte {
+	file_executor_v1_executor_proto_rawDescOnce.Do(func() {
+		file_executor_v1_executor_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_executor_v1_executor_proto_rawDesc), len(file_executor_v1_executor_proto_rawDesc)))
+	})
+	return file_executor_v1_executor_proto_rawDescData
+}
+
+var file_executor_v1_executor_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_executor_v1_executor_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_executor_v1_executor_proto_goTypes = []any{
+	(InstanceState)(0),         // 0: executor.v1.InstanceState
+	(DrainStatus)(0),           // 1: executor.v1.DrainStatus
+	(*Task)(nil),               // 2: executor.v1.Task
+	(*RunRequest)(nil),         // 3: executor.v1.RunRequest
+	(*RunResponse)(nil),        // 4: executor.v1.RunResponse
+	(*KillRequest)(nil),        // 5: executor.v1.KillRequest
+	(*KillResponse)(nil),       // 6: executor.v1.KillResponse
+	(*StatusRequest)(nil),      // 7: executor.v1.StatusRequest
+	(*StatusResponse)(nil),     // 8: executor.v1.StatusResponse
+	(*BeatRequest)(nil),        // 9: executor.v1.BeatRequest
+	(*BeatResponse)(nil),       // 10: executor.v1.BeatResponse
+	(*RegisterRequest)(nil),    // 11: executor.v1.RegisterRequest
+	(*RegisterResponse)(nil),   // 12: executor.v1.RegisterResponse
+	(*UnregisterRequest)(nil),  // 13: executor.v1.UnregisterRequest
+	(*UnregisterResponse)(nil), // 14: executor.v1.UnregisterResponse
+	(*HeartbeatRequest)(nil),   // 15: executor.v1.HeartbeatRequest
+	(*HeartbeatResponse)(nil),  // 16: executor.v1.HeartbeatResponse
+	(*DrainRequest)(nil),       // 17: executor.v1.DrainRequest
+	(*DrainResponse)(nil),      // 18: executor.v1.DrainResponse
+	(*CallbackRequest)(nil),    // 19: executor.v1.CallbackRequest
+	(*CallbackResponse)(nil),   // 20: executor.v1.CallbackResponse
+	(*ReportLogsRequest)(nil),  // 21: executor.v1.ReportLogsRequest
+	(*ReportLogsResponse)(nil), // 22: executor.v1.ReportLogsResponse
+	nil,                        // 23: executor.v1.RegisterRequest.LabelsEntry
+	nil,                        // 24: executor.v1.HeartbeatRequest.LabelsEntry
+}
+var file_executor_v1_executor_proto_depIdxs = []int32{
+	2,  // 0: executor.v1.RunRequest.task:type_name -> executor.v1.Task
+	0,  // 1: executor.v1.StatusResponse.state:type_name -> executor.v1.InstanceState
+	23, // 2: executor.v1.RegisterRequest.labels:type_name -> executor.v1.RegisterRequest.LabelsEntry
+	24, // 3: executor.v1.HeartbeatRequest.labels:type_name -> executor.v1.HeartbeatRequest.LabelsEntry
+	1,  // 4: executor.v1.HeartbeatResponse.drain_status:type_name -> executor.v1.DrainStatus
+	1,  // 5: executor.v1.DrainResponse.drain_status:type_name -> executor.v1.DrainStatus
+	3,  // 6: executor.v1.ExecutorService.Run:input_type -> executor.v1.RunRequest
+	5,  // 7: executor.v1.ExecutorService.Kill:input_type -> executor.v1.KillRequest
+	7,  // 8: executor.v1.ExecutorService.Status:input_type -> executor.v1.StatusRequest
+	9,  // 9: executor.v1.ExecutorService.Beat:input_type -> executor.v1.BeatRequest
+	11, // 10: executor.v1.SchedulerService.Register:input_type -> executor.v1.RegisterRequest
+	13, // 11: executor.v1.SchedulerService.Unregister:input_type -> executor.v1.UnregisterRequest
+	15, // 12: executor.v1.SchedulerService.Heartbeat:input_type -> executor.v1.HeartbeatRequest
+	17, // 13: executor.v1.SchedulerService.Drain:input_type -> executor.v1.DrainRequest
+	19, // 14: executor.v1.SchedulerService.Callback:input_type -> executor.v1.CallbackRequest
+	21, // 15: executor.v1.SchedulerService.ReportLogs:input_type -> executor.v1.ReportLogsRequest
+	4,  // 16: executor.v1.ExecutorService.Run:output_type -> executor.v1.RunResponse
+	6,  // 17: executor.v1.ExecutorService.Kill:output_type -> executor.v1.KillResponse
+	8,  // 18: executor.v1.ExecutorService.Status:output_type -> executor.v1.StatusResponse
+	10, // 19: executor.v1.ExecutorService.Beat:output_type -> executor.v1.BeatResponse
+	12, // 20: executor.v1.SchedulerService.Register:output_type -> executor.v1.RegisterResponse
+	14, // 21: executor.v1.SchedulerService.Unregister:output_type -> executor.v1.UnregisterResponse
+	16, // 22: executor.v1.SchedulerService.Heartbeat:output_type -> executor.v1.HeartbeatResponse
+	18, // 23: executor.v1.SchedulerService.Drain:output_type -> executor.v1.DrainResponse
+	20, // 24: executor.v1.SchedulerService.Callback:output_type -> executor.v1.CallbackResponse
+	22, // 25: executor.v1.SchedulerService.ReportLogs:output_type -> executor.v1.ReportLogsResponse
+	16, // [16:26] is the sub-list for method output_type
+	6,  // [6:16] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_executor_v1_executor_proto_init() }
+func file_executor_v1_executor_proto_init() {
+	if File_executor_v1_executor_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_executor_v1_executor_proto_rawDesc), len(file_executor_v1_executor_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   23,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_executor_v1_executor_proto_goTypes,
+		DependencyIndexes: file_executor_v1_executor_proto_depIdxs,
+		EnumInfos:         file_executor_v1_executor_proto_enumTypes,
+		MessageInfos:      file_executor_v1_executor_proto_msgTypes,
+	}.Build()
+	File_executor_v1_executor_proto = out.File
+	file_executor_v1_executor_proto_goTypes = nil
+	file_executor_v1_executor_proto_depIdxs = nil
+}
@@ -11,7 +11,13 @@ import (
 	"time"
 
 	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/executor/pool"
+	"distributed-scheduler/internal/middleware"
 	"distributed-scheduler/internal/router"
+	"distributed-scheduler/internal/rpc"
+	"distributed-scheduler/internal/scheduler/dispatcher"
+	"distributed-scheduler/internal/scheduler/monitor"
+	"distributed-scheduler/internal/scheduler/timewheel"
 	"distributed-scheduler/pkg/logger"
 	"distributed-scheduler/pkg/mysql"
 	"distributed-scheduler/pkg/redis"
@@ -50,6 +56,26 @@ func main() {
 	}
 	defer redis.Close()
 
+	// 初始化限流器
+	middleware.InitRateLimiter(&cfg.RateLimit)
+	defer middleware.StopRateLimiter()
+
+	// 启动时间轮、任务分发器和执行器监控
+	var timeWheel *timewheel.TimeWheel
+	var timeWheelPool *pool.WorkerPool
+	var taskDispatcher *dispatcher.Dispatcher
+	var executorMonitor *monitor.ExecutorMonitor
+	if cfg.Scheduler.Enable {
+		timeWheel = timewheel.NewSchedulerTimeWheel()
+		timeWheelPool = timewheel.NewSchedulerPool()
+		timeWheel.SetExecutor(timeWheelPool)
+		timeWheel.Start()
+		taskDispatcher = dispatcher.NewDispatcher()
+		taskDispatcher.Start()
+		executorMonitor = monitor.NewExecutorMonitor()
+		executorMonitor.Start()
+	}
+
 	// 设置路由
 	r := router.SetupRouter(cfg.Server.Mode)
 
@@ -70,6 +96,15 @@ func main() {
 		}
 	}()
 
+	// 启动gRPC服务
+	var grpcServer *rpc.Server
+	if cfg.Server.GRPCPort > 0 {
+		grpcServer = rpc.NewServer(cfg.Server.GRPCPort)
+		if err := grpcServer.Start(); err != nil {
+			logger.Fatalf("gRPC服务启动失败: %v", err)
+		}
+	}
+
 	// 优雅关闭
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -83,6 +118,23 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Errorf("服务关闭失败: %v", err)
 	}
+	if grpcServer != nil {
+		grpcServer.Stop(5 * time.Second)
+	}
+
+	if executorMonitor != nil {
+		executorMonitor.Stop()
+	}
+	if taskDispatcher != nil {
+		taskDispatcher.Stop()
+	}
+	if timeWheel != nil {
+		timeWheel.Stop()
+		// 时间轮停止时已取消回调的上下文, 等待执行中的回调退出
+		if err := timeWheelPool.Shutdown(5 * time.Second); err != nil {
+			logger.Errorf("时间轮回调执行器关闭超时: %v", err)
+		}
+	}
 
 	logger.Info("服务已关闭")
 }
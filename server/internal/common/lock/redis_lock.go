@@ -3,6 +3,8 @@ package lock
 import (
 	"context"
 	"errors"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,85 +19,267 @@ var (
 	ErrUnlockFailed = errors.New("释放锁失败")
 )
 
+// 默认锁过期时间
+const DefaultExpiration = 30 * time.Second
+
+// 等待锁释放通知的最长时间, 持有者崩溃时锁只能等待过期, 不会发布释放通知
+const maxWaitInterval = time.Second
+
+// 防护令牌计数器在锁最后一次获取或续期后的保留时间
+// 调度器锁和执行器锁按触发时间和派发令牌生成键, 计数器不设过期会无限增长;
+// 持有期间看门狗不断续期计数器, 因此计数器只会在锁空闲超过该时间后过期并从1重新计数
+const fenceRetention = 24 * time.Hour
+
+// 锁的键:
+//   KEYS[1] lock:{key}          锁(hash: owner/count/token)
+//   KEYS[2] lock:{key}:fence    防护令牌计数器
+//   KEYS[3] lock:key            旧版本的字符串锁, 滚动升级期间与旧节点互斥, 值为持有者
+// 参数: ARGV[1] 持有者, ARGV[2] 锁过期时间(毫秒), ARGV[3] 计数器保留时间(毫秒)
+
+// acquireScript 获取锁, 锁不存在时生成新的防护令牌, 持有者相同时重入计数加一
+// 旧版本的字符串锁被其他持有者占用时视为锁被占用
+// 返回 {防护令牌, 0}, 锁被其他持有者占用时返回 {0, 剩余过期时间(毫秒)}
+var acquireScript = redis.NewScript(`
+local legacy = redis.call("get", KEYS[3])
+if legacy and legacy ~= ARGV[1] then
+	return {0, redis.call("pttl", KEYS[3])}
+end
+local owner = redis.call("hget", KEYS[1], "owner")
+local token
+if not owner then
+	token = redis.call("incr", KEYS[2])
+	redis.call("hset", KEYS[1], "owner", ARGV[1], "count", 1, "token", token)
+elseif owner == ARGV[1] then
+	redis.call("hincrby", KEYS[1], "count", 1)
+	token = tonumber(redis.call("hget", KEYS[1], "token"))
+else
+	return {0, redis.call("pttl", KEYS[1])}
+end
+redis.call("pexpire", KEYS[1], ARGV[2])
+redis.call("pexpire", KEYS[2], ARGV[3])
+redis.call("set", KEYS[3], ARGV[1], "px", ARGV[2])
+return {token, 0}
+`)
+
+// releaseScript 释放锁, 重入计数减为0时删除锁并发布释放通知
+// 返回剩余重入次数, 锁不属于当前持有者时返回-1
+var releaseScript = redis.NewScript(`
+if redis.call("hget", KEYS[1], "owner") ~= ARGV[1] then
+	return -1
+end
+local count = redis.call("hincrby", KEYS[1], "count", -1)
+if count > 0 then
+	redis.call("pexpire", KEYS[1], ARGV[2])
+	redis.call("pexpire", KEYS[3], ARGV[2])
+	return count
+end
+redis.call("del", KEYS[1])
+if redis.call("get", KEYS[3]) == ARGV[1] then
+	redis.call("del", KEYS[3])
+end
+redis.call("publish", ARGV[3], ARGV[1])
+return 0
+`)
+
+// refreshScript 刷新锁和防护令牌计数器的过期时间, 锁不属于当前持有者时返回0
+var refreshScript = redis.NewScript(`
+if redis.call("hget", KEYS[1], "owner") ~= ARGV[1] then
+	return 0
+end
+redis.call("pexpire", KEYS[2], ARGV[3])
+redis.call("set", KEYS[3], ARGV[1], "px", ARGV[2])
+return redis.call("pexpire", KEYS[1], ARGV[2])
+`)
+
 // RedisLock Redis分布式锁
+// 同一持有者可重入, 释放次数与获取次数相同时才真正释放; 每次新获取锁时生成单调递增的防护令牌(fencing token),
+// 重入时令牌不变, 受保护的资源可拒绝令牌小于已见令牌的写入;
+// 持有期间看门狗每隔过期时间的1/3自动续期, 续期失败(锁已过期被他人获取)时关闭Lost()
 type RedisLock struct {
 	key        string
-	value      string
+	fenceKey   string
+	legacyKey  string
+	channel    string
+	owner      string
 	expiration time.Duration
 	client     *redis.Client
+
+	mu      sync.Mutex
+	holds   int   // 本实例的重入次数
+	token   int64 // 当前持有的防护令牌
+	lost    chan struct{}
+	stopDog context.CancelFunc
+	dogDone chan struct{}
 }
 
-// NewRedisLock 创建Redis分布式锁
+// NewRedisLock 创建Redis分布式锁, 持有者为随机生成的唯一标识
 func NewRedisLock(key string, expiration time.Duration) *RedisLock {
+	return NewRedisLockWithOwner(key, uuid.New().String(), expiration)
+}
+
+// NewRedisLockWithOwner 创建指定持有者的Redis分布式锁, 持有者相同的锁实例之间可重入
+func NewRedisLockWithOwner(key, owner string, expiration time.Duration) *RedisLock {
+	if expiration <= 0 {
+		expiration = DefaultExpiration
+	}
+	// 使用hash tag保证锁和防护令牌在集群模式下位于同一个槽
+	base := "lock:{" + key + "}"
 	return &RedisLock{
-		key:        "lock:" + key,
-		value:      uuid.New().String(),
+		key:        base,
+		fenceKey:   base + ":fence",
+		legacyKey:  "lock:" + key,
+		channel:    base + ":released",
+		owner:      owner,
 		expiration: expiration,
 		client:     pkgRedis.GetClient(),
 	}
 }
 
-// Lock 获取锁
+// Lock 获取锁, 锁被其他持有者占用时立即返回ErrLockFailed
 func (l *RedisLock) Lock(ctx context.Context) error {
-	success, err := l.client.SetNX(ctx, l.key, l.value, l.expiration).Result()
+	acquired, _, err := l.acquire(ctx)
 	if err != nil {
 		return err
 	}
-	if !success {
+	if !acquired {
 		return ErrLockFailed
 	}
 	return nil
 }
 
-// TryLock 尝试获取锁(带超时)
+// TryLock 获取锁, 锁被占用时订阅释放通知等待, 超时返回ErrLockFailed
+// 持有者崩溃时没有释放通知, 等待时间不超过锁的剩余过期时间和maxWaitInterval后重试
 func (l *RedisLock) TryLock(ctx context.Context, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-	for time.Now().Before(deadline) {
-		if err := l.Lock(ctx); err == nil {
+	acquired, wait, err := l.acquire(ctx)
+	if err != nil || acquired {
+		return err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	sub := l.client.Subscribe(waitCtx, l.channel)
+	defer sub.Close()
+	if _, err := sub.Receive(waitCtx); err != nil {
+		return l.waitError(ctx, err)
+	}
+	released := sub.Channel()
+
+	for {
+		// 订阅完成后再尝试一次, 避免订阅前发布的释放通知丢失
+		acquired, wait, err = l.acquire(waitCtx)
+		if err != nil {
+			return l.waitError(ctx, err)
+		}
+		if acquired {
 			return nil
 		}
-		// 等待一段时间后重试
+
+		if wait <= 0 || wait > maxWaitInterval {
+			wait = maxWaitInterval
+		}
+		timer := time.NewTimer(wait)
 		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(50 * time.Millisecond):
-			continue
+		case <-released:
+		case <-timer.C:
+		case <-waitCtx.Done():
+			timer.Stop()
+			return l.waitError(ctx, waitCtx.Err())
 		}
+		timer.Stop()
+	}
+}
+
+// waitError 等待超时返回ErrLockFailed, 调用方取消时返回ctx的错误
+func (l *RedisLock) waitError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return ErrLockFailed
+	}
+	return err
+}
+
+// acquire 尝试获取锁, 未获取到时返回锁的剩余过期时间
+func (l *RedisLock) acquire(ctx context.Context) (bool, time.Duration, error) {
+	result, err := acquireScript.Run(ctx, l.client, l.keys(),
+		l.owner, l.expiration.Milliseconds(), fenceRetention.Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(result) != 2 {
+		return false, 0, ErrLockFailed
+	}
+	if result[0] <= 0 {
+		return false, time.Duration(result[1]) * time.Millisecond, nil
+	}
+	l.held(result[0])
+	return true, 0, nil
+}
+
+// keys 锁脚本使用的键, 顺序见脚本说明
+func (l *RedisLock) keys() []string {
+	return []string{l.key, l.fenceKey, l.legacyKey}
+}
+
+// held 记录获取成功, 首次持有时启动看门狗
+func (l *RedisLock) held(token int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.holds++
+	l.token = token
+	if l.holds > 1 {
+		return
 	}
-	return ErrLockFailed
+
+	ctx, cancel := context.WithCancel(context.Background())
+	l.lost = make(chan struct{})
+	l.stopDog = cancel
+	l.dogDone = make(chan struct{})
+	go l.watchdog(ctx, l.lost, l.dogDone)
 }
 
-// Unlock 释放锁
+// Unlock 释放锁, 重入的锁需释放相同次数
+// 锁已过期或被其他持有者获取时返回ErrLockNotHeld
 func (l *RedisLock) Unlock(ctx context.Context) error {
-	// Lua脚本保证原子性：只有锁的持有者才能释放锁
-	script := `
-		if redis.call("get", KEYS[1]) == ARGV[1] then
-			return redis.call("del", KEYS[1])
-		else
-			return 0
-		end
-	`
-	result, err := l.client.Eval(ctx, script, []string{l.key}, l.value).Int64()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holds == 0 {
+		return ErrLockNotHeld
+	}
+
+	result, err := releaseScript.Run(ctx, l.client, l.keys(),
+		l.owner, l.expiration.Milliseconds(), l.channel).Int64()
 	if err != nil {
 		return err
 	}
-	if result == 0 {
+	if result < 0 {
+		l.holds = 0
+		l.stopWatchdog()
 		return ErrLockNotHeld
 	}
+	l.holds--
+	if l.holds == 0 {
+		l.stopWatchdog()
+	}
 	return nil
 }
 
+// stopWatchdog 停止看门狗并等待退出, 调用方需持有mu
+func (l *RedisLock) stopWatchdog() {
+	if l.stopDog == nil {
+		return
+	}
+	l.stopDog()
+	<-l.dogDone
+	l.stopDog = nil
+}
+
 // Refresh 刷新锁的过期时间
 func (l *RedisLock) Refresh(ctx context.Context) error {
-	// Lua脚本保证原子性：只有锁的持有者才能刷新过期时间
-	script := `
-		if redis.call("get", KEYS[1]) == ARGV[1] then
-			return redis.call("pexpire", KEYS[1], ARGV[2])
-		else
-			return 0
-		end
-	`
-	result, err := l.client.Eval(ctx, script, []string{l.key}, l.value, l.expiration.Milliseconds()).Int64()
+	result, err := refreshScript.Run(ctx, l.client, l.keys(),
+		l.owner, l.expiration.Milliseconds(), fenceRetention.Milliseconds()).Int64()
 	if err != nil {
 		return err
 	}
@@ -105,24 +289,87 @@ func (l *RedisLock) Refresh(ctx context.Context) error {
 	return nil
 }
 
-// WithLock 在锁保护下执行函数
-func WithLock(ctx context.Context, key string, expiration time.Duration, fn func() error) error {
+// Token 获取当前持有的防护令牌, 未持有锁时为0
+func (l *RedisLock) Token() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holds == 0 {
+		return 0
+	}
+	return l.token
+}
+
+// Lost 锁在持有期间丢失时关闭, 未获取过锁时返回nil
+func (l *RedisLock) Lost() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lost
+}
+
+// watchdog 持有期间定期续期, 锁已不属于当前持有者或超过过期时间仍未续期成功时关闭lost
+func (l *RedisLock) watchdog(ctx context.Context, lost, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(l.expiration / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := l.Refresh(ctx)
+		switch {
+		case err == nil:
+			renewed = time.Now()
+			continue
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, ErrLockNotHeld), time.Since(renewed) >= l.expiration:
+			close(lost)
+			return
+		}
+		// 网络错误时在过期前继续重试
+	}
+}
+
+// WithLock 在锁保护下执行函数, 锁被占用时返回ErrLockFailed
+// 锁在执行期间丢失时fn的ctx被取消
+func WithLock(ctx context.Context, key string, expiration time.Duration, fn func(ctx context.Context) error) error {
 	lock := NewRedisLock(key, expiration)
 	if err := lock.Lock(ctx); err != nil {
 		return err
 	}
-	defer lock.Unlock(ctx)
-	return fn()
+	return lock.run(ctx, fn)
 }
 
-// WithTryLock 尝试获取锁并执行函数
-func WithTryLock(ctx context.Context, key string, expiration, timeout time.Duration, fn func() error) error {
+// WithTryLock 等待获取锁并执行函数, 锁在执行期间丢失时fn的ctx被取消
+func WithTryLock(ctx context.Context, key string, expiration, timeout time.Duration, fn func(ctx context.Context) error) error {
 	lock := NewRedisLock(key, expiration)
 	if err := lock.TryLock(ctx, timeout); err != nil {
 		return err
 	}
-	defer lock.Unlock(ctx)
-	return fn()
+	return lock.run(ctx, fn)
+}
+
+// run 执行fn后释放锁
+func (l *RedisLock) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	defer l.Unlock(context.Background())
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	lost := l.Lost()
+	go func() {
+		select {
+		case <-lost:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return fn(ctx)
 }
 
 // SchedulerLock 调度器锁(用于防止任务重复调度)
@@ -132,22 +379,22 @@ type SchedulerLock struct {
 
 // NewSchedulerLock 创建调度器锁
 func NewSchedulerLock(taskID uint64, triggerTime time.Time) *SchedulerLock {
-	key := "scheduler:" + string(rune(taskID)) + ":" + triggerTime.Format("20060102150405")
+	key := "scheduler:" + strconv.FormatUint(taskID, 10) + ":" + triggerTime.Format("20060102150405")
 	return &SchedulerLock{
 		RedisLock: NewRedisLock(key, 5*time.Minute),
 	}
 }
 
 // ExecutorLock 执行器锁(用于任务执行互斥)
+// 同一实例的每次分发使用不同的派发令牌, 锁按实例和令牌区分, 共享Redis的执行器可据此保证同一次分发只执行一次
 type ExecutorLock struct {
 	*RedisLock
 }
 
 // NewExecutorLock 创建执行器锁
-func NewExecutorLock(instanceID uint64) *ExecutorLock {
-	key := "executor:" + string(rune(instanceID))
+func NewExecutorLock(instanceID, dispatchToken uint64) *ExecutorLock {
+	key := "executor:" + strconv.FormatUint(instanceID, 10) + ":" + strconv.FormatUint(dispatchToken, 10)
 	return &ExecutorLock{
 		RedisLock: NewRedisLock(key, 10*time.Minute),
 	}
 }
-
@@ -0,0 +1,293 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	pkgRedis "distributed-scheduler/pkg/redis"
+)
+
+// setupRedis 使用miniredis替代Redis
+func setupRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	pkgRedis.Client = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		pkgRedis.Client.Close()
+		pkgRedis.Client = nil
+	})
+	return mr
+}
+
+func TestLockReentrantPerOwner(t *testing.T) {
+	setupRedis(t)
+	ctx := context.Background()
+
+	l1 := NewRedisLockWithOwner("reentrant", "node-1", time.Minute)
+	if err := l1.Lock(ctx); err != nil {
+		t.Fatalf("首次获取锁失败: %v", err)
+	}
+	token := l1.Token()
+
+	// 同一持有者的其他锁实例可重入, 令牌不变
+	l2 := NewRedisLockWithOwner("reentrant", "node-1", time.Minute)
+	if err := l2.Lock(ctx); err != nil {
+		t.Fatalf("同一持有者重入失败: %v", err)
+	}
+	if err := l1.Lock(ctx); err != nil {
+		t.Fatalf("同一实例重入失败: %v", err)
+	}
+	if l2.Token() != token {
+		t.Errorf("重入时令牌应保持为%d, 实际: %d", token, l2.Token())
+	}
+
+	other := NewRedisLockWithOwner("reentrant", "node-2", time.Minute)
+	if err := other.Lock(ctx); !errors.Is(err, ErrLockFailed) {
+		t.Fatalf("其他持有者应获取失败, 实际: %v", err)
+	}
+
+	// 获取3次, 释放3次后其他持有者才能获取
+	for i := 0; i < 2; i++ {
+		if err := l1.Unlock(ctx); err != nil {
+			t.Fatalf("释放锁失败: %v", err)
+		}
+		if err := other.Lock(ctx); !errors.Is(err, ErrLockFailed) {
+			t.Fatalf("未完全释放时其他持有者应获取失败, 实际: %v", err)
+		}
+	}
+	if err := l1.Unlock(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("释放次数超过本实例获取次数应返回ErrLockNotHeld, 实际: %v", err)
+	}
+	if err := l2.Unlock(ctx); err != nil {
+		t.Fatalf("释放锁失败: %v", err)
+	}
+	if err := other.Lock(ctx); err != nil {
+		t.Fatalf("完全释放后其他持有者应获取成功: %v", err)
+	}
+	defer other.Unlock(ctx)
+}
+
+func TestLockFencingTokenMonotonic(t *testing.T) {
+	setupRedis(t)
+	ctx := context.Background()
+
+	var last int64
+	for i := 0; i < 5; i++ {
+		l := NewRedisLock("fencing", time.Minute)
+		if err := l.Lock(ctx); err != nil {
+			t.Fatalf("获取锁失败: %v", err)
+		}
+		if l.Token() <= last {
+			t.Fatalf("令牌应单调递增, 上次: %d, 本次: %d", last, l.Token())
+		}
+		last = l.Token()
+		if err := l.Unlock(ctx); err != nil {
+			t.Fatalf("释放锁失败: %v", err)
+		}
+		if l.Token() != 0 {
+			t.Errorf("释放后令牌应为0, 实际: %d", l.Token())
+		}
+	}
+
+	// 其他锁的令牌互不影响
+	l := NewRedisLock("fencing-other", time.Minute)
+	if err := l.Lock(ctx); err != nil {
+		t.Fatalf("获取锁失败: %v", err)
+	}
+	defer l.Unlock(ctx)
+	if l.Token() != 1 {
+		t.Errorf("新锁的首个令牌应为1, 实际: %d", l.Token())
+	}
+}
+
+func TestLockWatchdogRenews(t *testing.T) {
+	mr := setupRedis(t)
+	ctx := context.Background()
+
+	l := NewRedisLock("watchdog", 300*time.Millisecond)
+	if err := l.Lock(ctx); err != nil {
+		t.Fatalf("获取锁失败: %v", err)
+	}
+
+	// miniredis的过期时间只随FastForward推进, 两次推进之间看门狗续期后锁才不会过期
+	mr.FastForward(200 * time.Millisecond)
+	time.Sleep(150 * time.Millisecond)
+	mr.FastForward(200 * time.Millisecond)
+	if !mr.Exists(l.key) {
+		t.Fatal("看门狗应在过期前续期")
+	}
+	select {
+	case <-l.Lost():
+		t.Fatal("续期成功时不应通知锁丢失")
+	default:
+	}
+
+	if err := l.Unlock(ctx); err != nil {
+		t.Fatalf("释放锁失败: %v", err)
+	}
+	if mr.Exists(l.key) {
+		t.Error("释放后锁应被删除")
+	}
+}
+
+func TestLockWatchdogDetectsLoss(t *testing.T) {
+	mr := setupRedis(t)
+	ctx := context.Background()
+
+	l := NewRedisLock("lost", 300*time.Millisecond)
+	if err := l.Lock(ctx); err != nil {
+		t.Fatalf("获取锁失败: %v", err)
+	}
+	mr.Del(l.key)
+
+	select {
+	case <-l.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("锁被删除后应通知锁丢失")
+	}
+	if err := l.Unlock(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Errorf("锁丢失后释放应返回ErrLockNotHeld, 实际: %v", err)
+	}
+}
+
+func TestTryLockWakesOnRelease(t *testing.T) {
+	setupRedis(t)
+	ctx := context.Background()
+
+	holder := NewRedisLock("wait", time.Minute)
+	if err := holder.Lock(ctx); err != nil {
+		t.Fatalf("获取锁失败: %v", err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		holder.Unlock(ctx)
+	}()
+
+	// 锁的过期时间为1分钟, 只有收到释放通知才能在等待超时前获取
+	waiter := NewRedisLock("wait", time.Minute)
+	start := time.Now()
+	if err := waiter.TryLock(ctx, 5*time.Second); err != nil {
+		t.Fatalf("持有者释放后应获取成功: %v", err)
+	}
+	defer waiter.Unlock(ctx)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("应在收到释放通知后立即获取, 实际等待: %v", elapsed)
+	}
+	if waiter.Token() <= 1 {
+		t.Errorf("等待者应获得新的令牌, 实际: %d", waiter.Token())
+	}
+}
+
+func TestTryLockTimeoutAndCancel(t *testing.T) {
+	setupRedis(t)
+	ctx := context.Background()
+
+	holder := NewRedisLock("timeout", time.Minute)
+	if err := holder.Lock(ctx); err != nil {
+		t.Fatalf("获取锁失败: %v", err)
+	}
+	defer holder.Unlock(ctx)
+
+	waiter := NewRedisLock("timeout", time.Minute)
+	if err := waiter.TryLock(ctx, 100*time.Millisecond); !errors.Is(err, ErrLockFailed) {
+		t.Errorf("等待超时应返回ErrLockFailed, 实际: %v", err)
+	}
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if err := waiter.TryLock(cancelCtx, time.Second); !errors.Is(err, context.Canceled) {
+		t.Errorf("调用方取消应返回context.Canceled, 实际: %v", err)
+	}
+}
+
+func TestWithLockCancelsOnLoss(t *testing.T) {
+	mr := setupRedis(t)
+
+	err := WithLock(context.Background(), "with", 300*time.Millisecond, func(ctx context.Context) error {
+		mr.Del("lock:{with}")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("锁丢失时应取消执行函数的ctx, 实际: %v", err)
+	}
+}
+
+func TestLockKeys(t *testing.T) {
+	setupRedis(t)
+	triggerTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+
+	// 超过0x10FFFF的ID不应映射到同一个键
+	a := NewSchedulerLock(0x110000, triggerTime)
+	b := NewSchedulerLock(0x110001, triggerTime)
+	if a.key == b.key {
+		t.Errorf("不同任务的调度器锁键相同: %s", a.key)
+	}
+	if want := "lock:{scheduler:1114112:20240101000000}"; a.key != want {
+		t.Errorf("调度器锁键应为%s, 实际: %s", want, a.key)
+	}
+	if NewExecutorLock(1, 1).key == NewExecutorLock(1, 2).key {
+		t.Error("不同派发令牌的执行器锁键相同")
+	}
+}
+
+func TestLockFenceKeyExpires(t *testing.T) {
+	mr := setupRedis(t)
+	ctx := context.Background()
+
+	l := NewRedisLock("fence-ttl", time.Minute)
+	if err := l.Lock(ctx); err != nil {
+		t.Fatalf("获取锁失败: %v", err)
+	}
+	if ttl := mr.TTL(l.fenceKey); ttl != fenceRetention {
+		t.Errorf("防护令牌计数器的过期时间应为%v, 实际: %v", fenceRetention, ttl)
+	}
+	if err := l.Unlock(ctx); err != nil {
+		t.Fatalf("释放锁失败: %v", err)
+	}
+
+	// 锁空闲超过保留时间后计数器过期, 按触发时间生成的键不会无限累积
+	mr.FastForward(fenceRetention)
+	if mr.Exists(l.fenceKey) {
+		t.Error("锁空闲超过保留时间后计数器应过期")
+	}
+}
+
+func TestLockLegacyKeyMutualExclusion(t *testing.T) {
+	mr := setupRedis(t)
+	ctx := context.Background()
+
+	// 旧版本节点持有字符串锁时新版本节点不能获取
+	if err := mr.Set("lock:legacy", "old-node"); err != nil {
+		t.Fatalf("写入旧版本锁失败: %v", err)
+	}
+	mr.SetTTL("lock:legacy", time.Minute)
+	l := NewRedisLock("legacy", time.Minute)
+	if err := l.Lock(ctx); !errors.Is(err, ErrLockFailed) {
+		t.Fatalf("旧版本锁被占用时应获取失败, 实际: %v", err)
+	}
+	mr.Del("lock:legacy")
+
+	// 新版本节点持有锁时同时写入字符串锁, 旧版本节点的SETNX失败
+	if err := l.Lock(ctx); err != nil {
+		t.Fatalf("获取锁失败: %v", err)
+	}
+	if ok, err := pkgRedis.Client.SetNX(ctx, "lock:legacy", "old-node", time.Minute).Result(); err != nil || ok {
+		t.Fatalf("新版本节点持有锁时旧版本节点应获取失败, ok=%v err=%v", ok, err)
+	}
+	if err := l.Unlock(ctx); err != nil {
+		t.Fatalf("释放锁失败: %v", err)
+	}
+	if mr.Exists("lock:legacy") {
+		t.Error("释放后应删除字符串锁")
+	}
+}
@@ -0,0 +1,210 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	pkgRedis "distributed-scheduler/pkg/redis"
+)
+
+var (
+	ErrRedisUnavailable = errors.New("Redis未初始化")
+)
+
+// Redis键前缀
+const keyPrefix = "ratelimit:"
+
+// Limit 限流规则
+type Limit struct {
+	Rate  float64 // 每秒允许的请求数
+	Burst int     // 允许的突发请求数, 即桶容量
+}
+
+// interval 两次请求之间的发放间隔
+func (l Limit) interval() time.Duration {
+	return time.Duration(float64(time.Second) / l.Rate)
+}
+
+// Result 限流结果
+type Result struct {
+	Allowed    bool
+	Limit      int           // 桶容量
+	Remaining  int           // 当前剩余的请求数
+	RetryAfter time.Duration // 被拒绝时距离下次允许请求的时间
+	ResetAfter time.Duration // 距离桶恢复满容量的时间
+}
+
+// Limiter 限流器
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (*Result, error)
+}
+
+// gcraScript 使用GCRA(通用信元速率算法)原子地判断并记录一次请求
+// 每个键只保存理论到达时间(TAT), 时间取Redis服务器时间, 多个调度节点共享同一个时钟
+// KEYS[1]: 限流键; ARGV[1]: 发放间隔(微秒); ARGV[2]: 桶容量
+// 返回 {是否允许, 剩余请求数, 重试等待(微秒), 恢复满容量时间(微秒)}
+var gcraScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local tat = tonumber(redis.call("GET", KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+local newTat = tat + interval
+local diff = now - (newTat - interval * burst)
+if diff < 0 then
+	return {0, 0, -diff, tat - now}
+end
+redis.call("SET", KEYS[1], string.format("%.0f", newTat), "PX", math.ceil((newTat - now) / 1000))
+return {1, math.floor(diff / interval), 0, newTat - now}
+`)
+
+// RedisLimiter 基于Redis的分布式限流器, 多个调度节点共享限流计数
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisLimiter 创建Redis限流器
+func NewRedisLimiter() *RedisLimiter {
+	return &RedisLimiter{
+		client: pkgRedis.GetClient(),
+	}
+}
+
+// Allow 判断是否允许请求
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	if l.client == nil {
+		return nil, ErrRedisUnavailable
+	}
+
+	values, err := gcraScript.Run(ctx, l.client, []string{keyPrefix + key},
+		l.interval(limit), limit.Burst).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 4 {
+		return nil, errors.New("限流脚本返回值格式错误")
+	}
+	return &Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit.Burst,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Microsecond,
+		ResetAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
+
+// interval 发放间隔(微秒), 至少为1
+func (l *RedisLimiter) interval(limit Limit) int64 {
+	return int64(math.Max(1, math.Round(float64(limit.interval())/float64(time.Microsecond))))
+}
+
+// LocalLimiter 进程内限流器, Redis不可用时降级使用
+// 与Redis限流器使用相同的GCRA算法, 理论到达时间早于当前时间的记录与不存在等价, 由清理协程定期删除
+type LocalLimiter struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+	now     func() time.Time
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewLocalLimiter 创建本地限流器, 并按janitorInterval定期清理过期记录
+func NewLocalLimiter(janitorInterval time.Duration) *LocalLimiter {
+	if janitorInterval <= 0 {
+		janitorInterval = time.Minute
+	}
+	l := &LocalLimiter{
+		entries: make(map[string]time.Time),
+		now:     time.Now,
+		stopCh:  make(chan struct{}),
+	}
+	l.wg.Add(1)
+	go l.janitor(janitorInterval)
+	return l
+}
+
+// Allow 判断是否允许请求
+func (l *LocalLimiter) Allow(_ context.Context, key string, limit Limit) (*Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	interval := limit.interval()
+	tat, ok := l.entries[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(interval)
+	diff := now.Sub(newTat.Add(-interval * time.Duration(limit.Burst)))
+	if diff < 0 {
+		return &Result{
+			Allowed:    false,
+			Limit:      limit.Burst,
+			RetryAfter: -diff,
+			ResetAfter: tat.Sub(now),
+		}, nil
+	}
+
+	l.entries[key] = newTat
+	return &Result{
+		Allowed:    true,
+		Limit:      limit.Burst,
+		Remaining:  int(diff / interval),
+		ResetAfter: newTat.Sub(now),
+	}, nil
+}
+
+// Len 当前记录数
+func (l *LocalLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.entries)
+}
+
+// Stop 停止清理协程
+func (l *LocalLimiter) Stop() {
+	close(l.stopCh)
+	l.wg.Wait()
+}
+
+// janitor 定期清理已恢复满容量的记录
+func (l *LocalLimiter) janitor(interval time.Duration) {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stopCh:
+			return
+		case <-ticker.C:
+			l.prune()
+		}
+	}
+}
+
+// prune 删除理论到达时间早于当前时间的记录
+func (l *LocalLimiter) prune() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	removed := 0
+	for key, tat := range l.entries {
+		if !tat.After(now) {
+			delete(l.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,152 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	pkgRedis "distributed-scheduler/pkg/redis"
+)
+
+// setupRedis 使用miniredis替代Redis
+func setupRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	pkgRedis.Client = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		pkgRedis.Client.Close()
+		pkgRedis.Client = nil
+	})
+	return mr
+}
+
+func TestRedisLimiterBurstAndRefill(t *testing.T) {
+	mr := setupRedis(t)
+	now := time.Now()
+	mr.SetTime(now)
+
+	ctx := context.Background()
+	l := NewRedisLimiter()
+	limit := Limit{Rate: 1, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		result, err := l.Allow(ctx, "burst", limit)
+		if err != nil {
+			t.Fatalf("限流失败: %v", err)
+		}
+		if !result.Allowed {
+			t.Fatalf("第%d次请求应在突发容量内", i+1)
+		}
+		if result.Remaining != 2-i {
+			t.Errorf("第%d次请求后剩余应为%d, 实际: %d", i+1, 2-i, result.Remaining)
+		}
+		if result.Limit != 3 {
+			t.Errorf("桶容量应为3, 实际: %d", result.Limit)
+		}
+	}
+
+	result, err := l.Allow(ctx, "burst", limit)
+	if err != nil {
+		t.Fatalf("限流失败: %v", err)
+	}
+	if result.Allowed {
+		t.Fatal("超出突发容量的请求应被拒绝")
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > time.Second {
+		t.Errorf("重试等待应在(0, 1s]内, 实际: %v", result.RetryAfter)
+	}
+	if result.ResetAfter < 2*time.Second || result.ResetAfter > 3*time.Second {
+		t.Errorf("恢复满容量时间应在[2s, 3s]内, 实际: %v", result.ResetAfter)
+	}
+
+	// 其他键互不影响
+	if result, _ := l.Allow(ctx, "other", limit); !result.Allowed {
+		t.Error("其他键的请求应被允许")
+	}
+
+	// 按速率恢复
+	mr.SetTime(now.Add(time.Second))
+	if result, _ := l.Allow(ctx, "burst", limit); !result.Allowed {
+		t.Error("恢复一个请求后应被允许")
+	}
+	if result, _ := l.Allow(ctx, "burst", limit); result.Allowed {
+		t.Error("只恢复了一个请求, 第二个请求应被拒绝")
+	}
+}
+
+func TestRedisLimiterKeyExpires(t *testing.T) {
+	mr := setupRedis(t)
+	ctx := context.Background()
+
+	l := NewRedisLimiter()
+	if _, err := l.Allow(ctx, "ttl", Limit{Rate: 10, Burst: 5}); err != nil {
+		t.Fatalf("限流失败: %v", err)
+	}
+	ttl := mr.TTL(keyPrefix + "ttl")
+	if ttl <= 0 || ttl > 100*time.Millisecond {
+		t.Errorf("限流键应在恢复满容量后过期, 实际TTL: %v", ttl)
+	}
+}
+
+func TestRedisLimiterUnavailable(t *testing.T) {
+	pkgRedis.Client = nil
+	l := NewRedisLimiter()
+	if _, err := l.Allow(context.Background(), "key", Limit{Rate: 1, Burst: 1}); err != ErrRedisUnavailable {
+		t.Errorf("Redis未初始化时应返回ErrRedisUnavailable, 实际: %v", err)
+	}
+}
+
+func TestLocalLimiter(t *testing.T) {
+	l := NewLocalLimiter(time.Hour)
+	defer l.Stop()
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := Limit{Rate: 2, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		if result, _ := l.Allow(ctx, "key", limit); !result.Allowed {
+			t.Fatalf("第%d次请求应在突发容量内", i+1)
+		}
+	}
+	result, _ := l.Allow(ctx, "key", limit)
+	if result.Allowed {
+		t.Fatal("超出突发容量的请求应被拒绝")
+	}
+	if result.RetryAfter != 500*time.Millisecond {
+		t.Errorf("重试等待应为500ms, 实际: %v", result.RetryAfter)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if result, _ := l.Allow(ctx, "key", limit); !result.Allowed {
+		t.Error("恢复一个请求后应被允许")
+	}
+}
+
+func TestLocalLimiterPrune(t *testing.T) {
+	l := NewLocalLimiter(time.Hour)
+	defer l.Stop()
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	l.Allow(ctx, "fast", Limit{Rate: 10, Burst: 10})
+	l.Allow(ctx, "slow", Limit{Rate: 0.1, Burst: 1})
+	if l.Len() != 2 {
+		t.Fatalf("记录数应为2, 实际: %d", l.Len())
+	}
+
+	// fast恢复满容量后被清理, slow仍在限流中
+	now = now.Add(time.Second)
+	if removed := l.prune(); removed != 1 {
+		t.Errorf("应清理1条记录, 实际: %d", removed)
+	}
+	if result, _ := l.Allow(ctx, "slow", Limit{Rate: 0.1, Burst: 1}); result.Allowed {
+		t.Error("清理不应影响仍在限流中的记录")
+	}
+}
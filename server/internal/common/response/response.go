@@ -28,6 +28,7 @@ const (
 	CodeUnauthorized = 401
 	CodeForbidden    = 403
 	CodeNotFound     = 404
+	CodeTooManyReqs  = 429
 	CodeServerError  = 500
 
 	// 业务错误码
@@ -52,6 +53,7 @@ var codeMsg = map[int]string{
 	CodeUnauthorized:   "未授权",
 	CodeForbidden:      "禁止访问",
 	CodeNotFound:       "资源不存在",
+	CodeTooManyReqs:    "请求过于频繁",
 	CodeServerError:    "服务器内部错误",
 	CodeParamError:     "参数错误",
 	CodeUserNotFound:   "用户不存在",
@@ -171,6 +173,17 @@ func NotFound(c *gin.Context, message string) {
 	})
 }
 
+// TooManyRequests 请求过于频繁响应
+func TooManyRequests(c *gin.Context, message string) {
+	if message == "" {
+		message = GetCodeMsg(CodeTooManyReqs)
+	}
+	c.JSON(http.StatusTooManyRequests, Response{
+		Code:    CodeTooManyReqs,
+		Message: message,
+	})
+}
+
 // ServerError 服务器错误响应
 func ServerError(c *gin.Context, message string) {
 	if message == "" {
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 
 	"distributed-scheduler/internal/config"
 )
@@ -14,32 +15,78 @@ var (
 	ErrTokenInvalid = errors.New("token无效")
 )
 
-// Claims JWT声明
+// Token类型
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// 刷新Token默认有效期
+const defaultRefreshExpire = 7 * 24 * time.Hour
+
+// Claims JWT声明, ID(jti)唯一标识一个Token, 用于吊销
 type Claims struct {
-	UserID   uint64 `json:"user_id"`
-	Username string `json:"username"`
-	RoleCode string `json:"role_code"`
+	UserID    uint64 `json:"user_id"`
+	Username  string `json:"username"`
+	RoleCode  string `json:"role_code"`
+	TokenType string `json:"token_type"`
+	// Generation 签发时用户的Token代数, 退出所有会话后代数增加, 之前签发的Token全部失效
+	Generation int64 `json:"generation"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT Token
-func GenerateToken(userID uint64, username, roleCode string) (string, error) {
+// TokenPair 访问Token和刷新Token
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	RefreshToken     string    `json:"refresh_token"`
+	ExpiresAt        time.Time `json:"expires_at"`         // 访问Token过期时间
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"` // 刷新Token过期时间
+}
+
+// GenerateToken 生成访问Token和刷新Token
+func GenerateToken(userID uint64, username, roleCode string, generation int64) (*TokenPair, error) {
 	cfg := config.GetConfig().JWT
+	now := time.Now()
 
-	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		RoleCode: roleCode,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(cfg.Expire) * time.Second)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    cfg.Issuer,
-		},
+	refreshExpire := time.Duration(cfg.RefreshExpire) * time.Second
+	if refreshExpire <= 0 {
+		refreshExpire = defaultRefreshExpire
+	}
+	pair := &TokenPair{
+		ExpiresAt:        now.Add(time.Duration(cfg.Expire) * time.Second),
+		RefreshExpiresAt: now.Add(refreshExpire),
+	}
+
+	var err error
+	newClaims := func(tokenType string, expiresAt time.Time) *Claims {
+		return &Claims{
+			UserID:     userID,
+			Username:   username,
+			RoleCode:   roleCode,
+			TokenType:  tokenType,
+			Generation: generation,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        uuid.NewString(),
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+				IssuedAt:  jwt.NewNumericDate(now),
+				NotBefore: jwt.NewNumericDate(now),
+				Issuer:    cfg.Issuer,
+			},
+		}
 	}
+	if pair.AccessToken, err = signToken(newClaims(TokenTypeAccess, pair.ExpiresAt), cfg.Secret); err != nil {
+		return nil, err
+	}
+	if pair.RefreshToken, err = signToken(newClaims(TokenTypeRefresh, pair.RefreshExpiresAt), cfg.Secret); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
 
+// signToken 签名Token
+func signToken(claims *Claims, secret string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.Secret))
+	return token.SignedString([]byte(secret))
 }
 
 // ParseToken 解析JWT Token
@@ -64,20 +111,20 @@ func ParseToken(tokenString string) (*Claims, error) {
 	return nil, ErrTokenInvalid
 }
 
-// RefreshToken 刷新Token
-func RefreshToken(tokenString string) (string, error) {
+// RefreshToken 使用刷新Token签发新的Token对, 同时返回原刷新Token的声明,
+// 调用方需检查并吊销原刷新Token(轮换), 保证每个刷新Token只能使用一次
+func RefreshToken(tokenString string) (*TokenPair, *Claims, error) {
 	claims, err := ParseToken(tokenString)
-	if err != nil && !errors.Is(err, ErrTokenExpired) {
-		return "", err
+	if err != nil {
+		return nil, nil, err
 	}
-
-	// 检查是否在可刷新期限内(过期后7天内可刷新)
-	if claims.ExpiresAt != nil {
-		if time.Since(claims.ExpiresAt.Time) > 7*24*time.Hour {
-			return "", ErrTokenExpired
-		}
+	if claims.TokenType != TokenTypeRefresh || claims.ID == "" {
+		return nil, nil, ErrTokenInvalid
 	}
 
-	return GenerateToken(claims.UserID, claims.Username, claims.RoleCode)
+	pair, err := GenerateToken(claims.UserID, claims.Username, claims.RoleCode, claims.Generation)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, claims, nil
 }
-
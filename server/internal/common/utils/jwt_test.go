@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"distributed-scheduler/internal/config"
+)
+
+// setupJWT 设置测试用的JWT配置
+func setupJWT(t *testing.T, expire, refreshExpire int64) {
+	t.Helper()
+	prev := config.GlobalConfig
+	config.GlobalConfig = &config.Config{JWT: config.JWTConfig{
+		Secret:        "test-secret",
+		Expire:        expire,
+		RefreshExpire: refreshExpire,
+		Issuer:        "test",
+	}}
+	t.Cleanup(func() { config.GlobalConfig = prev })
+}
+
+func TestGenerateToken(t *testing.T) {
+	setupJWT(t, 900, 3600)
+
+	pair, err := GenerateToken(1, "admin", "SUPER_ADMIN", 3)
+	if err != nil {
+		t.Fatalf("生成Token失败: %v", err)
+	}
+
+	access, err := ParseToken(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("解析访问Token失败: %v", err)
+	}
+	refresh, err := ParseToken(pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("解析刷新Token失败: %v", err)
+	}
+
+	if access.TokenType != TokenTypeAccess || refresh.TokenType != TokenTypeRefresh {
+		t.Errorf("Token类型错误: access=%s, refresh=%s", access.TokenType, refresh.TokenType)
+	}
+	if access.ID == "" || refresh.ID == "" || access.ID == refresh.ID {
+		t.Errorf("每个Token应有唯一的jti: access=%q, refresh=%q", access.ID, refresh.ID)
+	}
+	if access.UserID != 1 || access.Generation != 3 || refresh.Generation != 3 {
+		t.Errorf("声明错误: %+v", access)
+	}
+	if d := time.Until(access.ExpiresAt.Time); d <= 14*time.Minute || d > 15*time.Minute {
+		t.Errorf("访问Token有效期应为15分钟, 实际剩余: %v", d)
+	}
+	if d := time.Until(refresh.ExpiresAt.Time); d <= 59*time.Minute || d > time.Hour {
+		t.Errorf("刷新Token有效期应为1小时, 实际剩余: %v", d)
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	setupJWT(t, 900, 0)
+
+	pair, err := GenerateToken(2, "user", "USER", 1)
+	if err != nil {
+		t.Fatalf("生成Token失败: %v", err)
+	}
+	if d := time.Until(pair.RefreshExpiresAt); d <= 7*24*time.Hour-time.Minute {
+		t.Errorf("未配置时刷新Token有效期应为7天, 实际剩余: %v", d)
+	}
+
+	// 访问Token不能用于刷新
+	if _, _, err := RefreshToken(pair.AccessToken); err != ErrTokenInvalid {
+		t.Errorf("使用访问Token刷新应返回ErrTokenInvalid, 实际: %v", err)
+	}
+
+	next, claims, err := RefreshToken(pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("刷新Token失败: %v", err)
+	}
+	old, _ := ParseToken(pair.RefreshToken)
+	if claims.ID != old.ID {
+		t.Errorf("应返回原刷新Token的声明, 实际jti: %s", claims.ID)
+	}
+	access, err := ParseToken(next.AccessToken)
+	if err != nil {
+		t.Fatalf("解析新的访问Token失败: %v", err)
+	}
+	if access.UserID != 2 || access.RoleCode != "USER" || access.Generation != 1 {
+		t.Errorf("新Token应沿用用户信息和代数: %+v", access)
+	}
+	if next.RefreshToken == pair.RefreshToken {
+		t.Error("刷新后应签发新的刷新Token")
+	}
+}
+
+func TestParseTokenExpired(t *testing.T) {
+	setupJWT(t, -60, 3600)
+
+	pair, err := GenerateToken(1, "admin", "SUPER_ADMIN", 0)
+	if err != nil {
+		t.Fatalf("生成Token失败: %v", err)
+	}
+	if _, err := ParseToken(pair.AccessToken); err != ErrTokenExpired {
+		t.Errorf("过期的Token应返回ErrTokenExpired, 实际: %v", err)
+	}
+	if _, err := ParseToken(pair.AccessToken + "x"); err != ErrTokenInvalid {
+		t.Errorf("签名错误的Token应返回ErrTokenInvalid, 实际: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// 执行器请求签名头, 调度中心调用执行器时使用相同的请求头
+const (
+	HeaderExecutorApp       = "X-Executor-App"       // 任务组应用名称
+	HeaderExecutorTimestamp = "X-Executor-Timestamp" // 请求时间戳(Unix秒)
+	HeaderExecutorNonce     = "X-Executor-Nonce"     // 请求随机串(仅执行器请求), 调度中心拒绝重复使用的随机串
+	HeaderExecutorSignature = "X-Executor-Signature" // 请求签名
+)
+
+// 执行器gRPC请求签名使用的方法, 签名路径为gRPC完整方法名
+const MethodGRPC = "GRPC"
+
+// GenerateAccessToken 生成执行器访问令牌(32字节随机数的十六进制)
+func GenerateAccessToken() (string, error) {
+	return randomHex(32)
+}
+
+// GenerateNonce 生成执行器请求随机串(16字节随机数的十六进制)
+func GenerateNonce() (string, error) {
+	return randomHex(16)
+}
+
+// randomHex 生成n字节随机数的十六进制
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// sign 计算HMAC-SHA256签名的十六进制, 签名内容为依次以换行结尾的各字段加请求体
+func sign(token string, body []byte, fields ...string) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	for _, field := range fields {
+		mac.Write([]byte(field + "\n"))
+	}
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignExecutorRequest 计算执行器请求签名
+// 签名 = hex(HMAC-SHA256(令牌, 应用名称 + "\n" + 时间戳 + "\n" + 随机串 + "\n" + 方法 + "\n" + 路径 + "\n" + 请求体)),
+// 令牌本身不在网络中传输; 签名绑定接口, 截获的请求不能用于其他接口, 随机串防止在时间戳有效期内重放
+func SignExecutorRequest(token, appName, timestamp, nonce, method, path string, body []byte) string {
+	return sign(token, body, appName, timestamp, nonce, method, path)
+}
+
+// VerifyExecutorSignature 校验执行器请求签名(常量时间比较)
+func VerifyExecutorSignature(token, appName, timestamp, nonce, method, path string, body []byte, signature string) bool {
+	if token == "" {
+		return false
+	}
+	expected := SignExecutorRequest(token, appName, timestamp, nonce, method, path, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// SignSchedulerRequest 计算调度中心调用执行器的请求签名
+// 签名 = hex(HMAC-SHA256(令牌, 应用名称 + "\n" + 时间戳 + "\n" + 目标接口 + "\n" + 请求体)),
+// 目标接口为HTTP路径或gRPC完整方法名, 签名不能用于其他接口
+func SignSchedulerRequest(token, appName, timestamp, target string, body []byte) string {
+	return sign(token, body, appName, timestamp, target)
+}
+
+// VerifySchedulerSignature 校验调度中心请求签名
+// 令牌轮换宽限期内调度中心同时使用新旧令牌签名, 以逗号分隔, 任一签名有效即通过
+func VerifySchedulerSignature(token, appName, timestamp, target string, body []byte, signatures string) bool {
+	if token == "" {
+		return false
+	}
+	expected := []byte(SignSchedulerRequest(token, appName, timestamp, target, body))
+	for _, signature := range strings.Split(signatures, ",") {
+		if hmac.Equal(expected, []byte(strings.TrimSpace(signature))) {
+			return true
+		}
+	}
+	return false
+}
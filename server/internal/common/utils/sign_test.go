@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestExecutorSignature(t *testing.T) {
+	body := []byte(`{"instance_id":1}`)
+	signature := SignExecutorRequest("token", "app", "1700000000", "n1", "POST", "/api/v1/executor/heartbeat", body)
+
+	if !VerifyExecutorSignature("token", "app", "1700000000", "n1", "POST", "/api/v1/executor/heartbeat", body, signature) {
+		t.Fatal("正确的签名应校验通过")
+	}
+	cases := []struct {
+		name, token, app, timestamp, nonce, method, path string
+		body                                             []byte
+	}{
+		{"令牌错误", "other", "app", "1700000000", "n1", "POST", "/api/v1/executor/heartbeat", body},
+		{"应用名称错误", "token", "other", "1700000000", "n1", "POST", "/api/v1/executor/heartbeat", body},
+		{"时间戳错误", "token", "app", "1700000001", "n1", "POST", "/api/v1/executor/heartbeat", body},
+		{"随机串错误", "token", "app", "1700000000", "n2", "POST", "/api/v1/executor/heartbeat", body},
+		{"方法错误", "token", "app", "1700000000", "n1", "PUT", "/api/v1/executor/heartbeat", body},
+		{"用于其他接口", "token", "app", "1700000000", "n1", "POST", "/api/v1/executor/unregister", body},
+		{"请求体被篡改", "token", "app", "1700000000", "n1", "POST", "/api/v1/executor/heartbeat", []byte(`{"instance_id":2}`)},
+		{"令牌为空", "", "app", "1700000000", "n1", "POST", "/api/v1/executor/heartbeat", body},
+	}
+	for _, c := range cases {
+		if VerifyExecutorSignature(c.token, c.app, c.timestamp, c.nonce, c.method, c.path, c.body, signature) {
+			t.Errorf("%s: 签名不应校验通过", c.name)
+		}
+	}
+}
+
+func TestSchedulerSignature(t *testing.T) {
+	body := []byte(`{"instance_id":1}`)
+	signature := SignSchedulerRequest("new", "app", "1700000000", "/run", body)
+
+	if VerifySchedulerSignature("new", "app", "1700000000", "/kill", body, signature) {
+		t.Error("签名绑定目标接口, 不能用于其他接口")
+	}
+	if signature == SignExecutorRequest("new", "app", "1700000000", "", "POST", "/run", body) {
+		t.Error("调度中心请求签名不应与执行器请求签名相同")
+	}
+
+	// 令牌轮换宽限期内同时携带新旧令牌的签名, 执行器只持有其中一个令牌
+	both := SignSchedulerRequest("old", "app", "1700000000", "/run", body) + ", " + signature
+	if !VerifySchedulerSignature("new", "app", "1700000000", "/run", body, both) {
+		t.Error("持有新令牌的执行器应校验通过")
+	}
+	if !VerifySchedulerSignature("old", "app", "1700000000", "/run", body, both) {
+		t.Error("持有旧令牌的执行器应校验通过")
+	}
+	if VerifySchedulerSignature("other", "app", "1700000000", "/run", body, both) {
+		t.Error("令牌不匹配时不应校验通过")
+	}
+	if VerifySchedulerSignature("", "app", "1700000000", "/run", body, both) {
+		t.Error("令牌为空时不应校验通过")
+	}
+}
+
+func TestGenerateAccessToken(t *testing.T) {
+	a, err := GenerateAccessToken()
+	if err != nil {
+		t.Fatalf("生成访问令牌失败: %v", err)
+	}
+	b, _ := GenerateAccessToken()
+	if len(a) != 64 || a == b {
+		t.Errorf("访问令牌应为64位十六进制随机串, 实际: %q %q", a, b)
+	}
+
+	n1, err := GenerateNonce()
+	if err != nil {
+		t.Fatalf("生成随机串失败: %v", err)
+	}
+	n2, _ := GenerateNonce()
+	if len(n1) != 32 || n1 == n2 {
+		t.Errorf("随机串应为32位十六进制随机串, 实际: %q %q", n1, n2)
+	}
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
@@ -14,13 +16,15 @@ type Config struct {
 	Log       LogConfig       `mapstructure:"log"`
 	Scheduler SchedulerConfig `mapstructure:"scheduler"`
 	Executor  ExecutorConfig  `mapstructure:"executor"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Mode string `mapstructure:"mode"`
-	Port int    `mapstructure:"port"`
-	Name string `mapstructure:"name"`
+	Mode     string `mapstructure:"mode"`
+	Port     int    `mapstructure:"port"`
+	GRPCPort int    `mapstructure:"grpc_port"` // gRPC服务端口, 供GRPC类型的执行器接入, 0表示不启动
+	Name     string `mapstructure:"name"`
 }
 
 // MySQLConfig MySQL配置
@@ -58,9 +62,11 @@ func (r *RedisConfig) Addr() string {
 
 // JWTConfig JWT配置
 type JWTConfig struct {
-	Secret string `mapstructure:"secret"`
-	Expire int64  `mapstructure:"expire"`
-	Issuer string `mapstructure:"issuer"`
+	Secret        string `mapstructure:"secret"`
+	Expire        int64  `mapstructure:"expire"`         // 访问Token有效期(秒)
+	RefreshExpire int64  `mapstructure:"refresh_expire"` // 刷新Token有效期(秒), 0表示7天
+	RefreshGrace  int64  `mapstructure:"refresh_grace"`  // 刷新Token轮换后再次使用的宽限期(秒), 期间返回已轮换的Token对, 0表示30秒
+	Issuer        string `mapstructure:"issuer"`
 }
 
 // LogConfig 日志配置
@@ -77,16 +83,76 @@ type LogConfig struct {
 
 // SchedulerConfig 调度器配置
 type SchedulerConfig struct {
-	Enable          bool            `mapstructure:"enable"`
-	TimeWheel       TimeWheelConfig `mapstructure:"time_wheel"`
-	TriggerPoolSize int             `mapstructure:"trigger_pool_size"`
-	PreReadTime     int             `mapstructure:"pre_read_time"`
+	Enable          bool                 `mapstructure:"enable"`
+	TimeWheel       TimeWheelConfig      `mapstructure:"time_wheel"`
+	TriggerPoolSize int                  `mapstructure:"trigger_pool_size"`
+	PreReadTime     int                  `mapstructure:"pre_read_time"`
+	ConsistentHash  ConsistentHashConfig `mapstructure:"consistent_hash"`
+	Dispatch        DispatchConfig       `mapstructure:"dispatch"`
+	Quota           QuotaConfig          `mapstructure:"quota"`
+	DelayQueue      DelayQueueConfig     `mapstructure:"delay_queue"`
+	Monitor         MonitorConfig        `mapstructure:"monitor"`
+	HTTPCall        HTTPCallConfig       `mapstructure:"http_call"`
+}
+
+// DispatchConfig 任务分发配置
+type DispatchConfig struct {
+	Interval      int `mapstructure:"interval"`       // 扫描待调度实例的间隔(毫秒)
+	BatchSize     int `mapstructure:"batch_size"`     // 每次扫描的最大实例数
+	QueueSize     int `mapstructure:"queue_size"`     // 分发协程池队列大小
+	AgingInterval int `mapstructure:"aging_interval"` // 优先级老化间隔(秒), 每等待一个间隔优先级加一, 0表示不老化
+	SendRetries   int `mapstructure:"send_retries"`   // 下发超时或网络错误时的重试次数, 为0时使用默认值, 负数表示不重试
+
+	SchedulingTimeout int `mapstructure:"scheduling_timeout"` // 实例抢占后未完成分发的超时时间(秒), 超时后恢复为待调度
 }
 
 // TimeWheelConfig 时间轮配置
 type TimeWheelConfig struct {
-	SlotNum  int `mapstructure:"slot_num"`
-	Interval int `mapstructure:"interval"`
+	SlotNum  int   `mapstructure:"slot_num"`
+	Interval int   `mapstructure:"interval"`
+	Levels   []int `mapstructure:"levels"` // 分层时间轮各层槽数(由低到高), 为空时每层均为SlotNum
+
+	PoolSize  int `mapstructure:"pool_size"`  // 并发执行回调的最大协程数
+	QueueSize int `mapstructure:"queue_size"` // 等待执行的回调队列大小, 队列满时回调以提交失败上报
+}
+
+// QuotaConfig 并发配额配置(任务组和任务的配额在各自定义上配置)
+type QuotaConfig struct {
+	GlobalMaxConcurrent uint `mapstructure:"global_max_concurrent"` // 全局最大并发实例数, 0表示不限制
+	ReconcileInterval   int  `mapstructure:"reconcile_interval"`    // 配额对账间隔(秒)
+}
+
+// DelayQueueConfig 持久化延迟队列配置
+type DelayQueueConfig struct {
+	Window            int `mapstructure:"window"`             // 预读窗口(秒), 窗口内到期的任务放入时间轮
+	VisibilityTimeout int `mapstructure:"visibility_timeout"` // 可见性超时(秒), 到期后未确认的任务会被重新投递
+	PollInterval      int `mapstructure:"poll_interval"`      // 领取间隔(毫秒)
+	BatchSize         int `mapstructure:"batch_size"`         // 每次领取的最大任务数
+	RetryDelay        int `mapstructure:"retry_delay"`        // 处理失败后的重试间隔(秒)
+}
+
+// MonitorConfig 执行器监控配置
+type MonitorConfig struct {
+	Interval         int `mapstructure:"interval"`          // 检查间隔(秒)
+	HeartbeatTimeout int `mapstructure:"heartbeat_timeout"` // 心跳超时(秒), 超时的执行器标记为离线
+	PruneAfter       int `mapstructure:"prune_after"`       // 离线超过该时长(秒)的执行器记录会被删除
+}
+
+// HTTPCallConfig HTTP_CALL类型任务配置
+type HTTPCallConfig struct {
+	PoolSize     int      `mapstructure:"pool_size"`     // 并发请求数
+	QueueSize    int      `mapstructure:"queue_size"`    // 等待请求的队列大小
+	Timeout      int      `mapstructure:"timeout"`       // 任务未配置超时时间时的默认请求超时(秒)
+	MaxBodySize  int      `mapstructure:"max_body_size"` // 读取响应体的最大字节数
+	AllowedHosts []string `mapstructure:"allowed_hosts"` // 允许请求的主机, 为空时不限制
+	AllowPrivate bool     `mapstructure:"allow_private"` // 允许请求回环、链路本地和内网地址, 默认禁止
+}
+
+// ConsistentHashConfig 一致性哈希路由配置
+type ConsistentHashConfig struct {
+	Replicas   int     `mapstructure:"replicas"`
+	HashFunc   string  `mapstructure:"hash_func"`
+	LoadFactor float64 `mapstructure:"load_factor"`
 }
 
 // ExecutorConfig 执行器配置
@@ -99,6 +165,59 @@ type ExecutorConfig struct {
 	MaxConcurrent     int    `mapstructure:"max_concurrent"`
 	LogBatchSize      int    `mapstructure:"log_batch_size"`
 	LogFlushInterval  int    `mapstructure:"log_flush_interval"`
+
+	Auth ExecutorAuthConfig `mapstructure:"auth"`
+}
+
+// ExecutorAuthConfig 执行器接口认证配置(调度中心侧)
+type ExecutorAuthConfig struct {
+	Enable           bool `mapstructure:"enable"`             // 是否校验执行器请求签名, 上线过渡开关, 需在ExecutorAuthDeadline前开启
+	MaxClockSkew     int  `mapstructure:"max_clock_skew"`     // 请求时间戳允许的最大偏差(秒)
+	TokenGracePeriod int  `mapstructure:"token_grace_period"` // 令牌轮换后旧令牌的有效期(秒)
+}
+
+// 执行器认证默认配置
+const (
+	DefaultMaxClockSkew     = 5 * time.Minute
+	DefaultTokenGracePeriod = time.Hour
+)
+
+// ExecutorAuthDeadline 执行器认证的上线截止日期
+// executor.auth.enable只用于已有部署的上线过渡, 所有部署需在此之前开启, 之后发布的版本将移除该开关并始终校验签名
+var ExecutorAuthDeadline = time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// ClockSkew 请求时间戳允许的最大偏差, 未配置时为DefaultMaxClockSkew
+func (c *ExecutorAuthConfig) ClockSkew() time.Duration {
+	if c.MaxClockSkew > 0 {
+		return time.Duration(c.MaxClockSkew) * time.Second
+	}
+	return DefaultMaxClockSkew
+}
+
+// GracePeriod 令牌轮换后旧令牌的有效期, 未配置时为DefaultTokenGracePeriod
+func (c *ExecutorAuthConfig) GracePeriod() time.Duration {
+	if c.TokenGracePeriod > 0 {
+		return time.Duration(c.TokenGracePeriod) * time.Second
+	}
+	return DefaultTokenGracePeriod
+}
+
+// RateLimitConfig 接口限流配置
+type RateLimitConfig struct {
+	Enable          bool            `mapstructure:"enable"`
+	Backend         string          `mapstructure:"backend"`          // redis/local, redis不可用时降级为本地限流
+	JanitorInterval int             `mapstructure:"janitor_interval"` // 本地限流器清理过期记录的间隔(秒)
+	Default         RateLimitRule   `mapstructure:"default"`          // 未匹配路由规则的请求使用的规则
+	Rules           []RateLimitRule `mapstructure:"rules"`            // 路由规则, 按顺序匹配第一条
+}
+
+// RateLimitRule 限流规则
+type RateLimitRule struct {
+	Method string  `mapstructure:"method"` // 请求方法, 为空时匹配所有方法
+	Path   string  `mapstructure:"path"`   // 路由路径(与注册的路由一致, 如/api/v1/task/:id/trigger), 以*结尾时按前缀匹配
+	By     string  `mapstructure:"by"`     // 限流维度: ip/user, user维度未登录时按IP限流
+	Rate   float64 `mapstructure:"rate"`   // 每秒允许的请求数, 0表示不限流
+	Burst  int     `mapstructure:"burst"`  // 允许的突发请求数, 为0时为每秒请求数
 }
 
 // 全局配置实例
@@ -126,4 +245,3 @@ func LoadConfig(configPath string) (*Config, error) {
 func GetConfig() *Config {
 	return GlobalConfig
 }
-
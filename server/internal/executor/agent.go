@@ -0,0 +1,584 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/common/utils"
+	"distributed-scheduler/internal/executor/pool"
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/pkg/logger"
+)
+
+var (
+	ErrHandlerNotFound = errors.New("Handler不存在")
+	ErrStaleDispatch   = errors.New("派发令牌已过期")
+)
+
+// 默认心跳间隔
+const DefaultHeartbeatInterval = 30 * time.Second
+
+// 已结束实例的派发令牌保留时长, 期间收到的重复下发不再执行
+const dispatchDedupTTL = 10 * time.Minute
+
+// 停止时等待队列中和执行中任务完成的默认时间(Stop的ctx未设置截止时间时生效)
+const DefaultShutdownTimeout = 30 * time.Second
+
+// ScriptHandler 脚本任务在隔离舱中的路由key
+const ScriptHandler = "SCRIPT"
+
+// SchedulerClient 调度中心客户端, HTTP协议使用Client, gRPC协议使用GRPCClient
+type SchedulerClient interface {
+	Register(ctx context.Context, reg *Registration) (*model.ExecutorNode, error)
+	Heartbeat(ctx context.Context, heartbeat *model.ExecutorHeartbeat) (*model.ExecutorHeartbeatAck, error)
+	Drain(ctx context.Context, executorID string) error
+	Unregister(ctx context.Context, executorID string) error
+	Callback(ctx context.Context, result *model.ExecutorResult) error
+	ReportLogs(ctx context.Context, logs []*model.TaskLog) (uint64, error)
+}
+
+// Options 执行器配置
+type Options struct {
+	AdminAddress      string            // 调度中心地址, HTTP协议如 http://127.0.0.1:8080, gRPC协议如 127.0.0.1:9091
+	Protocol          string            // 通信协议 HTTP(默认)/GRPC, 与任务的executor_type一致
+	AppName           string            // 任务组应用名称
+	AccessToken       string            // 任务组访问令牌, 同时用于校验调度中心的请求签名; 为空时不校验且不接收脚本任务
+	InstanceID        string            // 实例标识, 为空时按host:port识别执行器
+	Host              string            // 调度中心下发任务时访问的地址
+	Port              uint              // 调度中心下发任务时访问的端口
+	MaxConcurrent     uint              // 最大并发任务数
+	QueueSize         uint              // 等待执行的任务数上限, 为0时与MaxConcurrent相同
+	Bulkheads         []BulkheadOptions // 按Handler隔离的子协程池, 未配置的Handler共用按MaxConcurrent和QueueSize创建的默认子池
+	Labels            map[string]string // 执行器标签
+	HeartbeatInterval time.Duration     // 心跳间隔, 为0时使用DefaultHeartbeatInterval
+	MaxClockSkew      time.Duration     // 调度中心请求时间戳允许的最大偏差, 为0时使用DefaultMaxClockSkew
+	Script            *ScriptOptions    // 脚本任务配置, 为空时不接收SCRIPT类型的任务
+}
+
+// BulkheadOptions 隔离舱子池配置, 子池有独立的并发数和队列, 某个Handler变慢或堆积时不影响其他Handler
+type BulkheadOptions struct {
+	Name          string   // 子池名称
+	Handlers      []string // 路由到该子池的Handler名称, 脚本任务使用ScriptHandler
+	MaxConcurrent uint     // 最大并发任务数
+	QueueSize     uint     // 等待执行的任务数上限, 为0时与MaxConcurrent相同
+}
+
+// runningTask 执行中的任务
+type runningTask struct {
+	cancel    context.CancelFunc
+	startTime time.Time
+	token     uint64
+}
+
+// finishedTask 已结束实例的派发令牌
+type finishedTask struct {
+	token   uint64
+	endTime time.Time
+}
+
+// Agent 执行器
+// 向调度中心注册并定期心跳, 通过ServeHTTP或RegisterGRPC接收调度中心下发的任务, 执行完成后回调上报结果;
+// 摘流时不再被分配新任务, 执行中的任务全部完成并经调度中心确认后Drained()关闭
+type Agent struct {
+	opts     Options
+	client   SchedulerClient
+	handlers map[string]Handler
+	mu       sync.RWMutex
+	script   *scriptRunner
+	pool     *pool.Bulkhead // 按Handler隔离执行任务
+
+	executorID string
+	running    map[uint64]*runningTask  // 执行中的任务, 按实例ID索引
+	finished   map[uint64]*finishedTask // 最近结束的实例, 按实例ID索引, 用于下发去重
+	runningMu  sync.Mutex
+
+	drainStatus int32 // 调度中心记录的摘流状态
+	drained     chan struct{}
+	drainOnce   sync.Once
+
+	beatCh   chan struct{} // 立即心跳信号
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewAgent 创建执行器
+func NewAgent(opts Options) *Agent {
+	if opts.HeartbeatInterval <= 0 {
+		opts.HeartbeatInterval = DefaultHeartbeatInterval
+	}
+	if opts.MaxConcurrent == 0 {
+		opts.MaxConcurrent = 100
+	}
+	if opts.MaxClockSkew <= 0 {
+		opts.MaxClockSkew = DefaultMaxClockSkew
+	}
+
+	var client SchedulerClient
+	if opts.Protocol == model.ExecutorTypeGRPC {
+		client = NewGRPCClient(opts.AdminAddress, opts.AppName, opts.AccessToken, 0)
+	} else {
+		client = NewClient(opts.AdminAddress, opts.AppName, opts.AccessToken, 0)
+	}
+
+	agent := &Agent{
+		opts:     opts,
+		client:   client,
+		handlers: make(map[string]Handler),
+		running:  make(map[uint64]*runningTask),
+		finished: make(map[uint64]*finishedTask),
+		drained:  make(chan struct{}),
+		beatCh:   make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+	}
+	if opts.Script != nil {
+		agent.script = newScriptRunner(*opts.Script, client)
+	}
+	agent.pool = newBulkhead(opts)
+	return agent
+}
+
+// newBulkhead 创建执行任务的隔离舱协程池, 子池配置无效时记录错误, 其Handler使用默认子池
+func newBulkhead(opts Options) *pool.Bulkhead {
+	queueSize := opts.QueueSize
+	if queueSize == 0 {
+		queueSize = opts.MaxConcurrent
+	}
+	bulkhead := pool.NewBulkhead(pool.Options{
+		MaxWorkers: int(opts.MaxConcurrent),
+		QueueSize:  int(queueSize),
+	})
+	for _, b := range opts.Bulkheads {
+		queueSize := b.QueueSize
+		if queueSize == 0 {
+			queueSize = b.MaxConcurrent
+		}
+		err := bulkhead.Add(pool.Options{
+			Name:       b.Name,
+			MaxWorkers: int(b.MaxConcurrent),
+			QueueSize:  int(queueSize),
+		}, b.Handlers...)
+		if err != nil {
+			logger.Errorf("创建隔离舱子池失败, name=%s: %v", b.Name, err)
+		}
+	}
+	return bulkhead
+}
+
+// RegisterHandler 注册任务处理函数, name对应任务的executor_handler
+func (a *Agent) RegisterHandler(name string, handler Handler) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.handlers[name] = handler
+}
+
+// Start 向调度中心注册并启动心跳
+func (a *Agent) Start(ctx context.Context) error {
+	node, err := a.register(ctx)
+	if err != nil {
+		return err
+	}
+	a.executorID = node.ID
+
+	a.wg.Add(1)
+	go a.run()
+	logger.Infof("执行器启动成功, executor_id=%s", a.executorID)
+	return nil
+}
+
+// Stop 停止心跳并注销执行器, 不等待执行中的任务, 需要等待时先调用Drain
+func (a *Agent) Stop(ctx context.Context) error {
+	var err error
+	a.stopOnce.Do(func() {
+		close(a.stopCh)
+		a.wg.Wait()
+		// 等待已接收的任务执行完成并回调结果后再注销
+		timeout := DefaultShutdownTimeout
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout = time.Until(deadline)
+		}
+		if err := a.pool.Shutdown(timeout); err != nil {
+			logger.Warnf("等待执行中的任务完成失败: %v", err)
+		}
+		err = a.client.Unregister(ctx, a.executorID)
+		if closer, ok := a.client.(interface{ Close() error }); ok {
+			closer.Close()
+		}
+		logger.Info("执行器已停止")
+	})
+	return err
+}
+
+// Drain 摘流并等待执行中的任务完成
+// 调度中心不再向该执行器分配新任务, 心跳继续上报, 任务全部完成并经调度中心确认后返回
+func (a *Agent) Drain(ctx context.Context) error {
+	if err := a.client.Drain(ctx, a.executorID); err != nil {
+		return err
+	}
+	atomic.CompareAndSwapInt32(&a.drainStatus, model.DrainStatusNone, model.DrainStatusDraining)
+	a.beat()
+
+	select {
+	case <-a.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Drained 摘流完成后关闭, 管理端发起的摘流也会触发
+func (a *Agent) Drained() <-chan struct{} {
+	return a.drained
+}
+
+// PoolStats 获取各隔离舱子池的统计信息
+func (a *Agent) PoolStats() []pool.Stats {
+	return a.pool.GetStats()
+}
+
+// Inflight 执行中的任务数
+func (a *Agent) Inflight() int64 {
+	a.runningMu.Lock()
+	defer a.runningMu.Unlock()
+	return int64(len(a.running))
+}
+
+// ServeHTTP 处理调度中心的任务下发、终止和探测请求
+// 配置了访问令牌时下发、终止和状态查询请求需携带调度中心的签名, 探测请求不校验
+func (a *Agent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body []byte
+	switch r.URL.Path {
+	case model.ExecutorPathRun, model.ExecutorPathKill, model.ExecutorPathStatus:
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			writeResponse(w, response.CodeParamError, "读取请求体失败", nil)
+			return
+		}
+		if err := a.verify(
+			r.Header.Get(utils.HeaderExecutorApp),
+			r.Header.Get(utils.HeaderExecutorTimestamp),
+			r.Header.Get(utils.HeaderExecutorSignature),
+			r.URL.Path, body); err != nil {
+			writeResponse(w, response.CodeUnauthorized, err.Error(), nil)
+			return
+		}
+	}
+
+	switch r.URL.Path {
+	case model.ExecutorPathRun:
+		var task model.ExecutorTask
+		if err := json.Unmarshal(body, &task); err != nil {
+			writeResponse(w, response.CodeParamError, "无效的任务参数", nil)
+			return
+		}
+		if err := a.Run(&task); err != nil {
+			writeResponse(w, response.CodeError, err.Error(), nil)
+			return
+		}
+		writeResponse(w, response.CodeSuccess, "ok", nil)
+	case model.ExecutorPathKill:
+		var req model.ExecutorKillRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeResponse(w, response.CodeParamError, "无效的实例ID", nil)
+			return
+		}
+		writeResponse(w, response.CodeSuccess, "ok", map[string]bool{"killed": a.Kill(req.InstanceID, req.DispatchToken)})
+	case model.ExecutorPathStatus:
+		var req model.ExecutorInstanceStatus
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeResponse(w, response.CodeParamError, "无效的实例ID", nil)
+			return
+		}
+		writeResponse(w, response.CodeSuccess, "ok", a.Status(req.InstanceID))
+	case model.ExecutorPathBeat:
+		writeResponse(w, response.CodeSuccess, "ok", nil)
+	case model.ExecutorPathIdleBeat:
+		if msg := a.busy(); msg != "" {
+			writeResponse(w, response.CodeError, msg, nil)
+			return
+		}
+		writeResponse(w, response.CodeSuccess, "ok", nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Run 接收任务并提交到Handler对应的隔离舱子池执行, 执行完成后回调上报结果, 子池已满时返回pool.ErrPoolFull
+// 摘流开始前已下发的任务仍然接收, 摘流期间的任务计入执行中任务数;
+// 按派发令牌去重: 相同令牌的重复下发直接返回成功而不再执行, 令牌小于已收到的令牌时返回ErrStaleDispatch,
+// 令牌更大说明调度中心已重新分发, 终止仍在执行的旧令牌任务后执行
+func (a *Agent) Run(task *model.ExecutorTask) error {
+	handler, err := a.handler(task)
+	if err != nil {
+		return err
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if task.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(task.Timeout)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	rt := &runningTask{cancel: cancel, startTime: time.Now(), token: task.DispatchToken}
+	a.runningMu.Lock()
+	if err := a.checkDispatch(task); err != nil {
+		a.runningMu.Unlock()
+		cancel()
+		if errors.Is(err, errDuplicateDispatch) {
+			logger.Infof("忽略重复下发, instance_id=%d, dispatch_token=%d", task.InstanceID, task.DispatchToken)
+			return nil
+		}
+		return err
+	}
+	a.running[task.InstanceID] = rt
+	a.runningMu.Unlock()
+
+	err = a.pool.Submit(bulkheadKey(task), func() {
+		defer a.finish(task.InstanceID, rt)
+		defer cancel()
+
+		result := Execute(ctx, handler, task)
+		result.DispatchToken = task.DispatchToken
+		if err := a.client.Callback(context.Background(), result); err != nil {
+			logger.Errorf("上报执行结果失败, instance_id=%d: %v", task.InstanceID, err)
+		}
+	})
+	if err != nil {
+		// 未执行的任务不记录派发令牌, 调度中心可以用相同令牌重试
+		cancel()
+		a.runningMu.Lock()
+		if a.running[task.InstanceID] == rt {
+			delete(a.running, task.InstanceID)
+		}
+		a.runningMu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// bulkheadKey 任务在隔离舱中的路由key
+func bulkheadKey(task *model.ExecutorTask) string {
+	if task.Script != nil {
+		return ScriptHandler
+	}
+	return task.ExecutorHandler
+}
+
+// errDuplicateDispatch 相同派发令牌的任务已在执行或已执行
+var errDuplicateDispatch = errors.New("重复下发")
+
+// checkDispatch 按派发令牌检查下发是否重复或过期, 新令牌会终止执行中的旧令牌任务; 调用方需持有runningMu
+// 令牌为0(调度中心未启用派发令牌)时不检查
+func (a *Agent) checkDispatch(task *model.ExecutorTask) error {
+	if task.DispatchToken == 0 {
+		return nil
+	}
+	var latest uint64
+	if rt, ok := a.running[task.InstanceID]; ok {
+		latest = rt.token
+	} else if ft, ok := a.finished[task.InstanceID]; ok {
+		latest = ft.token
+	}
+	switch {
+	case task.DispatchToken == latest:
+		return errDuplicateDispatch
+	case task.DispatchToken < latest:
+		return fmt.Errorf("%w: 实例%d已收到令牌%d", ErrStaleDispatch, task.InstanceID, latest)
+	}
+	if rt, ok := a.running[task.InstanceID]; ok {
+		logger.Warnf("实例已重新分发, 终止旧的执行, instance_id=%d, dispatch_token=%d", task.InstanceID, rt.token)
+		rt.cancel()
+	}
+	return nil
+}
+
+// handler 获取任务的处理函数, 脚本任务由脚本执行器处理
+// 未配置访问令牌时无法确认脚本来自调度中心, 拒绝脚本任务
+func (a *Agent) handler(task *model.ExecutorTask) (Handler, error) {
+	if task.Script != nil {
+		if a.script == nil {
+			return nil, ErrScriptDisabled
+		}
+		if a.opts.AccessToken == "" {
+			return nil, ErrScriptUnsigned
+		}
+		if _, err := a.script.interpreter(task.Script.ScriptType); err != nil {
+			return nil, err
+		}
+		return a.script.Handle, nil
+	}
+
+	a.mu.RLock()
+	handler, ok := a.handlers[task.ExecutorHandler]
+	a.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrHandlerNotFound, task.ExecutorHandler)
+	}
+	return handler, nil
+}
+
+// Kill 取消执行中任务的上下文, 返回是否已终止
+// dispatchToken不为0时只终止该派发令牌的执行, 实例已按新令牌重新下发到本执行器时忽略旧令牌的终止请求
+func (a *Agent) Kill(instanceID, dispatchToken uint64) bool {
+	a.runningMu.Lock()
+	defer a.runningMu.Unlock()
+	task, ok := a.running[instanceID]
+	if !ok {
+		return false
+	}
+	if dispatchToken != 0 && task.token != dispatchToken {
+		logger.Infof("忽略令牌不一致的终止请求, instance_id=%d, dispatch_token=%d, 执行中令牌=%d", instanceID, dispatchToken, task.token)
+		return false
+	}
+	task.cancel()
+	return true
+}
+
+// Status 查询实例的执行状态
+func (a *Agent) Status(instanceID uint64) *model.ExecutorInstanceStatus {
+	a.runningMu.Lock()
+	defer a.runningMu.Unlock()
+	status := &model.ExecutorInstanceStatus{
+		InstanceID:  instanceID,
+		CurrentLoad: uint(len(a.running)),
+	}
+	if task, ok := a.running[instanceID]; ok {
+		status.Running = true
+		status.StartTime = task.startTime.UnixMilli()
+	}
+	return status
+}
+
+// busy 空闲检查, 摘流中或达到最大并发时返回原因
+func (a *Agent) busy() string {
+	if a.isDraining() {
+		return "执行器摘流中"
+	}
+	if a.Inflight() >= int64(a.opts.MaxConcurrent) {
+		return "执行器忙碌"
+	}
+	return ""
+}
+
+// finish 任务结束并记录派发令牌, 摘流中的最后一个任务结束时立即心跳以尽快完成摘流
+// 实例已被新令牌的任务替换时只记录令牌
+func (a *Agent) finish(instanceID uint64, rt *runningTask) {
+	a.runningMu.Lock()
+	if a.running[instanceID] == rt {
+		delete(a.running, instanceID)
+		if rt.token > 0 {
+			a.finished[instanceID] = &finishedTask{token: rt.token, endTime: time.Now()}
+		}
+	}
+	remaining := len(a.running)
+	a.runningMu.Unlock()
+
+	if remaining == 0 && a.isDraining() {
+		a.beat()
+	}
+}
+
+// run 心跳循环
+func (a *Agent) run() {
+	defer a.wg.Done()
+
+	ticker := time.NewTicker(a.opts.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-a.beatCh:
+		case <-a.stopCh:
+			return
+		}
+		a.heartbeat(context.Background())
+		a.pruneFinished()
+	}
+}
+
+// pruneFinished 清理超过保留时长的已结束实例令牌
+func (a *Agent) pruneFinished() {
+	expire := time.Now().Add(-dispatchDedupTTL)
+	a.runningMu.Lock()
+	defer a.runningMu.Unlock()
+	for id, ft := range a.finished {
+		if ft.endTime.Before(expire) {
+			delete(a.finished, id)
+		}
+	}
+}
+
+// heartbeat 上报心跳并同步调度中心的摘流状态, 执行器被清理时重新注册
+func (a *Agent) heartbeat(ctx context.Context) {
+	inflight := a.Inflight()
+	ack, err := a.client.Heartbeat(ctx, &model.ExecutorHeartbeat{
+		ExecutorID:  a.executorID,
+		InstanceID:  a.opts.InstanceID,
+		AppName:     a.opts.AppName,
+		Host:        a.opts.Host,
+		Port:        a.opts.Port,
+		CurrentLoad: uint(inflight),
+		ReadyToStop: a.isDraining() && inflight == 0,
+	})
+	if errors.Is(err, ErrNotRegistered) && !a.isDraining() {
+		// 执行器ID由应用名称和实例标识确定, 重新注册后保持不变
+		_, err = a.register(ctx)
+	}
+	if err != nil {
+		logger.Errorf("执行器心跳失败: %v", err)
+		return
+	}
+	if ack == nil {
+		return
+	}
+
+	atomic.StoreInt32(&a.drainStatus, int32(ack.DrainStatus))
+	if ack.DrainStatus == model.DrainStatusDrained {
+		a.drainOnce.Do(func() {
+			close(a.drained)
+			logger.Info("执行器摘流完成, 可以停止")
+		})
+	}
+}
+
+// register 注册执行器
+func (a *Agent) register(ctx context.Context) (*model.ExecutorNode, error) {
+	return a.client.Register(ctx, &Registration{
+		AppName:       a.opts.AppName,
+		InstanceID:    a.opts.InstanceID,
+		Host:          a.opts.Host,
+		Port:          a.opts.Port,
+		MaxConcurrent: a.opts.MaxConcurrent,
+		Labels:        a.opts.Labels,
+	})
+}
+
+// beat 触发一次立即心跳
+func (a *Agent) beat() {
+	select {
+	case a.beatCh <- struct{}{}:
+	default:
+	}
+}
+
+// isDraining 是否处于摘流中
+func (a *Agent) isDraining() bool {
+	return atomic.LoadInt32(&a.drainStatus) != model.DrainStatusNone
+}
+
+// writeResponse 写入统一格式的响应
+func writeResponse(w http.ResponseWriter, code int, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response.Response{Code: code, Message: message, Data: data})
+}
@@ -0,0 +1,194 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"distributed-scheduler/internal/executor/pool"
+	"distributed-scheduler/internal/model"
+)
+
+// waitCallback 等待一次回调
+func waitCallback(t *testing.T, client *fakeClient) {
+	t.Helper()
+	select {
+	case <-client.done:
+	case <-time.After(time.Second):
+		t.Fatal("callback not received")
+	}
+}
+
+func TestRunUsesBulkhead(t *testing.T) {
+	agent, client := newTestAgent(Options{
+		MaxConcurrent: 1,
+		Bulkheads:     []BulkheadOptions{{Name: "slow", Handlers: []string{"slow"}, MaxConcurrent: 1}},
+	})
+	started, release := make(chan struct{}, 3), make(chan struct{})
+	agent.RegisterHandler("slow", func(ctx context.Context, task *model.ExecutorTask) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+	agent.RegisterHandler("fast", func(ctx context.Context, task *model.ExecutorTask) error { return nil })
+
+	slow := func(id uint64) *model.ExecutorTask {
+		return &model.ExecutorTask{InstanceID: id, ExecutorHandler: "slow", DispatchToken: 1}
+	}
+	// slow子池: 一个执行中, 一个排队, 之后的任务被拒绝
+	if err := agent.Run(slow(1)); err != nil {
+		t.Fatalf("Run slow 1: %v", err)
+	}
+	<-started
+	if err := agent.Run(slow(2)); err != nil {
+		t.Fatalf("Run slow 2: %v", err)
+	}
+	if err := agent.Run(slow(3)); !errors.Is(err, pool.ErrPoolFull) {
+		t.Fatalf("Run on full bulkhead: err = %v, want ErrPoolFull", err)
+	}
+
+	// 其他Handler使用默认子池, 不受slow子池影响
+	if err := agent.Run(&model.ExecutorTask{InstanceID: 4, ExecutorHandler: "fast", DispatchToken: 1}); err != nil {
+		t.Fatalf("Run fast: %v", err)
+	}
+	waitCallback(t, client)
+
+	close(release)
+	waitCallback(t, client)
+	waitCallback(t, client)
+
+	// 被拒绝的下发没有记录令牌, 调度中心以相同令牌重试时正常执行
+	if err := agent.Run(slow(3)); err != nil {
+		t.Fatalf("retry rejected dispatch: %v", err)
+	}
+	waitCallback(t, client)
+	if n := agent.Inflight(); n != 0 {
+		t.Fatalf("inflight = %d after all tasks finished", n)
+	}
+}
+
+func TestRunDedupsDispatchToken(t *testing.T) {
+	agent, client := newTestAgent(Options{MaxConcurrent: 4})
+	started := make(chan context.Context, 4)
+	agent.RegisterHandler("block", func(ctx context.Context, task *model.ExecutorTask) error {
+		started <- ctx
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	task := func(token uint64) *model.ExecutorTask {
+		return &model.ExecutorTask{InstanceID: 1, ExecutorHandler: "block", DispatchToken: token}
+	}
+
+	if err := agent.Run(task(1)); err != nil {
+		t.Fatalf("Run token 1: %v", err)
+	}
+	first := <-started
+	// 相同令牌重复下发时忽略, 不重复执行
+	if err := agent.Run(task(1)); err != nil {
+		t.Fatalf("duplicate dispatch: err = %v, want nil", err)
+	}
+	select {
+	case <-started:
+		t.Fatal("duplicate dispatch should not start another execution")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 新令牌终止旧令牌的执行
+	if err := agent.Run(task(2)); err != nil {
+		t.Fatalf("Run token 2: %v", err)
+	}
+	select {
+	case <-first.Done():
+	case <-time.After(time.Second):
+		t.Fatal("newer dispatch token should cancel the old execution")
+	}
+	second := <-started
+	waitCallback(t, client)
+
+	// 旧令牌晚到时拒绝
+	if err := agent.Run(task(1)); !errors.Is(err, ErrStaleDispatch) {
+		t.Fatalf("stale dispatch: err = %v, want ErrStaleDispatch", err)
+	}
+	// 终止请求只作用于令牌一致的执行
+	if agent.Kill(1, 1) {
+		t.Fatal("kill with a stale token should be ignored")
+	}
+	select {
+	case <-second.Done():
+		t.Fatal("kill with a stale token cancelled the current execution")
+	default:
+	}
+	if !agent.Kill(1, 2) {
+		t.Fatal("kill with the current token should cancel the execution")
+	}
+	<-second.Done()
+	waitCallback(t, client)
+	// 执行结束后相同令牌仍视为重复
+	if err := agent.Run(task(2)); err != nil {
+		t.Fatalf("duplicate dispatch after finish: err = %v, want nil", err)
+	}
+	select {
+	case <-started:
+		t.Fatal("finished dispatch token should not run again")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// drainClient 模拟调度中心的摘流状态: 摘流中的执行器上报ReadyToStop后确认摘流完成
+type drainClient struct {
+	*fakeClient
+	drainStatus int8
+	heartbeats  []*model.ExecutorHeartbeat
+}
+
+func (c *drainClient) Heartbeat(ctx context.Context, heartbeat *model.ExecutorHeartbeat) (*model.ExecutorHeartbeatAck, error) {
+	c.heartbeats = append(c.heartbeats, heartbeat)
+	if heartbeat.ReadyToStop && c.drainStatus == model.DrainStatusDraining {
+		c.drainStatus = model.DrainStatusDrained
+	}
+	return &model.ExecutorHeartbeatAck{DrainStatus: c.drainStatus}, nil
+}
+
+func TestHeartbeatFollowsAdminDrain(t *testing.T) {
+	agent, fake := newTestAgent(Options{MaxConcurrent: 2})
+	client := &drainClient{fakeClient: fake}
+	agent.client = client
+	release := make(chan struct{})
+	agent.RegisterHandler("demo", func(ctx context.Context, task *model.ExecutorTask) error {
+		<-release
+		return nil
+	})
+	if err := agent.Run(&model.ExecutorTask{InstanceID: 1, ExecutorHandler: "demo", DispatchToken: 1}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// 管理端发起摘流, 执行器通过心跳应答得知摘流状态
+	client.drainStatus = model.DrainStatusDraining
+	agent.heartbeat(context.Background())
+	if reason := agent.busy(); reason == "" {
+		t.Fatal("draining agent should refuse new tasks")
+	}
+	agent.heartbeat(context.Background())
+	if client.heartbeats[1].ReadyToStop {
+		t.Fatal("ReadyToStop reported while a task is still running")
+	}
+
+	close(release)
+	waitCallback(t, fake)
+	// 回调先于任务结束上报, 等待任务移出执行中列表
+	for deadline := time.Now().Add(time.Second); agent.Inflight() > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("task still inflight after callback")
+		}
+	}
+	agent.heartbeat(context.Background())
+	if !client.heartbeats[2].ReadyToStop {
+		t.Fatal("ReadyToStop not reported after the last task finished")
+	}
+	select {
+	case <-agent.Drained():
+	case <-time.After(time.Second):
+		t.Fatal("Drained not closed after the scheduler confirmed the drain")
+	}
+}
@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	executorv1 "distributed-scheduler/api/executor/v1"
+	"distributed-scheduler/internal/common/utils"
+)
+
+var (
+	ErrUnauthorized   = errors.New("调度中心请求认证失败")
+	ErrScriptUnsigned = errors.New("执行器未配置访问令牌, 不接收脚本任务")
+)
+
+// 调度中心请求时间戳允许的默认最大偏差
+const DefaultMaxClockSkew = 5 * time.Minute
+
+// verify 校验调度中心请求签名, 签名算法见 utils.SignSchedulerRequest; 未配置访问令牌时不校验
+// target为HTTP路径或gRPC完整方法名
+func (a *Agent) verify(appName, timestamp, signature, target string, body []byte) error {
+	if a.opts.AccessToken == "" {
+		return nil
+	}
+	if appName != a.opts.AppName {
+		return fmt.Errorf("%w: 应用名称不匹配", ErrUnauthorized)
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: 无效的时间戳", ErrUnauthorized)
+	}
+	// 限制时间戳范围, 防止截获的请求被重放
+	if skew := time.Since(time.Unix(ts, 0)); skew > a.opts.MaxClockSkew || skew < -a.opts.MaxClockSkew {
+		return fmt.Errorf("%w: 时间戳超出允许范围", ErrUnauthorized)
+	}
+	if !utils.VerifySchedulerSignature(a.opts.AccessToken, appName, timestamp, target, body, signature) {
+		return fmt.Errorf("%w: 签名错误", ErrUnauthorized)
+	}
+	return nil
+}
+
+// grpcAuthInterceptor 校验gRPC请求签名, 签名内容为完整方法名和确定性序列化的请求消息
+// 探测接口(Beat)不校验, 调度中心按执行器地址探测时不区分任务组
+func (a *Agent) grpcAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if info.FullMethod == executorv1.ExecutorService_Beat_FullMethodName || a.opts.AccessToken == "" {
+		return handler(ctx, req)
+	}
+
+	message, ok := req.(proto.Message)
+	if !ok {
+		return nil, status.Error(codes.Internal, "无效的请求消息")
+	}
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if err := a.verify(
+		firstValue(md, utils.HeaderExecutorApp),
+		firstValue(md, utils.HeaderExecutorTimestamp),
+		firstValue(md, utils.HeaderExecutorSignature),
+		info.FullMethod, body); err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return handler(ctx, req)
+}
+
+// firstValue 获取metadata中键的第一个值, 键不区分大小写
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
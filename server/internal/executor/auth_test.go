@@ -0,0 +1,192 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	executorv1 "distributed-scheduler/api/executor/v1"
+	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/common/utils"
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/pkg/logger"
+)
+
+const (
+	testAppName = "test-app"
+	testToken   = "test-token"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	logger.Sugar = logger.Logger.Sugar()
+	os.Exit(m.Run())
+}
+
+// fakeClient 记录回调结果的调度中心客户端
+type fakeClient struct {
+	mu      sync.Mutex
+	results []*model.ExecutorResult
+	done    chan struct{}
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{done: make(chan struct{}, 16)}
+}
+
+func (c *fakeClient) Register(ctx context.Context, reg *Registration) (*model.ExecutorNode, error) {
+	return &model.ExecutorNode{ID: "test"}, nil
+}
+
+func (c *fakeClient) Heartbeat(ctx context.Context, heartbeat *model.ExecutorHeartbeat) (*model.ExecutorHeartbeatAck, error) {
+	return &model.ExecutorHeartbeatAck{}, nil
+}
+
+func (c *fakeClient) Drain(ctx context.Context, executorID string) error      { return nil }
+func (c *fakeClient) Unregister(ctx context.Context, executorID string) error { return nil }
+
+func (c *fakeClient) Callback(ctx context.Context, result *model.ExecutorResult) error {
+	c.mu.Lock()
+	c.results = append(c.results, result)
+	c.mu.Unlock()
+	c.done <- struct{}{}
+	return nil
+}
+
+func (c *fakeClient) ReportLogs(ctx context.Context, logs []*model.TaskLog) (uint64, error) {
+	return uint64(len(logs)), nil
+}
+
+// newTestAgent 创建使用fakeClient的执行器
+func newTestAgent(opts Options) (*Agent, *fakeClient) {
+	opts.AppName = testAppName
+	agent := NewAgent(opts)
+	client := newFakeClient()
+	agent.client = client
+	if agent.script != nil {
+		agent.script.client = client
+	}
+	return agent, client
+}
+
+// serve 发送HTTP请求, sign为true时使用testToken签名
+func serve(agent *Agent, path string, body interface{}, sign bool, signPath string) response.Response {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	if sign {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(utils.HeaderExecutorApp, testAppName)
+		req.Header.Set(utils.HeaderExecutorTimestamp, timestamp)
+		req.Header.Set(utils.HeaderExecutorSignature, utils.SignSchedulerRequest(testToken, testAppName, timestamp, signPath, payload))
+	}
+	rec := httptest.NewRecorder()
+	agent.ServeHTTP(rec, req)
+
+	var resp response.Response
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	return resp
+}
+
+func TestServeHTTPRequiresSignature(t *testing.T) {
+	agent, client := newTestAgent(Options{AccessToken: testToken})
+	agent.RegisterHandler("echo", func(ctx context.Context, task *model.ExecutorTask) error { return nil })
+	task := &model.ExecutorTask{InstanceID: 1, ExecutorHandler: "echo", DispatchToken: 1}
+
+	if resp := serve(agent, model.ExecutorPathRun, task, false, ""); resp.Code != response.CodeUnauthorized {
+		t.Fatalf("unsigned run: code = %d, want %d", resp.Code, response.CodeUnauthorized)
+	}
+	// 签名绑定接口路径, 其他接口的签名不能用于下发任务
+	if resp := serve(agent, model.ExecutorPathRun, task, true, model.ExecutorPathStatus); resp.Code != response.CodeUnauthorized {
+		t.Fatalf("run signed for /status: code = %d, want %d", resp.Code, response.CodeUnauthorized)
+	}
+	if resp := serve(agent, model.ExecutorPathRun, task, true, model.ExecutorPathRun); resp.Code != response.CodeSuccess {
+		t.Fatalf("signed run: code = %d, message = %s", resp.Code, resp.Message)
+	}
+	select {
+	case <-client.done:
+	case <-time.After(time.Second):
+		t.Fatal("task was not executed")
+	}
+
+	// 探测接口不校验签名
+	if resp := serve(agent, model.ExecutorPathBeat, struct{}{}, false, ""); resp.Code != response.CodeSuccess {
+		t.Fatalf("beat: code = %d", resp.Code)
+	}
+}
+
+func TestServeHTTPRejectsStaleTimestamp(t *testing.T) {
+	agent, _ := newTestAgent(Options{AccessToken: testToken, MaxClockSkew: time.Minute})
+
+	payload, _ := json.Marshal(model.ExecutorInstanceStatus{InstanceID: 1})
+	timestamp := strconv.FormatInt(time.Now().Add(-2*time.Minute).Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, model.ExecutorPathKill, bytes.NewReader(payload))
+	req.Header.Set(utils.HeaderExecutorApp, testAppName)
+	req.Header.Set(utils.HeaderExecutorTimestamp, timestamp)
+	req.Header.Set(utils.HeaderExecutorSignature, utils.SignSchedulerRequest(testToken, testAppName, timestamp, model.ExecutorPathKill, payload))
+	rec := httptest.NewRecorder()
+	agent.ServeHTTP(rec, req)
+
+	var resp response.Response
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Code != response.CodeUnauthorized {
+		t.Fatalf("stale timestamp: code = %d, want %d", resp.Code, response.CodeUnauthorized)
+	}
+}
+
+func TestScriptRequiresAccessToken(t *testing.T) {
+	agent, _ := newTestAgent(Options{Script: &ScriptOptions{}})
+	task := &model.ExecutorTask{
+		InstanceID: 1,
+		Script:     &model.ExecutorScript{ScriptType: model.ScriptTypeSh, Content: "echo hi"},
+	}
+	if resp := serve(agent, model.ExecutorPathRun, task, false, ""); resp.Code != response.CodeError || resp.Message != ErrScriptUnsigned.Error() {
+		t.Fatalf("unsigned script: code = %d, message = %s", resp.Code, resp.Message)
+	}
+}
+
+func TestGRPCAuthInterceptor(t *testing.T) {
+	agent, _ := newTestAgent(Options{AccessToken: testToken})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	kill := &grpc.UnaryServerInfo{FullMethod: executorv1.ExecutorService_Kill_FullMethodName}
+	req := &executorv1.KillRequest{InstanceId: 1}
+
+	signed := func(method string, msg proto.Message) context.Context {
+		body, _ := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+			utils.HeaderExecutorApp, testAppName,
+			utils.HeaderExecutorTimestamp, timestamp,
+			// 宽限期内调度中心同时携带新旧令牌的签名
+			utils.HeaderExecutorSignature, "stale,"+utils.SignSchedulerRequest(testToken, testAppName, timestamp, method, body),
+		))
+	}
+
+	if _, err := agent.grpcAuthInterceptor(context.Background(), req, kill, handler); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("unsigned kill: err = %v, want Unauthenticated", err)
+	}
+	if _, err := agent.grpcAuthInterceptor(signed(kill.FullMethod, &executorv1.KillRequest{InstanceId: 2}), req, kill, handler); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("kill signed for another instance: err = %v, want Unauthenticated", err)
+	}
+	if _, err := agent.grpcAuthInterceptor(signed(kill.FullMethod, req), req, kill, handler); err != nil {
+		t.Fatalf("signed kill: %v", err)
+	}
+
+	beat := &grpc.UnaryServerInfo{FullMethod: executorv1.ExecutorService_Beat_FullMethodName}
+	if _, err := agent.grpcAuthInterceptor(context.Background(), &executorv1.BeatRequest{}, beat, handler); err != nil {
+		t.Fatalf("beat: %v", err)
+	}
+}
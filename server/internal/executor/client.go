@@ -0,0 +1,151 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/common/utils"
+	"distributed-scheduler/internal/model"
+)
+
+var (
+	ErrNotRegistered = errors.New("执行器未注册或已被清理")
+)
+
+// 默认请求超时时间
+const DefaultRequestTimeout = 5 * time.Second
+
+// Client 调度中心执行器接口客户端, 请求使用任务组访问令牌签名
+type Client struct {
+	baseURL     string
+	appName     string
+	accessToken string
+	client      *http.Client
+}
+
+// NewClient 创建调度中心客户端, adminAddress如 http://127.0.0.1:8080
+func NewClient(adminAddress, appName, accessToken string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = DefaultRequestTimeout
+	}
+	return &Client{
+		baseURL:     strings.TrimRight(adminAddress, "/") + "/api/v1/executor",
+		appName:     appName,
+		accessToken: accessToken,
+		client:      &http.Client{Timeout: timeout},
+	}
+}
+
+// Registration 注册参数
+type Registration struct {
+	AppName       string            `json:"app_name"`
+	InstanceID    string            `json:"instance_id,omitempty"`
+	Host          string            `json:"host"`
+	Port          uint              `json:"port"`
+	MaxConcurrent uint              `json:"max_concurrent"`
+	Labels        map[string]string `json:"labels,omitempty"`
+}
+
+// Register 注册执行器
+func (c *Client) Register(ctx context.Context, reg *Registration) (*model.ExecutorNode, error) {
+	var node model.ExecutorNode
+	if err := c.post(ctx, "/register", reg, &node); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// Heartbeat 上报心跳, 返回调度中心记录的摘流状态
+func (c *Client) Heartbeat(ctx context.Context, heartbeat *model.ExecutorHeartbeat) (*model.ExecutorHeartbeatAck, error) {
+	var ack model.ExecutorHeartbeatAck
+	if err := c.post(ctx, "/heartbeat", heartbeat, &ack); err != nil {
+		return nil, err
+	}
+	return &ack, nil
+}
+
+// Drain 请求调度中心停止向执行器分配新任务
+func (c *Client) Drain(ctx context.Context, executorID string) error {
+	return c.post(ctx, "/drain", map[string]string{"executor_id": executorID}, nil)
+}
+
+// Unregister 注销执行器
+func (c *Client) Unregister(ctx context.Context, executorID string) error {
+	return c.post(ctx, "/unregister", map[string]string{"executor_id": executorID}, nil)
+}
+
+// Callback 上报执行结果
+func (c *Client) Callback(ctx context.Context, result *model.ExecutorResult) error {
+	return c.post(ctx, "/callback", result, nil)
+}
+
+// ReportLogs 上报执行日志, 返回调度中心保存的条数
+func (c *Client) ReportLogs(ctx context.Context, logs []*model.TaskLog) (uint64, error) {
+	entries := make([]*model.ExecutorLog, 0, len(logs))
+	for _, log := range logs {
+		entries = append(entries, &model.ExecutorLog{
+			InstanceID: log.InstanceID,
+			LogTime:    log.LogTime.UnixMilli(),
+			LogLevel:   log.LogLevel,
+			LogContent: log.LogContent,
+		})
+	}
+
+	var result struct {
+		Saved uint64 `json:"saved"`
+	}
+	if err := c.post(ctx, "/log", map[string]interface{}{"logs": entries}, &result); err != nil {
+		return 0, err
+	}
+	return result.Saved, nil
+}
+
+// post 发送签名请求, 业务码非0时返回错误, 执行器不存在时返回ErrNotRegistered
+func (c *Client) post(ctx context.Context, path string, body, data interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	nonce, err := utils.GenerateNonce()
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(utils.HeaderExecutorApp, c.appName)
+	req.Header.Set(utils.HeaderExecutorTimestamp, timestamp)
+	req.Header.Set(utils.HeaderExecutorNonce, nonce)
+	req.Header.Set(utils.HeaderExecutorSignature,
+		utils.SignExecutorRequest(c.accessToken, c.appName, timestamp, nonce, http.MethodPost, req.URL.Path, payload))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	result := response.Response{Data: data}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("HTTP %d, 响应解析失败: %w", resp.StatusCode, err)
+	}
+	if result.Code == response.CodeNotFound {
+		return fmt.Errorf("%w: %s", ErrNotRegistered, result.Message)
+	}
+	if result.Code != response.CodeSuccess {
+		return fmt.Errorf("%s失败: %s", path, result.Message)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/executor/pool"
+	"distributed-scheduler/internal/model"
+)
+
+// Handler 任务处理函数, 返回error表示执行失败
+type Handler func(ctx context.Context, task *model.ExecutorTask) error
+
+// Execute 调用处理函数并转换为执行结果
+// 处理函数panic时不会向上传播, 而是返回附带调用栈的失败结果
+func Execute(ctx context.Context, handler Handler, task *model.ExecutorTask) (result *model.ExecutorResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = PanicResult(task.InstanceID, &pool.PanicError{Value: r, Stack: debug.Stack()})
+		}
+	}()
+
+	if err := handler(ctx, task); err != nil {
+		return &model.ExecutorResult{
+			InstanceID: task.InstanceID,
+			Code:       response.CodeError,
+			Message:    err.Error(),
+		}
+	}
+	return &model.ExecutorResult{
+		InstanceID: task.InstanceID,
+		Code:       response.CodeSuccess,
+		Message:    "执行成功",
+	}
+}
+
+// PanicResult 将panic转换为失败的执行结果
+func PanicResult(instanceID uint64, err *pool.PanicError) *model.ExecutorResult {
+	return &model.ExecutorResult{
+		InstanceID: instanceID,
+		Code:       response.CodeError,
+		Message:    fmt.Sprintf("执行器Handler发生panic: %v", err.Value),
+		Stack:      string(err.Stack),
+	}
+}
@@ -0,0 +1,33 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/model"
+)
+
+func TestExecute(t *testing.T) {
+	task := &model.ExecutorTask{InstanceID: 7}
+
+	result := Execute(context.Background(), func(ctx context.Context, task *model.ExecutorTask) error { return nil }, task)
+	if result.InstanceID != 7 || result.Code != response.CodeSuccess {
+		t.Fatalf("success result = %+v", result)
+	}
+
+	result = Execute(context.Background(), func(ctx context.Context, task *model.ExecutorTask) error { return errors.New("bad input") }, task)
+	if result.Code != response.CodeError || result.Message != "bad input" {
+		t.Fatalf("error result = %+v", result)
+	}
+
+	result = Execute(context.Background(), func(ctx context.Context, task *model.ExecutorTask) error { panic("boom") }, task)
+	if result.InstanceID != 7 || result.Code != response.CodeError {
+		t.Fatalf("panic result = %+v", result)
+	}
+	if !strings.Contains(result.Message, "boom") || !strings.Contains(result.Stack, "executor_test.go") {
+		t.Fatalf("panic result should carry the value and the handler stack, got message %q, stack %q", result.Message, result.Stack)
+	}
+}
@@ -0,0 +1,289 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	executorv1 "distributed-scheduler/api/executor/v1"
+	"distributed-scheduler/internal/common/utils"
+	"distributed-scheduler/internal/model"
+)
+
+// GRPCClient 基于gRPC协议的调度中心客户端
+// 每次调用在metadata中携带访问令牌对完整方法名的签名, 心跳复用同一个双向流
+type GRPCClient struct {
+	address     string
+	appName     string
+	accessToken string
+	timeout     time.Duration
+
+	conn   *grpc.ClientConn
+	client executorv1.SchedulerServiceClient
+	connMu sync.Mutex
+
+	stream   executorv1.SchedulerService_HeartbeatClient
+	streamMu sync.Mutex
+}
+
+// NewGRPCClient 创建gRPC调度中心客户端, address如 127.0.0.1:9091, 连接在首次调用时建立
+func NewGRPCClient(address, appName, accessToken string, timeout time.Duration) *GRPCClient {
+	if timeout <= 0 {
+		timeout = DefaultRequestTimeout
+	}
+	return &GRPCClient{
+		address:     address,
+		appName:     appName,
+		accessToken: accessToken,
+		timeout:     timeout,
+	}
+}
+
+// Register 注册执行器
+func (c *GRPCClient) Register(ctx context.Context, reg *Registration) (*model.ExecutorNode, error) {
+	client, err := c.getClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := client.Register(ctx, &executorv1.RegisterRequest{
+		AppName:       reg.AppName,
+		InstanceId:    reg.InstanceID,
+		Host:          reg.Host,
+		Port:          uint32(reg.Port),
+		MaxConcurrent: uint32(reg.MaxConcurrent),
+		Labels:        reg.Labels,
+	})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return &model.ExecutorNode{
+		ID:            resp.GetExecutorId(),
+		GroupID:       resp.GetGroupId(),
+		AppName:       reg.AppName,
+		Host:          reg.Host,
+		Port:          reg.Port,
+		MaxConcurrent: reg.MaxConcurrent,
+		Labels:        reg.Labels,
+		Status:        model.ExecutorStatusOnline,
+	}, nil
+}
+
+// Heartbeat 通过心跳流上报心跳并等待调度中心的响应, 流断开时下次心跳重新建立
+func (c *GRPCClient) Heartbeat(ctx context.Context, heartbeat *model.ExecutorHeartbeat) (*model.ExecutorHeartbeatAck, error) {
+	c.streamMu.Lock()
+	defer c.streamMu.Unlock()
+
+	if c.stream == nil {
+		client, err := c.getClient()
+		if err != nil {
+			return nil, err
+		}
+		// 心跳流的生命周期与客户端一致, 不使用调用方的ctx
+		stream, err := client.Heartbeat(context.Background())
+		if err != nil {
+			return nil, fromStatus(err)
+		}
+		c.stream = stream
+	}
+
+	err := c.stream.Send(&executorv1.HeartbeatRequest{
+		ExecutorId:  heartbeat.ExecutorID,
+		InstanceId:  heartbeat.InstanceID,
+		AppName:     heartbeat.AppName,
+		Host:        heartbeat.Host,
+		Port:        uint32(heartbeat.Port),
+		CurrentLoad: uint32(heartbeat.CurrentLoad),
+		CpuUsage:    heartbeat.CPUUsage,
+		MemoryUsage: heartbeat.MemoryUsage,
+		Labels:      heartbeat.Labels,
+		ReadyToStop: heartbeat.ReadyToStop,
+	})
+	var resp *executorv1.HeartbeatResponse
+	if err == nil {
+		resp, err = c.stream.Recv()
+	}
+	if err != nil {
+		// Send失败时真实错误需通过Recv获取
+		if _, recvErr := c.stream.Recv(); recvErr != nil {
+			err = recvErr
+		}
+		c.stream = nil
+		return nil, fromStatus(err)
+	}
+
+	return &model.ExecutorHeartbeatAck{
+		ExecutorID:  resp.GetExecutorId(),
+		DrainStatus: int8(resp.GetDrainStatus()),
+	}, nil
+}
+
+// Drain 请求调度中心停止向执行器分配新任务
+func (c *GRPCClient) Drain(ctx context.Context, executorID string) error {
+	client, err := c.getClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err = client.Drain(ctx, &executorv1.DrainRequest{ExecutorId: executorID})
+	return fromStatus(err)
+}
+
+// Unregister 注销执行器
+func (c *GRPCClient) Unregister(ctx context.Context, executorID string) error {
+	client, err := c.getClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err = client.Unregister(ctx, &executorv1.UnregisterRequest{ExecutorId: executorID})
+	return fromStatus(err)
+}
+
+// Callback 上报执行结果
+func (c *GRPCClient) Callback(ctx context.Context, result *model.ExecutorResult) error {
+	client, err := c.getClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err = client.Callback(ctx, &executorv1.CallbackRequest{
+		InstanceId:    result.InstanceID,
+		Code:          int32(result.Code),
+		Message:       result.Message,
+		Stack:         result.Stack,
+		DispatchToken: result.DispatchToken,
+	})
+	return fromStatus(err)
+}
+
+// ReportLogs 流式上报执行日志, 返回调度中心保存的条数
+func (c *GRPCClient) ReportLogs(ctx context.Context, logs []*model.TaskLog) (uint64, error) {
+	client, err := c.getClient()
+	if err != nil {
+		return 0, err
+	}
+
+	stream, err := client.ReportLogs(ctx)
+	if err != nil {
+		return 0, fromStatus(err)
+	}
+	for _, log := range logs {
+		err := stream.Send(&executorv1.ReportLogsRequest{
+			InstanceId: log.InstanceID,
+			LogTime:    log.LogTime.UnixMilli(),
+			LogLevel:   log.LogLevel,
+			LogContent: log.LogContent,
+		})
+		if err != nil {
+			break
+		}
+	}
+	// Send失败时CloseAndRecv返回真实错误
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return 0, fromStatus(err)
+	}
+	return resp.GetSaved(), nil
+}
+
+// Close 关闭心跳流和连接
+func (c *GRPCClient) Close() error {
+	c.streamMu.Lock()
+	if c.stream != nil {
+		c.stream.CloseSend()
+		c.stream = nil
+	}
+	c.streamMu.Unlock()
+
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn, c.client = nil, nil
+	return err
+}
+
+// getClient 获取客户端, 首次调用时建立连接
+func (c *GRPCClient) getClient() (executorv1.SchedulerServiceClient, error) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	if c.client != nil {
+		return c.client, nil
+	}
+
+	conn, err := grpc.NewClient(c.address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(c.signUnary),
+		grpc.WithStreamInterceptor(c.signStream),
+	)
+	if err != nil {
+		return nil, err
+	}
+	c.conn, c.client = conn, executorv1.NewSchedulerServiceClient(conn)
+	return c.client, nil
+}
+
+// sign 在metadata中附加签名, 每次调用使用新的随机串
+func (c *GRPCClient) sign(ctx context.Context, method string) (context.Context, error) {
+	nonce, err := utils.GenerateNonce()
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	return metadata.AppendToOutgoingContext(ctx,
+		strings.ToLower(utils.HeaderExecutorApp), c.appName,
+		strings.ToLower(utils.HeaderExecutorTimestamp), timestamp,
+		strings.ToLower(utils.HeaderExecutorNonce), nonce,
+		strings.ToLower(utils.HeaderExecutorSignature),
+		utils.SignExecutorRequest(c.accessToken, c.appName, timestamp, nonce, utils.MethodGRPC, method, nil),
+	), nil
+}
+
+// signUnary 一元调用签名拦截器
+func (c *GRPCClient) signUnary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx, err := c.sign(ctx, method)
+	if err != nil {
+		return err
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// signStream 流式调用签名拦截器
+func (c *GRPCClient) signStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx, err := c.sign(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+// fromStatus 将gRPC状态转换为错误, 执行器不存在时返回ErrNotRegistered
+func fromStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if st, ok := status.FromError(err); ok && st.Code() == codes.NotFound {
+		return errors.Join(ErrNotRegistered, err)
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+package executor
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	executorv1 "distributed-scheduler/api/executor/v1"
+	"distributed-scheduler/internal/model"
+)
+
+// RegisterGRPC 在gRPC服务上注册执行器服务, 供调度中心下发、终止和查询任务
+// 配置了访问令牌时所有方法(探测除外)先校验调度中心的请求签名, 再执行gRPC服务上配置的拦截器
+func (a *Agent) RegisterGRPC(registrar grpc.ServiceRegistrar) {
+	desc := executorv1.ExecutorService_ServiceDesc
+	desc.Methods = make([]grpc.MethodDesc, len(executorv1.ExecutorService_ServiceDesc.Methods))
+	for i, method := range executorv1.ExecutorService_ServiceDesc.Methods {
+		desc.Methods[i] = grpc.MethodDesc{
+			MethodName: method.MethodName,
+			Handler:    a.withAuth(method.Handler),
+		}
+	}
+	registrar.RegisterService(&desc, &executorServer{agent: a})
+}
+
+// withAuth 在方法处理函数的拦截器链之前加入签名校验, 签名校验不依赖创建gRPC服务时的配置
+func (a *Agent) withAuth(handler grpc.MethodHandler) grpc.MethodHandler {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		chained := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+			return a.grpcAuthInterceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				if interceptor == nil {
+					return next(ctx, req)
+				}
+				return interceptor(ctx, req, info, next)
+			})
+		}
+		return handler(srv, ctx, dec, chained)
+	}
+}
+
+// executorServer 执行器gRPC服务实现
+type executorServer struct {
+	executorv1.UnimplementedExecutorServiceServer
+
+	agent *Agent
+}
+
+// Run 接收任务并异步执行
+func (s *executorServer) Run(ctx context.Context, req *executorv1.RunRequest) (*executorv1.RunResponse, error) {
+	task := req.GetTask()
+	if task == nil {
+		return nil, status.Error(codes.InvalidArgument, "任务参数不能为空")
+	}
+
+	err := s.agent.Run(&model.ExecutorTask{
+		InstanceID:      task.GetInstanceId(),
+		TaskID:          task.GetTaskId(),
+		ExecutorHandler: task.GetExecutorHandler(),
+		ExecutorParam:   task.GetExecutorParam(),
+		ShardIndex:      uint(task.GetShardIndex()),
+		ShardTotal:      uint(task.GetShardTotal()),
+		Timeout:         uint(task.GetTimeout()),
+		DispatchToken:   task.GetDispatchToken(),
+	})
+	if errors.Is(err, ErrHandlerNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if errors.Is(err, ErrStaleDispatch) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &executorv1.RunResponse{}, nil
+}
+
+// Kill 终止执行中的任务
+func (s *executorServer) Kill(ctx context.Context, req *executorv1.KillRequest) (*executorv1.KillResponse, error) {
+	return &executorv1.KillResponse{Killed: s.agent.Kill(req.GetInstanceId(), req.GetDispatchToken())}, nil
+}
+
+// Status 查询实例执行状态
+func (s *executorServer) Status(ctx context.Context, req *executorv1.StatusRequest) (*executorv1.StatusResponse, error) {
+	st := s.agent.Status(req.GetInstanceId())
+	resp := &executorv1.StatusResponse{
+		State:       executorv1.InstanceState_INSTANCE_STATE_NOT_FOUND,
+		StartTime:   st.StartTime,
+		CurrentLoad: uint32(st.CurrentLoad),
+	}
+	if st.Running {
+		resp.State = executorv1.InstanceState_INSTANCE_STATE_RUNNING
+	}
+	return resp, nil
+}
+
+// Beat 探测执行器
+func (s *executorServer) Beat(ctx context.Context, req *executorv1.BeatRequest) (*executorv1.BeatResponse, error) {
+	if req.GetIdle() {
+		if msg := s.agent.busy(); msg != "" {
+			return &executorv1.BeatResponse{Busy: true, Message: msg}, nil
+		}
+	}
+	return &executorv1.BeatResponse{}, nil
+}
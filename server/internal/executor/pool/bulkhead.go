@@ -0,0 +1,143 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBulkheadExists  = errors.New("隔离舱已存在")
+	ErrInvalidBulkhead = errors.New("无效的隔离舱配置")
+)
+
+// 默认隔离舱名称
+const DefaultBulkhead = "default"
+
+// Bulkhead 隔离舱协程池
+// 按key(如执行器Handler名称)将任务路由到独立的子协程池, 每个子池拥有自己的
+// 工作协程上限和队列, 某类任务变慢或堆积时不会占满其他类型任务的容量;
+// 未单独配置的key使用默认子池
+type Bulkhead struct {
+	mu     sync.RWMutex
+	pools  map[string]*WorkerPool
+	routes map[string]string // key -> 子池名称
+	closed bool
+}
+
+// NewBulkhead 创建隔离舱协程池, defaultOpts为默认子池配置
+func NewBulkhead(defaultOpts Options) *Bulkhead {
+	defaultOpts.Name = DefaultBulkhead
+	return &Bulkhead{
+		pools:  map[string]*WorkerPool{DefaultBulkhead: NewWorkerPoolWithOptions(defaultOpts)},
+		routes: make(map[string]string),
+	}
+}
+
+// Add 添加子池, keys为路由到该子池的任务key(为空时仅可通过子池名称路由)
+func (b *Bulkhead) Add(opts Options, keys ...string) error {
+	if opts.Name == "" {
+		return fmt.Errorf("%w: 名称不能为空", ErrInvalidBulkhead)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrPoolClosed
+	}
+	if _, ok := b.pools[opts.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrBulkheadExists, opts.Name)
+	}
+	b.pools[opts.Name] = NewWorkerPoolWithOptions(opts)
+	for _, key := range keys {
+		b.routes[key] = opts.Name
+	}
+	return nil
+}
+
+// Route 将key路由到指定子池
+func (b *Bulkhead) Route(key, name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.routes[key] = name
+}
+
+// Get 获取key对应的子池: 优先按路由表, 其次按子池名称, 否则返回默认子池
+func (b *Bulkhead) Get(key string) *WorkerPool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if name, ok := b.routes[key]; ok {
+		if p, ok := b.pools[name]; ok {
+			return p
+		}
+	}
+	if p, ok := b.pools[key]; ok {
+		return p
+	}
+	return b.pools[DefaultBulkhead]
+}
+
+// Submit 提交任务到key对应的子池
+func (b *Bulkhead) Submit(key string, task Task) error {
+	return b.Get(key).Submit(task)
+}
+
+// SubmitWithPriority 按优先级提交任务到key对应的子池
+func (b *Bulkhead) SubmitWithPriority(key string, task Task, priority int) error {
+	return b.Get(key).SubmitWithPriority(task, priority)
+}
+
+// SubmitCtx 提交带上下文的任务到key对应的子池
+func (b *Bulkhead) SubmitCtx(ctx context.Context, key string, task CtxTask, priority int) error {
+	return b.Get(key).SubmitCtx(ctx, task, priority)
+}
+
+// GetStats 获取各子池的统计信息(按名称排序)
+func (b *Bulkhead) GetStats() []Stats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	stats := make([]Stats, 0, len(b.pools))
+	for _, p := range b.pools {
+		stats = append(stats, p.GetStats())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// Shutdown 优雅关闭所有子池
+func (b *Bulkhead) Shutdown(timeout time.Duration) error {
+	b.mu.Lock()
+	b.closed = true
+	pools := make([]*WorkerPool, 0, len(b.pools))
+	for _, p := range b.pools {
+		pools = append(pools, p)
+	}
+	b.mu.Unlock()
+
+	for _, p := range pools {
+		p.Close()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for _, p := range pools {
+			p.Wait()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return errors.New("关闭超时")
+	}
+}
@@ -0,0 +1,75 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBulkheadRouting(t *testing.T) {
+	b := NewBulkhead(Options{MaxWorkers: 1, QueueSize: 1})
+	defer b.Shutdown(time.Second)
+
+	if err := b.Add(Options{Name: "slow", MaxWorkers: 1, QueueSize: 1}, "report", "export"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Add(Options{Name: "slow"}); !errors.Is(err, ErrBulkheadExists) {
+		t.Fatalf("Add duplicate: err = %v, want ErrBulkheadExists", err)
+	}
+	if err := b.Add(Options{}); !errors.Is(err, ErrInvalidBulkhead) {
+		t.Fatalf("Add without name: err = %v, want ErrInvalidBulkhead", err)
+	}
+
+	slow := b.Get("slow")
+	for _, key := range []string{"report", "export", "slow"} {
+		if b.Get(key) != slow {
+			t.Fatalf("Get(%q) is not the slow pool", key)
+		}
+	}
+	if b.Get("other") != b.Get(DefaultBulkhead) || b.Get("other") == slow {
+		t.Fatal("unrouted key should use the default pool")
+	}
+
+	b.Route("other", "slow")
+	if b.Get("other") != slow {
+		t.Fatal("Route did not move key to the slow pool")
+	}
+}
+
+func TestBulkheadIsolation(t *testing.T) {
+	b := NewBulkhead(Options{MaxWorkers: 1, QueueSize: 1})
+	if err := b.Add(Options{Name: "slow", MaxWorkers: 1, QueueSize: 1}, "report"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 占满slow子池: 一个执行中, 一个排队
+	release := block(t, b.Get("report"))
+	if err := b.Submit("report", func() {}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Submit("report", func() {}); !errors.Is(err, ErrPoolFull) {
+		t.Fatalf("Submit to full pool: err = %v, want ErrPoolFull", err)
+	}
+
+	// 其他Handler不受影响
+	done := make(chan struct{})
+	if err := b.Submit("other", func() { close(done) }); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("task in default pool blocked by the slow pool")
+	}
+
+	release()
+	if err := b.Shutdown(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Add(Options{Name: "late"}); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("Add after Shutdown: err = %v, want ErrPoolClosed", err)
+	}
+	if err := b.Submit("other", func() {}); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("Submit after Shutdown: err = %v, want ErrPoolClosed", err)
+	}
+}
@@ -0,0 +1,110 @@
+package pool
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBuckets 默认直方图桶上界
+var DefaultBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	time.Minute,
+}
+
+// TaskInfo 任务执行信息, 传给钩子函数
+type TaskInfo struct {
+	Pool       string        // 协程池名称
+	Priority   int           // 任务优先级
+	EnqueuedAt time.Time     // 入队时间
+	StartedAt  time.Time     // 开始执行时间
+	QueueWait  time.Duration // 排队时长
+	Duration   time.Duration // 执行时长(OnStart时为0)
+}
+
+// Hooks 任务生命周期钩子, 在工作协程中同步调用, 应尽快返回
+type Hooks struct {
+	OnStart  func(info TaskInfo)
+	OnFinish func(info TaskInfo)
+	OnPanic  func(info TaskInfo, err *PanicError)
+}
+
+// PanicError 任务panic信息
+type PanicError struct {
+	Value interface{} // recover()的返回值
+	Stack []byte      // panic时的调用栈
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("任务执行panic: %v", e.Value)
+}
+
+// Histogram 耗时直方图(并发安全)
+type Histogram struct {
+	bounds []time.Duration
+	counts []uint64 // 最后一个为+Inf桶
+	count  uint64
+	sum    int64 // 纳秒
+}
+
+// NewHistogram 创建直方图, bounds需升序, 为空时使用DefaultBuckets
+func NewHistogram(bounds []time.Duration) *Histogram {
+	if len(bounds) == 0 {
+		bounds = DefaultBuckets
+	}
+	return &Histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// Observe 记录一次耗时
+func (h *Histogram) Observe(d time.Duration) {
+	i := 0
+	for i < len(h.bounds) && d > h.bounds[i] {
+		i++
+	}
+	atomic.AddUint64(&h.counts[i], 1)
+	atomic.AddUint64(&h.count, 1)
+	atomic.AddInt64(&h.sum, int64(d))
+}
+
+// Snapshot 获取直方图快照
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	snapshot := HistogramSnapshot{
+		Count:   atomic.LoadUint64(&h.count),
+		SumMs:   float64(atomic.LoadInt64(&h.sum)) / float64(time.Millisecond),
+		Buckets: make([]Bucket, 0, len(h.counts)),
+	}
+	for i := range h.counts {
+		bucket := Bucket{Count: atomic.LoadUint64(&h.counts[i])}
+		if i < len(h.bounds) {
+			bucket.Le = h.bounds[i].String()
+		} else {
+			bucket.Le = "+Inf"
+		}
+		snapshot.Buckets = append(snapshot.Buckets, bucket)
+	}
+	return snapshot
+}
+
+// HistogramSnapshot 直方图快照
+type HistogramSnapshot struct {
+	Count   uint64   `json:"count"`
+	SumMs   float64  `json:"sum_ms"`
+	Buckets []Bucket `json:"buckets"` // 各桶计数(非累计)
+}
+
+// Bucket 直方图桶
+type Bucket struct {
+	Le    string `json:"le"` // 桶上界
+	Count uint64 `json:"count"`
+}
@@ -0,0 +1,105 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	h := NewHistogram([]time.Duration{time.Millisecond, time.Second})
+	for _, d := range []time.Duration{500 * time.Microsecond, time.Millisecond, 2 * time.Millisecond, time.Minute} {
+		h.Observe(d)
+	}
+
+	snapshot := h.Snapshot()
+	if snapshot.Count != 4 {
+		t.Fatalf("Count = %d, want 4", snapshot.Count)
+	}
+	if want := 60003.5; snapshot.SumMs != want {
+		t.Fatalf("SumMs = %v, want %v", snapshot.SumMs, want)
+	}
+	want := []Bucket{{Le: "1ms", Count: 2}, {Le: "1s", Count: 1}, {Le: "+Inf", Count: 1}}
+	if len(snapshot.Buckets) != len(want) {
+		t.Fatalf("Buckets = %+v, want %+v", snapshot.Buckets, want)
+	}
+	for i := range want {
+		if snapshot.Buckets[i] != want[i] {
+			t.Fatalf("Buckets = %+v, want %+v", snapshot.Buckets, want)
+		}
+	}
+
+	if got := len(NewHistogram(nil).Snapshot().Buckets); got != len(DefaultBuckets)+1 {
+		t.Fatalf("默认桶数量 = %d, want %d", got, len(DefaultBuckets)+1)
+	}
+}
+
+func TestHooksAndPanic(t *testing.T) {
+	var mu sync.Mutex
+	var started, finished []TaskInfo
+	var panics []*PanicError
+	p := NewWorkerPoolWithOptions(Options{
+		Name:       "hooks",
+		MaxWorkers: 1,
+		QueueSize:  2,
+		Hooks: Hooks{
+			OnStart: func(info TaskInfo) {
+				mu.Lock()
+				started = append(started, info)
+				mu.Unlock()
+			},
+			OnFinish: func(info TaskInfo) {
+				mu.Lock()
+				finished = append(finished, info)
+				mu.Unlock()
+			},
+			OnPanic: func(info TaskInfo, err *PanicError) {
+				mu.Lock()
+				panics = append(panics, err)
+				mu.Unlock()
+			},
+		},
+	})
+
+	if err := p.SubmitWithPriority(func() { panic("boom") }, 3); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	if err := p.Submit(func() { close(done) }); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("任务panic后协程池未继续执行后续任务")
+	}
+	if err := p.Shutdown(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(started) != 2 || len(finished) != 2 {
+		t.Fatalf("OnStart调用%d次, OnFinish调用%d次, want 2", len(started), len(finished))
+	}
+	if started[0].Pool != "hooks" || started[0].Priority != 3 || started[0].StartedAt.IsZero() {
+		t.Fatalf("OnStart info = %+v", started[0])
+	}
+	if finished[0].StartedAt != started[0].StartedAt || finished[0].Duration < 0 {
+		t.Fatalf("OnFinish info = %+v", finished[0])
+	}
+	if len(panics) != 1 {
+		t.Fatalf("OnPanic调用%d次, want 1", len(panics))
+	}
+	if panics[0].Value != "boom" || len(panics[0].Stack) == 0 {
+		t.Fatalf("PanicError = %v, stack %d bytes", panics[0].Value, len(panics[0].Stack))
+	}
+
+	stats := p.GetStats()
+	if stats.Completed != 2 || stats.Panics != 1 {
+		t.Fatalf("Completed = %d, Panics = %d, want 2, 1", stats.Completed, stats.Panics)
+	}
+	if stats.Latency.Count != 2 || stats.QueueWait.Count != 2 {
+		t.Fatalf("Latency.Count = %d, QueueWait.Count = %d, want 2", stats.Latency.Count, stats.QueueWait.Count)
+	}
+}
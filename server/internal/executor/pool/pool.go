@@ -1,8 +1,10 @@
 package pool
 
 import (
+	"container/heap"
 	"context"
 	"errors"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -19,13 +21,50 @@ var (
 // Task 任务函数
 type Task func()
 
+// CtxTask 带上下文的任务函数, ctx在任务截止时间到达或协程池关闭时取消
+// 协程池关闭时仍在队列中的任务以已取消的ctx执行, 由任务自行处理(如上报失败)
+type CtxTask func(ctx context.Context)
+
+// 默认空闲工作协程退出时间
+const DefaultIdleTimeout = 30 * time.Second
+
+// Options 协程池配置
+type Options struct {
+	Name          string          // 名称, 用于日志和统计
+	MaxWorkers    int             // 最大工作协程数
+	QueueSize     int             // 队列大小, 小于MaxWorkers时使用MaxWorkers
+	IdleTimeout   time.Duration   // 空闲工作协程退出时间, 为0时使用DefaultIdleTimeout
+	AgingInterval time.Duration   // 优先级老化间隔, 为0时不老化
+	TaskTimeout   time.Duration   // SubmitCtx提交的任务默认截止时间(ctx未设置截止时间时生效), 为0时不限制
+	Hooks         Hooks           // 任务生命周期钩子
+	Buckets       []time.Duration // 执行耗时和排队时长直方图的桶上界, 为空时使用DefaultBuckets
+}
+
 // WorkerPool Goroutine池
+// 等待中的任务按优先级(数值越大越优先)出队, 同优先级按提交顺序出队;
+// 设置老化间隔后任务每等待一个间隔优先级加一, 避免低优先级任务饿死;
+// 关闭后不再接收新任务, 已入队的任务由工作协程执行完后退出
 type WorkerPool struct {
+	// 64位原子计数器放在结构体开头以保证对齐
+	completed   uint64 // 已完成任务数(含panic)
+	panics      uint64 // panic任务数
+	rejected    uint64 // 因队列已满、超时或ctx取消被拒绝的任务数
+	expired     uint64 // 在队列中超过截止时间被跳过的任务数
+	name        string
+	idleTimeout time.Duration
+	taskTimeout time.Duration
+	hooks       Hooks
+	latency     *Histogram    // 执行耗时
+	queueWait   *Histogram    // 排队时长
 	maxWorkers  int32         // 最大工作协程数
-	taskQueue   chan Task     // 任务队列
+	queue       taskHeap      // 等待中的任务
+	queueMu     sync.Mutex    // 保护queue、seq, 以及关闭时closed和ready的变更
+	seq         uint64        // 提交序号
+	slots       chan struct{} // 队列容量信号量, 入队占用, 出队释放
+	ready       chan struct{} // 可出队的任务信号, 数量与queue长度一致
 	workerCount int32         // 当前工作协程数
 	running     int32         // 运行中的任务数
-	closed      int32         // 是否已关闭
+	closed      int32         // 是否已关闭, 在queueMu内设置
 	wg          sync.WaitGroup
 	ctx         context.Context
 	cancel      context.CancelFunc
@@ -33,17 +72,41 @@ type WorkerPool struct {
 
 // NewWorkerPool 创建协程池
 func NewWorkerPool(maxWorkers, queueSize int) *WorkerPool {
+	return NewWorkerPoolWithOptions(Options{MaxWorkers: maxWorkers, QueueSize: queueSize})
+}
+
+// NewWorkerPoolWithOptions 根据配置创建协程池
+func NewWorkerPoolWithOptions(opts Options) *WorkerPool {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	if opts.MaxWorkers < 1 {
+		opts.MaxWorkers = 1
+	}
+	// 任务先入队再由工作协程取出, 队列容量为0时任务无法提交; 容量至少为最大工作协程数, 空闲的工作协程总能接收任务
+	if opts.QueueSize < opts.MaxWorkers {
+		opts.QueueSize = opts.MaxWorkers
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = DefaultIdleTimeout
+	}
+
 	pool := &WorkerPool{
-		maxWorkers: int32(maxWorkers),
-		taskQueue:  make(chan Task, queueSize),
-		ctx:        ctx,
-		cancel:     cancel,
+		name:        opts.Name,
+		idleTimeout: opts.IdleTimeout,
+		taskTimeout: opts.TaskTimeout,
+		hooks:       opts.Hooks,
+		latency:     NewHistogram(opts.Buckets),
+		queueWait:   NewHistogram(opts.Buckets),
+		maxWorkers:  int32(opts.MaxWorkers),
+		queue:       taskHeap{agingInterval: opts.AgingInterval},
+		slots:       make(chan struct{}, opts.QueueSize),
+		ready:       make(chan struct{}, opts.QueueSize),
+		ctx:         ctx,
+		cancel:      cancel,
 	}
 
 	// 启动初始工作协程
-	initialWorkers := maxWorkers / 4
+	initialWorkers := opts.MaxWorkers / 4
 	if initialWorkers < 1 {
 		initialWorkers = 1
 	}
@@ -51,7 +114,7 @@ func NewWorkerPool(maxWorkers, queueSize int) *WorkerPool {
 		pool.addWorker()
 	}
 
-	logger.Infof("协程池启动成功, 最大工作协程数: %d, 队列大小: %d", maxWorkers, queueSize)
+	logger.Infof("协程池%s启动成功, 最大工作协程数: %d, 队列大小: %d", opts.Name, opts.MaxWorkers, opts.QueueSize)
 	return pool
 }
 
@@ -61,59 +124,95 @@ func (p *WorkerPool) addWorker() {
 	p.wg.Add(1)
 
 	go func() {
+		retired := false // 空闲退出时已扣减工作协程数
 		defer func() {
-			atomic.AddInt32(&p.workerCount, -1)
+			if !retired {
+				atomic.AddInt32(&p.workerCount, -1)
+			}
 			p.wg.Done()
 			if r := recover(); r != nil {
 				logger.Errorf("协程池任务panic: %v", r)
 			}
 		}()
 
-		idleTimeout := time.NewTimer(30 * time.Second)
+		idleTimeout := time.NewTimer(p.idleTimeout)
 		defer idleTimeout.Stop()
 
 		for {
 			select {
-			case task, ok := <-p.taskQueue:
+			case _, ok := <-p.ready:
 				if !ok {
 					return
 				}
-				idleTimeout.Reset(30 * time.Second)
-
-				atomic.AddInt32(&p.running, 1)
-				func() {
-					defer func() {
-						atomic.AddInt32(&p.running, -1)
-						if r := recover(); r != nil {
-							logger.Errorf("任务执行panic: %v", r)
-						}
-					}()
-					task()
-				}()
+				idleTimeout.Reset(p.idleTimeout)
+				p.execute(p.dequeue())
 
 			case <-idleTimeout.C:
-				// 超时退出，但保证最少有1个worker
-				if atomic.LoadInt32(&p.workerCount) > 1 {
+				// 超时退出，但保证最少有1个worker; CAS扣减避免多个协程同时退出后没有worker处理队列
+				if n := atomic.LoadInt32(&p.workerCount); n > 1 && atomic.CompareAndSwapInt32(&p.workerCount, n, n-1) {
+					retired = true
 					return
 				}
-				idleTimeout.Reset(30 * time.Second)
+				idleTimeout.Reset(p.idleTimeout)
+			}
+		}
+	}()
+}
 
-			case <-p.ctx.Done():
-				return
+// execute 执行任务, 记录耗时并调用钩子; 任务panic时记录调用栈
+func (p *WorkerPool) execute(item *queuedTask) {
+	info := TaskInfo{
+		Pool:       p.name,
+		Priority:   item.priority,
+		EnqueuedAt: item.enqueuedAt,
+		StartedAt:  time.Now(),
+	}
+	info.QueueWait = info.StartedAt.Sub(item.enqueuedAt)
+	p.queueWait.Observe(info.QueueWait)
+
+	atomic.AddInt32(&p.running, 1)
+	defer func() {
+		atomic.AddInt32(&p.running, -1)
+		info.Duration = time.Since(info.StartedAt)
+		p.latency.Observe(info.Duration)
+		atomic.AddUint64(&p.completed, 1)
+
+		if r := recover(); r != nil {
+			panicErr := &PanicError{Value: r, Stack: debug.Stack()}
+			atomic.AddUint64(&p.panics, 1)
+			logger.Errorf("协程池%s任务执行panic: %v\n%s", p.name, r, panicErr.Stack)
+			if p.hooks.OnPanic != nil {
+				p.hooks.OnPanic(info, panicErr)
 			}
 		}
+		if p.hooks.OnFinish != nil {
+			p.hooks.OnFinish(info)
+		}
 	}()
+
+	if p.hooks.OnStart != nil {
+		p.hooks.OnStart(info)
+	}
+	item.task()
 }
 
-// Submit 提交任务
+// Submit 提交任务(默认优先级0)
 func (p *WorkerPool) Submit(task Task) error {
+	return p.SubmitWithPriority(task, 0)
+}
+
+// SubmitWithPriority 按优先级提交任务, 数值越大越优先
+func (p *WorkerPool) SubmitWithPriority(task Task, priority int) error {
 	if atomic.LoadInt32(&p.closed) == 1 {
 		return ErrPoolClosed
 	}
 
 	// 尝试直接放入队列
 	select {
-	case p.taskQueue <- task:
+	case p.slots <- struct{}{}:
+		if err := p.enqueue(task, priority); err != nil {
+			return err
+		}
 		// 检查是否需要扩容
 		p.maybeExpand()
 		return nil
@@ -122,12 +221,12 @@ func (p *WorkerPool) Submit(task Task) error {
 		if p.maybeExpand() {
 			// 再次尝试
 			select {
-			case p.taskQueue <- task:
-				return nil
+			case p.slots <- struct{}{}:
+				return p.enqueue(task, priority)
 			default:
-				return ErrPoolFull
 			}
 		}
+		atomic.AddUint64(&p.rejected, 1)
 		return ErrPoolFull
 	}
 }
@@ -142,18 +241,109 @@ func (p *WorkerPool) SubmitWithTimeout(task Task, timeout time.Duration) error {
 	defer timer.Stop()
 
 	select {
-	case p.taskQueue <- task:
+	case p.slots <- struct{}{}:
+		if err := p.enqueue(task, 0); err != nil {
+			return err
+		}
 		p.maybeExpand()
 		return nil
+	case <-p.ctx.Done():
+		return ErrPoolClosed
 	case <-timer.C:
+		atomic.AddUint64(&p.rejected, 1)
 		return ErrTimeout
 	}
 }
 
+// SubmitCtx 提交带上下文的任务, 队列已满时阻塞直到有空位、ctx取消或协程池关闭
+// ctx的截止时间同时作为任务的截止时间: 在队列中等待超过截止时间的任务不再执行,
+// 执行中的任务通过ctx感知截止时间; ctx未设置截止时间时使用Options.TaskTimeout
+func (p *WorkerPool) SubmitCtx(ctx context.Context, task CtxTask, priority int) error {
+	if atomic.LoadInt32(&p.closed) == 1 {
+		return ErrPoolClosed
+	}
+
+	cancelTimeout := context.CancelFunc(func() {})
+	if _, ok := ctx.Deadline(); !ok && p.taskTimeout > 0 {
+		ctx, cancelTimeout = context.WithTimeout(ctx, p.taskTimeout)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	// 协程池关闭时取消任务, 取消原因为ErrPoolClosed
+	stop := context.AfterFunc(p.ctx, func() { cancel(ErrPoolClosed) })
+	release := func() {
+		stop()
+		cancel(nil)
+		cancelTimeout()
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		release()
+		if p.ctx.Err() != nil {
+			return ErrPoolClosed
+		}
+		atomic.AddUint64(&p.rejected, 1)
+		return ctx.Err()
+	}
+
+	err := p.enqueue(func() {
+		defer release()
+		// AfterFunc异步执行, 协程池已关闭时在执行前同步取消
+		if p.ctx.Err() != nil {
+			cancel(ErrPoolClosed)
+		}
+		// 协程池关闭导致的取消交给任务处理, 等待超过截止时间的任务直接跳过
+		if ctx.Err() != nil && !errors.Is(context.Cause(ctx), ErrPoolClosed) {
+			atomic.AddUint64(&p.expired, 1)
+			logger.Warnf("协程池%s任务在队列中等待超时, 已跳过: %v", p.name, ctx.Err())
+			return
+		}
+		task(ctx)
+	}, priority)
+	if err != nil {
+		release()
+		return err
+	}
+	p.maybeExpand()
+	return nil
+}
+
+// enqueue 任务入队, 调用前需已占用slots; 协程池已关闭时释放slots并返回ErrPoolClosed
+// ready的容量与slots相同, 占用slots后发送不会阻塞, 在锁内发送保证不会与Close关闭ready并发
+func (p *WorkerPool) enqueue(task Task, priority int) error {
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+	if atomic.LoadInt32(&p.closed) == 1 {
+		<-p.slots
+		return ErrPoolClosed
+	}
+
+	p.seq++
+	heap.Push(&p.queue, &queuedTask{
+		task:       task,
+		priority:   priority,
+		seq:        p.seq,
+		enqueuedAt: time.Now(),
+	})
+	p.ready <- struct{}{}
+	return nil
+}
+
+// dequeue 取出优先级最高的任务并释放slots
+func (p *WorkerPool) dequeue() *queuedTask {
+	p.queueMu.Lock()
+	item := heap.Pop(&p.queue).(*queuedTask)
+	p.queueMu.Unlock()
+
+	<-p.slots
+	return item
+}
+
 // maybeExpand 尝试扩容
 func (p *WorkerPool) maybeExpand() bool {
 	currentWorkers := atomic.LoadInt32(&p.workerCount)
-	queueLen := len(p.taskQueue)
+	queueLen := p.QueueSize()
 
 	// 如果队列积压较多且未达到最大工作协程数，则扩容
 	if queueLen > int(currentWorkers) && currentWorkers < p.maxWorkers {
@@ -175,7 +365,7 @@ func (p *WorkerPool) WorkerCount() int {
 
 // QueueSize 获取队列中等待的任务数
 func (p *WorkerPool) QueueSize() int {
-	return len(p.taskQueue)
+	return len(p.slots)
 }
 
 // IsClosed 是否已关闭
@@ -183,15 +373,19 @@ func (p *WorkerPool) IsClosed() bool {
 	return atomic.LoadInt32(&p.closed) == 1
 }
 
-// Close 关闭协程池
+// Close 关闭协程池: 不再接收新任务, 取消SubmitCtx任务的ctx, 已入队的任务执行完后工作协程退出
 func (p *WorkerPool) Close() {
+	p.queueMu.Lock()
 	if !atomic.CompareAndSwapInt32(&p.closed, 0, 1) {
+		p.queueMu.Unlock()
 		return
 	}
+	close(p.ready)
+	queued := p.queue.Len()
+	p.queueMu.Unlock()
 
 	p.cancel()
-	close(p.taskQueue)
-	logger.Info("协程池开始关闭")
+	logger.Infof("协程池%s开始关闭, 队列中剩余%d个任务", p.name, queued)
 }
 
 // Wait 等待所有任务完成
@@ -220,21 +414,75 @@ func (p *WorkerPool) Shutdown(timeout time.Duration) error {
 
 // Stats 获取统计信息
 type Stats struct {
-	MaxWorkers  int `json:"max_workers"`
-	Workers     int `json:"workers"`
-	Running     int `json:"running"`
-	QueueSize   int `json:"queue_size"`
-	QueueCap    int `json:"queue_cap"`
+	Name       string            `json:"name,omitempty"`
+	MaxWorkers int               `json:"max_workers"`
+	Workers    int               `json:"workers"`
+	Running    int               `json:"running"`
+	QueueSize  int               `json:"queue_size"`
+	QueueCap   int               `json:"queue_cap"`
+	Completed  uint64            `json:"completed"`
+	Panics     uint64            `json:"panics"`
+	Rejected   uint64            `json:"rejected"`
+	Expired    uint64            `json:"expired"`
+	Latency    HistogramSnapshot `json:"latency"`
+	QueueWait  HistogramSnapshot `json:"queue_wait"`
 }
 
 // GetStats 获取统计信息
 func (p *WorkerPool) GetStats() Stats {
 	return Stats{
+		Name:       p.name,
 		MaxWorkers: int(p.maxWorkers),
 		Workers:    p.WorkerCount(),
 		Running:    p.Running(),
 		QueueSize:  p.QueueSize(),
-		QueueCap:   cap(p.taskQueue),
+		QueueCap:   cap(p.slots),
+		Completed:  atomic.LoadUint64(&p.completed),
+		Panics:     atomic.LoadUint64(&p.panics),
+		Rejected:   atomic.LoadUint64(&p.rejected),
+		Expired:    atomic.LoadUint64(&p.expired),
+		Latency:    p.latency.Snapshot(),
+		QueueWait:  p.queueWait.Snapshot(),
+	}
+}
+
+// queuedTask 队列中的任务
+type queuedTask struct {
+	task       Task
+	priority   int
+	seq        uint64
+	enqueuedAt time.Time
+}
+
+// taskHeap 任务优先队列(实现heap.Interface)
+type taskHeap struct {
+	items         []*queuedTask
+	agingInterval time.Duration
+}
+
+func (h taskHeap) Len() int { return len(h.items) }
+
+func (h taskHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if a.priority != b.priority && h.agingInterval > 0 {
+		// 有效优先级 = priority + 等待时长/老化间隔, 比较时当前时间可以约去
+		return float64(a.priority)-float64(a.enqueuedAt.UnixNano())/float64(h.agingInterval) >
+			float64(b.priority)-float64(b.enqueuedAt.UnixNano())/float64(h.agingInterval)
+	}
+	if a.priority != b.priority {
+		return a.priority > b.priority
 	}
+	return a.seq < b.seq
 }
 
+func (h taskHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *taskHeap) Push(x interface{}) { h.items = append(h.items, x.(*queuedTask)) }
+
+func (h *taskHeap) Pop() interface{} {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items[n-1] = nil
+	h.items = h.items[:n-1]
+	return item
+}
@@ -0,0 +1,194 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"distributed-scheduler/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	logger.Sugar = logger.Logger.Sugar()
+	os.Exit(m.Run())
+}
+
+// block 占用协程池唯一的工作协程, 返回释放函数
+func block(t *testing.T, p *WorkerPool) func() {
+	t.Helper()
+	started, release := make(chan struct{}), make(chan struct{})
+	if err := p.Submit(func() {
+		close(started)
+		<-release
+	}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	return func() { close(release) }
+}
+
+func TestPoolPriority(t *testing.T) {
+	p := NewWorkerPoolWithOptions(Options{MaxWorkers: 1, QueueSize: 10})
+	release := block(t, p)
+
+	var mu sync.Mutex
+	var order []int
+	for _, priority := range []int{1, 5, 3, 5} {
+		priority := priority
+		if err := p.SubmitWithPriority(func() {
+			mu.Lock()
+			order = append(order, priority)
+			mu.Unlock()
+		}, priority); err != nil {
+			t.Fatal(err)
+		}
+	}
+	release()
+	if err := p.Shutdown(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int{5, 5, 3, 1}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestPoolCloseDrainsQueue(t *testing.T) {
+	p := NewWorkerPoolWithOptions(Options{MaxWorkers: 1, QueueSize: 10})
+	release := block(t, p)
+
+	var executed int32
+	for i := 0; i < 5; i++ {
+		if err := p.Submit(func() { atomic.AddInt32(&executed, 1) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.Close()
+	if err := p.Submit(func() {}); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("Submit after Close: err = %v, want ErrPoolClosed", err)
+	}
+	release()
+	p.Wait()
+
+	if executed != 5 {
+		t.Fatalf("executed %d queued tasks, want 5", executed)
+	}
+}
+
+func TestPoolConcurrentSubmitAndClose(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		p := NewWorkerPoolWithOptions(Options{MaxWorkers: 4, QueueSize: 64})
+		var accepted, executed int32
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					if p.Submit(func() { atomic.AddInt32(&executed, 1) }) == nil {
+						atomic.AddInt32(&accepted, 1)
+					}
+				}
+			}()
+		}
+		p.Close()
+		wg.Wait()
+		p.Wait()
+
+		// 接收的任务都被执行, 没有任务被静默丢弃
+		if accepted != executed {
+			t.Fatalf("accepted %d tasks but executed %d", accepted, executed)
+		}
+	}
+}
+
+func TestSubmitCtx(t *testing.T) {
+	p := NewWorkerPoolWithOptions(Options{MaxWorkers: 1, QueueSize: 10})
+	release := block(t, p)
+
+	// 在队列中超过截止时间的任务被跳过
+	expiredCtx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var expiredRan bool
+	if err := p.SubmitCtx(expiredCtx, func(ctx context.Context) { expiredRan = true }, 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// 协程池关闭时队列中的任务以已取消的ctx执行
+	cancelled := make(chan error, 1)
+	if err := p.SubmitCtx(context.Background(), func(ctx context.Context) { cancelled <- ctx.Err() }, 0); err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+	release()
+	p.Wait()
+
+	if expiredRan {
+		t.Fatal("expired task ran")
+	}
+	if stats := p.GetStats(); stats.Expired != 1 {
+		t.Fatalf("expired = %d, want 1", stats.Expired)
+	}
+	select {
+	case err := <-cancelled:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("queued task ctx err = %v, want context.Canceled", err)
+		}
+	default:
+		t.Fatal("queued ctx task was dropped on close")
+	}
+}
+
+func TestSubmitWithTimeoutOnClose(t *testing.T) {
+	p := NewWorkerPoolWithOptions(Options{MaxWorkers: 1, QueueSize: 1})
+	release := block(t, p)
+	defer release()
+	if err := p.Submit(func() {}); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- p.SubmitWithTimeout(func() {}, time.Minute) }()
+	time.Sleep(10 * time.Millisecond)
+	p.Close()
+	if err := <-done; !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("SubmitWithTimeout: err = %v, want ErrPoolClosed", err)
+	}
+}
+
+func TestZeroQueueSize(t *testing.T) {
+	p := NewWorkerPool(2, 0)
+	defer p.Close()
+
+	// 队列容量至少为最大工作协程数, 空闲的工作协程可以接收任务
+	done := make(chan struct{}, 2)
+	for i := 0; i < 2; i++ {
+		if err := p.Submit(func() { done <- struct{}{} }); err != nil {
+			t.Fatalf("Submit to idle pool with zero queue size: %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("task not executed")
+		}
+	}
+	if got := p.GetStats().QueueCap; got != 2 {
+		t.Errorf("QueueCap = %d, want 2", got)
+	}
+}
@@ -0,0 +1,354 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/pkg/logger"
+)
+
+var (
+	ErrScriptDisabled      = errors.New("执行器未启用脚本任务")
+	ErrInterpreterNotFound = errors.New("脚本类型未配置解释器")
+)
+
+// 脚本执行默认配置
+const (
+	DefaultMaxLogBytes = 1 << 20 // 单个实例采集的最大输出字节数
+
+	scriptWaitDelay     = 5 * time.Second // 脚本终止后等待输出读取完成的时间
+	logFlushInterval    = time.Second
+	logFlushSize        = 100
+	maxLineBytes        = 64 << 10 // 无换行输出的切分长度
+	errorTailLines      = 5        // 失败信息中附带的标准错误行数
+	scriptFileName      = "script"
+	scriptEnvPrefix     = "SCHEDULER_"
+	defaultScriptSubDir = "distributed-scheduler-scripts"
+)
+
+// DefaultInterpreters 默认的脚本类型到解释器的映射
+var DefaultInterpreters = map[string]string{
+	model.ScriptTypeBash:   "bash",
+	model.ScriptTypeSh:     "sh",
+	model.ScriptTypePython: "python3",
+}
+
+// ScriptOptions 脚本任务配置, 同时配置了访问令牌时执行器可接收SCRIPT类型的任务(通过HTTP协议下发)
+type ScriptOptions struct {
+	WorkDir      string            // 工作目录根路径, 每次执行在其下使用独立目录(实例ID-派发令牌-随机后缀), 为空时使用系统临时目录
+	KeepWorkDir  bool              // 执行结束后保留实例工作目录, 便于排查
+	Interpreters map[string]string // 脚本类型到解释器的映射, 为空时使用DefaultInterpreters, 未配置的脚本类型拒绝执行
+	Env          map[string]string // 所有脚本共用的环境变量, 脚本版本中的同名变量优先
+	Limits       ResourceLimits    // 资源限制
+	MaxLogBytes  int               // 单个实例采集的最大输出字节数, 超出部分丢弃, 为0时使用DefaultMaxLogBytes
+}
+
+// ResourceLimits 脚本进程的资源限制(rlimit, 仅支持Linux), 为0表示不限制
+// 设置了资源限制时脚本经由当前程序的辅助进程启动, 辅助进程设置限制后exec解释器, 无法设置时实例失败
+type ResourceLimits struct {
+	CPUSeconds uint64 // CPU时间(秒)
+	MemoryMB   uint64 // 虚拟内存(MB)
+	FileSizeMB uint64 // 单个文件大小(MB)
+	OpenFiles  uint64 // 打开文件数
+}
+
+// scriptRunner 脚本执行器
+// 脚本写入实例独立的工作目录后由解释器执行, 标准输出和标准错误按行上报为执行日志
+type scriptRunner struct {
+	opts   ScriptOptions
+	client SchedulerClient
+}
+
+// newScriptRunner 创建脚本执行器
+func newScriptRunner(opts ScriptOptions, client SchedulerClient) *scriptRunner {
+	if len(opts.Interpreters) == 0 {
+		opts.Interpreters = DefaultInterpreters
+	}
+	if opts.WorkDir == "" {
+		opts.WorkDir = filepath.Join(os.TempDir(), defaultScriptSubDir)
+	}
+	if opts.MaxLogBytes <= 0 {
+		opts.MaxLogBytes = DefaultMaxLogBytes
+	}
+	return &scriptRunner{opts: opts, client: client}
+}
+
+// interpreter 获取脚本类型对应的解释器
+func (r *scriptRunner) interpreter(scriptType string) (string, error) {
+	interpreter, ok := r.opts.Interpreters[scriptType]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrInterpreterNotFound, scriptType)
+	}
+	return interpreter, nil
+}
+
+// Handle 执行脚本, 作为任务处理函数使用, 上下文取消或超时时终止脚本的整个进程组
+func (r *scriptRunner) Handle(ctx context.Context, task *model.ExecutorTask) error {
+	script := task.Script
+	interpreter, err := r.interpreter(script.ScriptType)
+	if err != nil {
+		return err
+	}
+
+	// 重新分发的实例可能与旧的执行同时存在, 每次执行使用新目录, 避免旧的执行结束时删除新执行的目录
+	if err := os.MkdirAll(r.opts.WorkDir, 0o700); err != nil {
+		return fmt.Errorf("创建工作目录失败: %w", err)
+	}
+	dir, err := os.MkdirTemp(r.opts.WorkDir, fmt.Sprintf("%d-%d-", task.InstanceID, task.DispatchToken))
+	if err != nil {
+		return fmt.Errorf("创建工作目录失败: %w", err)
+	}
+	if !r.opts.KeepWorkDir {
+		defer os.RemoveAll(dir)
+	}
+	file := filepath.Join(dir, scriptFileName)
+	if err := os.WriteFile(file, []byte(script.Content), 0o700); err != nil {
+		return fmt.Errorf("写入脚本失败: %w", err)
+	}
+
+	logs := newLogCollector(r.client, task.InstanceID, r.opts.MaxLogBytes)
+	defer logs.Close()
+	logs.add(model.LogLevelInfo, fmt.Sprintf("执行脚本: %s 版本%d, 工作目录 %s", script.ScriptType, script.Version, dir))
+
+	stdout, stderr := logs.writer(model.LogLevelInfo), logs.writer(model.LogLevelError)
+	cmd := exec.CommandContext(ctx, interpreter, file)
+	cmd.Dir = dir
+	cmd.Env = r.env(task, dir)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = scriptWaitDelay
+	prepareCommand(cmd)
+	checkLimits, err := applyLimits(cmd, r.opts.Limits)
+	if err != nil {
+		return fmt.Errorf("设置资源限制失败: %w", err)
+	}
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		checkLimits()
+		return fmt.Errorf("启动脚本失败: %w", err)
+	}
+	// 资源限制在exec解释器前设置, 设置失败时解释器不会启动
+	if err := checkLimits(); err != nil {
+		cmd.Wait()
+		return fmt.Errorf("设置资源限制失败: %w", err)
+	}
+	err = cmd.Wait()
+	stdout.Flush()
+	stderr.Flush()
+	logs.add(model.LogLevelInfo, fmt.Sprintf("脚本结束, 耗时%dms", time.Since(start).Milliseconds()))
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("脚本执行超时, 已终止")
+	case ctx.Err() != nil:
+		return fmt.Errorf("脚本已终止")
+	case err != nil:
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			err = fmt.Errorf("脚本退出码%d", exitErr.ExitCode())
+		}
+		if tail := stderr.Tail(); tail != "" {
+			return fmt.Errorf("%w: %s", err, tail)
+		}
+		return err
+	}
+	return nil
+}
+
+// env 构建脚本的环境变量: 只继承执行器的PATH和LANG, HOME和TMPDIR指向实例工作目录,
+// 依次叠加执行器配置、脚本版本和调度信息(SCHEDULER_*)
+func (r *scriptRunner) env(task *model.ExecutorTask, dir string) []string {
+	values := map[string]string{
+		"PATH":   os.Getenv("PATH"),
+		"LANG":   os.Getenv("LANG"),
+		"HOME":   dir,
+		"TMPDIR": dir,
+	}
+	for name, value := range r.opts.Env {
+		values[name] = value
+	}
+	for name, value := range task.Script.Env {
+		values[name] = value
+	}
+	values[scriptEnvPrefix+"TASK_ID"] = strconv.FormatUint(task.TaskID, 10)
+	values[scriptEnvPrefix+"INSTANCE_ID"] = strconv.FormatUint(task.InstanceID, 10)
+	values[scriptEnvPrefix+"SHARD_INDEX"] = strconv.FormatUint(uint64(task.ShardIndex), 10)
+	values[scriptEnvPrefix+"SHARD_TOTAL"] = strconv.FormatUint(uint64(task.ShardTotal), 10)
+	values[scriptEnvPrefix+"EXECUTOR_PARAM"] = task.ExecutorParam
+	values[scriptEnvPrefix+"SCRIPT_VERSION"] = strconv.FormatUint(uint64(task.Script.Version), 10)
+
+	env := make([]string, 0, len(values))
+	for name, value := range values {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// logCollector 执行日志收集器, 按条数或时间间隔批量上报
+type logCollector struct {
+	client     SchedulerClient
+	instanceID uint64
+	maxBytes   int
+
+	logs      []*model.TaskLog
+	bytes     int
+	truncated bool
+	mu        sync.Mutex
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// newLogCollector 创建执行日志收集器并启动定时上报
+func newLogCollector(client SchedulerClient, instanceID uint64, maxBytes int) *logCollector {
+	c := &logCollector{
+		client:     client,
+		instanceID: instanceID,
+		maxBytes:   maxBytes,
+		stopCh:     make(chan struct{}),
+	}
+	c.wg.Add(1)
+	go c.run()
+	return c
+}
+
+// add 添加一条日志, 累计输出超过上限后丢弃并记录一次截断提示
+func (c *logCollector) add(level, content string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.truncated {
+		return
+	}
+	c.bytes += len(content)
+	if c.bytes > c.maxBytes {
+		c.truncated = true
+		level, content = model.LogLevelWarn, fmt.Sprintf("输出超过%d字节, 后续日志已丢弃", c.maxBytes)
+	}
+	c.logs = append(c.logs, &model.TaskLog{
+		InstanceID: c.instanceID,
+		LogTime:    time.Now(),
+		LogLevel:   level,
+		LogContent: content,
+	})
+}
+
+// writer 创建按行写入指定级别日志的输出
+func (c *logCollector) writer(level string) *lineWriter {
+	return &lineWriter{collector: c, level: level}
+}
+
+// run 定时上报
+func (c *logCollector) run() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(logFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.flush()
+		case <-c.stopCh:
+			c.flush()
+			return
+		}
+	}
+}
+
+// flush 上报缓存的日志, 上报失败时丢弃
+func (c *logCollector) flush() {
+	for {
+		c.mu.Lock()
+		n := len(c.logs)
+		if n > logFlushSize {
+			n = logFlushSize
+		}
+		batch := c.logs[:n:n]
+		c.logs = c.logs[n:]
+		c.mu.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+		if _, err := c.client.ReportLogs(context.Background(), batch); err != nil {
+			logger.Errorf("上报执行日志失败, instance_id=%d: %v", c.instanceID, err)
+		}
+	}
+}
+
+// Close 停止定时上报并上报剩余日志
+func (c *logCollector) Close() {
+	close(c.stopCh)
+	c.wg.Wait()
+}
+
+// lineWriter 按行切分输出写入日志收集器, 并保留最后几行用于失败信息
+type lineWriter struct {
+	collector *logCollector
+	level     string
+	buf       []byte
+	tail      []string
+	mu        sync.Mutex
+}
+
+// Write 实现io.Writer接口
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(string(bytes.TrimRight(w.buf[:i], "\r")))
+		w.buf = w.buf[i+1:]
+	}
+	// 超长的行按上限切分, 避免无换行的输出占用过多内存
+	if len(w.buf) >= maxLineBytes {
+		w.emit(string(w.buf))
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+// Flush 写入未以换行结尾的剩余输出
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.emit(string(w.buf))
+		w.buf = nil
+	}
+}
+
+// Tail 最后几行输出
+func (w *lineWriter) Tail() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return strings.Join(w.tail, "\n")
+}
+
+// emit 写入一行日志
+func (w *lineWriter) emit(line string) {
+	w.collector.add(w.level, line)
+	w.tail = append(w.tail, line)
+	if len(w.tail) > errorTailLines {
+		w.tail = w.tail[1:]
+	}
+}
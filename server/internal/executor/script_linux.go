@@ -0,0 +1,124 @@
+//go:build linux
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// 资源限制辅助进程配置
+const (
+	limitsEnv      = "DISTRIBUTED_SCHEDULER_SCRIPT_LIMITS" // 辅助进程的资源限制参数, 存在时当前进程作为辅助进程运行
+	limitsArg0     = "distributed-scheduler-script-limits"
+	limitsErrFD    = 3 // 辅助进程回报错误的管道
+	limitsExitCode = 127
+)
+
+// init 以资源限制辅助进程启动时设置资源限制后执行解释器, 不再返回
+func init() {
+	if spec, ok := os.LookupEnv(limitsEnv); ok {
+		runLimitsHelper(spec)
+	}
+}
+
+// prepareCommand 脚本在独立的进程组中运行, 终止时结束整个进程组, 避免遗留子进程
+func prepareCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// applyLimits 使命令在执行解释器前设置资源限制, 需在设置命令的环境变量后调用
+// 命令改为以辅助进程方式重新执行当前程序, 辅助进程设置资源限制后exec解释器, 脚本及其子进程从第一条指令起受限;
+// 返回的函数在命令启动后调用, 等待辅助进程exec并返回设置资源限制的错误
+func applyLimits(cmd *exec.Cmd, limits ResourceLimits) (func() error, error) {
+	if limits == (ResourceLimits{}) {
+		return func() error { return nil }, nil
+	}
+	if cmd.Err != nil {
+		return nil, cmd.Err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	spec := strings.Join([]string{
+		strconv.FormatUint(limits.CPUSeconds, 10),
+		strconv.FormatUint(limits.MemoryMB, 10),
+		strconv.FormatUint(limits.FileSizeMB, 10),
+		strconv.FormatUint(limits.OpenFiles, 10),
+	}, ",")
+	cmd.Args = append([]string{limitsArg0, cmd.Path}, cmd.Args...)
+	cmd.Path = self
+	cmd.Env = append(cmd.Env, limitsEnv+"="+spec)
+	cmd.ExtraFiles = []*os.File{w}
+
+	return func() error {
+		w.Close()
+		defer r.Close()
+		// 辅助进程exec成功时管道随之关闭, 失败时写入错误原因后退出
+		msg, _ := io.ReadAll(r)
+		if len(msg) > 0 {
+			return errors.New(string(msg))
+		}
+		return nil
+	}, nil
+}
+
+// runLimitsHelper 设置资源限制后exec解释器, 参数为解释器路径和解释器的命令行参数
+// 失败时将原因写入错误管道并退出
+func runLimitsHelper(spec string) {
+	errPipe := os.NewFile(limitsErrFD, "limits")
+	fail := func(err error) {
+		fmt.Fprint(errPipe, err.Error())
+		os.Exit(limitsExitCode)
+	}
+
+	if len(os.Args) < 3 {
+		fail(errors.New("缺少解释器参数"))
+	}
+	values := strings.Split(spec, ",")
+	resources := []int{unix.RLIMIT_CPU, unix.RLIMIT_AS, unix.RLIMIT_FSIZE, unix.RLIMIT_NOFILE}
+	shifts := []uint{0, 20, 20, 0}
+	if len(values) != len(resources) {
+		fail(fmt.Errorf("资源限制参数无效: %s", spec))
+	}
+	for i, resource := range resources {
+		value, err := strconv.ParseUint(values[i], 10, 64)
+		if err != nil {
+			fail(fmt.Errorf("资源限制参数无效: %s", spec))
+		}
+		if value == 0 {
+			continue
+		}
+		value <<= shifts[i]
+		// 使用syscall.Setrlimit, 运行时不会在exec时恢复打开文件数的原始限制
+		if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: value, Max: value}); err != nil {
+			fail(err)
+		}
+	}
+
+	env := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, limitsEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+	syscall.CloseOnExec(limitsErrFD)
+	fail(syscall.Exec(os.Args[1], os.Args[2:], env))
+}
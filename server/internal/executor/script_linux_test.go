@@ -0,0 +1,33 @@
+//go:build linux
+
+package executor
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScriptLimitsAppliedBeforeExec(t *testing.T) {
+	runner := newScriptRunner(ScriptOptions{WorkDir: t.TempDir(), Limits: ResourceLimits{OpenFiles: 64}}, newFakeClient())
+	// 解释器和其子进程从启动起受限, 资源限制参数不会传给脚本
+	content := `test "$(ulimit -n)" = 64 && test "$(sh -c 'ulimit -n')" = 64 && test -z "$` + limitsEnv + `"`
+	if err := runner.Handle(context.Background(), newTestScriptTask(1, 1, content)); err != nil {
+		t.Fatalf("limited script: %v", err)
+	}
+}
+
+func TestScriptLimitsFailure(t *testing.T) {
+	dir := t.TempDir()
+	// 打开文件数超过系统上限, 设置失败时脚本不执行
+	runner := newScriptRunner(ScriptOptions{WorkDir: dir, KeepWorkDir: true, Limits: ResourceLimits{OpenFiles: 1 << 40}}, newFakeClient())
+	err := runner.Handle(context.Background(), newTestScriptTask(1, 1, "touch ran"))
+	if err == nil || !strings.Contains(err.Error(), "设置资源限制失败") {
+		t.Fatalf("err = %v, want limits failure", err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*", "ran"))
+	if len(matches) > 0 {
+		t.Fatal("script ran although limits could not be applied")
+	}
+}
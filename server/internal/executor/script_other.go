@@ -0,0 +1,19 @@
+//go:build !linux
+
+package executor
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// prepareCommand 非Linux系统使用默认的终止方式, 只结束解释器进程
+func prepareCommand(cmd *exec.Cmd) {}
+
+// applyLimits 非Linux系统不支持资源限制, 配置了资源限制时返回错误
+func applyLimits(cmd *exec.Cmd, limits ResourceLimits) (func() error, error) {
+	if limits == (ResourceLimits{}) {
+		return func() error { return nil }, nil
+	}
+	return nil, errors.New("当前系统不支持资源限制")
+}
@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"distributed-scheduler/internal/model"
+)
+
+// newTestScriptTask 创建sh脚本任务
+func newTestScriptTask(instanceID, token uint64, content string) *model.ExecutorTask {
+	return &model.ExecutorTask{
+		InstanceID:    instanceID,
+		DispatchToken: token,
+		Script:        &model.ExecutorScript{ScriptType: model.ScriptTypeSh, Version: 1, Content: content},
+	}
+}
+
+func TestScriptWorkDirPerDispatch(t *testing.T) {
+	root := t.TempDir()
+	runner := newScriptRunner(ScriptOptions{WorkDir: root}, newFakeClient())
+
+	// 旧的执行先结束并删除自己的目录, 不能影响同一实例重新分发后的执行
+	oldDone := make(chan error, 1)
+	go func() {
+		oldDone <- runner.Handle(context.Background(), newTestScriptTask(1, 1, "sleep 0.2"))
+	}()
+	time.Sleep(50 * time.Millisecond)
+	err := runner.Handle(context.Background(), newTestScriptTask(1, 2, "touch marker; sleep 0.6; test -f marker"))
+	if err != nil {
+		t.Fatalf("new dispatch: %v", err)
+	}
+	if err := <-oldDone; err != nil {
+		t.Fatalf("old dispatch: %v", err)
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("work dirs left behind: %d", len(entries))
+	}
+}
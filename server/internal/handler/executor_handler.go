@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 
 	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/middleware"
 	"distributed-scheduler/internal/model"
 	"distributed-scheduler/internal/service"
 )
@@ -13,21 +15,25 @@ import (
 // ExecutorHandler 执行器处理器
 type ExecutorHandler struct {
 	executorService service.ExecutorService
+	instanceService service.InstanceService
 }
 
 // NewExecutorHandler 创建执行器处理器
 func NewExecutorHandler() *ExecutorHandler {
 	return &ExecutorHandler{
 		executorService: service.NewExecutorService(),
+		instanceService: service.NewInstanceService(),
 	}
 }
 
 // RegisterRequest 注册请求
 type RegisterRequest struct {
-	AppName       string `json:"app_name" binding:"required,max=64"`
-	Host          string `json:"host" binding:"required"`
-	Port          uint   `json:"port" binding:"required,min=1,max=65535"`
-	MaxConcurrent uint   `json:"max_concurrent" binding:"min=1"`
+	AppName       string            `json:"app_name" binding:"required,max=64"`
+	InstanceID    string            `json:"instance_id" binding:"max=128"` // 实例标识(可选), 为空时按host:port识别执行器
+	Host          string            `json:"host" binding:"required"`
+	Port          uint              `json:"port" binding:"required,min=1,max=65535"`
+	MaxConcurrent uint              `json:"max_concurrent" binding:"min=1"`
+	Labels        map[string]string `json:"labels"`
 }
 
 // Register 注册执行器
@@ -49,7 +55,13 @@ func (h *ExecutorHandler) Register(c *gin.Context) {
 		req.MaxConcurrent = 100
 	}
 
-	node, err := h.executorService.Register(c.Request.Context(), req.AppName, req.Host, req.Port, req.MaxConcurrent)
+	// 只能注册到签名令牌所属的任务组
+	if group := middleware.GetExecutorGroup(c); group != nil && group.AppName != req.AppName {
+		response.Forbidden(c, "应用名称与访问令牌所属的任务组不一致")
+		return
+	}
+
+	node, err := h.executorService.Register(c.Request.Context(), req.AppName, req.InstanceID, req.Host, req.Port, req.MaxConcurrent, req.Labels)
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -78,8 +90,8 @@ func (h *ExecutorHandler) Unregister(c *gin.Context) {
 		return
 	}
 
-	if err := h.executorService.Unregister(c.Request.Context(), req.ExecutorID); err != nil {
-		response.ServerError(c, err.Error())
+	if err := h.executorService.Unregister(c.Request.Context(), middleware.GetExecutorGroupID(c), req.ExecutorID); err != nil {
+		h.handleError(c, err)
 		return
 	}
 
@@ -92,7 +104,7 @@ func (h *ExecutorHandler) Unregister(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body model.ExecutorHeartbeat true "心跳请求"
-// @Success 200 {object} response.Response
+// @Success 200 {object} response.Response{data=model.ExecutorHeartbeatAck}
 // @Router /api/v1/executor/heartbeat [post]
 func (h *ExecutorHandler) Heartbeat(c *gin.Context) {
 	var req model.ExecutorHeartbeat
@@ -101,7 +113,115 @@ func (h *ExecutorHandler) Heartbeat(c *gin.Context) {
 		return
 	}
 
-	if err := h.executorService.Heartbeat(c.Request.Context(), &req); err != nil {
+	ack, err := h.executorService.Heartbeat(c.Request.Context(), middleware.GetExecutorGroupID(c), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, ack)
+}
+
+// DrainRequest 执行器摘流请求
+type DrainRequest struct {
+	ExecutorID string `json:"executor_id" binding:"required"`
+}
+
+// Drain 执行器主动摘流
+// @Summary 执行器主动摘流
+// @Tags 执行器管理
+// @Accept json
+// @Produce json
+// @Param request body DrainRequest true "摘流请求"
+// @Success 200 {object} response.Response{data=model.ExecutorNode}
+// @Router /api/v1/executor/drain [post]
+func (h *ExecutorHandler) Drain(c *gin.Context) {
+	var req DrainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	node, err := h.executorService.Drain(c.Request.Context(), middleware.GetExecutorGroupID(c), req.ExecutorID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, node)
+}
+
+// StartDrain 管理端对执行器发起摘流
+// @Summary 执行器摘流
+// @Tags 执行器管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "执行器ID"
+// @Success 200 {object} response.Response{data=model.ExecutorNode}
+// @Router /api/v1/executor/{id}/drain [post]
+func (h *ExecutorHandler) StartDrain(c *gin.Context) {
+	if _, err := h.executorService.GetByID(c.Request.Context(), middleware.GetGroupScope(c), c.Param("id")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	node, err := h.executorService.Drain(c.Request.Context(), 0, c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, node)
+}
+
+// CancelDrain 取消执行器摘流
+// @Summary 取消执行器摘流
+// @Tags 执行器管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "执行器ID"
+// @Success 200 {object} response.Response{data=model.ExecutorNode}
+// @Router /api/v1/executor/{id}/undrain [post]
+func (h *ExecutorHandler) CancelDrain(c *gin.Context) {
+	node, err := h.executorService.CancelDrain(c.Request.Context(), middleware.GetGroupScope(c), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, node)
+}
+
+// Callback 执行结果回调
+// @Summary 执行器上报执行结果
+// @Tags 执行器管理
+// @Accept json
+// @Produce json
+// @Param request body model.ExecutorResult true "执行结果"
+// @Success 200 {object} response.Response
+// @Router /api/v1/executor/callback [post]
+func (h *ExecutorHandler) Callback(c *gin.Context) {
+	var req model.ExecutorResult
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	if err := h.instanceService.Callback(c.Request.Context(), middleware.GetExecutorGroupID(c), &req); err != nil {
+		if err == service.ErrInstanceNotFound {
+			response.NotFound(c, "任务实例不存在")
+			return
+		}
+		if err == service.ErrInstanceForbidden {
+			response.Forbidden(c, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrStaleDispatch) {
+			response.Error(c, response.CodeScheduleError, err.Error())
+			return
+		}
 		response.ServerError(c, err.Error())
 		return
 	}
@@ -109,6 +229,58 @@ func (h *ExecutorHandler) Heartbeat(c *gin.Context) {
 	response.Success(c, nil)
 }
 
+// ReportLogsRequest 执行日志上报请求
+type ReportLogsRequest struct {
+	Logs []*model.ExecutorLog `json:"logs" binding:"required,max=1000,dive"`
+}
+
+// ReportLogs 上报执行日志
+// @Summary 执行器上报执行日志
+// @Tags 执行器管理
+// @Accept json
+// @Produce json
+// @Param request body ReportLogsRequest true "执行日志"
+// @Success 200 {object} response.Response
+// @Router /api/v1/executor/log [post]
+func (h *ExecutorHandler) ReportLogs(c *gin.Context) {
+	var req ReportLogsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	logs := make([]*model.TaskLog, 0, len(req.Logs))
+	for _, log := range req.Logs {
+		logs = append(logs, log.ToTaskLog())
+	}
+	if err := h.instanceService.SaveLogs(c.Request.Context(), middleware.GetExecutorGroupID(c), logs); err != nil {
+		if err == service.ErrInstanceNotFound {
+			response.NotFound(c, "任务实例不存在")
+			return
+		}
+		if err == service.ErrInstanceForbidden {
+			response.Forbidden(c, err.Error())
+			return
+		}
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"saved": len(logs)})
+}
+
+// handleError 处理执行器注销/心跳/摘流的错误
+func (h *ExecutorHandler) handleError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrExecutorNotFound:
+		response.NotFound(c, "执行器不存在")
+	case service.ErrExecutorForbidden, service.ErrGroupForbidden:
+		response.Forbidden(c, err.Error())
+	default:
+		response.ServerError(c, err.Error())
+	}
+}
+
 // GetByID 获取执行器详情
 // @Summary 获取执行器详情
 // @Tags 执行器管理
@@ -125,13 +297,9 @@ func (h *ExecutorHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	node, err := h.executorService.GetByID(c.Request.Context(), id)
+	node, err := h.executorService.GetByID(c.Request.Context(), middleware.GetGroupScope(c), id)
 	if err != nil {
-		if err == service.ErrExecutorNotFound {
-			response.NotFound(c, "执行器不存在")
-			return
-		}
-		response.ServerError(c, err.Error())
+		h.handleError(c, err)
 		return
 	}
 
@@ -140,10 +308,11 @@ func (h *ExecutorHandler) GetByID(c *gin.Context) {
 
 // ExecutorListRequest 执行器列表请求
 type ExecutorListRequest struct {
-	Page     int    `form:"page" binding:"min=1"`
-	PageSize int    `form:"page_size" binding:"min=1,max=100"`
-	GroupID  uint64 `form:"group_id"`
-	Status   int8   `form:"status" binding:"min=-1,max=1"`
+	Page        int    `form:"page" binding:"min=1"`
+	PageSize    int    `form:"page_size" binding:"min=1,max=100"`
+	GroupID     uint64 `form:"group_id"`
+	Status      int8   `form:"status" binding:"min=-1,max=1"`
+	DrainStatus int8   `form:"drain_status" binding:"min=-1,max=2"`
 }
 
 // List 执行器列表
@@ -156,11 +325,13 @@ type ExecutorListRequest struct {
 // @Param page_size query int false "每页数量"
 // @Param group_id query int false "任务组ID"
 // @Param status query int false "状态"
+// @Param drain_status query int false "摘流状态"
 // @Success 200 {object} response.Response{data=response.PageResult}
 // @Router /api/v1/executor [get]
 func (h *ExecutorHandler) List(c *gin.Context) {
 	var req ExecutorListRequest
 	req.Status = -1
+	req.DrainStatus = -1
 	if err := c.ShouldBindQuery(&req); err != nil {
 		response.ParamError(c, err.Error())
 		return
@@ -173,7 +344,7 @@ func (h *ExecutorHandler) List(c *gin.Context) {
 		req.PageSize = 10
 	}
 
-	nodes, total, err := h.executorService.List(c.Request.Context(), req.Page, req.PageSize, req.GroupID, req.Status)
+	nodes, total, err := h.executorService.List(c.Request.Context(), middleware.GetGroupScope(c), req.Page, req.PageSize, req.GroupID, req.Status, req.DrainStatus)
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -198,12 +369,11 @@ func (h *ExecutorHandler) GetOnlineByGroupID(c *gin.Context) {
 		return
 	}
 
-	nodes, err := h.executorService.GetOnlineByGroupID(c.Request.Context(), groupID)
+	nodes, err := h.executorService.GetOnlineByGroupID(c.Request.Context(), middleware.GetGroupScope(c), groupID)
 	if err != nil {
-		response.ServerError(c, err.Error())
+		h.handleError(c, err)
 		return
 	}
 
 	response.Success(c, nodes)
 }
-
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -25,9 +27,10 @@ func NewGroupHandler() *GroupHandler {
 
 // CreateGroupRequest 创建任务组请求
 type CreateGroupRequest struct {
-	Name        string `json:"name" binding:"required,max=128"`
-	Description string `json:"description" binding:"max=512"`
-	AppName     string `json:"app_name" binding:"required,max=64"`
+	Name          string `json:"name" binding:"required,max=128"`
+	Description   string `json:"description" binding:"max=512"`
+	AppName       string `json:"app_name" binding:"required,max=64"`
+	MaxConcurrent uint   `json:"max_concurrent"` // 最大并发实例数, 0表示不限制
 }
 
 // Create 创建任务组
@@ -47,14 +50,15 @@ func (h *GroupHandler) Create(c *gin.Context) {
 	}
 
 	group := &model.TaskGroup{
-		Name:        req.Name,
-		Description: req.Description,
-		AppName:     req.AppName,
-		Status:      1,
-		CreatedBy:   middleware.GetUserID(c),
+		Name:          req.Name,
+		Description:   req.Description,
+		AppName:       req.AppName,
+		MaxConcurrent: req.MaxConcurrent,
+		Status:        1,
+		CreatedBy:     middleware.GetUserID(c),
 	}
 
-	if err := h.groupService.Create(c.Request.Context(), group); err != nil {
+	if err := h.groupService.Create(c.Request.Context(), group, middleware.GetUserID(c)); err != nil {
 		response.ServerError(c, err.Error())
 		return
 	}
@@ -85,18 +89,20 @@ func (h *GroupHandler) Update(c *gin.Context) {
 		return
 	}
 
-	group, err := h.groupService.GetByID(c.Request.Context(), id)
+	scope := middleware.GetGroupScope(c)
+	group, err := h.groupService.GetByID(c.Request.Context(), scope, id)
 	if err != nil {
-		response.ServerError(c, err.Error())
+		handleGroupError(c, err)
 		return
 	}
 
 	group.Name = req.Name
 	group.Description = req.Description
 	group.AppName = req.AppName
+	group.MaxConcurrent = req.MaxConcurrent
 
-	if err := h.groupService.Update(c.Request.Context(), group); err != nil {
-		response.ServerError(c, err.Error())
+	if err := h.groupService.Update(c.Request.Context(), scope, group); err != nil {
+		handleGroupError(c, err)
 		return
 	}
 
@@ -119,8 +125,8 @@ func (h *GroupHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.groupService.Delete(c.Request.Context(), id); err != nil {
-		response.ServerError(c, err.Error())
+	if err := h.groupService.Delete(c.Request.Context(), middleware.GetGroupScope(c), id); err != nil {
+		handleGroupError(c, err)
 		return
 	}
 
@@ -143,15 +149,81 @@ func (h *GroupHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	group, err := h.groupService.GetByID(c.Request.Context(), id)
+	group, err := h.groupService.GetByID(c.Request.Context(), middleware.GetGroupScope(c), id)
 	if err != nil {
-		response.ServerError(c, err.Error())
+		handleGroupError(c, err)
 		return
 	}
 
 	response.Success(c, group)
 }
 
+// GroupTokenResponse 任务组访问令牌
+type GroupTokenResponse struct {
+	AppName       string     `json:"app_name"`
+	AccessToken   string     `json:"access_token"`
+	TokenRotateAt *time.Time `json:"token_rotate_at"`
+}
+
+// GetToken 获取任务组的执行器访问令牌
+// @Summary 获取任务组的执行器访问令牌
+// @Tags 任务组管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "任务组ID"
+// @Success 200 {object} response.Response{data=GroupTokenResponse}
+// @Router /api/v1/group/{id}/token [get]
+func (h *GroupHandler) GetToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ParamError(c, "无效的任务组ID")
+		return
+	}
+
+	group, err := h.groupService.GetByID(c.Request.Context(), middleware.GetGroupScope(c), id)
+	if err != nil {
+		handleGroupError(c, err)
+		return
+	}
+
+	response.Success(c, newGroupTokenResponse(group))
+}
+
+// RotateToken 轮换任务组的执行器访问令牌
+// @Summary 轮换任务组的执行器访问令牌(旧令牌在宽限期内仍然有效)
+// @Tags 任务组管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "任务组ID"
+// @Success 200 {object} response.Response{data=GroupTokenResponse}
+// @Router /api/v1/group/{id}/token/rotate [post]
+func (h *GroupHandler) RotateToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ParamError(c, "无效的任务组ID")
+		return
+	}
+
+	group, err := h.groupService.RotateToken(c.Request.Context(), middleware.GetGroupScope(c), id)
+	if err != nil {
+		handleGroupError(c, err)
+		return
+	}
+
+	response.Success(c, newGroupTokenResponse(group))
+}
+
+// newGroupTokenResponse 构造访问令牌响应
+func newGroupTokenResponse(group *model.TaskGroup) *GroupTokenResponse {
+	return &GroupTokenResponse{
+		AppName:       group.AppName,
+		AccessToken:   group.AccessToken,
+		TokenRotateAt: group.TokenRotateAt,
+	}
+}
+
 // GroupListRequest 任务组列表请求
 type GroupListRequest struct {
 	Page     int    `form:"page" binding:"min=1"`
@@ -184,7 +256,7 @@ func (h *GroupHandler) List(c *gin.Context) {
 		req.PageSize = 10
 	}
 
-	groups, total, err := h.groupService.List(c.Request.Context(), req.Page, req.PageSize, req.Keyword)
+	groups, total, err := h.groupService.List(c.Request.Context(), middleware.GetGroupScope(c), req.Page, req.PageSize, req.Keyword)
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -202,7 +274,7 @@ func (h *GroupHandler) List(c *gin.Context) {
 // @Success 200 {object} response.Response
 // @Router /api/v1/group/all [get]
 func (h *GroupHandler) GetAll(c *gin.Context) {
-	groups, err := h.groupService.GetAll(c.Request.Context())
+	groups, err := h.groupService.GetAll(c.Request.Context(), middleware.GetGroupScope(c))
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -211,3 +283,118 @@ func (h *GroupHandler) GetAll(c *gin.Context) {
 	response.Success(c, groups)
 }
 
+// ListMembers 获取任务组成员
+// @Summary 获取任务组成员
+// @Tags 任务组管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "任务组ID"
+// @Success 200 {object} response.Response{data=[]model.TaskGroupMember}
+// @Router /api/v1/group/{id}/members [get]
+func (h *GroupHandler) ListMembers(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ParamError(c, "无效的任务组ID")
+		return
+	}
+
+	members, err := h.groupService.ListMembers(c.Request.Context(), middleware.GetGroupScope(c), id)
+	if err != nil {
+		handleGroupError(c, err)
+		return
+	}
+
+	response.Success(c, members)
+}
+
+// SaveMemberRequest 任务组成员授权请求
+type SaveMemberRequest struct {
+	UserID uint64 `json:"user_id" binding:"required"`
+	Role   string `json:"role" binding:"required,oneof=OWNER OPERATOR VIEWER"`
+}
+
+// SaveMember 授权用户访问任务组
+// @Summary 授权用户访问任务组(已是成员时更新组内角色)
+// @Tags 任务组管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "任务组ID"
+// @Param request body SaveMemberRequest true "成员授权请求"
+// @Success 200 {object} response.Response{data=model.TaskGroupMember}
+// @Router /api/v1/group/{id}/members [put]
+func (h *GroupHandler) SaveMember(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ParamError(c, "无效的任务组ID")
+		return
+	}
+
+	var req SaveMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	member := &model.TaskGroupMember{
+		GroupID:   id,
+		UserID:    req.UserID,
+		Role:      req.Role,
+		CreatedBy: middleware.GetUserID(c),
+	}
+	if err := h.groupService.SaveMember(c.Request.Context(), middleware.GetGroupScope(c), member); err != nil {
+		handleGroupError(c, err)
+		return
+	}
+
+	response.Success(c, member)
+}
+
+// RemoveMember 取消用户对任务组的授权
+// @Summary 取消用户对任务组的授权
+// @Tags 任务组管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "任务组ID"
+// @Param user_id path int true "用户ID"
+// @Success 200 {object} response.Response
+// @Router /api/v1/group/{id}/members/{user_id} [delete]
+func (h *GroupHandler) RemoveMember(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ParamError(c, "无效的任务组ID")
+		return
+	}
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		response.ParamError(c, "无效的用户ID")
+		return
+	}
+
+	if err := h.groupService.RemoveMember(c.Request.Context(), middleware.GetGroupScope(c), id, userID); err != nil {
+		handleGroupError(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// handleGroupError 处理任务组操作的错误
+func handleGroupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrGroupNotFound):
+		response.Error(c, response.CodeGroupNotFound, "")
+	case errors.Is(err, service.ErrGroupForbidden):
+		response.Forbidden(c, err.Error())
+	case errors.Is(err, service.ErrUserNotFound):
+		response.Error(c, response.CodeUserNotFound, "")
+	case errors.Is(err, service.ErrGroupMemberNotFound):
+		response.NotFound(c, err.Error())
+	case errors.Is(err, service.ErrInvalidGroupRole):
+		response.ParamError(c, err.Error())
+	default:
+		response.ServerError(c, err.Error())
+	}
+}
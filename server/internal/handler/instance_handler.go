@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/middleware"
 	"distributed-scheduler/internal/service"
 )
 
@@ -38,13 +39,9 @@ func (h *InstanceHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	instance, err := h.instanceService.GetByID(c.Request.Context(), id)
+	instance, err := h.instanceService.GetByID(c.Request.Context(), middleware.GetGroupScope(c), id)
 	if err != nil {
-		if err == service.ErrInstanceNotFound {
-			response.NotFound(c, "任务实例不存在")
-			return
-		}
-		response.ServerError(c, err.Error())
+		handleInstanceError(c, err)
 		return
 	}
 
@@ -104,7 +101,7 @@ func (h *InstanceHandler) List(c *gin.Context) {
 		}
 	}
 
-	instances, total, err := h.instanceService.List(c.Request.Context(), req.Page, req.PageSize, req.TaskID, req.Status, startTime, endTime)
+	instances, total, err := h.instanceService.List(c.Request.Context(), middleware.GetGroupScope(c), req.Page, req.PageSize, req.TaskID, req.Status, startTime, endTime)
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -129,8 +126,8 @@ func (h *InstanceHandler) Cancel(c *gin.Context) {
 		return
 	}
 
-	if err := h.instanceService.Cancel(c.Request.Context(), id); err != nil {
-		response.ServerError(c, err.Error())
+	if err := h.instanceService.Cancel(c.Request.Context(), middleware.GetGroupScope(c), id); err != nil {
+		handleInstanceError(c, err)
 		return
 	}
 
@@ -153,9 +150,9 @@ func (h *InstanceHandler) Retry(c *gin.Context) {
 		return
 	}
 
-	instance, err := h.instanceService.Retry(c.Request.Context(), id)
+	instance, err := h.instanceService.Retry(c.Request.Context(), middleware.GetGroupScope(c), id)
 	if err != nil {
-		response.ServerError(c, err.Error())
+		handleInstanceError(c, err)
 		return
 	}
 
@@ -183,9 +180,9 @@ func (h *InstanceHandler) GetLogs(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "100"))
 
-	logs, total, err := h.instanceService.GetLogs(c.Request.Context(), id, page, pageSize)
+	logs, total, err := h.instanceService.GetLogs(c.Request.Context(), middleware.GetGroupScope(c), id, page, pageSize)
 	if err != nil {
-		response.ServerError(c, err.Error())
+		handleInstanceError(c, err)
 		return
 	}
 
@@ -212,7 +209,7 @@ func (h *InstanceHandler) GetStatistics(c *gin.Context) {
 	startTime, _ := time.ParseInLocation("2006-01-02 15:04:05", startTimeStr, time.Local)
 	endTime, _ := time.ParseInLocation("2006-01-02 15:04:05", endTimeStr, time.Local)
 
-	stats, err := h.instanceService.GetStatistics(c.Request.Context(), taskID, startTime, endTime)
+	stats, err := h.instanceService.GetStatistics(c.Request.Context(), middleware.GetGroupScope(c), taskID, startTime, endTime)
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -236,7 +233,7 @@ func (h *InstanceHandler) GetRecentInstances(c *gin.Context) {
 		limit = 10
 	}
 
-	instances, err := h.instanceService.GetRecentInstances(c.Request.Context(), limit)
+	instances, err := h.instanceService.GetRecentInstances(c.Request.Context(), middleware.GetGroupScope(c), limit)
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -245,3 +242,16 @@ func (h *InstanceHandler) GetRecentInstances(c *gin.Context) {
 	response.Success(c, instances)
 }
 
+// handleInstanceError 处理任务实例操作的错误
+func handleInstanceError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrInstanceNotFound:
+		response.NotFound(c, "任务实例不存在")
+	case service.ErrGroupForbidden:
+		response.Forbidden(c, err.Error())
+	case service.ErrInstanceFinished, service.ErrInstanceChanged:
+		response.ParamError(c, err.Error())
+	default:
+		response.ServerError(c, err.Error())
+	}
+}
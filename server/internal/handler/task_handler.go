@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -25,22 +26,53 @@ func NewTaskHandler() *TaskHandler {
 
 // CreateTaskRequest 创建任务请求
 type CreateTaskRequest struct {
-	GroupID         uint64   `json:"group_id" binding:"required"`
-	Name            string   `json:"name" binding:"required,max=128"`
-	Description     string   `json:"description" binding:"max=512"`
-	Cron            string   `json:"cron" binding:"required"`
-	ExecutorType    string   `json:"executor_type" binding:"required,oneof=HTTP GRPC SCRIPT"`
-	ExecutorHandler string   `json:"executor_handler" binding:"required,max=256"`
-	ExecutorParam   string   `json:"executor_param"`
-	RouteStrategy   string   `json:"route_strategy" binding:"omitempty,oneof=ROUND_ROBIN RANDOM CONSISTENT_HASH LEAST_FREQUENTLY_USED LEAST_RECENTLY_USED FAILOVER SHARDING_BROADCAST"`
-	BlockStrategy   string   `json:"block_strategy" binding:"omitempty,oneof=SERIAL_EXECUTION DISCARD_LATER COVER_EARLY"`
-	ShardNum        uint     `json:"shard_num"`
-	RetryCount      uint     `json:"retry_count"`
-	RetryInterval   uint     `json:"retry_interval"`
-	Timeout         uint     `json:"timeout"`
-	AlarmEmail      string   `json:"alarm_email"`
-	Priority        int      `json:"priority"`
-	DependencyIDs   []uint64 `json:"dependency_ids"`
+	GroupID           uint64   `json:"group_id" binding:"required"`
+	Name              string   `json:"name" binding:"required,max=128"`
+	Description       string   `json:"description" binding:"max=512"`
+	Cron              string   `json:"cron" binding:"required"`
+	ExecutorType      string   `json:"executor_type" binding:"required,oneof=HTTP GRPC SCRIPT HTTP_CALL"`
+	ExecutorHandler   string   `json:"executor_handler" binding:"max=256"` // HTTP、GRPC类型任务必填
+	ExecutorParam     string   `json:"executor_param"`
+	RouteStrategy     string   `json:"route_strategy" binding:"max=32"` // 已注册的路由策略, 见 /task/route-strategies
+	RouteOptions      string   `json:"route_options"`                   // 路由策略参数(JSON)
+	LabelSelector     string   `json:"label_selector" binding:"max=512"`
+	PreferredSelector string   `json:"preferred_selector" binding:"max=512"`
+	AntiAffinity      bool     `json:"anti_affinity"`
+	BlockStrategy     string   `json:"block_strategy" binding:"omitempty,oneof=SERIAL_EXECUTION DISCARD_LATER COVER_EARLY"`
+	FailoverStrategy  string   `json:"failover_strategy" binding:"omitempty,oneof=FAIL REROUTE"`
+	ShardNum          uint     `json:"shard_num"`
+	RetryCount        uint     `json:"retry_count"`
+	RetryInterval     uint     `json:"retry_interval"`
+	Timeout           uint     `json:"timeout"`
+	AlarmEmail        string   `json:"alarm_email"`
+	Priority          int      `json:"priority"`
+	MaxConcurrent     uint     `json:"max_concurrent"` // 最大并发实例数, 0表示不限制
+	DependencyIDs     []uint64 `json:"dependency_ids"`
+
+	Script   *TaskScriptRequest    `json:"script"`    // SCRIPT类型任务的脚本, 更新时为空表示不修改
+	HTTPCall *model.HTTPCallConfig `json:"http_call"` // HTTP_CALL类型任务的请求配置
+}
+
+// TaskScriptRequest 任务脚本请求
+type TaskScriptRequest struct {
+	ScriptType string            `json:"script_type" binding:"required,max=32"`
+	Content    string            `json:"content" binding:"required"`
+	Env        map[string]string `json:"env"`
+	Remark     string            `json:"remark" binding:"max=256"`
+}
+
+// toModel 转换为脚本版本, 请求为空时返回nil
+func (r *TaskScriptRequest) toModel(userID uint64) *model.TaskScript {
+	if r == nil {
+		return nil
+	}
+	return &model.TaskScript{
+		ScriptType: r.ScriptType,
+		Content:    r.Content,
+		Env:        r.Env,
+		Remark:     r.Remark,
+		CreatedBy:  userID,
+	}
 }
 
 // Create 创建任务
@@ -60,23 +92,31 @@ func (h *TaskHandler) Create(c *gin.Context) {
 	}
 
 	task := &model.Task{
-		GroupID:         req.GroupID,
-		Name:            req.Name,
-		Description:     req.Description,
-		Cron:            req.Cron,
-		ExecutorType:    req.ExecutorType,
-		ExecutorHandler: req.ExecutorHandler,
-		ExecutorParam:   req.ExecutorParam,
-		RouteStrategy:   req.RouteStrategy,
-		BlockStrategy:   req.BlockStrategy,
-		ShardNum:        req.ShardNum,
-		RetryCount:      req.RetryCount,
-		RetryInterval:   req.RetryInterval,
-		Timeout:         req.Timeout,
-		AlarmEmail:      req.AlarmEmail,
-		Priority:        req.Priority,
-		Status:          model.TaskStatusDisabled,
-		CreatedBy:       middleware.GetUserID(c),
+		GroupID:           req.GroupID,
+		Name:              req.Name,
+		Description:       req.Description,
+		Cron:              req.Cron,
+		ExecutorType:      req.ExecutorType,
+		ExecutorHandler:   req.ExecutorHandler,
+		ExecutorParam:     req.ExecutorParam,
+		RouteStrategy:     req.RouteStrategy,
+		RouteOptions:      req.RouteOptions,
+		LabelSelector:     req.LabelSelector,
+		PreferredSelector: req.PreferredSelector,
+		AntiAffinity:      req.AntiAffinity,
+		BlockStrategy:     req.BlockStrategy,
+		FailoverStrategy:  req.FailoverStrategy,
+		ShardNum:          req.ShardNum,
+		RetryCount:        req.RetryCount,
+		RetryInterval:     req.RetryInterval,
+		Timeout:           req.Timeout,
+		AlarmEmail:        req.AlarmEmail,
+		Priority:          req.Priority,
+		MaxConcurrent:     req.MaxConcurrent,
+		Status:            model.TaskStatusDisabled,
+		CreatedBy:         middleware.GetUserID(c),
+		Script:            req.Script.toModel(middleware.GetUserID(c)),
+		HTTPCall:          req.HTTPCall,
 	}
 
 	// 设置默认值
@@ -86,16 +126,24 @@ func (h *TaskHandler) Create(c *gin.Context) {
 	if task.BlockStrategy == "" {
 		task.BlockStrategy = model.BlockStrategySerialExecution
 	}
+	if task.FailoverStrategy == "" {
+		task.FailoverStrategy = model.FailoverStrategyFail
+	}
 	if task.ShardNum == 0 {
 		task.ShardNum = 1
 	}
 
-	if err := h.taskService.Create(c.Request.Context(), task); err != nil {
-		switch err {
-		case service.ErrInvalidCron:
+	if err := h.taskService.Create(c.Request.Context(), middleware.GetGroupScope(c), task); err != nil {
+		switch {
+		case err == service.ErrInvalidCron:
 			response.ParamError(c, "无效的Cron表达式")
-		case service.ErrGroupNotFound:
+		case errors.Is(err, service.ErrInvalidSelector), errors.Is(err, service.ErrInvalidRoute), errors.Is(err, service.ErrInvalidScript),
+			errors.Is(err, service.ErrInvalidHTTPCall), errors.Is(err, service.ErrHandlerRequired):
+			response.ParamError(c, err.Error())
+		case err == service.ErrGroupNotFound:
 			response.Error(c, response.CodeGroupNotFound, "")
+		case err == service.ErrGroupForbidden:
+			response.Forbidden(c, err.Error())
 		default:
 			response.ServerError(c, err.Error())
 		}
@@ -129,13 +177,10 @@ func (h *TaskHandler) Update(c *gin.Context) {
 	}
 
 	// 获取原任务
-	task, err := h.taskService.GetByID(c.Request.Context(), id)
+	scope := middleware.GetGroupScope(c)
+	task, err := h.taskService.GetByID(c.Request.Context(), scope, id)
 	if err != nil {
-		if err == service.ErrTaskNotFound {
-			response.Error(c, response.CodeTaskNotFound, "")
-			return
-		}
-		response.ServerError(c, err.Error())
+		handleTaskError(c, err)
 		return
 	}
 
@@ -148,19 +193,39 @@ func (h *TaskHandler) Update(c *gin.Context) {
 	task.ExecutorHandler = req.ExecutorHandler
 	task.ExecutorParam = req.ExecutorParam
 	task.RouteStrategy = req.RouteStrategy
+	task.RouteOptions = req.RouteOptions
+	task.LabelSelector = req.LabelSelector
+	task.PreferredSelector = req.PreferredSelector
+	task.AntiAffinity = req.AntiAffinity
 	task.BlockStrategy = req.BlockStrategy
+	task.FailoverStrategy = req.FailoverStrategy
+	if task.FailoverStrategy == "" {
+		task.FailoverStrategy = model.FailoverStrategyFail
+	}
 	task.ShardNum = req.ShardNum
 	task.RetryCount = req.RetryCount
 	task.RetryInterval = req.RetryInterval
 	task.Timeout = req.Timeout
 	task.AlarmEmail = req.AlarmEmail
 	task.Priority = req.Priority
+	task.MaxConcurrent = req.MaxConcurrent
+	task.Script = req.Script.toModel(middleware.GetUserID(c))
+	task.HTTPCall = req.HTTPCall
 
-	if err := h.taskService.Update(c.Request.Context(), task); err != nil {
+	if err := h.taskService.Update(c.Request.Context(), scope, task); err != nil {
+		if err == service.ErrGroupForbidden {
+			response.Forbidden(c, err.Error())
+			return
+		}
 		if err == service.ErrInvalidCron {
 			response.ParamError(c, "无效的Cron表达式")
 			return
 		}
+		if errors.Is(err, service.ErrInvalidSelector) || errors.Is(err, service.ErrInvalidRoute) || errors.Is(err, service.ErrInvalidScript) ||
+			errors.Is(err, service.ErrInvalidHTTPCall) || errors.Is(err, service.ErrHandlerRequired) {
+			response.ParamError(c, err.Error())
+			return
+		}
 		response.ServerError(c, err.Error())
 		return
 	}
@@ -184,8 +249,8 @@ func (h *TaskHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.taskService.Delete(c.Request.Context(), id); err != nil {
-		response.ServerError(c, err.Error())
+	if err := h.taskService.Delete(c.Request.Context(), middleware.GetGroupScope(c), id); err != nil {
+		handleTaskError(c, err)
 		return
 	}
 
@@ -208,13 +273,9 @@ func (h *TaskHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	task, err := h.taskService.GetByID(c.Request.Context(), id)
+	task, err := h.taskService.GetByID(c.Request.Context(), middleware.GetGroupScope(c), id)
 	if err != nil {
-		if err == service.ErrTaskNotFound {
-			response.Error(c, response.CodeTaskNotFound, "")
-			return
-		}
-		response.ServerError(c, err.Error())
+		handleTaskError(c, err)
 		return
 	}
 
@@ -258,7 +319,7 @@ func (h *TaskHandler) List(c *gin.Context) {
 		req.PageSize = 10
 	}
 
-	tasks, total, err := h.taskService.List(c.Request.Context(), req.Page, req.PageSize, req.GroupID, req.Keyword, req.Status)
+	tasks, total, err := h.taskService.List(c.Request.Context(), middleware.GetGroupScope(c), req.Page, req.PageSize, req.GroupID, req.Keyword, req.Status)
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -283,8 +344,8 @@ func (h *TaskHandler) Start(c *gin.Context) {
 		return
 	}
 
-	if err := h.taskService.Start(c.Request.Context(), id); err != nil {
-		response.ServerError(c, err.Error())
+	if err := h.taskService.Start(c.Request.Context(), middleware.GetGroupScope(c), id); err != nil {
+		handleTaskError(c, err)
 		return
 	}
 
@@ -307,8 +368,8 @@ func (h *TaskHandler) Stop(c *gin.Context) {
 		return
 	}
 
-	if err := h.taskService.Stop(c.Request.Context(), id); err != nil {
-		response.ServerError(c, err.Error())
+	if err := h.taskService.Stop(c.Request.Context(), middleware.GetGroupScope(c), id); err != nil {
+		handleTaskError(c, err)
 		return
 	}
 
@@ -340,13 +401,9 @@ func (h *TaskHandler) Trigger(c *gin.Context) {
 	var req TriggerRequest
 	_ = c.ShouldBindJSON(&req)
 
-	instance, err := h.taskService.Trigger(c.Request.Context(), id, req.Param)
+	instance, err := h.taskService.Trigger(c.Request.Context(), middleware.GetGroupScope(c), id, req.Param)
 	if err != nil {
-		if err == service.ErrTaskNotFound {
-			response.Error(c, response.CodeTaskNotFound, "")
-			return
-		}
-		response.ServerError(c, err.Error())
+		handleTaskError(c, err)
 		return
 	}
 
@@ -389,3 +446,131 @@ func (h *TaskHandler) GetNextTriggerTimes(c *gin.Context) {
 	response.Success(c, times)
 }
 
+// GetRouteStrategies 获取可用的路由策略
+// @Summary 获取可用的路由策略
+// @Tags 任务管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} response.Response
+// @Router /api/v1/task/route-strategies [get]
+func (h *TaskHandler) GetRouteStrategies(c *gin.Context) {
+	response.Success(c, h.taskService.GetRouteStrategies(c.Request.Context()))
+}
+
+// ListScripts 获取任务的脚本版本列表
+// @Summary 获取任务的脚本版本列表
+// @Tags 任务管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "任务ID"
+// @Success 200 {object} response.Response{data=[]model.TaskScript}
+// @Router /api/v1/task/{id}/scripts [get]
+func (h *TaskHandler) ListScripts(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ParamError(c, "无效的任务ID")
+		return
+	}
+
+	scripts, err := h.taskService.ListScripts(c.Request.Context(), middleware.GetGroupScope(c), id)
+	if err != nil {
+		handleTaskError(c, err)
+		return
+	}
+
+	response.Success(c, scripts)
+}
+
+// GetScript 获取任务指定版本的脚本
+// @Summary 获取任务指定版本的脚本
+// @Tags 任务管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "任务ID"
+// @Param version path int true "脚本版本"
+// @Success 200 {object} response.Response{data=model.TaskScript}
+// @Router /api/v1/task/{id}/scripts/{version} [get]
+func (h *TaskHandler) GetScript(c *gin.Context) {
+	id, version, ok := parseScriptVersion(c)
+	if !ok {
+		return
+	}
+
+	script, err := h.taskService.GetScript(c.Request.Context(), middleware.GetGroupScope(c), id, version)
+	if err != nil {
+		if errors.Is(err, service.ErrScriptNotFound) {
+			response.Error(c, response.CodeNotFound, err.Error())
+			return
+		}
+		handleTaskError(c, err)
+		return
+	}
+
+	response.Success(c, script)
+}
+
+// RollbackScript 回滚任务脚本到指定版本
+// @Summary 回滚任务脚本到指定版本
+// @Tags 任务管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "任务ID"
+// @Param version path int true "脚本版本"
+// @Success 200 {object} response.Response{data=model.TaskScript}
+// @Router /api/v1/task/{id}/scripts/{version}/rollback [post]
+func (h *TaskHandler) RollbackScript(c *gin.Context) {
+	id, version, ok := parseScriptVersion(c)
+	if !ok {
+		return
+	}
+
+	script, err := h.taskService.RollbackScript(c.Request.Context(), middleware.GetGroupScope(c), id, version, middleware.GetUserID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTaskNotFound):
+			response.Error(c, response.CodeTaskNotFound, "")
+		case errors.Is(err, service.ErrGroupForbidden):
+			response.Forbidden(c, err.Error())
+		case errors.Is(err, service.ErrScriptNotFound):
+			response.Error(c, response.CodeNotFound, err.Error())
+		case errors.Is(err, service.ErrInvalidScript):
+			response.ParamError(c, err.Error())
+		default:
+			response.ServerError(c, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, script)
+}
+
+// handleTaskError 处理获取或操作单个任务的错误
+func handleTaskError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrTaskNotFound:
+		response.Error(c, response.CodeTaskNotFound, "")
+	case service.ErrGroupForbidden:
+		response.Forbidden(c, err.Error())
+	default:
+		response.ServerError(c, err.Error())
+	}
+}
+
+// parseScriptVersion 解析路径中的任务ID和脚本版本
+func parseScriptVersion(c *gin.Context) (uint64, uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ParamError(c, "无效的任务ID")
+		return 0, 0, false
+	}
+	version, err := strconv.ParseUint(c.Param("version"), 10, 32)
+	if err != nil || version == 0 {
+		response.ParamError(c, "无效的脚本版本")
+		return 0, 0, false
+	}
+	return id, uint(version), true
+}
@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"errors"
+
 	"github.com/gin-gonic/gin"
 
 	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/common/utils"
 	"distributed-scheduler/internal/middleware"
 	"distributed-scheduler/internal/model"
 	"distributed-scheduler/internal/service"
@@ -11,13 +14,17 @@ import (
 
 // UserHandler 用户处理器
 type UserHandler struct {
-	userService service.UserService
+	userService       service.UserService
+	permissionService service.PermissionService
+	tokenService      service.TokenService
 }
 
 // NewUserHandler 创建用户处理器
 func NewUserHandler() *UserHandler {
 	return &UserHandler{
-		userService: service.NewUserService(),
+		userService:       service.NewUserService(),
+		permissionService: service.NewPermissionService(),
+		tokenService:      service.NewTokenService(),
 	}
 }
 
@@ -29,8 +36,8 @@ type LoginRequest struct {
 
 // LoginResponse 登录响应
 type LoginResponse struct {
-	Token string          `json:"token"`
-	User  *model.SysUser `json:"user"`
+	*utils.TokenPair
+	User *model.SysUser `json:"user"`
 }
 
 // Login 用户登录
@@ -64,11 +71,53 @@ func (h *UserHandler) Login(c *gin.Context) {
 	}
 
 	response.Success(c, LoginResponse{
-		Token: token,
-		User:  user,
+		TokenPair: token,
+		User:      user,
 	})
 }
 
+// RefreshTokenRequest 刷新Token请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken 刷新Token
+// @Summary 使用刷新Token签发新的访问Token和刷新Token(原刷新Token随即失效)
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body RefreshTokenRequest true "刷新Token请求"
+// @Success 200 {object} response.Response{data=utils.TokenPair}
+// @Router /api/v1/auth/refresh [post]
+func (h *UserHandler) RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	token, err := h.tokenService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrTokenExpired):
+			response.Error(c, response.CodeTokenExpired, "刷新Token已过期")
+		case errors.Is(err, utils.ErrTokenInvalid):
+			response.Error(c, response.CodeTokenInvalid, "刷新Token无效")
+		case errors.Is(err, service.ErrTokenRevoked):
+			response.Error(c, response.CodeTokenInvalid, "刷新Token已失效")
+		case errors.Is(err, service.ErrUserNotFound):
+			response.Error(c, response.CodeUserNotFound, "")
+		case errors.Is(err, service.ErrUserDisabled):
+			response.Error(c, response.CodeUserDisabled, "")
+		default:
+			response.ServerError(c, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, token)
+}
+
 // GetCurrentUser 获取当前用户信息
 // @Summary 获取当前用户信息
 // @Tags 用户管理
@@ -87,6 +136,23 @@ func (h *UserHandler) GetCurrentUser(c *gin.Context) {
 	response.Success(c, user)
 }
 
+// GetPermissions 获取当前用户的有效权限
+// @Summary 获取当前用户的有效权限
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} response.Response{data=service.UserPermissions}
+// @Router /api/v1/user/permissions [get]
+func (h *UserHandler) GetPermissions(c *gin.Context) {
+	permissions, err := h.permissionService.GetUserPermissions(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, permissions)
+}
+
 // CreateUserRequest 创建用户请求
 type CreateUserRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=64"`
@@ -208,16 +274,58 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 	response.Success(c, nil)
 }
 
+// LogoutRequest 退出登录请求
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"` // 同时吊销的刷新Token, 可选
+}
+
 // Logout 退出登录
-// @Summary 退出登录
+// @Summary 退出登录(吊销当前访问Token及请求中的刷新Token)
 // @Tags 用户管理
 // @Accept json
 // @Produce json
 // @Security Bearer
+// @Param request body LogoutRequest false "退出登录请求"
 // @Success 200 {object} response.Response
 // @Router /api/v1/auth/logout [post]
 func (h *UserHandler) Logout(c *gin.Context) {
-	// TODO: 可以在Redis中记录Token黑名单
+	var req LogoutRequest
+	_ = c.ShouldBindJSON(&req)
+
+	ctx := c.Request.Context()
+	if err := h.tokenService.Revoke(ctx, middleware.GetClaims(c)); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	// 只吊销属于当前用户的刷新Token, 已过期或无效的刷新Token无需吊销
+	if req.RefreshToken != "" {
+		claims, err := utils.ParseToken(req.RefreshToken)
+		if err == nil && claims.TokenType == utils.TokenTypeRefresh && claims.UserID == middleware.GetUserID(c) {
+			if err := h.tokenService.Revoke(ctx, claims); err != nil {
+				response.ServerError(c, err.Error())
+				return
+			}
+		}
+	}
+
+	response.Success(c, nil)
+}
+
+// LogoutAll 退出所有会话
+// @Summary 退出所有会话(吊销当前用户已签发的所有Token)
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} response.Response
+// @Router /api/v1/auth/logout-all [post]
+func (h *UserHandler) LogoutAll(c *gin.Context) {
+	if err := h.tokenService.RevokeAll(c.Request.Context(), middleware.GetUserID(c)); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
 	response.Success(c, nil)
 }
 
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/common/utils"
+	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/service"
+	"distributed-scheduler/pkg/logger"
+)
+
+// ContextKeyExecutorGroup 执行器请求签名对应的任务组
+const ContextKeyExecutorGroup = "executor_group"
+
+// ExecutorAuth 执行器接口认证中间件
+// 执行器需携带 X-Executor-App、X-Executor-Timestamp、X-Executor-Nonce 和 X-Executor-Signature 请求头,
+// 签名算法见 utils.SignExecutorRequest; 未启用认证时直接放行
+func ExecutorAuth() gin.HandlerFunc {
+	cfg := config.GetConfig()
+	if cfg == nil || !cfg.Executor.Auth.Enable {
+		deadline := config.ExecutorAuthDeadline.Format(time.DateOnly)
+		if time.Now().Before(config.ExecutorAuthDeadline) {
+			logger.Warnf("执行器接口未启用签名认证, 任何人都可以注册执行器和回调实例结果, 请在%s前开启executor.auth.enable", deadline)
+		} else {
+			logger.Errorf("执行器接口未启用签名认证, 已超过上线截止日期%s, 请立即开启executor.auth.enable, 之后的版本将始终校验签名", deadline)
+		}
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	executorService := service.NewExecutorService()
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			response.ParamError(c, "读取请求体失败")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		group, err := executorService.Authenticate(c.Request.Context(), &service.ExecutorCredentials{
+			AppName:   c.GetHeader(utils.HeaderExecutorApp),
+			Timestamp: c.GetHeader(utils.HeaderExecutorTimestamp),
+			Nonce:     c.GetHeader(utils.HeaderExecutorNonce),
+			Signature: c.GetHeader(utils.HeaderExecutorSignature),
+		}, c.Request.Method, c.Request.URL.Path, body)
+		if err != nil {
+			if errors.Is(err, service.ErrExecutorUnauthorized) {
+				response.Unauthorized(c, err.Error())
+			} else {
+				response.ServerError(c, err.Error())
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set(ContextKeyExecutorGroup, group)
+		c.Next()
+	}
+}
+
+// GetExecutorGroup 从上下文获取执行器所属任务组, 未启用认证时返回nil
+func GetExecutorGroup(c *gin.Context) *model.TaskGroup {
+	if group, exists := c.Get(ContextKeyExecutorGroup); exists {
+		return group.(*model.TaskGroup)
+	}
+	return nil
+}
+
+// GetExecutorGroupID 从上下文获取执行器所属任务组ID, 未启用认证时返回0
+func GetExecutorGroupID(c *gin.Context) uint64 {
+	if group := GetExecutorGroup(c); group != nil {
+		return group.ID
+	}
+	return 0
+}
@@ -1,12 +1,14 @@
 package middleware
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"distributed-scheduler/internal/common/response"
 	"distributed-scheduler/internal/common/utils"
+	"distributed-scheduler/internal/service"
 )
 
 // 上下文键
@@ -14,10 +16,12 @@ const (
 	ContextKeyUserID   = "user_id"
 	ContextKeyUsername = "username"
 	ContextKeyRoleCode = "role_code"
+	ContextKeyClaims   = "claims"
 )
 
-// JWTAuth JWT认证中间件
+// JWTAuth JWT认证中间件, 只接受未被吊销的访问Token
 func JWTAuth() gin.HandlerFunc {
+	tokenService := service.NewTokenService()
 	return func(c *gin.Context) {
 		// 获取Authorization头
 		authHeader := c.GetHeader("Authorization")
@@ -46,8 +50,25 @@ func JWTAuth() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if claims.TokenType != utils.TokenTypeAccess {
+			response.Error(c, response.CodeTokenInvalid, "Token无效")
+			c.Abort()
+			return
+		}
+
+		// 检查Token是否已退出登录
+		if err := tokenService.Validate(c.Request.Context(), claims); err != nil {
+			if errors.Is(err, service.ErrTokenRevoked) {
+				response.Error(c, response.CodeTokenInvalid, "Token已失效")
+			} else {
+				response.ServerError(c, err.Error())
+			}
+			c.Abort()
+			return
+		}
 
 		// 将用户信息存入上下文
+		c.Set(ContextKeyClaims, claims)
 		c.Set(ContextKeyUserID, claims.UserID)
 		c.Set(ContextKeyUsername, claims.Username)
 		c.Set(ContextKeyRoleCode, claims.RoleCode)
@@ -56,6 +77,14 @@ func JWTAuth() gin.HandlerFunc {
 	}
 }
 
+// GetClaims 从上下文获取当前访问Token的声明
+func GetClaims(c *gin.Context) *utils.Claims {
+	if claims, exists := c.Get(ContextKeyClaims); exists {
+		return claims.(*utils.Claims)
+	}
+	return nil
+}
+
 // GetUserID 从上下文获取用户ID
 func GetUserID(c *gin.Context) uint64 {
 	if userID, exists := c.Get(ContextKeyUserID); exists {
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/service"
+)
+
+// ContextKeyGroupScope 上下文中当前请求可访问的任务组范围
+const ContextKeyGroupScope = "groupScope"
+
+// RequirePermission 权限校验中间件, 需在JWTAuth之后使用
+// 用户的所有已启用角色中任一角色拥有该权限(资源:操作)即可访问,
+// 并按该权限要求的组内角色将可访问的任务组范围写入上下文
+func RequirePermission(code string) gin.HandlerFunc {
+	permissionService := service.NewPermissionService()
+	groupRole := model.PermissionGroupRole(code)
+	return func(c *gin.Context) {
+		permissions, err := permissionService.GetUserPermissions(c.Request.Context(), GetUserID(c))
+		if err != nil {
+			response.ServerError(c, err.Error())
+			c.Abort()
+			return
+		}
+		if !permissions.Has(code) {
+			response.Forbidden(c, "权限不足: "+code)
+			c.Abort()
+			return
+		}
+
+		if groupRole != "" {
+			c.Set(ContextKeyGroupScope, permissions.GroupScope(groupRole))
+		}
+		c.Next()
+	}
+}
+
+// GetGroupScope 获取当前请求可访问的任务组范围, 未经RequirePermission设置时不允许访问任何任务组
+func GetGroupScope(c *gin.Context) *model.GroupScope {
+	if scope, exists := c.Get(ContextKeyGroupScope); exists {
+		return scope.(*model.GroupScope)
+	}
+	return &model.GroupScope{}
+}
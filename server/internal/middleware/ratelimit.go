@@ -1,106 +1,202 @@
 package middleware
 
 import (
-	"sync"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"distributed-scheduler/internal/common/ratelimit"
 	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/common/utils"
+	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/service"
+	"distributed-scheduler/pkg/logger"
 )
 
-// RateLimiter 令牌桶限流器
-type RateLimiter struct {
-	rate       int           // 每秒产生的令牌数
-	capacity   int           // 桶容量
-	tokens     int           // 当前令牌数
-	lastUpdate time.Time     // 上次更新时间
-	mu         sync.Mutex    // 互斥锁
+// 限流维度
+const (
+	RateLimitByIP   = "ip"
+	RateLimitByUser = "user"
+)
+
+// Redis限流失败时告警日志的最小间隔
+const rateLimitWarnInterval = time.Minute
+
+// rateLimitRule 解析后的限流规则
+type rateLimitRule struct {
+	name   string // 限流键中的规则名
+	method string
+	path   string
+	prefix bool
+	by     string
+	limit  ratelimit.Limit
 }
 
-// NewRateLimiter 创建限流器
-func NewRateLimiter(rate, capacity int) *RateLimiter {
-	return &RateLimiter{
-		rate:       rate,
-		capacity:   capacity,
-		tokens:     capacity,
-		lastUpdate: time.Now(),
+// match 规则是否匹配请求的路由
+func (r *rateLimitRule) match(method, path string) bool {
+	if r.method != "" && r.method != method {
+		return false
 	}
+	if r.prefix {
+		return strings.HasPrefix(path, r.path)
+	}
+	return r.path == path
 }
 
-// Allow 是否允许请求
-func (rl *RateLimiter) Allow() bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+// rateLimiter 接口限流器
+type rateLimiter struct {
+	rules       []*rateLimitRule
+	defaultRule *rateLimitRule
+	redis       *ratelimit.RedisLimiter // 为nil时只使用本地限流
+	local       *ratelimit.LocalLimiter
+	tokens      service.TokenService // 校验Token是否已吊销, 与JWTAuth一致
+	lastWarn    atomic.Int64
+}
 
-	now := time.Now()
-	// 计算需要添加的令牌数
-	elapsed := now.Sub(rl.lastUpdate).Seconds()
-	tokensToAdd := int(elapsed * float64(rl.rate))
+// 全局限流器实例
+var globalRateLimiter *rateLimiter
 
-	if tokensToAdd > 0 {
-		rl.tokens = min(rl.capacity, rl.tokens+tokensToAdd)
-		rl.lastUpdate = now
+// InitRateLimiter 初始化限流器, 未启用时不限流
+func InitRateLimiter(cfg *config.RateLimitConfig) {
+	StopRateLimiter()
+	if cfg == nil || !cfg.Enable {
+		return
+	}
+
+	l := &rateLimiter{
+		defaultRule: newRateLimitRule("default", cfg.Default),
+		local:       ratelimit.NewLocalLimiter(time.Duration(cfg.JanitorInterval) * time.Second),
+		tokens:      service.NewTokenService(),
+	}
+	if cfg.Backend != "local" {
+		l.redis = ratelimit.NewRedisLimiter()
 	}
+	for _, rule := range cfg.Rules {
+		l.rules = append(l.rules, newRateLimitRule(strings.TrimSpace(rule.Method+" "+rule.Path), rule))
+	}
+	globalRateLimiter = l
+}
 
-	if rl.tokens > 0 {
-		rl.tokens--
-		return true
+// StopRateLimiter 停止限流器的清理协程
+func StopRateLimiter() {
+	if globalRateLimiter != nil {
+		globalRateLimiter.local.Stop()
+		globalRateLimiter = nil
 	}
-	return false
 }
 
-// IPRateLimiter IP限流器
-type IPRateLimiter struct {
-	limiters map[string]*RateLimiter
-	rate     int
-	capacity int
-	mu       sync.Mutex
+// newRateLimitRule 解析限流规则, 未配置突发请求数时为每秒请求数
+func newRateLimitRule(name string, cfg config.RateLimitRule) *rateLimitRule {
+	rule := &rateLimitRule{
+		name:   name,
+		method: strings.ToUpper(cfg.Method),
+		path:   strings.TrimSuffix(cfg.Path, "*"),
+		prefix: strings.HasSuffix(cfg.Path, "*"),
+		by:     cfg.By,
+		limit:  ratelimit.Limit{Rate: cfg.Rate, Burst: cfg.Burst},
+	}
+	if rule.by != RateLimitByUser {
+		rule.by = RateLimitByIP
+	}
+	if rule.limit.Burst <= 0 {
+		rule.limit.Burst = int(math.Max(1, math.Ceil(cfg.Rate)))
+	}
+	return rule
 }
 
-// NewIPRateLimiter 创建IP限流器
-func NewIPRateLimiter(rate, capacity int) *IPRateLimiter {
-	return &IPRateLimiter{
-		limiters: make(map[string]*RateLimiter),
-		rate:     rate,
-		capacity: capacity,
+// find 查找请求匹配的规则, 未匹配路由规则时使用默认规则
+func (l *rateLimiter) find(method, path string) *rateLimitRule {
+	for _, rule := range l.rules {
+		if rule.match(method, path) {
+			return rule
+		}
 	}
+	return l.defaultRule
 }
 
-// GetLimiter 获取指定IP的限流器
-func (ipl *IPRateLimiter) GetLimiter(ip string) *RateLimiter {
-	ipl.mu.Lock()
-	defer ipl.mu.Unlock()
+// subject 限流对象, user维度优先使用登录用户, 未登录或Token无效、已吊销时使用客户端IP
+func (l *rateLimiter) subject(c *gin.Context, by string) string {
+	if by == RateLimitByUser {
+		if userID := GetUserID(c); userID > 0 {
+			return "user:" + strconv.FormatUint(userID, 10)
+		}
+		if claims := l.parseAccessToken(c); claims != nil {
+			return "user:" + strconv.FormatUint(claims.UserID, 10)
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
 
-	limiter, exists := ipl.limiters[ip]
-	if !exists {
-		limiter = NewRateLimiter(ipl.rate, ipl.capacity)
-		ipl.limiters[ip] = limiter
+// parseAccessToken 解析请求中未被吊销的访问Token, 校验与JWTAuth一致, 无效时返回nil
+// 已退出登录的Token不能继续占用用户的限流配额, 也不能借此绕开IP限流
+func (l *rateLimiter) parseAccessToken(c *gin.Context) *utils.Claims {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		return nil
+	}
+	claims, err := utils.ParseToken(token)
+	if err != nil || claims.TokenType != utils.TokenTypeAccess {
+		return nil
 	}
-	return limiter
+	if l.tokens == nil || l.tokens.Validate(c.Request.Context(), claims) != nil {
+		return nil
+	}
+	return claims
 }
 
-// 全局IP限流器实例
-var globalIPLimiter *IPRateLimiter
+// allow 判断是否允许请求, Redis限流失败时降级为本地限流
+func (l *rateLimiter) allow(c *gin.Context, rule *rateLimitRule) *ratelimit.Result {
+	key := rule.name + ":" + l.subject(c, rule.by)
+	if l.redis != nil {
+		result, err := l.redis.Allow(c.Request.Context(), key, rule.limit)
+		if err == nil {
+			return result
+		}
+		l.warn(err)
+	}
+	result, _ := l.local.Allow(c.Request.Context(), key, rule.limit)
+	return result
+}
 
-// InitRateLimiter 初始化限流器
-func InitRateLimiter(rate, capacity int) {
-	globalIPLimiter = NewIPRateLimiter(rate, capacity)
+// warn 记录Redis限流失败, 降级期间每隔rateLimitWarnInterval记录一次
+func (l *rateLimiter) warn(err error) {
+	now := time.Now().UnixNano()
+	last := l.lastWarn.Load()
+	if now-last < int64(rateLimitWarnInterval) || !l.lastWarn.CompareAndSwap(last, now) {
+		return
+	}
+	logger.Warnf("Redis限流失败, 降级为本地限流: %v", err)
 }
 
 // RateLimit 限流中间件
+// 按路由规则(未匹配时为默认规则)和限流维度计数, 响应头返回X-RateLimit-Limit/X-RateLimit-Remaining/X-RateLimit-Reset,
+// 超限时返回429并通过Retry-After告知重试等待的秒数
 func RateLimit() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if globalIPLimiter == nil {
+		l := globalRateLimiter
+		if l == nil {
 			c.Next()
 			return
 		}
 
-		ip := c.ClientIP()
-		limiter := globalIPLimiter.GetLimiter(ip)
+		rule := l.find(c.Request.Method, c.FullPath())
+		if rule.limit.Rate <= 0 {
+			c.Next()
+			return
+		}
+
+		result := l.allow(c, rule)
+		c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.ResetAfter), 10))
 
-		if !limiter.Allow() {
-			response.Error(c, response.CodeError, "请求过于频繁，请稍后再试")
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.FormatInt(ceilSeconds(result.RetryAfter), 10))
+			response.TooManyRequests(c, "请求过于频繁，请稍后再试")
 			c.Abort()
 			return
 		}
@@ -109,11 +205,11 @@ func RateLimit() gin.HandlerFunc {
 	}
 }
 
-// min 返回两个整数中的较小值
-func min(a, b int) int {
-	if a < b {
-		return a
+// ceilSeconds 向上取整的秒数, 至少为1
+func ceilSeconds(d time.Duration) int64 {
+	seconds := int64(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		return 1
 	}
-	return b
+	return seconds
 }
-
@@ -45,6 +45,11 @@ func (OperationLog) TableName() string {
 	return "operation_log"
 }
 
+// 系统配置键
+const (
+	ConfigKeyExecutorDeadTimeout = "executor.dead_timeout" // 执行器离线判定时间(秒)
+)
+
 // 配置类型常量
 const (
 	ConfigTypeString  = "STRING"
@@ -1,26 +1,32 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 )
 
 // ExecutorNode 执行器节点
 type ExecutorNode struct {
-	ID            string    `gorm:"primaryKey;size:128" json:"id"`
-	GroupID       uint64    `gorm:"not null;index" json:"group_id"`
-	AppName       string    `gorm:"size:64;not null;index" json:"app_name"`
-	Host          string    `gorm:"size:128;not null" json:"host"`
-	Port          uint      `gorm:"not null" json:"port"`
-	Weight        uint      `gorm:"default:100" json:"weight"`
-	MaxConcurrent uint      `gorm:"default:100" json:"max_concurrent"`
-	CurrentLoad   uint      `gorm:"default:0" json:"current_load"`
-	CPUUsage      float64   `gorm:"type:decimal(5,2);default:0" json:"cpu_usage"`
-	MemoryUsage   float64   `gorm:"type:decimal(5,2);default:0" json:"memory_usage"`
-	Status        int8      `gorm:"default:1;index" json:"status"`
-	LastHeartbeat time.Time `gorm:"index" json:"last_heartbeat"`
-	RegisteredAt  time.Time `json:"registered_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            string     `gorm:"primaryKey;size:128" json:"id"`
+	GroupID       uint64     `gorm:"not null;index" json:"group_id"`
+	AppName       string     `gorm:"size:64;not null;index" json:"app_name"`
+	Host          string     `gorm:"size:128;not null" json:"host"`
+	Port          uint       `gorm:"not null" json:"port"`
+	Weight        uint       `gorm:"default:100" json:"weight"`
+	MaxConcurrent uint       `gorm:"default:100" json:"max_concurrent"`
+	CurrentLoad   uint       `gorm:"default:0" json:"current_load"`
+	CPUUsage      float64    `gorm:"type:decimal(5,2);default:0" json:"cpu_usage"`
+	MemoryUsage   float64    `gorm:"type:decimal(5,2);default:0" json:"memory_usage"`
+	Labels        Labels     `gorm:"type:text" json:"labels"`
+	Status        int8       `gorm:"default:1;index" json:"status"`
+	DrainStatus   int8       `gorm:"default:0" json:"drain_status"` // 摘流状态, 摘流中的执行器不再接收新任务
+	DrainAt       *time.Time `json:"drain_at"`                      // 开始摘流时间
+	LastHeartbeat time.Time  `gorm:"index" json:"last_heartbeat"`
+	RegisteredAt  time.Time  `json:"registered_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
 // TableName 指定表名
@@ -38,26 +44,98 @@ func (e *ExecutorNode) IsOnline() bool {
 	return e.Status == ExecutorStatusOnline
 }
 
+// IsDraining 是否处于摘流中或已摘流完成
+func (e *ExecutorNode) IsDraining() bool {
+	return e.DrainStatus != DrainStatusNone
+}
+
+// IsSchedulable 是否可以接收新任务: 在线且未摘流
+func (e *ExecutorNode) IsSchedulable() bool {
+	return e.IsOnline() && !e.IsDraining()
+}
+
 // IsOverload 是否过载
 func (e *ExecutorNode) IsOverload() bool {
 	return e.CurrentLoad >= e.MaxConcurrent
 }
 
+// Labels 执行器标签(如 zone=sh, gpu=false, disk=ssd, version=1.2), 以JSON格式存储
+type Labels map[string]string
+
+// Value 实现driver.Valuer接口
+func (l Labels) Value() (driver.Value, error) {
+	if l == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现sql.Scanner接口
+func (l *Labels) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = Labels{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("无效的标签数据类型")
+	}
+	if len(data) == 0 {
+		*l = Labels{}
+		return nil
+	}
+	return json.Unmarshal(data, l)
+}
+
 // 执行器状态常量
 const (
 	ExecutorStatusOffline = 0 // 离线
 	ExecutorStatusOnline  = 1 // 在线
 )
 
+// 执行器摘流状态常量
+const (
+	DrainStatusNone     = 0 // 正常
+	DrainStatusDraining = 1 // 摘流中: 不再分配新任务, 等待执行中的任务完成
+	DrainStatusDrained  = 2 // 摘流完成: 执行中的任务已全部完成, 可以停止
+)
+
+// 执行器HTTP接口路径
+const (
+	ExecutorPathRun      = "/run"       // 执行任务
+	ExecutorPathBeat     = "/beat"      // 健康检查
+	ExecutorPathIdleBeat = "/idle-beat" // 空闲检查
+	ExecutorPathKill     = "/kill"      // 终止任务
+	ExecutorPathStatus   = "/status"    // 查询实例执行状态
+)
+
 // ExecutorHeartbeat 执行器心跳数据
+// ExecutorID为空时按 AppName+InstanceID 或 AppName+Host+Port 定位执行器
 type ExecutorHeartbeat struct {
 	ExecutorID  string  `json:"executor_id"`
+	InstanceID  string  `json:"instance_id,omitempty"` // 执行器实例标识, 与注册时一致
 	AppName     string  `json:"app_name"`
 	Host        string  `json:"host"`
 	Port        uint    `json:"port"`
 	CurrentLoad uint    `json:"current_load"`
 	CPUUsage    float64 `json:"cpu_usage"`
 	MemoryUsage float64 `json:"memory_usage"`
+	Labels      Labels  `json:"labels,omitempty"`        // 为空时不更新标签
+	ReadyToStop bool    `json:"ready_to_stop,omitempty"` // 摘流中的执行器已没有执行中的任务
+}
+
+// ExecutorHeartbeatAck 心跳响应, 执行器据此感知管理端发起的摘流
+type ExecutorHeartbeatAck struct {
+	ExecutorID  string `json:"executor_id"`
+	DrainStatus int8   `json:"drain_status"`
 }
 
 // ExecutorTask 执行器任务参数
@@ -69,6 +147,56 @@ type ExecutorTask struct {
 	ShardIndex      uint   `json:"shard_index"`
 	ShardTotal      uint   `json:"shard_total"`
 	Timeout         uint   `json:"timeout"`
+	DispatchToken   uint64 `json:"dispatch_token"` // 派发令牌, 同一实例的重复下发令牌相同, 重新分发时递增
+
+	Script *ExecutorScript `json:"script,omitempty"` // SCRIPT类型任务的脚本
+}
+
+// ExecutorScript 下发到执行器的脚本
+type ExecutorScript struct {
+	ScriptType string            `json:"script_type"`
+	Version    uint              `json:"version"`
+	Content    string            `json:"content"`
+	Env        map[string]string `json:"env,omitempty"`
+}
+
+// ExecutorLog 执行器上报的执行日志
+type ExecutorLog struct {
+	InstanceID uint64 `json:"instance_id" binding:"required"`
+	LogTime    int64  `json:"log_time"`  // 日志时间(Unix毫秒), 为0时使用接收时间
+	LogLevel   string `json:"log_level"` // DEBUG/INFO/WARN/ERROR, 为空时为INFO
+	LogContent string `json:"log_content"`
+}
+
+// ToTaskLog 转换为执行日志记录
+func (l *ExecutorLog) ToTaskLog() *TaskLog {
+	log := &TaskLog{
+		InstanceID: l.InstanceID,
+		LogTime:    time.UnixMilli(l.LogTime),
+		LogLevel:   l.LogLevel,
+		LogContent: l.LogContent,
+	}
+	if l.LogTime == 0 {
+		log.LogTime = time.Now()
+	}
+	if log.LogLevel == "" {
+		log.LogLevel = LogLevelInfo
+	}
+	return log
+}
+
+// ExecutorKillRequest 终止实例请求
+type ExecutorKillRequest struct {
+	InstanceID    uint64 `json:"instance_id"`
+	DispatchToken uint64 `json:"dispatch_token,omitempty"` // 要终止的派发令牌, 与执行中的令牌不一致时忽略; 为0时终止实例当前的执行
+}
+
+// ExecutorInstanceStatus 实例在执行器上的执行状态
+type ExecutorInstanceStatus struct {
+	InstanceID  uint64 `json:"instance_id"`
+	Running     bool   `json:"running"`              // 执行器上是否正在执行该实例
+	StartTime   int64  `json:"start_time,omitempty"` // 开始执行时间(Unix毫秒)
+	CurrentLoad uint   `json:"current_load"`         // 执行器当前执行中的任务数
 }
 
 // ExecutorResult 执行器返回结果
@@ -76,5 +204,7 @@ type ExecutorResult struct {
 	InstanceID uint64 `json:"instance_id"`
 	Code       int    `json:"code"`
 	Message    string `json:"message"`
+	Stack      string `json:"stack,omitempty"` // Handler发生panic时的调用栈
+	// DispatchToken 执行的派发令牌, 与实例当前令牌不一致(包括未携带)的结果被拒绝; 实例尚未分配令牌时不校验
+	DispatchToken uint64 `json:"dispatch_token,omitempty"`
 }
-
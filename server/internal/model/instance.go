@@ -23,6 +23,8 @@ type TaskInstance struct {
 	Status          int8       `gorm:"default:0;index" json:"status"`
 	ResultCode      int        `gorm:"default:0" json:"result_code"`
 	ResultMsg       string     `gorm:"type:text" json:"result_msg"`
+	PendingReason   string     `gorm:"size:256" json:"pending_reason"`  // 待调度原因(如配额已满、无可用执行器)
+	DispatchToken   uint64     `gorm:"default:0" json:"dispatch_token"` // 派发令牌, 每次分发递增, 执行器据此去重, 旧令牌的回调被拒绝
 	RetryCount      uint       `gorm:"default:0" json:"retry_count"`
 	AlarmStatus     int8       `gorm:"default:0" json:"alarm_status"`
 	CreatedAt       time.Time  `json:"created_at"`
@@ -111,4 +113,3 @@ func (i *TaskInstance) Duration() int64 {
 	}
 	return i.EndTime.Sub(*i.StartTime).Milliseconds()
 }
-
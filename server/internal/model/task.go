@@ -1,6 +1,9 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,15 +11,19 @@ import (
 
 // TaskGroup 任务组
 type TaskGroup struct {
-	ID          uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
-	Name        string         `gorm:"size:128;not null" json:"name"`
-	Description string         `gorm:"size:512" json:"description"`
-	AppName     string         `gorm:"size:64;not null;uniqueIndex" json:"app_name"`
-	Status      int8           `gorm:"default:1" json:"status"`
-	CreatedBy   uint64         `json:"created_by"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID            uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name          string         `gorm:"size:128;not null" json:"name"`
+	Description   string         `gorm:"size:512" json:"description"`
+	AppName       string         `gorm:"size:64;not null;uniqueIndex" json:"app_name"`
+	MaxConcurrent uint           `gorm:"default:0" json:"max_concurrent"` // 最大并发实例数, 0表示不限制
+	AccessToken   string         `gorm:"size:64" json:"-"`                // 执行器访问令牌, 用于请求签名
+	PrevToken     string         `gorm:"size:64" json:"-"`                // 轮换前的令牌, 宽限期内仍然有效
+	TokenRotateAt *time.Time     `json:"token_rotate_at"`                 // 令牌轮换时间
+	Status        int8           `gorm:"default:1" json:"status"`
+	CreatedBy     uint64         `json:"created_by"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // TableName 指定表名
@@ -24,34 +31,112 @@ func (TaskGroup) TableName() string {
 	return "task_group"
 }
 
+// ValidTokens 当前有效的访问令牌: 当前令牌, 以及轮换后宽限期内的旧令牌
+func (g *TaskGroup) ValidTokens(gracePeriod time.Duration) []string {
+	if g.AccessToken == "" {
+		return nil
+	}
+	tokens := []string{g.AccessToken}
+	if g.PrevToken != "" && g.TokenRotateAt != nil && time.Since(*g.TokenRotateAt) < gracePeriod {
+		tokens = append(tokens, g.PrevToken)
+	}
+	return tokens
+}
+
+// TaskGroupMember 任务组成员, 授权用户访问任务组及其任务、实例和执行器
+type TaskGroupMember struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID   uint64    `gorm:"not null;uniqueIndex:uk_group_user" json:"group_id"`
+	UserID    uint64    `gorm:"not null;uniqueIndex:uk_group_user;index" json:"user_id"`
+	Role      string    `gorm:"size:16;not null" json:"role"` // 成员角色 OWNER/OPERATOR/VIEWER
+	CreatedBy uint64    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	User *SysUser `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName 指定表名
+func (TaskGroupMember) TableName() string {
+	return "task_group_member"
+}
+
+// 任务组成员角色
+const (
+	GroupRoleOwner    = "OWNER"    // 负责人: 管理任务组、成员和执行器访问令牌
+	GroupRoleOperator = "OPERATOR" // 操作员: 管理和执行任务, 操作实例和执行器
+	GroupRoleViewer   = "VIEWER"   // 观察者: 只读
+)
+
+// GroupRoleLevel 成员角色的级别, 级别高的角色包含级别低的角色的所有权限, 未知角色为0
+func GroupRoleLevel(role string) int {
+	switch role {
+	case GroupRoleOwner:
+		return 3
+	case GroupRoleOperator:
+		return 2
+	case GroupRoleViewer:
+		return 1
+	}
+	return 0
+}
+
+// GroupScope 用户可访问的任务组范围
+type GroupScope struct {
+	All      bool     // 不限制任务组(超级管理员)
+	GroupIDs []uint64 // 可访问的任务组ID
+}
+
+// Allows 是否可访问任务组, scope为nil时不限制(内部调用)
+func (s *GroupScope) Allows(groupID uint64) bool {
+	if s == nil || s.All {
+		return true
+	}
+	for _, id := range s.GroupIDs {
+		if id == groupID {
+			return true
+		}
+	}
+	return false
+}
+
 // Task 任务定义
 type Task struct {
-	ID              uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
-	GroupID         uint64         `gorm:"not null;index" json:"group_id"`
-	Name            string         `gorm:"size:128;not null" json:"name"`
-	Description     string         `gorm:"size:512" json:"description"`
-	Cron            string         `gorm:"size:64;not null" json:"cron"`
-	ExecutorType    string         `gorm:"size:32;not null;default:HTTP" json:"executor_type"`
-	ExecutorHandler string         `gorm:"size:256;not null" json:"executor_handler"`
-	ExecutorParam   string         `gorm:"type:text" json:"executor_param"`
-	RouteStrategy   string         `gorm:"size:32;default:ROUND_ROBIN" json:"route_strategy"`
-	BlockStrategy   string         `gorm:"size:32;default:SERIAL_EXECUTION" json:"block_strategy"`
-	ShardNum        uint           `gorm:"default:1" json:"shard_num"`
-	RetryCount      uint           `gorm:"default:0" json:"retry_count"`
-	RetryInterval   uint           `gorm:"default:0" json:"retry_interval"`
-	Timeout         uint           `gorm:"default:0" json:"timeout"`
-	AlarmEmail      string         `gorm:"size:512" json:"alarm_email"`
-	Priority        int            `gorm:"default:0" json:"priority"`
-	Status          int8           `gorm:"default:1;index" json:"status"`
-	Version         uint           `gorm:"default:0" json:"version"`
-	NextTriggerTime *time.Time     `gorm:"index" json:"next_trigger_time"`
-	LastTriggerTime *time.Time     `json:"last_trigger_time"`
-	CreatedBy       uint64         `json:"created_by"`
-	CreatedAt       time.Time      `json:"created_at"`
-	UpdatedAt       time.Time      `json:"updated_at"`
-	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
-	Group           *TaskGroup     `gorm:"foreignKey:GroupID" json:"group,omitempty"`
-	Dependencies    []Task         `gorm:"many2many:task_dependency;joinForeignKey:TaskID;joinReferences:DependTaskID" json:"dependencies,omitempty"`
+	ID                uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID           uint64          `gorm:"not null;index" json:"group_id"`
+	Name              string          `gorm:"size:128;not null" json:"name"`
+	Description       string          `gorm:"size:512" json:"description"`
+	Cron              string          `gorm:"size:64;not null" json:"cron"`
+	ExecutorType      string          `gorm:"size:32;not null;default:HTTP" json:"executor_type"`
+	ExecutorHandler   string          `gorm:"size:256;not null" json:"executor_handler"`
+	ExecutorParam     string          `gorm:"type:text" json:"executor_param"`
+	RouteStrategy     string          `gorm:"size:32;default:ROUND_ROBIN" json:"route_strategy"`
+	RouteOptions      string          `gorm:"type:text" json:"route_options"`
+	LabelSelector     string          `gorm:"size:512" json:"label_selector"`
+	PreferredSelector string          `gorm:"size:512" json:"preferred_selector"`
+	AntiAffinity      bool            `gorm:"default:false" json:"anti_affinity"`
+	BlockStrategy     string          `gorm:"size:32;default:SERIAL_EXECUTION" json:"block_strategy"`
+	FailoverStrategy  string          `gorm:"size:32;default:FAIL" json:"failover_strategy"` // 执行器离线时执行中实例的处理策略
+	ShardNum          uint            `gorm:"default:1" json:"shard_num"`
+	RetryCount        uint            `gorm:"default:0" json:"retry_count"`
+	RetryInterval     uint            `gorm:"default:0" json:"retry_interval"`
+	Timeout           uint            `gorm:"default:0" json:"timeout"`
+	AlarmEmail        string          `gorm:"size:512" json:"alarm_email"`
+	Priority          int             `gorm:"default:0" json:"priority"`
+	MaxConcurrent     uint            `gorm:"default:0" json:"max_concurrent"`                       // 最大并发实例数, 0表示不限制
+	ScriptVersion     uint            `gorm:"default:0" json:"script_version"`                       // 当前脚本版本, 仅SCRIPT类型任务
+	HTTPCall          *HTTPCallConfig `gorm:"column:http_call;type:text" json:"http_call,omitempty"` // HTTP请求配置, 仅HTTP_CALL类型任务
+	Status            int8            `gorm:"default:1;index" json:"status"`
+	Version           uint            `gorm:"default:0" json:"version"`
+	NextTriggerTime   *time.Time      `gorm:"index" json:"next_trigger_time"`
+	LastTriggerTime   *time.Time      `json:"last_trigger_time"`
+	CreatedBy         uint64          `json:"created_by"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt  `gorm:"index" json:"-"`
+	Group             *TaskGroup      `gorm:"foreignKey:GroupID" json:"group,omitempty"`
+	Dependencies      []Task          `gorm:"many2many:task_dependency;joinForeignKey:TaskID;joinReferences:DependTaskID" json:"dependencies,omitempty"`
+	Script            *TaskScript     `gorm:"-" json:"script,omitempty"` // 当前版本的脚本
 }
 
 // TableName 指定表名
@@ -72,6 +157,96 @@ func (TaskDependency) TableName() string {
 	return "task_dependency"
 }
 
+// TaskScript 任务脚本版本, 修改脚本时生成新版本, 历史版本只读
+type TaskScript struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID     uint64    `gorm:"not null;uniqueIndex:uk_task_version" json:"task_id"`
+	Version    uint      `gorm:"not null;uniqueIndex:uk_task_version" json:"version"`
+	ScriptType string    `gorm:"size:32;not null" json:"script_type"` // 脚本类型, 由执行器映射到解释器, 如 bash/sh/python
+	Content    string    `gorm:"type:mediumtext" json:"content"`
+	Env        ScriptEnv `gorm:"type:text" json:"env"` // 脚本环境变量
+	Remark     string    `gorm:"size:256" json:"remark"`
+	CreatedBy  uint64    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (TaskScript) TableName() string {
+	return "task_script"
+}
+
+// SameAs 脚本内容、类型和环境变量是否一致
+func (s *TaskScript) SameAs(other *TaskScript) bool {
+	if other == nil || s.ScriptType != other.ScriptType || s.Content != other.Content || len(s.Env) != len(other.Env) {
+		return false
+	}
+	for k, v := range s.Env {
+		if value, ok := other.Env[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ScriptEnv 脚本环境变量, 以JSON格式存储
+type ScriptEnv map[string]string
+
+// Value 实现driver.Valuer接口
+func (e ScriptEnv) Value() (driver.Value, error) {
+	return Labels(e).Value()
+}
+
+// Scan 实现sql.Scanner接口
+func (e *ScriptEnv) Scan(value interface{}) error {
+	var labels Labels
+	if err := labels.Scan(value); err != nil {
+		return err
+	}
+	*e = ScriptEnv(labels)
+	return nil
+}
+
+// HTTPCallConfig HTTP_CALL类型任务的请求配置, 以JSON格式存储
+// URL、请求头的值和请求体为text/template模板, 可引用 .TaskID .InstanceID .Param .ShardIndex .ShardTotal .TriggerType .TriggerTime
+type HTTPCallConfig struct {
+	Method          string            `json:"method"` // 请求方法, 为空时为GET
+	URL             string            `json:"url"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Body            string            `json:"body,omitempty"`
+	Timeout         uint              `json:"timeout,omitempty"`           // 请求超时(秒), 为0时使用任务超时时间, 均未设置时使用默认值
+	ExpectStatus    []int             `json:"expect_status,omitempty"`     // 期望的响应状态码, 为空时要求2xx
+	ExpectBody      string            `json:"expect_body,omitempty"`       // 期望响应体包含的内容
+	ExpectBodyRegex string            `json:"expect_body_regex,omitempty"` // 期望响应体匹配的正则表达式
+}
+
+// Value 实现driver.Valuer接口
+func (c HTTPCallConfig) Value() (driver.Value, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现sql.Scanner接口
+func (c *HTTPCallConfig) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("无效的HTTP请求配置数据类型")
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, c)
+}
+
 // 任务状态常量
 const (
 	TaskStatusDisabled = 0 // 禁用
@@ -83,6 +258,15 @@ const (
 	ExecutorTypeHTTP   = "HTTP"
 	ExecutorTypeGRPC   = "GRPC"
 	ExecutorTypeSCRIPT = "SCRIPT"
+	// ExecutorTypeHTTPCall 由调度中心直接发起HTTP请求, 不需要注册执行器
+	ExecutorTypeHTTPCall = "HTTP_CALL"
+)
+
+// 脚本类型常量, 执行器可配置更多解释器
+const (
+	ScriptTypeBash   = "bash"
+	ScriptTypeSh     = "sh"
+	ScriptTypePython = "python"
 )
 
 // 路由策略常量
@@ -93,6 +277,7 @@ const (
 	RouteStrategyLeastFrequentlyUsed = "LEAST_FREQUENTLY_USED" // 最少使用
 	RouteStrategyLeastRecentlyUsed   = "LEAST_RECENTLY_USED"   // 最近最少使用
 	RouteStrategyFailover            = "FAILOVER"              // 故障转移
+	RouteStrategyBusyover            = "BUSYOVER"              // 忙碌转移
 	RouteStrategyShardingBroadcast   = "SHARDING_BROADCAST"    // 分片广播
 )
 
@@ -103,3 +288,8 @@ const (
 	BlockStrategyCoverEarly      = "COVER_EARLY"      // 覆盖之前调度
 )
 
+// 故障转移策略常量(执行器离线时执行中实例的处理方式)
+const (
+	FailoverStrategyFail    = "FAIL"    // 标记为失败
+	FailoverStrategyReroute = "REROUTE" // 重新调度到其他执行器
+)
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidTokens(t *testing.T) {
+	grace := 10 * time.Minute
+	recent := time.Now().Add(-time.Minute)
+	expired := time.Now().Add(-time.Hour)
+
+	cases := []struct {
+		name  string
+		group TaskGroup
+		want  []string
+	}{
+		{"未配置令牌", TaskGroup{}, nil},
+		{"未轮换", TaskGroup{AccessToken: "new"}, []string{"new"}},
+		{"宽限期内", TaskGroup{AccessToken: "new", PrevToken: "old", TokenRotateAt: &recent}, []string{"new", "old"}},
+		{"宽限期已过", TaskGroup{AccessToken: "new", PrevToken: "old", TokenRotateAt: &expired}, []string{"new"}},
+		{"缺少轮换时间", TaskGroup{AccessToken: "new", PrevToken: "old"}, []string{"new"}},
+	}
+	for _, c := range cases {
+		got := c.group.ValidTokens(grace)
+		if len(got) != len(c.want) {
+			t.Errorf("%s: 有效令牌应为%v, 实际: %v", c.name, c.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: 有效令牌应为%v, 实际: %v", c.name, c.want, got)
+				break
+			}
+		}
+	}
+}
+
+func TestGroupScopeAllows(t *testing.T) {
+	var internal *GroupScope
+	if !internal.Allows(1) {
+		t.Error("scope为nil时不应限制任务组")
+	}
+	if !(&GroupScope{All: true}).Allows(1) {
+		t.Error("超级管理员不应限制任务组")
+	}
+	scope := &GroupScope{GroupIDs: []uint64{1, 3}}
+	if !scope.Allows(3) || scope.Allows(2) {
+		t.Error("只能访问成员所在的任务组")
+	}
+	if (&GroupScope{}).Allows(1) {
+		t.Error("不属于任何任务组时不能访问任务组")
+	}
+}
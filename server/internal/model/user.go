@@ -65,3 +65,75 @@ const (
 	UserStatusEnabled  = 1 // 启用
 )
 
+// 角色编码
+const (
+	RoleSuperAdmin = "SUPER_ADMIN" // 超级管理员, 拥有所有权限
+	RoleAdmin      = "ADMIN"
+	RoleUser       = "USER"
+)
+
+// SysPermission 系统权限(资源×操作)
+type SysPermission struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Resource    string    `gorm:"size:64;not null;uniqueIndex:uk_resource_action" json:"resource"`
+	Action      string    `gorm:"size:32;not null;uniqueIndex:uk_resource_action" json:"action"`
+	Name        string    `gorm:"size:64;not null" json:"name"`
+	Description string    `gorm:"size:256" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (SysPermission) TableName() string {
+	return "sys_permission"
+}
+
+// Code 权限编码, 格式为 资源:操作
+func (p *SysPermission) Code() string {
+	return p.Resource + ":" + p.Action
+}
+
+// SysRolePermission 角色权限关联
+type SysRolePermission struct {
+	ID           uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	RoleID       uint64    `gorm:"not null;uniqueIndex:uk_role_permission" json:"role_id"`
+	PermissionID uint64    `gorm:"not null;uniqueIndex:uk_role_permission;index" json:"permission_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (SysRolePermission) TableName() string {
+	return "sys_role_permission"
+}
+
+// 权限编码(资源:操作), 与sys_permission表的初始化数据一致
+const (
+	PermUserView       = "user:view"       // 查看用户
+	PermUserCreate     = "user:create"     // 创建用户
+	PermGroupView      = "group:view"      // 查看任务组
+	PermGroupCreate    = "group:create"    // 创建任务组
+	PermGroupUpdate    = "group:update"    // 更新任务组
+	PermGroupDelete    = "group:delete"    // 删除任务组
+	PermGroupToken     = "group:token"     // 查看/轮换执行器访问令牌
+	PermTaskView       = "task:view"       // 查看任务
+	PermTaskCreate     = "task:create"     // 创建任务
+	PermTaskUpdate     = "task:update"     // 更新任务(含脚本回滚)
+	PermTaskDelete     = "task:delete"     // 删除任务
+	PermTaskExecute    = "task:execute"    // 启动/停止/手动触发任务
+	PermInstanceView   = "instance:view"   // 查看执行记录和日志
+	PermInstanceManage = "instance:manage" // 取消/重试任务实例
+	PermExecutorView   = "executor:view"   // 查看执行器
+	PermExecutorManage = "executor:manage" // 执行器摘流/取消摘流
+)
+
+// PermissionGroupRole 权限在任务组内要求的最低成员角色, 不按任务组授权的权限返回空
+func PermissionGroupRole(code string) string {
+	switch code {
+	case PermGroupView, PermTaskView, PermInstanceView, PermExecutorView:
+		return GroupRoleViewer
+	case PermTaskCreate, PermTaskUpdate, PermTaskDelete, PermTaskExecute, PermInstanceManage, PermExecutorManage:
+		return GroupRoleOperator
+	case PermGroupUpdate, PermGroupDelete, PermGroupToken:
+		return GroupRoleOwner
+	}
+	return ""
+}
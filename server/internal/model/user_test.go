@@ -0,0 +1,29 @@
+package model
+
+import "testing"
+
+func TestPermissionGroupRole(t *testing.T) {
+	cases := map[string]string{
+		PermTaskView:       GroupRoleViewer,
+		PermInstanceView:   GroupRoleViewer,
+		PermTaskExecute:    GroupRoleOperator,
+		PermInstanceManage: GroupRoleOperator,
+		PermExecutorManage: GroupRoleOperator,
+		PermGroupUpdate:    GroupRoleOwner,
+		PermGroupToken:     GroupRoleOwner,
+		PermUserCreate:     "",
+		PermGroupCreate:    "",
+	}
+	for code, want := range cases {
+		if got := PermissionGroupRole(code); got != want {
+			t.Errorf("权限%s要求的角色应为%q, 实际: %q", code, want, got)
+		}
+	}
+
+	// 级别高的角色包含级别低的角色的权限
+	if !(GroupRoleLevel(GroupRoleOwner) > GroupRoleLevel(GroupRoleOperator) &&
+		GroupRoleLevel(GroupRoleOperator) > GroupRoleLevel(GroupRoleViewer) &&
+		GroupRoleLevel(GroupRoleViewer) > GroupRoleLevel("UNKNOWN")) {
+		t.Error("角色级别应为 OWNER > OPERATOR > VIEWER > 未知角色")
+	}
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/pkg/mysql"
+)
+
+// AlarmRepository 告警仓库接口
+type AlarmRepository interface {
+	GetEnabledRules(ctx context.Context, ruleType string, groupID, taskID uint64) ([]*model.AlarmRule, error)
+	CreateRecord(ctx context.Context, record *model.AlarmRecord) error
+}
+
+// alarmRepository 告警仓库实现
+type alarmRepository struct {
+	db *gorm.DB
+}
+
+// NewAlarmRepository 创建告警仓库
+func NewAlarmRepository() AlarmRepository {
+	return &alarmRepository{db: mysql.GetDB()}
+}
+
+// GetEnabledRules 获取匹配的启用告警规则(任务组/任务为0的规则表示全局)
+func (r *alarmRepository) GetEnabledRules(ctx context.Context, ruleType string, groupID, taskID uint64) ([]*model.AlarmRule, error) {
+	var rules []*model.AlarmRule
+	err := r.db.WithContext(ctx).
+		Where("rule_type = ? AND status = ?", ruleType, 1).
+		Where("group_id IN ?", []uint64{0, groupID}).
+		Where("task_id IN ?", []uint64{0, taskID}).
+		Find(&rules).Error
+	return rules, err
+}
+
+// CreateRecord 创建告警记录
+func (r *alarmRepository) CreateRecord(ctx context.Context, record *model.AlarmRecord) error {
+	return r.db.WithContext(ctx).Create(record).Error
+}
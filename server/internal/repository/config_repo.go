@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/pkg/mysql"
+)
+
+// SysConfigRepository 系统配置仓库接口
+type SysConfigRepository interface {
+	GetByKey(ctx context.Context, key string) (*model.SysConfig, error)
+}
+
+// sysConfigRepository 系统配置仓库实现
+type sysConfigRepository struct {
+	db *gorm.DB
+}
+
+// NewSysConfigRepository 创建系统配置仓库
+func NewSysConfigRepository() SysConfigRepository {
+	return &sysConfigRepository{db: mysql.GetDB()}
+}
+
+// GetByKey 根据配置键获取配置
+func (r *sysConfigRepository) GetByKey(ctx context.Context, key string) (*model.SysConfig, error) {
+	var cfg model.SysConfig
+	err := r.db.WithContext(ctx).Where("config_key = ?", key).First(&cfg).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
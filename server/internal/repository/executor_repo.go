@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"distributed-scheduler/internal/model"
 	"distributed-scheduler/pkg/mysql"
@@ -14,15 +15,21 @@ import (
 type ExecutorRepository interface {
 	Register(ctx context.Context, node *model.ExecutorNode) error
 	Unregister(ctx context.Context, id string) error
-	UpdateHeartbeat(ctx context.Context, heartbeat *model.ExecutorHeartbeat) error
+	UpdateHeartbeat(ctx context.Context, heartbeat *model.ExecutorHeartbeat) (int64, error)
 	GetByID(ctx context.Context, id string) (*model.ExecutorNode, error)
 	GetOnlineByGroupID(ctx context.Context, groupID uint64) ([]*model.ExecutorNode, error)
 	GetOnlineByAppName(ctx context.Context, appName string) ([]*model.ExecutorNode, error)
 	GetAllOnline(ctx context.Context) ([]*model.ExecutorNode, error)
 	SetOffline(ctx context.Context, id string) error
 	SetOfflineByTimeout(ctx context.Context, timeout time.Duration) (int64, error)
+	GetHeartbeatTimeout(ctx context.Context, deadline time.Time) ([]*model.ExecutorNode, error)
+	SetOfflineIfTimeout(ctx context.Context, id string, deadline time.Time) (bool, error)
+	DeleteOfflineBefore(ctx context.Context, before time.Time) (int64, error)
 	UpdateLoad(ctx context.Context, id string, load uint) error
-	List(ctx context.Context, page, pageSize int, groupID uint64, status int8) ([]*model.ExecutorNode, int64, error)
+	StartDrain(ctx context.Context, id string) error
+	CancelDrain(ctx context.Context, id string) error
+	FinishDrain(ctx context.Context, id string) (bool, error)
+	List(ctx context.Context, scope *model.GroupScope, page, pageSize int, groupID uint64, status, drainStatus int8) ([]*model.ExecutorNode, int64, error)
 }
 
 // executorRepository 执行器仓库实现
@@ -36,9 +43,14 @@ func NewExecutorRepository() ExecutorRepository {
 }
 
 // Register 注册执行器
+// 使用ON DUPLICATE KEY UPDATE实现注册/更新, 重新注册时保留权重和首次注册时间并清除摘流状态
 func (r *executorRepository) Register(ctx context.Context, node *model.ExecutorNode) error {
-	// 使用ON DUPLICATE KEY UPDATE实现注册/更新
-	return r.db.WithContext(ctx).Save(node).Error
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{
+			"group_id", "app_name", "host", "port", "max_concurrent", "current_load",
+			"labels", "status", "drain_status", "drain_at", "last_heartbeat", "updated_at",
+		}),
+	}).Create(node).Error
 }
 
 // Unregister 注销执行器
@@ -46,16 +58,21 @@ func (r *executorRepository) Unregister(ctx context.Context, id string) error {
 	return r.db.WithContext(ctx).Delete(&model.ExecutorNode{}, "id = ?", id).Error
 }
 
-// UpdateHeartbeat 更新心跳
-func (r *executorRepository) UpdateHeartbeat(ctx context.Context, heartbeat *model.ExecutorHeartbeat) error {
-	return r.db.WithContext(ctx).Model(&model.ExecutorNode{}).Where("id = ?", heartbeat.ExecutorID).
-		Updates(map[string]interface{}{
-			"current_load":   heartbeat.CurrentLoad,
-			"cpu_usage":      heartbeat.CPUUsage,
-			"memory_usage":   heartbeat.MemoryUsage,
-			"status":         model.ExecutorStatusOnline,
-			"last_heartbeat": gorm.Expr("NOW()"),
-		}).Error
+// UpdateHeartbeat 更新心跳, 返回更新的行数
+func (r *executorRepository) UpdateHeartbeat(ctx context.Context, heartbeat *model.ExecutorHeartbeat) (int64, error) {
+	updates := map[string]interface{}{
+		"current_load":   heartbeat.CurrentLoad,
+		"cpu_usage":      heartbeat.CPUUsage,
+		"memory_usage":   heartbeat.MemoryUsage,
+		"status":         model.ExecutorStatusOnline,
+		"last_heartbeat": gorm.Expr("NOW()"),
+	}
+	if heartbeat.Labels != nil {
+		updates["labels"] = heartbeat.Labels
+	}
+	result := r.db.WithContext(ctx).Model(&model.ExecutorNode{}).Where("id = ?", heartbeat.ExecutorID).
+		Updates(updates)
+	return result.RowsAffected, result.Error
 }
 
 // GetByID 根据ID获取执行器
@@ -110,18 +127,77 @@ func (r *executorRepository) SetOfflineByTimeout(ctx context.Context, timeout ti
 	return result.RowsAffected, result.Error
 }
 
+// GetHeartbeatTimeout 获取最后心跳早于deadline的在线执行器
+func (r *executorRepository) GetHeartbeatTimeout(ctx context.Context, deadline time.Time) ([]*model.ExecutorNode, error) {
+	var nodes []*model.ExecutorNode
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND last_heartbeat < ?", model.ExecutorStatusOnline, deadline).
+		Find(&nodes).Error
+	return nodes, err
+}
+
+// SetOfflineIfTimeout 仅当执行器在线且最后心跳早于deadline时设置为离线, 返回是否更新成功
+// 多个调度节点同时检查时只有一个节点会更新成功
+func (r *executorRepository) SetOfflineIfTimeout(ctx context.Context, id string, deadline time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.ExecutorNode{}).
+		Where("id = ? AND status = ? AND last_heartbeat < ?", id, model.ExecutorStatusOnline, deadline).
+		Update("status", model.ExecutorStatusOffline)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteOfflineBefore 删除最后心跳早于before的离线执行器
+func (r *executorRepository) DeleteOfflineBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status = ? AND last_heartbeat < ?", model.ExecutorStatusOffline, before).
+		Delete(&model.ExecutorNode{})
+	return result.RowsAffected, result.Error
+}
+
 // UpdateLoad 更新执行器负载
 func (r *executorRepository) UpdateLoad(ctx context.Context, id string, load uint) error {
 	return r.db.WithContext(ctx).Model(&model.ExecutorNode{}).Where("id = ?", id).
 		Update("current_load", load).Error
 }
 
-// List 获取执行器列表
-func (r *executorRepository) List(ctx context.Context, page, pageSize int, groupID uint64, status int8) ([]*model.ExecutorNode, int64, error) {
+// StartDrain 开始摘流, 已在摘流中或已摘流完成的执行器保持不变
+func (r *executorRepository) StartDrain(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Model(&model.ExecutorNode{}).
+		Where("id = ? AND drain_status = ?", id, model.DrainStatusNone).
+		Updates(map[string]interface{}{
+			"drain_status": model.DrainStatusDraining,
+			"drain_at":     gorm.Expr("NOW()"),
+		}).Error
+}
+
+// CancelDrain 取消摘流, 执行器恢复接收新任务
+func (r *executorRepository) CancelDrain(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Model(&model.ExecutorNode{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"drain_status": model.DrainStatusNone,
+			"drain_at":     nil,
+		}).Error
+}
+
+// FinishDrain 仅当执行器处于摘流中时标记为摘流完成, 返回是否更新成功
+func (r *executorRepository) FinishDrain(ctx context.Context, id string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.ExecutorNode{}).
+		Where("id = ? AND drain_status = ?", id, model.DrainStatusDraining).
+		Update("drain_status", model.DrainStatusDrained)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// List 获取执行器列表, 只返回scope内任务组的执行器
+func (r *executorRepository) List(ctx context.Context, scope *model.GroupScope, page, pageSize int, groupID uint64, status, drainStatus int8) ([]*model.ExecutorNode, int64, error) {
 	var nodes []*model.ExecutorNode
 	var total int64
 
-	db := r.db.WithContext(ctx).Model(&model.ExecutorNode{})
+	db := r.db.WithContext(ctx).Model(&model.ExecutorNode{}).Scopes(scopeGroups(scope, "group_id"))
 
 	if groupID > 0 {
 		db = db.Where("group_id = ?", groupID)
@@ -129,6 +205,9 @@ func (r *executorRepository) List(ctx context.Context, page, pageSize int, group
 	if status >= 0 {
 		db = db.Where("status = ?", status)
 	}
+	if drainStatus >= 0 {
+		db = db.Where("drain_status = ?", drainStatus)
+	}
 
 	if err := db.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -140,4 +219,3 @@ func (r *executorRepository) List(ctx context.Context, page, pageSize int, group
 
 	return nodes, total, nil
 }
-
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"distributed-scheduler/internal/model"
 	"distributed-scheduler/pkg/mysql"
@@ -15,14 +16,27 @@ type InstanceRepository interface {
 	Create(ctx context.Context, instance *model.TaskInstance) error
 	Update(ctx context.Context, instance *model.TaskInstance) error
 	GetByID(ctx context.Context, id uint64) (*model.TaskInstance, error)
-	List(ctx context.Context, page, pageSize int, taskID uint64, status int8, startTime, endTime *time.Time) ([]*model.TaskInstance, int64, error)
+	List(ctx context.Context, scope *model.GroupScope, page, pageSize int, taskID uint64, status int8, startTime, endTime *time.Time) ([]*model.TaskInstance, int64, error)
 	UpdateStatus(ctx context.Context, id uint64, status int8, resultCode int, resultMsg string) error
 	UpdateStartTime(ctx context.Context, id uint64) error
 	UpdateEndTime(ctx context.Context, id uint64, status int8, resultCode int, resultMsg string) error
 	GetRunningInstances(ctx context.Context, taskID uint64) ([]*model.TaskInstance, error)
 	GetInstancesByTriggerTime(ctx context.Context, taskID uint64, triggerTime time.Time) ([]*model.TaskInstance, error)
-	CountByStatus(ctx context.Context, taskID uint64, startTime, endTime time.Time) (map[int8]int64, error)
-	GetRecentInstances(ctx context.Context, limit int) ([]*model.TaskInstance, error)
+	CountByStatus(ctx context.Context, scope *model.GroupScope, taskID uint64, startTime, endTime time.Time) (map[int8]int64, error)
+	GetRecentInstances(ctx context.Context, scope *model.GroupScope, limit int) ([]*model.TaskInstance, error)
+	GetPendingInstances(ctx context.Context, limit int, agingInterval time.Duration) ([]*model.TaskInstance, error)
+	CompareAndSwapStatus(ctx context.Context, id uint64, from, to int8) (bool, error)
+	ReleaseToPending(ctx context.Context, id uint64, reason string) (bool, error)
+	GetActiveInstanceIDs(ctx context.Context) ([]uint64, error)
+	ClaimForDispatch(ctx context.Context, id, token uint64) (bool, error)
+	ReleaseStaleScheduling(ctx context.Context, timeout time.Duration, reason string) (int64, error)
+	UpdateDispatched(ctx context.Context, id, token uint64, executorID, executorAddress, resultMsg string) (bool, error)
+	FinishAttempt(ctx context.Context, id, token uint64, status int8, resultCode int, resultMsg string) (bool, error)
+	GetOrphanedInstances(ctx context.Context, limit int) ([]*model.TaskInstance, error)
+	GetRunningHTTPCalls(ctx context.Context, startedBefore time.Time, limit int) ([]*model.TaskInstance, error)
+	CountActiveByExecutorID(ctx context.Context, executorID string) (int64, error)
+	FinishIfStatus(ctx context.Context, id uint64, from, status int8, resultCode int, resultMsg string) (bool, error)
+	RequeueIfStatus(ctx context.Context, id uint64, from int8, reason string) (bool, error)
 }
 
 // instanceRepository 任务实例仓库实现
@@ -55,12 +69,12 @@ func (r *instanceRepository) GetByID(ctx context.Context, id uint64) (*model.Tas
 	return &instance, nil
 }
 
-// List 获取任务实例列表
-func (r *instanceRepository) List(ctx context.Context, page, pageSize int, taskID uint64, status int8, startTime, endTime *time.Time) ([]*model.TaskInstance, int64, error) {
+// List 获取任务实例列表, 只返回scope内任务组的实例
+func (r *instanceRepository) List(ctx context.Context, scope *model.GroupScope, page, pageSize int, taskID uint64, status int8, startTime, endTime *time.Time) ([]*model.TaskInstance, int64, error) {
 	var instances []*model.TaskInstance
 	var total int64
 
-	db := r.db.WithContext(ctx).Model(&model.TaskInstance{})
+	db := r.db.WithContext(ctx).Model(&model.TaskInstance{}).Scopes(scopeGroups(scope, "group_id"))
 
 	if taskID > 0 {
 		db = db.Where("task_id = ?", taskID)
@@ -139,8 +153,8 @@ func (r *instanceRepository) GetInstancesByTriggerTime(ctx context.Context, task
 	return instances, err
 }
 
-// CountByStatus 按状态统计实例数量
-func (r *instanceRepository) CountByStatus(ctx context.Context, taskID uint64, startTime, endTime time.Time) (map[int8]int64, error) {
+// CountByStatus 按状态统计scope内任务组的实例数量
+func (r *instanceRepository) CountByStatus(ctx context.Context, scope *model.GroupScope, taskID uint64, startTime, endTime time.Time) (map[int8]int64, error) {
 	type Result struct {
 		Status int8
 		Count  int64
@@ -149,6 +163,7 @@ func (r *instanceRepository) CountByStatus(ctx context.Context, taskID uint64, s
 
 	db := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
 		Select("status, COUNT(*) as count").
+		Scopes(scopeGroups(scope, "group_id")).
 		Where("trigger_time BETWEEN ? AND ?", startTime, endTime)
 
 	if taskID > 0 {
@@ -166,10 +181,11 @@ func (r *instanceRepository) CountByStatus(ctx context.Context, taskID uint64, s
 	return countMap, nil
 }
 
-// GetRecentInstances 获取最近的实例
-func (r *instanceRepository) GetRecentInstances(ctx context.Context, limit int) ([]*model.TaskInstance, error) {
+// GetRecentInstances 获取scope内任务组最近的实例
+func (r *instanceRepository) GetRecentInstances(ctx context.Context, scope *model.GroupScope, limit int) ([]*model.TaskInstance, error) {
 	var instances []*model.TaskInstance
 	err := r.db.WithContext(ctx).
+		Scopes(scopeGroups(scope, "group_id")).
 		Preload("Task").
 		Order("id DESC").
 		Limit(limit).
@@ -177,6 +193,200 @@ func (r *instanceRepository) GetRecentInstances(ctx context.Context, limit int)
 	return instances, err
 }
 
+// GetPendingInstances 获取待调度的实例, 按任务优先级降序、触发时间升序排序
+// 设置老化间隔时按有效优先级(优先级 + 等待时长/老化间隔)排序, 与分发器优先队列的出队顺序一致,
+// 积压超过一批时新触发的高优先级实例和等待已久的低优先级实例都能被加载
+func (r *instanceRepository) GetPendingInstances(ctx context.Context, limit int, agingInterval time.Duration) ([]*model.TaskInstance, error) {
+	var instances []*model.TaskInstance
+	// 有效优先级中的当前时间对所有实例相同, 排序时可以约去
+	priority := "COALESCE(task.priority, 0)"
+	var vars []interface{}
+	if agingInterval > 0 {
+		priority += " - UNIX_TIMESTAMP(task_instance.trigger_time) / ?"
+		vars = append(vars, agingInterval.Seconds())
+	}
+	err := r.db.WithContext(ctx).
+		Preload("Task.Group").
+		Joins("LEFT JOIN task ON task.id = task_instance.task_id").
+		Where("task_instance.status = ? AND task_instance.trigger_time <= ?", model.InstanceStatusPending, time.Now()).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  priority + " DESC, task_instance.trigger_time ASC, task_instance.id ASC",
+			Vars: vars,
+		}}).
+		Limit(limit).
+		Find(&instances).Error
+	return instances, err
+}
+
+// CompareAndSwapStatus 仅当实例处于from状态时更新为to状态, 返回是否更新成功
+func (r *instanceRepository) CompareAndSwapStatus(ctx context.Context, id uint64, from, to int8) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReleaseToPending 将调度中的实例恢复为待调度状态并记录原因, 返回是否更新成功
+func (r *instanceRepository) ReleaseToPending(ctx context.Context, id uint64, reason string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("id = ? AND status = ?", id, model.InstanceStatusScheduling).
+		Updates(map[string]interface{}{
+			"status":         model.InstanceStatusPending,
+			"pending_reason": reason,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetActiveInstanceIDs 获取调度中和执行中的实例ID
+func (r *instanceRepository) GetActiveInstanceIDs(ctx context.Context) ([]uint64, error) {
+	var ids []uint64
+	err := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("status IN ?", []int8{model.InstanceStatusScheduling, model.InstanceStatusRunning}).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ClaimForDispatch 抢占待调度实例并递增派发令牌, token为加载实例时的令牌, 返回是否抢占成功
+// 抢占成功后实例的派发令牌为token+1, 调度时间记录抢占时间, 超时未分发完成的实例由ReleaseStaleScheduling恢复
+func (r *instanceRepository) ClaimForDispatch(ctx context.Context, id, token uint64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("id = ? AND status = ? AND dispatch_token = ?", id, model.InstanceStatusPending, token).
+		Updates(map[string]interface{}{
+			"status":         model.InstanceStatusScheduling,
+			"dispatch_token": token + 1,
+			"schedule_time":  gorm.Expr("NOW()"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReleaseStaleScheduling 将抢占超过timeout仍处于调度中的实例恢复为待调度状态, 返回恢复的数量
+// 调度节点在抢占实例后退出, 或分发任务在协程池中丢失时, 实例会一直停留在调度中;
+// 恢复后占用的并发配额由对账回收, 已下发的旧令牌任务由执行器按派发令牌终止
+func (r *instanceRepository) ReleaseStaleScheduling(ctx context.Context, timeout time.Duration, reason string) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("status = ?", model.InstanceStatusScheduling).
+		Where("schedule_time IS NULL OR schedule_time < DATE_SUB(NOW(), INTERVAL ? SECOND)", int64(timeout.Seconds())).
+		Updates(map[string]interface{}{
+			"status":         model.InstanceStatusPending,
+			"pending_reason": reason,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// UpdateDispatched 实例分发成功, 记录执行器并更新为执行中
+// 仅当实例仍处于调度中且派发令牌未变化时更新, 实例已结束(如执行器先回调)或被重新分发时返回false
+func (r *instanceRepository) UpdateDispatched(ctx context.Context, id, token uint64, executorID, executorAddress, resultMsg string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("id = ? AND status = ? AND dispatch_token = ?", id, model.InstanceStatusScheduling, token).
+		Updates(map[string]interface{}{
+			"executor_id":      executorID,
+			"executor_address": executorAddress,
+			"status":           model.InstanceStatusRunning,
+			"result_msg":       resultMsg,
+			"pending_reason":   "",
+			"schedule_time":    gorm.Expr("NOW()"),
+			"start_time":       gorm.Expr("NOW()"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FinishAttempt 结束令牌为token的执行, 仅当实例处于调度中或执行中且令牌未变化时更新, 返回是否更新成功
+func (r *instanceRepository) FinishAttempt(ctx context.Context, id, token uint64, status int8, resultCode int, resultMsg string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("id = ? AND dispatch_token = ? AND status IN ?", id, token,
+			[]int8{model.InstanceStatusScheduling, model.InstanceStatusRunning}).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"result_code": resultCode,
+			"result_msg":  resultMsg,
+			"end_time":    gorm.Expr("NOW()"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetOrphanedInstances 获取执行器已离线或已删除的执行中实例(预加载任务)
+func (r *instanceRepository) GetOrphanedInstances(ctx context.Context, limit int) ([]*model.TaskInstance, error) {
+	var instances []*model.TaskInstance
+	online := r.db.Model(&model.ExecutorNode{}).Select("id").Where("status = ?", model.ExecutorStatusOnline)
+	err := r.db.WithContext(ctx).Preload("Task").
+		Where("status = ? AND executor_id <> ''", model.InstanceStatusRunning).
+		Where("executor_id NOT IN (?)", online).
+		Order("id ASC").
+		Limit(limit).
+		Find(&instances).Error
+	return instances, err
+}
+
+// GetRunningHTTPCalls 获取开始时间早于startedBefore的执行中HTTP_CALL实例(预加载任务)
+// HTTP_CALL实例由调度中心直接执行, 执行器ID为空
+func (r *instanceRepository) GetRunningHTTPCalls(ctx context.Context, startedBefore time.Time, limit int) ([]*model.TaskInstance, error) {
+	var instances []*model.TaskInstance
+	err := r.db.WithContext(ctx).Preload("Task").
+		Where("status = ? AND executor_id = '' AND start_time < ?", model.InstanceStatusRunning, startedBefore).
+		Order("start_time ASC").
+		Limit(limit).
+		Find(&instances).Error
+	return instances, err
+}
+
+// CountActiveByExecutorID 统计已下发到执行器但尚未结束的实例数
+func (r *instanceRepository) CountActiveByExecutorID(ctx context.Context, executorID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("executor_id = ? AND status IN ?", executorID, []int8{model.InstanceStatusScheduling, model.InstanceStatusRunning}).
+		Count(&count).Error
+	return count, err
+}
+
+// FinishIfStatus 仅当实例处于from状态时结束实例, 返回是否更新成功
+func (r *instanceRepository) FinishIfStatus(ctx context.Context, id uint64, from, status int8, resultCode int, resultMsg string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"result_code": resultCode,
+			"result_msg":  resultMsg,
+			"end_time":    gorm.Expr("NOW()"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RequeueIfStatus 仅当实例处于from状态时恢复为待调度状态并清空执行器, 返回是否更新成功
+func (r *instanceRepository) RequeueIfStatus(ctx context.Context, id uint64, from int8, reason string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(map[string]interface{}{
+			"status":           model.InstanceStatusPending,
+			"executor_id":      "",
+			"executor_address": "",
+			"pending_reason":   reason,
+			"schedule_time":    nil,
+			"start_time":       nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
 // TaskLogRepository 任务日志仓库接口
 type TaskLogRepository interface {
 	Create(ctx context.Context, log *model.TaskLog) error
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/pkg/mysql"
+)
+
+// PermissionRepository 权限仓库接口
+type PermissionRepository interface {
+	List(ctx context.Context) ([]*model.SysPermission, error)
+	GetByUserID(ctx context.Context, userID uint64) ([]*model.SysPermission, error)
+}
+
+// permissionRepository 权限仓库实现
+type permissionRepository struct {
+	db *gorm.DB
+}
+
+// NewPermissionRepository 创建权限仓库
+func NewPermissionRepository() PermissionRepository {
+	return &permissionRepository{db: mysql.GetDB()}
+}
+
+// List 获取所有权限
+func (r *permissionRepository) List(ctx context.Context) ([]*model.SysPermission, error) {
+	var permissions []*model.SysPermission
+	err := r.db.WithContext(ctx).Order("resource, action").Find(&permissions).Error
+	return permissions, err
+}
+
+// GetByUserID 获取用户通过已启用角色获得的权限
+func (r *permissionRepository) GetByUserID(ctx context.Context, userID uint64) ([]*model.SysPermission, error) {
+	var permissions []*model.SysPermission
+	err := r.db.WithContext(ctx).
+		Distinct("sys_permission.*").
+		Joins("JOIN sys_role_permission ON sys_role_permission.permission_id = sys_permission.id").
+		Joins("JOIN sys_role ON sys_role.id = sys_role_permission.role_id AND sys_role.deleted_at IS NULL").
+		Joins("JOIN sys_user_role ON sys_user_role.role_id = sys_role.id").
+		Where("sys_user_role.user_id = ? AND sys_role.status = ?", userID, 1).
+		Find(&permissions).Error
+	return permissions, err
+}
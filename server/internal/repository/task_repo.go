@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"distributed-scheduler/internal/model"
 	"distributed-scheduler/pkg/mysql"
@@ -16,7 +17,7 @@ type TaskRepository interface {
 	Update(ctx context.Context, task *model.Task) error
 	Delete(ctx context.Context, id uint64) error
 	GetByID(ctx context.Context, id uint64) (*model.Task, error)
-	List(ctx context.Context, page, pageSize int, groupID uint64, keyword string, status int8) ([]*model.Task, int64, error)
+	List(ctx context.Context, scope *model.GroupScope, page, pageSize int, groupID uint64, keyword string, status int8) ([]*model.Task, int64, error)
 	GetEnabledTasks(ctx context.Context) ([]*model.Task, error)
 	GetTasksToTrigger(ctx context.Context, beforeTime time.Time, limit int) ([]*model.Task, error)
 	UpdateNextTriggerTime(ctx context.Context, id uint64, nextTime time.Time, lastTime time.Time) error
@@ -40,9 +41,9 @@ func (r *taskRepository) Create(ctx context.Context, task *model.Task) error {
 	return r.db.WithContext(ctx).Create(task).Error
 }
 
-// Update 更新任务
+// Update 更新任务, 脚本版本只由TaskScriptRepository.Publish修改
 func (r *taskRepository) Update(ctx context.Context, task *model.Task) error {
-	return r.db.WithContext(ctx).Save(task).Error
+	return r.db.WithContext(ctx).Omit("script_version").Save(task).Error
 }
 
 // Delete 删除任务(软删除)
@@ -60,12 +61,12 @@ func (r *taskRepository) GetByID(ctx context.Context, id uint64) (*model.Task, e
 	return &task, nil
 }
 
-// List 获取任务列表
-func (r *taskRepository) List(ctx context.Context, page, pageSize int, groupID uint64, keyword string, status int8) ([]*model.Task, int64, error) {
+// List 获取任务列表, 只返回scope内任务组的任务
+func (r *taskRepository) List(ctx context.Context, scope *model.GroupScope, page, pageSize int, groupID uint64, keyword string, status int8) ([]*model.Task, int64, error) {
 	var tasks []*model.Task
 	var total int64
 
-	db := r.db.WithContext(ctx).Model(&model.Task{})
+	db := r.db.WithContext(ctx).Model(&model.Task{}).Scopes(scopeGroups(scope, "group_id"))
 
 	if groupID > 0 {
 		db = db.Where("group_id = ?", groupID)
@@ -157,6 +158,70 @@ func (r *taskRepository) SetDependencies(ctx context.Context, taskID uint64, dep
 	})
 }
 
+// TaskScriptRepository 任务脚本仓库接口
+type TaskScriptRepository interface {
+	Publish(ctx context.Context, script *model.TaskScript) error
+	GetByVersion(ctx context.Context, taskID uint64, version uint) (*model.TaskScript, error)
+	ListByTaskID(ctx context.Context, taskID uint64) ([]*model.TaskScript, error)
+}
+
+// taskScriptRepository 任务脚本仓库实现
+type taskScriptRepository struct {
+	db *gorm.DB
+}
+
+// NewTaskScriptRepository 创建任务脚本仓库
+func NewTaskScriptRepository() TaskScriptRepository {
+	return &taskScriptRepository{db: mysql.GetDB()}
+}
+
+// Publish 发布脚本新版本并设为任务的当前版本, 版本号在任务内递增
+// 锁定任务行以串行化同一任务的并发发布
+func (r *taskScriptRepository) Publish(ctx context.Context, script *model.TaskScript) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var task model.Task
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").First(&task, script.TaskID).Error; err != nil {
+			return err
+		}
+
+		var maxVersion uint
+		if err := tx.Model(&model.TaskScript{}).Where("task_id = ?", script.TaskID).
+			Select("COALESCE(MAX(version), 0)").Scan(&maxVersion).Error; err != nil {
+			return err
+		}
+		script.ID = 0
+		script.Version = maxVersion + 1
+		if err := tx.Create(script).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Task{}).Where("id = ?", script.TaskID).
+			Update("script_version", script.Version).Error
+	})
+}
+
+// GetByVersion 获取任务指定版本的脚本
+func (r *taskScriptRepository) GetByVersion(ctx context.Context, taskID uint64, version uint) (*model.TaskScript, error) {
+	var script model.TaskScript
+	err := r.db.WithContext(ctx).
+		Where("task_id = ? AND version = ?", taskID, version).
+		First(&script).Error
+	if err != nil {
+		return nil, err
+	}
+	return &script, nil
+}
+
+// ListByTaskID 获取任务的脚本版本列表, 按版本倒序
+func (r *taskScriptRepository) ListByTaskID(ctx context.Context, taskID uint64) ([]*model.TaskScript, error) {
+	var scripts []*model.TaskScript
+	err := r.db.WithContext(ctx).
+		Where("task_id = ?", taskID).
+		Order("version DESC").
+		Find(&scripts).Error
+	return scripts, err
+}
+
 // TaskGroupRepository 任务组仓库接口
 type TaskGroupRepository interface {
 	Create(ctx context.Context, group *model.TaskGroup) error
@@ -164,8 +229,8 @@ type TaskGroupRepository interface {
 	Delete(ctx context.Context, id uint64) error
 	GetByID(ctx context.Context, id uint64) (*model.TaskGroup, error)
 	GetByAppName(ctx context.Context, appName string) (*model.TaskGroup, error)
-	List(ctx context.Context, page, pageSize int, keyword string) ([]*model.TaskGroup, int64, error)
-	GetAll(ctx context.Context) ([]*model.TaskGroup, error)
+	List(ctx context.Context, scope *model.GroupScope, page, pageSize int, keyword string) ([]*model.TaskGroup, int64, error)
+	GetAll(ctx context.Context, scope *model.GroupScope) ([]*model.TaskGroup, error)
 }
 
 // taskGroupRepository 任务组仓库实现
@@ -213,12 +278,12 @@ func (r *taskGroupRepository) GetByAppName(ctx context.Context, appName string)
 	return &group, nil
 }
 
-// List 获取任务组列表
-func (r *taskGroupRepository) List(ctx context.Context, page, pageSize int, keyword string) ([]*model.TaskGroup, int64, error) {
+// List 获取任务组列表, 只返回scope内的任务组
+func (r *taskGroupRepository) List(ctx context.Context, scope *model.GroupScope, page, pageSize int, keyword string) ([]*model.TaskGroup, int64, error) {
 	var groups []*model.TaskGroup
 	var total int64
 
-	db := r.db.WithContext(ctx).Model(&model.TaskGroup{})
+	db := r.db.WithContext(ctx).Model(&model.TaskGroup{}).Scopes(scopeGroups(scope, "id"))
 
 	if keyword != "" {
 		db = db.Where("name LIKE ? OR app_name LIKE ?", "%"+keyword+"%", "%"+keyword+"%")
@@ -235,10 +300,84 @@ func (r *taskGroupRepository) List(ctx context.Context, page, pageSize int, keyw
 	return groups, total, nil
 }
 
-// GetAll 获取所有任务组
-func (r *taskGroupRepository) GetAll(ctx context.Context) ([]*model.TaskGroup, error) {
+// GetAll 获取scope内所有启用的任务组
+func (r *taskGroupRepository) GetAll(ctx context.Context, scope *model.GroupScope) ([]*model.TaskGroup, error) {
 	var groups []*model.TaskGroup
-	err := r.db.WithContext(ctx).Where("status = ?", 1).Find(&groups).Error
+	err := r.db.WithContext(ctx).Scopes(scopeGroups(scope, "id")).Where("status = ?", 1).Find(&groups).Error
 	return groups, err
 }
 
+// scopeGroups 按任务组范围过滤, scope为nil或不限制时不过滤
+func scopeGroups(scope *model.GroupScope, column string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if scope == nil || scope.All {
+			return db
+		}
+		if len(scope.GroupIDs) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where(column+" IN ?", scope.GroupIDs)
+	}
+}
+
+// TaskGroupMemberRepository 任务组成员仓库接口
+type TaskGroupMemberRepository interface {
+	Save(ctx context.Context, member *model.TaskGroupMember) error
+	Delete(ctx context.Context, groupID, userID uint64) (bool, error)
+	DeleteByGroupID(ctx context.Context, groupID uint64) error
+	ListByGroupID(ctx context.Context, groupID uint64) ([]*model.TaskGroupMember, error)
+	ListByUserID(ctx context.Context, userID uint64) ([]*model.TaskGroupMember, error)
+}
+
+// taskGroupMemberRepository 任务组成员仓库实现
+type taskGroupMemberRepository struct {
+	db *gorm.DB
+}
+
+// NewTaskGroupMemberRepository 创建任务组成员仓库
+func NewTaskGroupMemberRepository() TaskGroupMemberRepository {
+	return &taskGroupMemberRepository{db: mysql.GetDB()}
+}
+
+// Save 添加成员, 已是成员时更新角色
+func (r *taskGroupMemberRepository) Save(ctx context.Context, member *model.TaskGroupMember) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(member).Error
+}
+
+// Delete 移除成员
+func (r *taskGroupMemberRepository) Delete(ctx context.Context, groupID, userID uint64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("group_id = ? AND user_id = ?", groupID, userID).
+		Delete(&model.TaskGroupMember{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteByGroupID 移除任务组的所有成员
+func (r *taskGroupMemberRepository) DeleteByGroupID(ctx context.Context, groupID uint64) error {
+	return r.db.WithContext(ctx).Where("group_id = ?", groupID).Delete(&model.TaskGroupMember{}).Error
+}
+
+// ListByGroupID 获取任务组的成员
+func (r *taskGroupMemberRepository) ListByGroupID(ctx context.Context, groupID uint64) ([]*model.TaskGroupMember, error) {
+	var members []*model.TaskGroupMember
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("group_id = ?", groupID).
+		Order("id").
+		Find(&members).Error
+	return members, err
+}
+
+// ListByUserID 获取用户加入的任务组
+func (r *taskGroupMemberRepository) ListByUserID(ctx context.Context, userID uint64) ([]*model.TaskGroupMember, error) {
+	var members []*model.TaskGroupMember
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&members).Error
+	return members, err
+}
+
@@ -5,6 +5,7 @@ import (
 
 	"distributed-scheduler/internal/handler"
 	"distributed-scheduler/internal/middleware"
+	"distributed-scheduler/internal/model"
 )
 
 // SetupRouter 设置路由
@@ -18,8 +19,7 @@ func SetupRouter(mode string) *gin.Engine {
 	r.Use(middleware.Logger())
 	r.Use(middleware.Cors())
 
-	// todo 初始化限流器
-	middleware.InitRateLimiter(100, 200) // 每秒100个请求，桶容量200
+	// 限流(规则由配置文件rate_limit指定, 启动时初始化)
 	r.Use(middleware.RateLimit())
 
 	// 健康检查
@@ -35,18 +35,23 @@ func SetupRouter(mode string) *gin.Engine {
 		auth := apiV1.Group("/auth")
 		{
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.RefreshToken)
 		}
 
-		// 执行器相关(无需登录，供执行器调用)
+		// 执行器相关(无需登录，供执行器调用, 使用任务组访问令牌签名认证)
 		executorHandler := handler.NewExecutorHandler()
 		executor := apiV1.Group("/executor")
+		executor.Use(middleware.ExecutorAuth())
 		{
 			executor.POST("/register", executorHandler.Register)
 			executor.POST("/unregister", executorHandler.Unregister)
 			executor.POST("/heartbeat", executorHandler.Heartbeat)
+			executor.POST("/callback", executorHandler.Callback)
+			executor.POST("/log", executorHandler.ReportLogs)
+			executor.POST("/drain", executorHandler.Drain)
 		}
 
-		// 需要认证的路由
+		// 需要认证的路由, 除当前用户相关接口外均按角色权限(资源:操作)校验
 		authorized := apiV1.Group("")
 		authorized.Use(middleware.JWTAuth())
 		{
@@ -54,58 +59,71 @@ func SetupRouter(mode string) *gin.Engine {
 			user := authorized.Group("/user")
 			{
 				user.GET("/current", authHandler.GetCurrentUser)
-				user.POST("", authHandler.Create)
-				user.GET("", authHandler.List)
+				user.GET("/permissions", authHandler.GetPermissions)
+				user.POST("", middleware.RequirePermission(model.PermUserCreate), authHandler.Create)
+				user.GET("", middleware.RequirePermission(model.PermUserView), authHandler.List)
 				user.PUT("/password", authHandler.ChangePassword)
 			}
 			authorized.POST("/auth/logout", authHandler.Logout)
+			authorized.POST("/auth/logout-all", authHandler.LogoutAll)
 
 			// 任务组相关
 			groupHandler := handler.NewGroupHandler()
 			group := authorized.Group("/group")
 			{
-				group.POST("", groupHandler.Create)
-				group.PUT("/:id", groupHandler.Update)
-				group.DELETE("/:id", groupHandler.Delete)
-				group.GET("/:id", groupHandler.GetByID)
-				group.GET("", groupHandler.List)
-				group.GET("/all", groupHandler.GetAll)
+				group.POST("", middleware.RequirePermission(model.PermGroupCreate), groupHandler.Create)
+				group.PUT("/:id", middleware.RequirePermission(model.PermGroupUpdate), groupHandler.Update)
+				group.DELETE("/:id", middleware.RequirePermission(model.PermGroupDelete), groupHandler.Delete)
+				group.GET("/:id", middleware.RequirePermission(model.PermGroupView), groupHandler.GetByID)
+				group.GET("", middleware.RequirePermission(model.PermGroupView), groupHandler.List)
+				group.GET("/all", middleware.RequirePermission(model.PermGroupView), groupHandler.GetAll)
+				group.GET("/:id/token", middleware.RequirePermission(model.PermGroupToken), groupHandler.GetToken)
+				group.POST("/:id/token/rotate", middleware.RequirePermission(model.PermGroupToken), groupHandler.RotateToken)
+				group.GET("/:id/members", middleware.RequirePermission(model.PermGroupView), groupHandler.ListMembers)
+				group.PUT("/:id/members", middleware.RequirePermission(model.PermGroupUpdate), groupHandler.SaveMember)
+				group.DELETE("/:id/members/:user_id", middleware.RequirePermission(model.PermGroupUpdate), groupHandler.RemoveMember)
 			}
 
 			// 任务相关
 			taskHandler := handler.NewTaskHandler()
 			task := authorized.Group("/task")
 			{
-				task.POST("", taskHandler.Create)
-				task.PUT("/:id", taskHandler.Update)
-				task.DELETE("/:id", taskHandler.Delete)
-				task.GET("/:id", taskHandler.GetByID)
-				task.GET("", taskHandler.List)
-				task.POST("/:id/start", taskHandler.Start)
-				task.POST("/:id/stop", taskHandler.Stop)
-				task.POST("/:id/trigger", taskHandler.Trigger)
-				task.GET("/next-trigger-times", taskHandler.GetNextTriggerTimes)
+				task.POST("", middleware.RequirePermission(model.PermTaskCreate), taskHandler.Create)
+				task.PUT("/:id", middleware.RequirePermission(model.PermTaskUpdate), taskHandler.Update)
+				task.DELETE("/:id", middleware.RequirePermission(model.PermTaskDelete), taskHandler.Delete)
+				task.GET("/:id", middleware.RequirePermission(model.PermTaskView), taskHandler.GetByID)
+				task.GET("", middleware.RequirePermission(model.PermTaskView), taskHandler.List)
+				task.POST("/:id/start", middleware.RequirePermission(model.PermTaskExecute), taskHandler.Start)
+				task.POST("/:id/stop", middleware.RequirePermission(model.PermTaskExecute), taskHandler.Stop)
+				task.POST("/:id/trigger", middleware.RequirePermission(model.PermTaskExecute), taskHandler.Trigger)
+				task.GET("/:id/scripts", middleware.RequirePermission(model.PermTaskView), taskHandler.ListScripts)
+				task.GET("/:id/scripts/:version", middleware.RequirePermission(model.PermTaskView), taskHandler.GetScript)
+				task.POST("/:id/scripts/:version/rollback", middleware.RequirePermission(model.PermTaskUpdate), taskHandler.RollbackScript)
+				task.GET("/next-trigger-times", middleware.RequirePermission(model.PermTaskView), taskHandler.GetNextTriggerTimes)
+				task.GET("/route-strategies", middleware.RequirePermission(model.PermTaskView), taskHandler.GetRouteStrategies)
 			}
 
 			// 任务实例相关
 			instanceHandler := handler.NewInstanceHandler()
 			instance := authorized.Group("/instance")
 			{
-				instance.GET("/:id", instanceHandler.GetByID)
-				instance.GET("", instanceHandler.List)
-				instance.POST("/:id/cancel", instanceHandler.Cancel)
-				instance.POST("/:id/retry", instanceHandler.Retry)
-				instance.GET("/:id/logs", instanceHandler.GetLogs)
-				instance.GET("/statistics", instanceHandler.GetStatistics)
-				instance.GET("/recent", instanceHandler.GetRecentInstances)
+				instance.GET("/:id", middleware.RequirePermission(model.PermInstanceView), instanceHandler.GetByID)
+				instance.GET("", middleware.RequirePermission(model.PermInstanceView), instanceHandler.List)
+				instance.POST("/:id/cancel", middleware.RequirePermission(model.PermInstanceManage), instanceHandler.Cancel)
+				instance.POST("/:id/retry", middleware.RequirePermission(model.PermInstanceManage), instanceHandler.Retry)
+				instance.GET("/:id/logs", middleware.RequirePermission(model.PermInstanceView), instanceHandler.GetLogs)
+				instance.GET("/statistics", middleware.RequirePermission(model.PermInstanceView), instanceHandler.GetStatistics)
+				instance.GET("/recent", middleware.RequirePermission(model.PermInstanceView), instanceHandler.GetRecentInstances)
 			}
 
 			// 执行器管理(需要认证)
 			executorAdmin := authorized.Group("/executor")
 			{
-				executorAdmin.GET("/:id", executorHandler.GetByID)
-				executorAdmin.GET("", executorHandler.List)
-				executorAdmin.GET("/online", executorHandler.GetOnlineByGroupID)
+				executorAdmin.GET("/:id", middleware.RequirePermission(model.PermExecutorView), executorHandler.GetByID)
+				executorAdmin.GET("", middleware.RequirePermission(model.PermExecutorView), executorHandler.List)
+				executorAdmin.GET("/online", middleware.RequirePermission(model.PermExecutorView), executorHandler.GetOnlineByGroupID)
+				executorAdmin.POST("/:id/drain", middleware.RequirePermission(model.PermExecutorManage), executorHandler.StartDrain)
+				executorAdmin.POST("/:id/undrain", middleware.RequirePermission(model.PermExecutorManage), executorHandler.CancelDrain)
 			}
 		}
 	}
@@ -0,0 +1,119 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"distributed-scheduler/internal/common/utils"
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/service"
+)
+
+// 执行器签名metadata键, 与HTTP请求头同名(小写)
+var (
+	MetadataExecutorApp       = strings.ToLower(utils.HeaderExecutorApp)
+	MetadataExecutorTimestamp = strings.ToLower(utils.HeaderExecutorTimestamp)
+	MetadataExecutorNonce     = strings.ToLower(utils.HeaderExecutorNonce)
+	MetadataExecutorSignature = strings.ToLower(utils.HeaderExecutorSignature)
+)
+
+// groupKey 上下文中签名对应任务组的键
+type groupKey struct{}
+
+// authenticator 执行器请求认证
+// 签名的方法为utils.MethodGRPC、路径为完整方法名、请求体为空, 流式调用只在建立流时认证一次
+type authenticator struct {
+	enable          bool
+	executorService service.ExecutorService
+}
+
+// authenticate 校验metadata中的签名, 返回携带任务组的上下文
+func (a *authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if !a.enable {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	group, err := a.executorService.Authenticate(ctx, &service.ExecutorCredentials{
+		AppName:   firstValue(md, MetadataExecutorApp),
+		Timestamp: firstValue(md, MetadataExecutorTimestamp),
+		Nonce:     firstValue(md, MetadataExecutorNonce),
+		Signature: firstValue(md, MetadataExecutorSignature),
+	}, utils.MethodGRPC, method, nil)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return context.WithValue(ctx, groupKey{}, group), nil
+}
+
+// unaryInterceptor 一元调用认证拦截器
+func (a *authenticator) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamInterceptor 流式调用认证拦截器
+func (a *authenticator) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+}
+
+// authStream 携带认证结果上下文的服务端流
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
+
+// GetExecutorGroup 从上下文获取执行器所属任务组, 未启用认证时返回nil
+func GetExecutorGroup(ctx context.Context) *model.TaskGroup {
+	group, _ := ctx.Value(groupKey{}).(*model.TaskGroup)
+	return group
+}
+
+// GetExecutorGroupID 从上下文获取执行器所属任务组ID, 未启用认证时返回0
+func GetExecutorGroupID(ctx context.Context) uint64 {
+	if group := GetExecutorGroup(ctx); group != nil {
+		return group.ID
+	}
+	return 0
+}
+
+// firstValue 获取metadata中键的第一个值
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// toStatus 将服务层错误转换为gRPC状态
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, service.ErrExecutorUnauthorized):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, service.ErrExecutorForbidden), errors.Is(err, service.ErrInstanceForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrExecutorNotFound), errors.Is(err, service.ErrInstanceNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrStaleDispatch):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,169 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	executorv1 "distributed-scheduler/api/executor/v1"
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/service"
+)
+
+// 日志流批量保存条数
+const logBatchSize = 100
+
+// schedulerServer 调度中心gRPC服务实现
+type schedulerServer struct {
+	executorv1.UnimplementedSchedulerServiceServer
+
+	executorService service.ExecutorService
+	instanceService service.InstanceService
+}
+
+// newSchedulerServer 创建调度中心gRPC服务
+func newSchedulerServer() *schedulerServer {
+	return &schedulerServer{
+		executorService: service.NewExecutorService(),
+		instanceService: service.NewInstanceService(),
+	}
+}
+
+// Register 注册执行器
+func (s *schedulerServer) Register(ctx context.Context, req *executorv1.RegisterRequest) (*executorv1.RegisterResponse, error) {
+	if req.GetAppName() == "" || req.GetHost() == "" || req.GetPort() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_name、host、port不能为空")
+	}
+	// 只能注册到签名令牌所属的任务组
+	if group := GetExecutorGroup(ctx); group != nil && group.AppName != req.GetAppName() {
+		return nil, status.Error(codes.PermissionDenied, "应用名称与访问令牌所属的任务组不一致")
+	}
+
+	maxConcurrent := uint(req.GetMaxConcurrent())
+	if maxConcurrent == 0 {
+		maxConcurrent = 100
+	}
+	node, err := s.executorService.Register(ctx, req.GetAppName(), req.GetInstanceId(), req.GetHost(),
+		uint(req.GetPort()), maxConcurrent, req.GetLabels())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &executorv1.RegisterResponse{ExecutorId: node.ID, GroupId: node.GroupID}, nil
+}
+
+// Unregister 注销执行器
+func (s *schedulerServer) Unregister(ctx context.Context, req *executorv1.UnregisterRequest) (*executorv1.UnregisterResponse, error) {
+	if err := s.executorService.Unregister(ctx, GetExecutorGroupID(ctx), req.GetExecutorId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &executorv1.UnregisterResponse{}, nil
+}
+
+// Heartbeat 心跳双向流, 每收到一次心跳返回一次摘流状态
+func (s *schedulerServer) Heartbeat(stream executorv1.SchedulerService_HeartbeatServer) error {
+	ctx := stream.Context()
+	groupID := GetExecutorGroupID(ctx)
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		ack, err := s.executorService.Heartbeat(ctx, groupID, &model.ExecutorHeartbeat{
+			ExecutorID:  req.GetExecutorId(),
+			InstanceID:  req.GetInstanceId(),
+			AppName:     req.GetAppName(),
+			Host:        req.GetHost(),
+			Port:        uint(req.GetPort()),
+			CurrentLoad: uint(req.GetCurrentLoad()),
+			CPUUsage:    req.GetCpuUsage(),
+			MemoryUsage: req.GetMemoryUsage(),
+			Labels:      req.GetLabels(),
+			ReadyToStop: req.GetReadyToStop(),
+		})
+		if err != nil {
+			return toStatus(err)
+		}
+
+		if err := stream.Send(&executorv1.HeartbeatResponse{
+			ExecutorId:  ack.ExecutorID,
+			DrainStatus: executorv1.DrainStatus(ack.DrainStatus),
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+// Drain 执行器主动摘流
+func (s *schedulerServer) Drain(ctx context.Context, req *executorv1.DrainRequest) (*executorv1.DrainResponse, error) {
+	node, err := s.executorService.Drain(ctx, GetExecutorGroupID(ctx), req.GetExecutorId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &executorv1.DrainResponse{DrainStatus: executorv1.DrainStatus(node.DrainStatus)}, nil
+}
+
+// Callback 上报执行结果
+func (s *schedulerServer) Callback(ctx context.Context, req *executorv1.CallbackRequest) (*executorv1.CallbackResponse, error) {
+	err := s.instanceService.Callback(ctx, GetExecutorGroupID(ctx), &model.ExecutorResult{
+		InstanceID:    req.GetInstanceId(),
+		Code:          int(req.GetCode()),
+		Message:       req.GetMessage(),
+		Stack:         req.GetStack(),
+		DispatchToken: req.GetDispatchToken(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &executorv1.CallbackResponse{}, nil
+}
+
+// ReportLogs 流式上报执行日志, 按批保存
+func (s *schedulerServer) ReportLogs(stream executorv1.SchedulerService_ReportLogsServer) error {
+	ctx := stream.Context()
+	groupID := GetExecutorGroupID(ctx)
+
+	var saved uint64
+	batch := make([]*model.TaskLog, 0, logBatchSize)
+	flush := func() error {
+		if err := s.instanceService.SaveLogs(ctx, groupID, batch); err != nil {
+			return toStatus(err)
+		}
+		saved += uint64(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			if err := flush(); err != nil {
+				return err
+			}
+			return stream.SendAndClose(&executorv1.ReportLogsResponse{Saved: saved})
+		}
+		if err != nil {
+			return err
+		}
+
+		log := &model.ExecutorLog{
+			InstanceID: req.GetInstanceId(),
+			LogTime:    req.GetLogTime(),
+			LogLevel:   req.GetLogLevel(),
+			LogContent: req.GetLogContent(),
+		}
+		batch = append(batch, log.ToTaskLog())
+		if len(batch) >= logBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+
+	executorv1 "distributed-scheduler/api/executor/v1"
+	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/service"
+	"distributed-scheduler/pkg/logger"
+)
+
+// Server 调度中心gRPC服务, 供GRPC类型的执行器注册、心跳和上报结果
+type Server struct {
+	server *grpc.Server
+	port   int
+}
+
+// NewServer 创建调度中心gRPC服务
+func NewServer(port int) *Server {
+	auth := &authenticator{executorService: service.NewExecutorService()}
+	if cfg := config.GetConfig(); cfg != nil {
+		auth.enable = cfg.Executor.Auth.Enable
+	}
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(auth.unaryInterceptor),
+		grpc.StreamInterceptor(auth.streamInterceptor),
+	)
+	executorv1.RegisterSchedulerServiceServer(server, newSchedulerServer())
+
+	return &Server{server: server, port: port}
+}
+
+// Start 启动gRPC服务
+func (s *Server) Start() error {
+	addr := fmt.Sprintf(":%d", s.port)
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		logger.Infof("gRPC服务启动成功，监听地址: %s", addr)
+		if err := s.server.Serve(lis); err != nil {
+			logger.Errorf("gRPC服务异常退出: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop 停止gRPC服务, 等待进行中的调用完成, 超过timeout后强制关闭(心跳流不会主动结束)
+func (s *Server) Stop(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		s.server.Stop()
+	}
+	logger.Info("gRPC服务已停止")
+}
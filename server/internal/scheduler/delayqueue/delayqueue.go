@@ -0,0 +1,361 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/scheduler/timewheel"
+	"distributed-scheduler/pkg/logger"
+	pkgRedis "distributed-scheduler/pkg/redis"
+)
+
+var (
+	ErrRedisNotInitialized = errors.New("Redis未初始化")
+	ErrInvalidItem         = errors.New("无效的延迟任务")
+)
+
+// 默认配置
+const (
+	defaultWindow            = time.Minute
+	defaultVisibilityTimeout = 30 * time.Second
+	defaultPollInterval      = time.Second
+	defaultBatchSize         = 500
+	defaultRetryDelay        = 5 * time.Second
+)
+
+// Redis键前缀, 每个队列包含三个键:
+// delay:{name}:ready 待投递的有序集合, 分数为到期时间(毫秒)
+// delay:{name}:processing 已领取的有序集合, 分数为可见性超时时间(毫秒)
+// delay:{name}:payload 任务内容的哈希表
+const keyPrefix = "delay:"
+
+// pushScript 原子地写入任务内容并加入待投递集合, 避免只写入一半时领取不到内容或留下无主的内容
+// KEYS: ready, processing, payload; ARGV: id, payload, dueAt
+var pushScript = redis.NewScript(`
+redis.call("HSET", KEYS[3], ARGV[1], ARGV[2])
+redis.call("ZADD", KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+// removeScript 原子地删除任务(包括已领取未确认的任务)
+// KEYS: ready, processing, payload; ARGV: id
+var removeScript = redis.NewScript(`
+redis.call("ZREM", KEYS[1], ARGV[1])
+redis.call("ZREM", KEYS[2], ARGV[1])
+redis.call("HDEL", KEYS[3], ARGV[1])
+return 1
+`)
+
+// claimScript 原子地领取到期任务
+// 先将可见性超时的任务放回待投递集合(领取方异常退出后由其他节点重新投递),
+// 再从待投递集合中取出until之前到期的任务移入已领取集合
+// KEYS: ready, processing, payload; ARGV: now, until, limit, visibility
+// 返回 [id, payload, dueAt, ...]
+var claimScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local expired = redis.call("ZRANGEBYSCORE", KEYS[2], "-inf", now)
+for _, id in ipairs(expired) do
+	redis.call("ZREM", KEYS[2], id)
+	redis.call("ZADD", KEYS[1], now, id)
+end
+
+local result = {}
+local ids = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[2], "WITHSCORES", "LIMIT", 0, tonumber(ARGV[3]))
+for i = 1, #ids, 2 do
+	local id, due = ids[i], tonumber(ids[i + 1])
+	redis.call("ZREM", KEYS[1], id)
+	redis.call("ZADD", KEYS[2], math.max(due, now) + tonumber(ARGV[4]), id)
+	local payload = redis.call("HGET", KEYS[3], id)
+	if payload then
+		table.insert(result, id)
+		table.insert(result, payload)
+		table.insert(result, ids[i + 1])
+	end
+end
+return result
+`)
+
+// ackScript 确认任务已处理; 处理期间被重新Push的任务保留内容
+// KEYS: ready, processing, payload; ARGV: id
+var ackScript = redis.NewScript(`
+redis.call("ZREM", KEYS[2], ARGV[1])
+if not redis.call("ZSCORE", KEYS[1], ARGV[1]) then
+	redis.call("HDEL", KEYS[3], ARGV[1])
+end
+return 1
+`)
+
+// nackScript 处理失败, 将已领取的任务放回待投递集合
+// KEYS: ready, processing; ARGV: id, retryAt
+var nackScript = redis.NewScript(`
+if redis.call("ZREM", KEYS[2], ARGV[1]) == 1 then
+	redis.call("ZADD", KEYS[1], "NX", ARGV[2], ARGV[1])
+end
+return 1
+`)
+
+// Item 延迟任务
+type Item struct {
+	ID      string    // 唯一标识, 相同ID的任务会被覆盖
+	Payload string    // 任务内容
+	DueAt   time.Time // 到期时间
+}
+
+// Handler 延迟任务处理函数, 返回错误时任务会在重试间隔后重新投递
+type Handler func(ctx context.Context, item *Item) error
+
+// Queue 基于Redis有序集合的持久化延迟队列
+// 任务持久化在Redis中, 调度节点重启或故障转移后不会丢失; 各节点定期领取近期窗口内
+// 到期的任务放入内存时间轮, 到期后调用处理函数并确认. 领取的任务在可见性超时内未确认时
+// 会被重新投递, 因此投递语义为至少一次, 处理函数需保证幂等
+type Queue struct {
+	name              string
+	window            time.Duration
+	visibilityTimeout time.Duration
+	pollInterval      time.Duration
+	batchSize         int
+	retryDelay        time.Duration
+
+	tw      *timewheel.TimeWheel
+	handler Handler
+
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewQueue 创建延迟队列
+func NewQueue(name string) *Queue {
+	q := &Queue{
+		name:              name,
+		window:            defaultWindow,
+		visibilityTimeout: defaultVisibilityTimeout,
+		pollInterval:      defaultPollInterval,
+		batchSize:         defaultBatchSize,
+		retryDelay:        defaultRetryDelay,
+		stopCh:            make(chan struct{}),
+	}
+
+	if cfg := config.GetConfig(); cfg != nil {
+		queueCfg := cfg.Scheduler.DelayQueue
+		if queueCfg.Window > 0 {
+			q.window = time.Duration(queueCfg.Window) * time.Second
+		}
+		if queueCfg.VisibilityTimeout > 0 {
+			q.visibilityTimeout = time.Duration(queueCfg.VisibilityTimeout) * time.Second
+		}
+		if queueCfg.PollInterval > 0 {
+			q.pollInterval = time.Duration(queueCfg.PollInterval) * time.Millisecond
+		}
+		if queueCfg.BatchSize > 0 {
+			q.batchSize = queueCfg.BatchSize
+		}
+		if queueCfg.RetryDelay > 0 {
+			q.retryDelay = time.Duration(queueCfg.RetryDelay) * time.Second
+		}
+	}
+	return q
+}
+
+// keys 队列的Redis键: ready, processing, payload
+func (q *Queue) keys() []string {
+	prefix := keyPrefix + q.name + ":"
+	return []string{prefix + "ready", prefix + "processing", prefix + "payload"}
+}
+
+// Push 添加延迟任务, 相同ID的任务会被覆盖
+func (q *Queue) Push(ctx context.Context, item *Item) error {
+	if item == nil || item.ID == "" {
+		return ErrInvalidItem
+	}
+	if pkgRedis.GetClient() == nil {
+		return ErrRedisNotInitialized
+	}
+
+	_, err := pkgRedis.RunScript(ctx, pushScript, q.keys(), item.ID, item.Payload, item.DueAt.UnixMilli())
+	return err
+}
+
+// Remove 删除延迟任务(包括已领取未确认的任务)
+func (q *Queue) Remove(ctx context.Context, id string) error {
+	if pkgRedis.GetClient() == nil {
+		return ErrRedisNotInitialized
+	}
+
+	if _, err := pkgRedis.RunScript(ctx, removeScript, q.keys(), id); err != nil {
+		return err
+	}
+	if q.tw != nil {
+		q.tw.RemoveTask(q.timerKey(id))
+	}
+	return nil
+}
+
+// Peek 查看until之前到期的待投递任务ID(不领取)
+func (q *Queue) Peek(ctx context.Context, until time.Time, limit int64) ([]string, error) {
+	if pkgRedis.GetClient() == nil {
+		return nil, ErrRedisNotInitialized
+	}
+	return pkgRedis.ZRangeByScore(ctx, q.keys()[0], &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(until.UnixMilli(), 10),
+		Count: limit,
+	})
+}
+
+// Claim 领取until之前到期的任务, 领取后需在到期时间加可见性超时之内Ack, 否则会被重新投递
+func (q *Queue) Claim(ctx context.Context, until time.Time, limit int) ([]*Item, error) {
+	if pkgRedis.GetClient() == nil {
+		return nil, ErrRedisNotInitialized
+	}
+
+	result, err := pkgRedis.RunScript(ctx, claimScript, q.keys(),
+		time.Now().UnixMilli(), until.UnixMilli(), limit, q.visibilityTimeout.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+
+	values, _ := result.([]interface{})
+	items := make([]*Item, 0, len(values)/3)
+	for i := 0; i+2 < len(values); i += 3 {
+		id, _ := values[i].(string)
+		payload, _ := values[i+1].(string)
+		due, _ := values[i+2].(string)
+		dueMs, _ := strconv.ParseFloat(due, 64)
+		items = append(items, &Item{
+			ID:      id,
+			Payload: payload,
+			DueAt:   time.UnixMilli(int64(dueMs)),
+		})
+	}
+	return items, nil
+}
+
+// Ack 确认任务处理成功
+func (q *Queue) Ack(ctx context.Context, id string) error {
+	if pkgRedis.GetClient() == nil {
+		return ErrRedisNotInitialized
+	}
+	_, err := pkgRedis.RunScript(ctx, ackScript, q.keys(), id)
+	return err
+}
+
+// Nack 任务处理失败, 在retryAt重新投递
+func (q *Queue) Nack(ctx context.Context, id string, retryAt time.Time) error {
+	if pkgRedis.GetClient() == nil {
+		return ErrRedisNotInitialized
+	}
+	_, err := pkgRedis.RunScript(ctx, nackScript, q.keys()[:2], id, retryAt.UnixMilli())
+	return err
+}
+
+// Start 启动投递: 定期领取近期窗口内到期的任务放入时间轮, 到期时调用handler
+// 时间轮需由调用方启动和停止
+func (q *Queue) Start(tw *timewheel.TimeWheel, handler Handler) {
+	q.tw = tw
+	q.handler = handler
+
+	q.wg.Add(1)
+	go q.run()
+	logger.Infof("延迟队列[%s]启动成功, 预读窗口: %v", q.name, q.window)
+}
+
+// Stop 停止投递
+// 已放入时间轮但未执行的任务会在可见性超时后由其他节点重新投递, 也可以将时间轮Stop
+// 返回的定时任务交给Requeue立即放回队列
+func (q *Queue) Stop() {
+	q.stopOnce.Do(func() {
+		close(q.stopCh)
+		q.wg.Wait()
+		logger.Infof("延迟队列[%s]已停止", q.name)
+	})
+}
+
+// run 领取主循环
+func (q *Queue) run() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		q.poll(context.Background())
+
+		select {
+		case <-ticker.C:
+		case <-q.stopCh:
+			return
+		}
+	}
+}
+
+// poll 领取近期窗口内到期的任务放入时间轮
+func (q *Queue) poll(ctx context.Context) {
+	for {
+		items, err := q.Claim(ctx, time.Now().Add(q.window), q.batchSize)
+		if err != nil {
+			logger.Errorf("延迟队列[%s]领取任务失败: %v", q.name, err)
+			return
+		}
+
+		for _, item := range items {
+			item := item
+			delay := time.Until(item.DueAt)
+			if delay <= 0 {
+				// 时间轮不接受非正延迟, 已到期的任务在下一个刻度执行
+				delay = time.Nanosecond
+			}
+			q.tw.AddTaskCtx(delay, q.timerKey(item.ID), func(ctx context.Context) error {
+				q.deliver(ctx, item)
+				return nil
+			})
+		}
+
+		if len(items) < q.batchSize {
+			return
+		}
+	}
+}
+
+// deliver 调用处理函数并确认, ctx在时间轮停止时取消
+func (q *Queue) deliver(ctx context.Context, item *Item) {
+	if err := q.handler(ctx, item); err != nil {
+		// 时间轮停止导致的失败也需要重新入队, 不能使用已取消的ctx
+		ctx = context.Background()
+		logger.Errorf("延迟队列[%s]任务处理失败, id=%s: %v", q.name, item.ID, err)
+		if err := q.Nack(ctx, item.ID, time.Now().Add(q.retryDelay)); err != nil {
+			logger.Errorf("延迟队列[%s]任务重新入队失败, id=%s: %v", q.name, item.ID, err)
+		}
+		return
+	}
+
+	if err := q.Ack(ctx, item.ID); err != nil {
+		logger.Errorf("延迟队列[%s]任务确认失败, id=%s: %v", q.name, item.ID, err)
+	}
+}
+
+// Requeue 将时间轮停止时未执行的本队列任务放回待投递集合, 其他节点可立即领取
+func (q *Queue) Requeue(ctx context.Context, timers []timewheel.Timer) error {
+	prefix := q.timerKey("")
+	for _, timer := range timers {
+		if !strings.HasPrefix(timer.Key, prefix) {
+			continue
+		}
+		if err := q.Nack(ctx, strings.TrimPrefix(timer.Key, prefix), timer.ExpireAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timerKey 任务在时间轮中的key
+func (q *Queue) timerKey(id string) string {
+	return keyPrefix + q.name + ":" + id
+}
@@ -0,0 +1,208 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"distributed-scheduler/internal/scheduler/timewheel"
+	"distributed-scheduler/pkg/logger"
+	pkgRedis "distributed-scheduler/pkg/redis"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	logger.Sugar = logger.Logger.Sugar()
+	os.Exit(m.Run())
+}
+
+// setupRedis 使用miniredis替代Redis
+func setupRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	pkgRedis.Client = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		pkgRedis.Client.Close()
+		pkgRedis.Client = nil
+	})
+	return mr
+}
+
+func TestPushAndClaim(t *testing.T) {
+	mr := setupRedis(t)
+	ctx := context.Background()
+	q := NewQueue("test")
+
+	now := time.Now()
+	if err := q.Push(ctx, &Item{ID: "due", Payload: "a", DueAt: now.Add(-time.Second)}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if err := q.Push(ctx, &Item{ID: "later", Payload: "b", DueAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if err := q.Push(ctx, &Item{Payload: "c"}); !errors.Is(err, ErrInvalidItem) {
+		t.Errorf("没有ID的任务应返回ErrInvalidItem, 实际: %v", err)
+	}
+
+	// 内容和待投递集合同时写入
+	if payload := mr.HGet("delay:test:payload", "due"); payload != "a" {
+		t.Errorf("任务内容应为a, 实际: %q", payload)
+	}
+	if score, err := mr.ZScore("delay:test:ready", "due"); err != nil || int64(score) != now.Add(-time.Second).UnixMilli() {
+		t.Errorf("待投递集合的分数应为到期时间, 实际: %v %v", score, err)
+	}
+
+	items, err := q.Claim(ctx, now, 10)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != "due" || items[0].Payload != "a" {
+		t.Fatalf("应只领取到期的任务, 实际: %+v", items)
+	}
+	if again, _ := q.Claim(ctx, now, 10); len(again) != 0 {
+		t.Errorf("已领取的任务在可见性超时前不应被重复领取, 实际: %d", len(again))
+	}
+
+	if err := q.Ack(ctx, "due"); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	if mr.Exists("delay:test:processing") || mr.HGet("delay:test:payload", "due") != "" {
+		t.Error("确认后应删除已领取记录和任务内容")
+	}
+}
+
+func TestVisibilityTimeoutRedelivers(t *testing.T) {
+	setupRedis(t)
+	ctx := context.Background()
+	q := NewQueue("visibility")
+	q.visibilityTimeout = 50 * time.Millisecond
+
+	if err := q.Push(ctx, &Item{ID: "1", Payload: "x", DueAt: time.Now()}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if items, err := q.Claim(ctx, time.Now(), 10); err != nil || len(items) != 1 {
+		t.Fatalf("首次领取应得到任务, 实际: %d %v", len(items), err)
+	}
+
+	// 领取方未确认, 可见性超时后重新投递
+	time.Sleep(100 * time.Millisecond)
+	items, err := q.Claim(ctx, time.Now(), 10)
+	if err != nil || len(items) != 1 || items[0].Payload != "x" {
+		t.Fatalf("可见性超时后应重新投递, 实际: %+v %v", items, err)
+	}
+}
+
+func TestNackAndRemove(t *testing.T) {
+	mr := setupRedis(t)
+	ctx := context.Background()
+	q := NewQueue("nack")
+
+	if err := q.Push(ctx, &Item{ID: "1", Payload: "x", DueAt: time.Now()}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if _, err := q.Claim(ctx, time.Now(), 10); err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	retryAt := time.Now().Add(time.Minute)
+	if err := q.Nack(ctx, "1", retryAt); err != nil {
+		t.Fatalf("Nack: %v", err)
+	}
+	if score, err := mr.ZScore("delay:nack:ready", "1"); err != nil || int64(score) != retryAt.UnixMilli() {
+		t.Errorf("Nack后应按重试时间放回待投递集合, 实际: %v %v", score, err)
+	}
+
+	if err := q.Remove(ctx, "1"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if mr.Exists("delay:nack:ready") || mr.Exists("delay:nack:payload") {
+		t.Error("删除后不应保留任务")
+	}
+}
+
+func TestStartDeliversThroughTimeWheel(t *testing.T) {
+	mr := setupRedis(t)
+	ctx := context.Background()
+
+	tw := timewheel.NewHierarchicalTimeWheel(10*time.Millisecond, 100, 60)
+	tw.Start()
+	defer tw.Stop()
+
+	q := NewQueue("deliver")
+	q.pollInterval = 20 * time.Millisecond
+	q.retryDelay = 50 * time.Millisecond
+
+	var mu sync.Mutex
+	var delivered []string
+	failed := false
+	done := make(chan struct{})
+	q.Start(tw, func(ctx context.Context, item *Item) error {
+		mu.Lock()
+		defer mu.Unlock()
+		// 第一次处理失败, 重试间隔后重新投递
+		if item.ID == "flaky" && !failed {
+			failed = true
+			return errors.New("处理失败")
+		}
+		delivered = append(delivered, item.ID)
+		if len(delivered) == 2 {
+			close(done)
+		}
+		return nil
+	})
+	defer q.Stop()
+
+	if err := q.Push(ctx, &Item{ID: "ok", DueAt: time.Now().Add(50 * time.Millisecond)}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if err := q.Push(ctx, &Item{ID: "flaky", DueAt: time.Now().Add(50 * time.Millisecond)}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		mu.Lock()
+		defer mu.Unlock()
+		t.Fatalf("任务应全部投递, 实际: %v", delivered)
+	}
+	// 确认在处理函数返回后执行
+	time.Sleep(50 * time.Millisecond)
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("处理成功后应确认并删除任务, 剩余键: %v", keys)
+	}
+}
+
+func TestRequeue(t *testing.T) {
+	mr := setupRedis(t)
+	ctx := context.Background()
+	q := NewQueue("requeue")
+
+	if err := q.Push(ctx, &Item{ID: "1", Payload: "x", DueAt: time.Now()}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if _, err := q.Claim(ctx, time.Now(), 10); err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+
+	expireAt := time.Now().Add(time.Second)
+	timers := []timewheel.Timer{
+		{Key: q.timerKey("1"), ExpireAt: expireAt},
+		{Key: "other:2", ExpireAt: expireAt},
+	}
+	if err := q.Requeue(ctx, timers); err != nil {
+		t.Fatalf("Requeue: %v", err)
+	}
+	if score, err := mr.ZScore("delay:requeue:ready", "1"); err != nil || int64(score) != expireAt.UnixMilli() {
+		t.Errorf("本队列的定时任务应按到期时间放回待投递集合, 实际: %v %v", score, err)
+	}
+	if members, _ := mr.ZMembers("delay:requeue:ready"); len(members) != 1 {
+		t.Errorf("其他队列的定时任务不应放回, 实际: %v", members)
+	}
+}
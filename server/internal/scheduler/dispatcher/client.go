@@ -0,0 +1,152 @@
+package dispatcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/common/utils"
+	"distributed-scheduler/internal/model"
+)
+
+var (
+	ErrExecutorRejected = errors.New("执行器拒绝执行")
+)
+
+// 默认调用执行器超时时间
+const DefaultRunTimeout = 10 * time.Second
+
+// ExecutorClient 执行器客户端
+type ExecutorClient interface {
+	// Run 将任务下发到执行器, 执行器接收后异步执行并通过回调上报结果
+	Run(ctx context.Context, node *model.ExecutorNode, task *model.ExecutorTask) error
+	// Kill 终止执行器上派发令牌为dispatchToken的执行, 返回实例是否在执行中并已终止
+	Kill(ctx context.Context, node *model.ExecutorNode, instanceID, dispatchToken uint64) (bool, error)
+	// Status 查询实例在执行器上的执行状态
+	Status(ctx context.Context, node *model.ExecutorNode, instanceID uint64) (*model.ExecutorInstanceStatus, error)
+}
+
+var (
+	clientsOnce sync.Once
+	httpClient  ExecutorClient
+	grpcClient  ExecutorClient
+)
+
+// ClientFor 根据任务的执行器类型获取执行器客户端, GRPC类型使用gRPC协议, 其他类型使用HTTP协议
+// 请求使用执行器所属任务组的访问令牌签名
+func ClientFor(executorType string) ExecutorClient {
+	clientsOnce.Do(func() {
+		signer := NewSigner()
+		httpClient = NewHTTPExecutorClient(DefaultRunTimeout, signer)
+		grpcClient = NewGRPCExecutorClient(DefaultRunTimeout, signer)
+	})
+	if executorType == model.ExecutorTypeGRPC {
+		return grpcClient
+	}
+	return httpClient
+}
+
+// PruneConns 关闭不属于在线执行器的gRPC连接, 返回关闭的数量
+// 执行器离线、被清理或更换地址后连接不再使用, 由调度器定期清理
+func PruneConns(online []*model.ExecutorNode) int {
+	client, _ := ClientFor(model.ExecutorTypeGRPC).(*GRPCExecutorClient)
+	if client == nil {
+		return 0
+	}
+	addresses := make(map[string]bool, len(online))
+	for _, node := range online {
+		addresses[node.Address()] = true
+	}
+	return client.Retain(addresses)
+}
+
+// HTTPExecutorClient 基于HTTP协议的执行器客户端
+type HTTPExecutorClient struct {
+	client *http.Client
+	signer Signer
+}
+
+// NewHTTPExecutorClient 创建HTTP执行器客户端, signer为空时请求不签名
+func NewHTTPExecutorClient(timeout time.Duration, signer Signer) *HTTPExecutorClient {
+	if timeout <= 0 {
+		timeout = DefaultRunTimeout
+	}
+	return &HTTPExecutorClient{
+		client: &http.Client{Timeout: timeout},
+		signer: signer,
+	}
+}
+
+// Run 下发任务
+func (c *HTTPExecutorClient) Run(ctx context.Context, node *model.ExecutorNode, task *model.ExecutorTask) error {
+	return c.call(ctx, node, model.ExecutorPathRun, task, nil)
+}
+
+// Kill 终止任务
+func (c *HTTPExecutorClient) Kill(ctx context.Context, node *model.ExecutorNode, instanceID, dispatchToken uint64) (bool, error) {
+	var result struct {
+		Killed bool `json:"killed"`
+	}
+	req := &model.ExecutorKillRequest{InstanceID: instanceID, DispatchToken: dispatchToken}
+	err := c.call(ctx, node, model.ExecutorPathKill, req, &result)
+	return result.Killed, err
+}
+
+// Status 查询实例执行状态
+func (c *HTTPExecutorClient) Status(ctx context.Context, node *model.ExecutorNode, instanceID uint64) (*model.ExecutorInstanceStatus, error) {
+	var status model.ExecutorInstanceStatus
+	if err := c.call(ctx, node, model.ExecutorPathStatus, map[string]uint64{"instance_id": instanceID}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// call 调用执行器接口, 请求签名包含接口路径, 业务码非0时返回ErrExecutorRejected
+func (c *HTTPExecutorClient) call(ctx context.Context, node *model.ExecutorNode, path string, body, data interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+node.Address()+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.signer != nil {
+		signature, err := c.signer.Sign(ctx, node.GroupID, path, payload)
+		if err != nil {
+			return fmt.Errorf("请求签名失败: %w", err)
+		}
+		if signature != nil {
+			req.Header.Set(utils.HeaderExecutorApp, signature.AppName)
+			req.Header.Set(utils.HeaderExecutorTimestamp, signature.Timestamp)
+			req.Header.Set(utils.HeaderExecutorSignature, signature.Signature)
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	result := response.Response{Data: data}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("响应解析失败: %w", err)
+	}
+	if result.Code != response.CodeSuccess {
+		return fmt.Errorf("%w: %s", ErrExecutorRejected, result.Message)
+	}
+	return nil
+}
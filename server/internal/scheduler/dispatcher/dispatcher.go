@@ -0,0 +1,532 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/executor/pool"
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/repository"
+	"distributed-scheduler/internal/scheduler/httpcall"
+	"distributed-scheduler/internal/scheduler/quota"
+	"distributed-scheduler/internal/scheduler/router"
+	"distributed-scheduler/pkg/logger"
+)
+
+// 默认配置
+const (
+	defaultInterval  = time.Second
+	defaultBatchSize = 500
+	defaultPoolSize  = 200
+	defaultQueueSize = 1000
+
+	defaultReconcileInterval = time.Minute
+
+	defaultSendRetries    = 2
+	defaultSendRetryDelay = 500 * time.Millisecond
+
+	defaultSchedulingTimeout = 5 * time.Minute
+)
+
+// 待调度原因
+const (
+	reasonNoExecutor        = "等待可用执行器"
+	reasonSchedulingTimeout = "分发超时, 重新调度"
+)
+
+// Dispatcher 任务分发器
+// 定期加载待调度实例放入优先队列, 按优先级依次路由到执行器并下发;
+// 某个任务组没有可用执行器或并发配额已满时, 该组的实例留在队列中等待下一轮,
+// 不影响其他任务组, 等待原因记录在实例的PendingReason上; 故障转移、忙碌转移等探测类策略在协程池中探测和路由,
+// 不可达的执行器不会阻塞其他任务组的分发
+type Dispatcher struct {
+	instanceRepo      repository.InstanceRepository
+	executorRepo      repository.ExecutorRepository
+	scriptRepo        repository.TaskScriptRepository
+	quota             *quota.Manager
+	caller            *httpcall.Caller
+	strategies        *strategyCache
+	shards            *shardTracker
+	queue             *PriorityQueue
+	pool              *pool.WorkerPool
+	interval          time.Duration
+	batchSize         int
+	agingInterval     time.Duration
+	reconcileInterval time.Duration
+	sendRetries       int
+	schedulingTimeout time.Duration
+
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewDispatcher 创建任务分发器
+func NewDispatcher() *Dispatcher {
+	interval, batchSize := defaultInterval, defaultBatchSize
+	poolSize, queueSize := defaultPoolSize, defaultQueueSize
+	reconcileInterval := defaultReconcileInterval
+	sendRetries := defaultSendRetries
+	schedulingTimeout := defaultSchedulingTimeout
+	var agingInterval time.Duration
+
+	if cfg := config.GetConfig(); cfg != nil {
+		dispatchCfg := cfg.Scheduler.Dispatch
+		if dispatchCfg.Interval > 0 {
+			interval = time.Duration(dispatchCfg.Interval) * time.Millisecond
+		}
+		if dispatchCfg.BatchSize > 0 {
+			batchSize = dispatchCfg.BatchSize
+		}
+		if dispatchCfg.QueueSize > 0 {
+			queueSize = dispatchCfg.QueueSize
+		}
+		if cfg.Scheduler.TriggerPoolSize > 0 {
+			poolSize = cfg.Scheduler.TriggerPoolSize
+		}
+		agingInterval = time.Duration(dispatchCfg.AgingInterval) * time.Second
+		if dispatchCfg.SendRetries != 0 {
+			sendRetries = max(dispatchCfg.SendRetries, 0)
+		}
+		if dispatchCfg.SchedulingTimeout > 0 {
+			schedulingTimeout = time.Duration(dispatchCfg.SchedulingTimeout) * time.Second
+		}
+		if cfg.Scheduler.Quota.ReconcileInterval > 0 {
+			reconcileInterval = time.Duration(cfg.Scheduler.Quota.ReconcileInterval) * time.Second
+		}
+	}
+
+	workerPool := pool.NewWorkerPoolWithOptions(pool.Options{
+		Name:          "dispatch",
+		MaxWorkers:    poolSize,
+		QueueSize:     queueSize,
+		AgingInterval: agingInterval,
+	})
+
+	quotaManager := quota.NewManager()
+	return &Dispatcher{
+		instanceRepo:      repository.NewInstanceRepository(),
+		executorRepo:      repository.NewExecutorRepository(),
+		scriptRepo:        repository.NewTaskScriptRepository(),
+		quota:             quotaManager,
+		caller:            httpcall.NewCaller(quotaManager),
+		strategies:        newStrategyCache(),
+		shards:            newShardTracker(),
+		queue:             NewPriorityQueue(agingInterval),
+		pool:              workerPool,
+		interval:          interval,
+		batchSize:         batchSize,
+		agingInterval:     agingInterval,
+		reconcileInterval: reconcileInterval,
+		sendRetries:       sendRetries,
+		schedulingTimeout: schedulingTimeout,
+		stopCh:            make(chan struct{}),
+	}
+}
+
+// Start 启动分发器
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+	go d.run()
+	logger.Infof("任务分发器启动成功, 扫描间隔: %v", d.interval)
+}
+
+// Stop 停止分发器, 等待已下发的任务处理完成
+func (d *Dispatcher) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stopCh)
+		d.wg.Wait()
+		if err := d.pool.Shutdown(10 * time.Second); err != nil {
+			logger.Errorf("任务分发协程池关闭失败: %v", err)
+		}
+		if err := d.caller.Shutdown(10 * time.Second); err != nil {
+			logger.Errorf("HTTP请求协程池关闭失败: %v", err)
+		}
+		logger.Info("任务分发器已停止")
+	})
+}
+
+// QueueLen 获取优先队列中等待分发的实例数
+func (d *Dispatcher) QueueLen() int {
+	return d.queue.Len()
+}
+
+// PoolStats 获取分发协程池的统计信息
+func (d *Dispatcher) PoolStats() pool.Stats {
+	return d.pool.GetStats()
+}
+
+// run 分发主循环
+func (d *Dispatcher) run() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	reconcileTicker := time.NewTicker(d.reconcileInterval)
+	defer reconcileTicker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.dispatchOnce(context.Background())
+		case <-reconcileTicker.C:
+			d.reconcile(context.Background())
+		case <-d.stopCh:
+			return
+		}
+	}
+}
+
+// reconcile 恢复分发超时的实例和调度中心重启遗留的HTTP_CALL实例, 然后对账回收未释放的并发配额
+// 先恢复实例, 使其占用的配额在同一轮对账中回收
+func (d *Dispatcher) reconcile(ctx context.Context) {
+	released, err := d.instanceRepo.ReleaseStaleScheduling(ctx, d.schedulingTimeout, reasonSchedulingTimeout)
+	if err != nil {
+		logger.Errorf("恢复分发超时的实例失败: %v", err)
+	} else if released > 0 {
+		logger.Warnf("%d 个实例分发超时, 已恢复为待调度", released)
+	}
+
+	recovered, err := d.caller.RecoverOrphaned(ctx)
+	if err != nil {
+		logger.Errorf("恢复超时的HTTP请求实例失败: %v", err)
+	} else if recovered > 0 {
+		logger.Warnf("%d 个HTTP请求实例超时未返回结果, 已标记为失败", recovered)
+	}
+
+	removed, err := d.quota.Reconcile(ctx, d.instanceRepo.GetActiveInstanceIDs)
+	if err != nil {
+		logger.Errorf("并发配额对账失败: %v", err)
+	} else if removed > 0 {
+		logger.Warnf("并发配额对账回收 %d 个未释放的配额", removed)
+	}
+
+	if online, err := d.executorRepo.GetAllOnline(ctx); err != nil {
+		logger.Errorf("查询在线执行器失败: %v", err)
+	} else if closed := PruneConns(online); closed > 0 {
+		logger.Infof("已关闭 %d 个离线执行器的gRPC连接", closed)
+	}
+
+	d.strategies.prune(strategyIdleTTL)
+	d.shards.prune(shardIdleTTL)
+}
+
+// dispatchOnce 加载待调度实例并按优先级分发, 直到队列为空或协程池饱和
+func (d *Dispatcher) dispatchOnce(ctx context.Context) {
+	if err := d.load(ctx); err != nil {
+		logger.Errorf("加载待调度实例失败: %v", err)
+	}
+
+	// 本轮没有可用执行器或配额已满的任务组/任务, 其后续实例直接放回队列
+	blockedGroups := make(map[uint64]bool)
+	blockedTasks := make(map[uint64]bool)
+	var deferred []*Item
+	defer func() {
+		for _, item := range deferred {
+			d.queue.Push(item)
+		}
+	}()
+
+	for d.hasCapacity() {
+		item, ok := d.queue.Pop()
+		if !ok {
+			return
+		}
+		if blockedGroups[item.Instance.GroupID] || blockedTasks[item.Instance.TaskID] {
+			deferred = append(deferred, item)
+			continue
+		}
+
+		err := d.dispatch(ctx, item)
+		switch {
+		case err == nil:
+		case errors.Is(err, router.ErrNoAvailableExecutor), errors.Is(err, quota.ErrGroupQuotaExceeded):
+			blockedGroups[item.Instance.GroupID] = true
+			deferred = append(deferred, item)
+		case errors.Is(err, quota.ErrTaskQuotaExceeded), errors.Is(err, httpcall.ErrCallerBusy):
+			blockedTasks[item.Instance.TaskID] = true
+			deferred = append(deferred, item)
+		case errors.Is(err, quota.ErrGlobalQuotaExceeded), errors.Is(err, pool.ErrPoolFull):
+			deferred = append(deferred, item)
+			return
+		default:
+			logger.Errorf("分发任务实例失败, instance_id=%d: %v", item.Instance.ID, err)
+		}
+	}
+}
+
+// load 从数据库按有效优先级加载待调度实例到优先队列
+func (d *Dispatcher) load(ctx context.Context) error {
+	instances, err := d.instanceRepo.GetPendingInstances(ctx, d.batchSize, d.agingInterval)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		d.queue.Push(NewItem(instance))
+	}
+	return nil
+}
+
+// hasCapacity 协程池是否还能接收任务
+func (d *Dispatcher) hasCapacity() bool {
+	stats := d.pool.GetStats()
+	return stats.QueueSize < stats.QueueCap
+}
+
+// dispatch 抢占实例、占用并发配额并选择执行器, 然后提交到协程池下发; 探测类策略在协程池中选择执行器
+// HTTP_CALL类型任务不需要执行器, 占用配额后直接提交给调度中心的HTTP请求执行器
+// 配额已满、没有可用执行器或协程池已满时实例恢复为待调度状态并返回对应错误
+func (d *Dispatcher) dispatch(ctx context.Context, item *Item) error {
+	instance := item.Instance
+
+	// 多个调度节点通过状态和派发令牌CAS抢占实例, 抢占失败说明已被分发或取消
+	claimed, err := d.instanceRepo.ClaimForDispatch(ctx, instance.ID, instance.DispatchToken)
+	if err != nil || !claimed {
+		return err
+	}
+	instance.DispatchToken++
+
+	task := instance.Task
+	if task == nil {
+		return d.fail(ctx, instance, "任务不存在")
+	}
+
+	affinity, err := router.NewAffinity(task)
+	if err != nil {
+		return d.fail(ctx, instance, err.Error())
+	}
+
+	if err := d.quota.Acquire(ctx, instance); err != nil {
+		reason := err.Error()
+		if !errors.Is(err, quota.ErrQuotaExceeded) {
+			reason = "占用并发配额失败"
+		}
+		d.release(ctx, instance, reason)
+		return err
+	}
+
+	if task.ExecutorType == model.ExecutorTypeHTTPCall {
+		if err := d.caller.Submit(instance, task, item.Priority); err != nil {
+			d.releaseQuota(ctx, instance)
+			d.release(ctx, instance, "HTTP请求执行繁忙")
+			return err
+		}
+		return nil
+	}
+
+	strategy := d.strategies.get(task)
+
+	executors, err := d.executorRepo.GetOnlineByGroupID(ctx, instance.GroupID)
+	if err != nil {
+		d.releaseQuota(ctx, instance)
+		d.release(ctx, instance, "查询执行器失败")
+		return err
+	}
+	if !router.HasCandidates(executors, affinity) {
+		d.releaseQuota(ctx, instance)
+		d.release(ctx, instance, reasonNoExecutor)
+		return router.ErrNoAvailableExecutor
+	}
+
+	var job func()
+	if _, probing := strategy.(router.TracingStrategy); probing {
+		// 探测类策略逐个探测执行器, 耗时可达数秒, 在协程池中路由, 不阻塞其他任务组的分发
+		job = func() {
+			d.routeAndSend(instance, task, strategy, executors, affinity)
+		}
+	} else {
+		node, trace, err := d.route(ctx, strategy, executors, instance, affinity)
+		if err != nil {
+			d.routeFailed(ctx, instance, trace)
+			return err
+		}
+		job = func() {
+			d.send(instance, task, node, trace)
+		}
+	}
+
+	if err := d.pool.SubmitWithPriority(job, item.Priority); err != nil {
+		d.releaseQuota(ctx, instance)
+		d.release(ctx, instance, "调度节点繁忙")
+		return err
+	}
+	return nil
+}
+
+// route 为实例选择执行器, 开启反亲和的分片实例优先分散到不同主机
+func (d *Dispatcher) route(ctx context.Context, strategy router.Strategy, executors []*model.ExecutorNode,
+	instance *model.TaskInstance, affinity *router.Affinity) (*model.ExecutorNode, router.ProbeTrace, error) {
+	if instance.ShardTotal > 1 && affinity.AntiAffinity {
+		return d.routeShard(ctx, strategy, executors, instance, affinity)
+	}
+	return router.RouteWithTrace(ctx, strategy, executors, strconv.FormatUint(instance.TaskID, 10), affinity)
+}
+
+// routeAndSend 在协程池中探测并选择执行器后下发
+func (d *Dispatcher) routeAndSend(instance *model.TaskInstance, task *model.Task, strategy router.Strategy,
+	executors []*model.ExecutorNode, affinity *router.Affinity) {
+	ctx := context.Background()
+	node, trace, err := d.route(ctx, strategy, executors, instance, affinity)
+	if err != nil {
+		d.routeFailed(ctx, instance, trace)
+		return
+	}
+	d.send(instance, task, node, trace)
+}
+
+// routeFailed 没有可用执行器时释放配额, 实例恢复为待调度状态并记录探测链路
+func (d *Dispatcher) routeFailed(ctx context.Context, instance *model.TaskInstance, trace router.ProbeTrace) {
+	reason := reasonNoExecutor
+	if msg := trace.String(); msg != "" {
+		reason += ", " + msg
+	}
+	d.releaseQuota(ctx, instance)
+	d.release(ctx, instance, reason)
+}
+
+// routeShard 为开启反亲和的分片实例选择执行器, 优先选择同一批次分片最少的主机
+func (d *Dispatcher) routeShard(ctx context.Context, strategy router.Strategy, executors []*model.ExecutorNode,
+	instance *model.TaskInstance, affinity *router.Affinity) (*model.ExecutorNode, router.ProbeTrace, error) {
+	siblings, err := d.instanceRepo.GetInstancesByTriggerTime(ctx, instance.TaskID, instance.TriggerTime)
+	if err != nil {
+		logger.Warnf("查询同批次分片实例失败, 仅按本节点的分配记录分散, instance_id=%d: %v", instance.ID, err)
+	}
+	hostOf := make(map[string]string, len(executors))
+	for _, node := range executors {
+		hostOf[node.ID] = node.Host
+	}
+
+	hostShards := d.shards.hostShards(instance, siblings, hostOf)
+	param := router.ShardParam(strconv.FormatUint(instance.TaskID, 10), int(instance.ShardIndex))
+	node, trace, err := router.RouteShard(ctx, strategy, executors, param, hostShards, affinity)
+	if err != nil {
+		return nil, trace, err
+	}
+	d.shards.record(instance, node.Host)
+	return node, trace, nil
+}
+
+// release 将抢占的实例恢复为待调度状态并记录原因
+func (d *Dispatcher) release(ctx context.Context, instance *model.TaskInstance, reason string) {
+	if runes := []rune(reason); len(runes) > 256 {
+		reason = string(runes[:256])
+	}
+	if _, err := d.instanceRepo.ReleaseToPending(ctx, instance.ID, reason); err != nil {
+		logger.Errorf("恢复实例待调度状态失败, instance_id=%d: %v", instance.ID, err)
+	}
+}
+
+// fail 以失败结束本次分发
+func (d *Dispatcher) fail(ctx context.Context, instance *model.TaskInstance, msg string) error {
+	_, err := d.instanceRepo.FinishAttempt(ctx, instance.ID, instance.DispatchToken, model.InstanceStatusFailed, response.CodeError, msg)
+	return err
+}
+
+// loadScript 加载任务当前版本的脚本
+func (d *Dispatcher) loadScript(ctx context.Context, task *model.Task) (*model.ExecutorScript, error) {
+	script, err := d.scriptRepo.GetByVersion(ctx, task.ID, task.ScriptVersion)
+	if err != nil {
+		return nil, fmt.Errorf("加载脚本版本%d失败: %w", task.ScriptVersion, err)
+	}
+	return &model.ExecutorScript{
+		ScriptType: script.ScriptType,
+		Version:    script.Version,
+		Content:    script.Content,
+		Env:        script.Env,
+	}, nil
+}
+
+// releaseQuota 释放实例占用的并发配额
+func (d *Dispatcher) releaseQuota(ctx context.Context, instance *model.TaskInstance) {
+	if err := d.quota.Release(ctx, instance); err != nil {
+		logger.Errorf("释放并发配额失败, instance_id=%d: %v", instance.ID, err)
+	}
+}
+
+// send 将实例下发到执行器
+func (d *Dispatcher) send(instance *model.TaskInstance, task *model.Task, node *model.ExecutorNode, trace router.ProbeTrace) {
+	ctx := context.Background()
+
+	executorTask := &model.ExecutorTask{
+		InstanceID:      instance.ID,
+		TaskID:          instance.TaskID,
+		ExecutorHandler: instance.ExecutorHandler,
+		ExecutorParam:   instance.ExecutorParam,
+		ShardIndex:      instance.ShardIndex,
+		ShardTotal:      instance.ShardTotal,
+		Timeout:         task.Timeout,
+		DispatchToken:   instance.DispatchToken,
+	}
+
+	client := ClientFor(task.ExecutorType)
+	var err error
+	if task.ExecutorType == model.ExecutorTypeSCRIPT {
+		executorTask.Script, err = d.loadScript(ctx, task)
+	}
+	if err == nil {
+		err = d.runWithRetry(ctx, client, node, executorTask)
+	}
+
+	msg := trace.String()
+	if err != nil {
+		failMsg := "下发任务失败: " + err.Error()
+		if msg != "" {
+			failMsg += "; " + msg
+		}
+		// 实例已因分发超时被恢复并重新分发时, 配额属于新的分发, 不释放
+		finished, err := d.instanceRepo.FinishAttempt(ctx, instance.ID, instance.DispatchToken, model.InstanceStatusFailed, response.CodeError, failMsg)
+		if err != nil {
+			logger.Errorf("更新实例状态失败, instance_id=%d: %v", instance.ID, err)
+		}
+		if finished {
+			d.releaseQuota(ctx, instance)
+		}
+		return
+	}
+
+	// 执行器可能在此之前已回调结束实例, 此时不再更新为执行中
+	dispatched, err := d.instanceRepo.UpdateDispatched(ctx, instance.ID, instance.DispatchToken, node.ID, node.Address(), msg)
+	if err != nil {
+		logger.Errorf("更新实例状态失败, instance_id=%d: %v", instance.ID, err)
+		return
+	}
+	// 下发期间实例被取消或因分发超时被重新分发, 终止本次下发的执行; 已回调结束的执行不受影响
+	if !dispatched {
+		d.kill(ctx, client, node, instance)
+	}
+}
+
+// kill 通知执行器终止实例本次派发令牌的执行, 失败时只记录日志
+func (d *Dispatcher) kill(ctx context.Context, client ExecutorClient, node *model.ExecutorNode, instance *model.TaskInstance) {
+	killed, err := client.Kill(ctx, node, instance.ID, instance.DispatchToken)
+	if err != nil {
+		logger.Warnf("通知执行器终止实例失败, instance_id=%d, executor=%s: %v", instance.ID, node.Address(), err)
+		return
+	}
+	if killed {
+		logger.Infof("实例已不在调度中, 已终止本次下发的执行, instance_id=%d, dispatch_token=%d, executor=%s",
+			instance.ID, instance.DispatchToken, node.Address())
+	}
+}
+
+// runWithRetry 下发任务, 超时或网络错误时以相同的派发令牌重试, 执行器按令牌去重保证同一次分发最多执行一次
+// 执行器明确拒绝时不重试
+func (d *Dispatcher) runWithRetry(ctx context.Context, client ExecutorClient, node *model.ExecutorNode, task *model.ExecutorTask) error {
+	var err error
+	for attempt := 0; attempt <= d.sendRetries; attempt++ {
+		if attempt > 0 {
+			logger.Warnf("下发任务失败, 第%d次重试, instance_id=%d: %v", attempt, task.InstanceID, err)
+			time.Sleep(defaultSendRetryDelay)
+		}
+		if err = client.Run(ctx, node, task); err == nil || errors.Is(err, ErrExecutorRejected) {
+			return err
+		}
+	}
+	return err
+}
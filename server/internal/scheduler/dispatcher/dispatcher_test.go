@@ -0,0 +1,174 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"distributed-scheduler/internal/executor/pool"
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/repository"
+	"distributed-scheduler/internal/scheduler/quota"
+	"distributed-scheduler/internal/scheduler/router"
+	"distributed-scheduler/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	logger.Sugar = logger.Logger.Sugar()
+	os.Exit(m.Run())
+}
+
+// fakeInstanceRepo 记录恢复为待调度的原因
+type fakeInstanceRepo struct {
+	repository.InstanceRepository
+	released  chan string
+	cancelled bool // 下发期间实例已被取消
+}
+
+func (r *fakeInstanceRepo) ClaimForDispatch(ctx context.Context, id uint64, token uint64) (bool, error) {
+	return true, nil
+}
+
+func (r *fakeInstanceRepo) ReleaseToPending(ctx context.Context, id uint64, reason string) (bool, error) {
+	r.released <- reason
+	return true, nil
+}
+
+func (r *fakeInstanceRepo) UpdateDispatched(ctx context.Context, id, token uint64, executorID, executorAddress, resultMsg string) (bool, error) {
+	return !r.cancelled, nil
+}
+
+// fakeExecutorRepo 按任务组返回在线执行器
+type fakeExecutorRepo struct {
+	repository.ExecutorRepository
+	groups map[uint64][]*model.ExecutorNode
+}
+
+func (r *fakeExecutorRepo) GetOnlineByGroupID(ctx context.Context, groupID uint64) ([]*model.ExecutorNode, error) {
+	return r.groups[groupID], nil
+}
+
+// blockingStrategy 探测类策略, 探测阻塞到unblock关闭后返回全部不可达
+type blockingStrategy struct {
+	probing chan struct{}
+	unblock chan struct{}
+}
+
+func (s *blockingStrategy) Select(executors []*model.ExecutorNode, param string) (*model.ExecutorNode, error) {
+	node, _, err := s.SelectWithTrace(context.Background(), executors, param)
+	return node, err
+}
+
+func (s *blockingStrategy) SelectWithTrace(ctx context.Context, executors []*model.ExecutorNode, param string) (*model.ExecutorNode, router.ProbeTrace, error) {
+	s.probing <- struct{}{}
+	<-s.unblock
+	trace := router.ProbeTrace{{Address: executors[0].Address(), Result: router.ProbeResultUnreachable}}
+	return nil, trace, router.ErrNoAvailableExecutor
+}
+
+func TestDispatchProbesInWorker(t *testing.T) {
+	instanceRepo := &fakeInstanceRepo{released: make(chan string, 4)}
+	strategy := &blockingStrategy{probing: make(chan struct{}, 4), unblock: make(chan struct{})}
+	d := &Dispatcher{
+		instanceRepo: instanceRepo,
+		executorRepo: &fakeExecutorRepo{groups: map[uint64][]*model.ExecutorNode{1: testNodes("a")}},
+		quota:        quota.NewManager(),
+		strategies:   newStrategyCache(),
+		shards:       newShardTracker(),
+		pool:         pool.NewWorkerPoolWithOptions(pool.Options{Name: "test", MaxWorkers: 2, QueueSize: 2}),
+	}
+	defer d.pool.Shutdown(time.Second)
+
+	task := &model.Task{ID: 1, GroupID: 1, RouteStrategy: model.RouteStrategyFailover, ExecutorType: model.ExecutorTypeHTTP}
+	d.strategies.entries[strategyKey{taskID: 1, name: task.RouteStrategy, executorType: task.ExecutorType}] =
+		&cachedStrategy{strategy: strategy, lastUsed: time.Now()}
+
+	// 探测在协程池中进行, 分发循环不等待探测结果
+	done := make(chan error, 1)
+	go func() {
+		done <- d.dispatch(context.Background(), NewItem(&model.TaskInstance{ID: 1, TaskID: 1, GroupID: 1, Task: task}))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("dispatch: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("dispatch blocked on probing")
+	}
+	<-strategy.probing
+
+	// 没有可调度执行器的任务组在分发循环中直接返回, 阻塞该组本轮的后续实例
+	other := &model.Task{ID: 2, GroupID: 2, RouteStrategy: model.RouteStrategyFailover, ExecutorType: model.ExecutorTypeHTTP}
+	err := d.dispatch(context.Background(), NewItem(&model.TaskInstance{ID: 2, TaskID: 2, GroupID: 2, Task: other}))
+	if !errors.Is(err, router.ErrNoAvailableExecutor) {
+		t.Fatalf("group without executors: err = %v, want ErrNoAvailableExecutor", err)
+	}
+	if reason := <-instanceRepo.released; reason != reasonNoExecutor {
+		t.Errorf("release reason = %q, want %q", reason, reasonNoExecutor)
+	}
+
+	// 探测失败后实例恢复为待调度, 原因中包含探测链路
+	close(strategy.unblock)
+	select {
+	case reason := <-instanceRepo.released:
+		if !strings.HasPrefix(reason, reasonNoExecutor+", 探测链路: ") {
+			t.Errorf("release reason = %q, want probe trace", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("instance not released after probing failed")
+	}
+}
+
+// killRequest 终止请求
+type killRequest struct {
+	instanceID uint64
+	token      uint64
+}
+
+// fakeExecutorClient 下发总是成功, 记录终止请求
+type fakeExecutorClient struct {
+	ExecutorClient
+	killed []killRequest
+}
+
+func (c *fakeExecutorClient) Run(ctx context.Context, node *model.ExecutorNode, task *model.ExecutorTask) error {
+	return nil
+}
+
+func (c *fakeExecutorClient) Kill(ctx context.Context, node *model.ExecutorNode, instanceID, dispatchToken uint64) (bool, error) {
+	c.killed = append(c.killed, killRequest{instanceID, dispatchToken})
+	return true, nil
+}
+
+func TestSendKillsWhenNoLongerScheduling(t *testing.T) {
+	client := &fakeExecutorClient{}
+	ClientFor(model.ExecutorTypeHTTP)
+	old := httpClient
+	httpClient = client
+	t.Cleanup(func() { httpClient = old })
+
+	instanceRepo := &fakeInstanceRepo{}
+	d := &Dispatcher{instanceRepo: instanceRepo}
+	task := &model.Task{ID: 1, ExecutorType: model.ExecutorTypeHTTP}
+	instance := &model.TaskInstance{ID: 1, TaskID: 1, DispatchToken: 7}
+	node := testNodes("a")[0]
+
+	d.send(instance, task, node, nil)
+	if len(client.killed) != 0 {
+		t.Fatalf("dispatched instance should not be killed, got %v", client.killed)
+	}
+
+	// 下发期间实例被取消, 更新为执行中失败后终止本次下发的执行
+	instanceRepo.cancelled = true
+	d.send(instance, task, node, nil)
+	if want := []killRequest{{1, 7}}; len(client.killed) != 1 || client.killed[0] != want[0] {
+		t.Fatalf("kill requests = %v, want %v", client.killed, want)
+	}
+}
@@ -0,0 +1,211 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	executorv1 "distributed-scheduler/api/executor/v1"
+	"distributed-scheduler/internal/common/utils"
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/scheduler/router"
+)
+
+// GRPCExecutorClient 基于gRPC协议的执行器客户端, 同时实现router.Prober
+// 按执行器地址复用连接, 每次调用在metadata中携带对完整方法名和请求消息的签名
+type GRPCExecutorClient struct {
+	timeout time.Duration
+	signer  Signer
+	conns   map[string]*grpc.ClientConn
+	mu      sync.Mutex
+}
+
+// NewGRPCExecutorClient 创建gRPC执行器客户端, signer为空时请求不签名
+func NewGRPCExecutorClient(timeout time.Duration, signer Signer) *GRPCExecutorClient {
+	if timeout <= 0 {
+		timeout = DefaultRunTimeout
+	}
+	return &GRPCExecutorClient{
+		timeout: timeout,
+		signer:  signer,
+		conns:   make(map[string]*grpc.ClientConn),
+	}
+}
+
+// Run 下发任务
+func (c *GRPCExecutorClient) Run(ctx context.Context, node *model.ExecutorNode, task *model.ExecutorTask) error {
+	client, err := c.client(node)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req := &executorv1.RunRequest{Task: &executorv1.Task{
+		InstanceId:      task.InstanceID,
+		TaskId:          task.TaskID,
+		ExecutorHandler: task.ExecutorHandler,
+		ExecutorParam:   task.ExecutorParam,
+		ShardIndex:      uint32(task.ShardIndex),
+		ShardTotal:      uint32(task.ShardTotal),
+		Timeout:         uint32(task.Timeout),
+		DispatchToken:   task.DispatchToken,
+	}}
+	if ctx, err = c.sign(ctx, node, executorv1.ExecutorService_Run_FullMethodName, req); err != nil {
+		return err
+	}
+	_, err = client.Run(ctx, req)
+	if err != nil {
+		// 连接失败或超时时执行器可能未收到任务, 由调用方重试
+		if code := status.Code(err); code == codes.Unavailable || code == codes.DeadlineExceeded {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrExecutorRejected, err)
+	}
+	return nil
+}
+
+// Kill 终止任务
+func (c *GRPCExecutorClient) Kill(ctx context.Context, node *model.ExecutorNode, instanceID, dispatchToken uint64) (bool, error) {
+	client, err := c.client(node)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req := &executorv1.KillRequest{InstanceId: instanceID, DispatchToken: dispatchToken}
+	if ctx, err = c.sign(ctx, node, executorv1.ExecutorService_Kill_FullMethodName, req); err != nil {
+		return false, err
+	}
+	resp, err := client.Kill(ctx, req)
+	if err != nil {
+		return false, err
+	}
+	return resp.GetKilled(), nil
+}
+
+// Status 查询实例执行状态
+func (c *GRPCExecutorClient) Status(ctx context.Context, node *model.ExecutorNode, instanceID uint64) (*model.ExecutorInstanceStatus, error) {
+	client, err := c.client(node)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req := &executorv1.StatusRequest{InstanceId: instanceID}
+	if ctx, err = c.sign(ctx, node, executorv1.ExecutorService_Status_FullMethodName, req); err != nil {
+		return nil, err
+	}
+	resp, err := client.Status(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ExecutorInstanceStatus{
+		InstanceID:  instanceID,
+		Running:     resp.GetState() == executorv1.InstanceState_INSTANCE_STATE_RUNNING,
+		StartTime:   resp.GetStartTime(),
+		CurrentLoad: uint(resp.GetCurrentLoad()),
+	}, nil
+}
+
+// Beat 健康检查
+func (c *GRPCExecutorClient) Beat(ctx context.Context, node *model.ExecutorNode) error {
+	return c.beat(ctx, node, false)
+}
+
+// IdleBeat 空闲检查
+func (c *GRPCExecutorClient) IdleBeat(ctx context.Context, node *model.ExecutorNode) error {
+	return c.beat(ctx, node, true)
+}
+
+// beat 调用执行器探测接口
+func (c *GRPCExecutorClient) beat(ctx context.Context, node *model.ExecutorNode, idle bool) error {
+	client, err := c.client(node)
+	if err != nil {
+		return fmt.Errorf("%w: %v", router.ErrExecutorUnreachable, err)
+	}
+	resp, err := client.Beat(ctx, &executorv1.BeatRequest{Idle: idle})
+	if err != nil {
+		return fmt.Errorf("%w: %v", router.ErrExecutorUnreachable, err)
+	}
+	if resp.GetBusy() {
+		return fmt.Errorf("%w: %s", router.ErrExecutorBusy, resp.GetMessage())
+	}
+	return nil
+}
+
+// sign 在outgoing metadata中携带请求签名, 任务组未生成访问令牌时不签名
+func (c *GRPCExecutorClient) sign(ctx context.Context, node *model.ExecutorNode, method string, req proto.Message) (context.Context, error) {
+	if c.signer == nil {
+		return ctx, nil
+	}
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return ctx, err
+	}
+	signature, err := c.signer.Sign(ctx, node.GroupID, method, body)
+	if err != nil {
+		return ctx, fmt.Errorf("请求签名失败: %w", err)
+	}
+	if signature == nil {
+		return ctx, nil
+	}
+	return metadata.AppendToOutgoingContext(ctx,
+		utils.HeaderExecutorApp, signature.AppName,
+		utils.HeaderExecutorTimestamp, signature.Timestamp,
+		utils.HeaderExecutorSignature, signature.Signature), nil
+}
+
+// Close 关闭所有连接
+func (c *GRPCExecutorClient) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for addr, conn := range c.conns {
+		conn.Close()
+		delete(c.conns, addr)
+	}
+}
+
+// Retain 关闭并移除不在addresses中的连接(如执行器已离线或已清理), 返回移除的数量
+func (c *GRPCExecutorClient) Retain(addresses map[string]bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := 0
+	for addr, conn := range c.conns {
+		if addresses[addr] {
+			continue
+		}
+		conn.Close()
+		delete(c.conns, addr)
+		removed++
+	}
+	return removed
+}
+
+// client 获取执行器地址对应的客户端, 连接在首次调用时建立
+func (c *GRPCExecutorClient) client(node *model.ExecutorNode) (executorv1.ExecutorServiceClient, error) {
+	addr := node.Address()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conn, ok := c.conns[addr]
+	if !ok {
+		var err error
+		conn, err = grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return nil, err
+		}
+		c.conns[addr] = conn
+	}
+	return executorv1.NewExecutorServiceClient(conn), nil
+}
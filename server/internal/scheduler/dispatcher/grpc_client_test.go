@@ -0,0 +1,30 @@
+package dispatcher
+
+import (
+	"testing"
+
+	"distributed-scheduler/internal/model"
+)
+
+func TestGRPCClientRetain(t *testing.T) {
+	c := NewGRPCExecutorClient(0, nil)
+	defer c.Close()
+
+	online := &model.ExecutorNode{ID: "a", Host: "127.0.0.1", Port: 9001}
+	offline := &model.ExecutorNode{ID: "b", Host: "127.0.0.1", Port: 9002}
+	for _, node := range []*model.ExecutorNode{online, offline} {
+		if _, err := c.client(node); err != nil {
+			t.Fatalf("client(%s): %v", node.Address(), err)
+		}
+	}
+
+	if removed := c.Retain(map[string]bool{online.Address(): true}); removed != 1 {
+		t.Fatalf("Retain removed %d connections, want 1", removed)
+	}
+	if _, ok := c.conns[offline.Address()]; ok {
+		t.Error("connection to the offline executor should be closed")
+	}
+	if _, ok := c.conns[online.Address()]; !ok {
+		t.Error("connection to the online executor should be kept")
+	}
+}
@@ -0,0 +1,147 @@
+package dispatcher
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"distributed-scheduler/internal/model"
+)
+
+// Item 待分发的任务实例
+type Item struct {
+	Instance *model.TaskInstance
+	Priority int // 任务优先级, 数值越大越优先
+	index    int
+}
+
+// NewItem 根据任务实例创建队列元素, 优先级取自实例所属任务
+func NewItem(instance *model.TaskInstance) *Item {
+	item := &Item{Instance: instance}
+	if instance.Task != nil {
+		item.Priority = instance.Task.Priority
+	}
+	return item
+}
+
+// PriorityQueue 待分发实例的优先队列(并发安全)
+// 按优先级降序、触发时间升序出队; 设置老化间隔后实例每等待一个间隔优先级加一,
+// 等待足够久的低优先级实例最终会排到高优先级实例之前, 避免饿死
+type PriorityQueue struct {
+	mu    sync.Mutex
+	items itemHeap
+	index map[uint64]*Item
+}
+
+// NewPriorityQueue 创建优先队列, agingInterval为0时不老化
+func NewPriorityQueue(agingInterval time.Duration) *PriorityQueue {
+	return &PriorityQueue{
+		items: itemHeap{agingInterval: agingInterval},
+		index: make(map[uint64]*Item),
+	}
+}
+
+// Push 实例入队, 已在队列中的实例返回false
+func (q *PriorityQueue) Push(item *Item) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.index[item.Instance.ID]; ok {
+		return false
+	}
+	heap.Push(&q.items, item)
+	q.index[item.Instance.ID] = item
+	return true
+}
+
+// Pop 取出优先级最高的实例
+func (q *PriorityQueue) Pop() (*Item, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.items.Len() == 0 {
+		return nil, false
+	}
+	item := heap.Pop(&q.items).(*Item)
+	delete(q.index, item.Instance.ID)
+	return item, true
+}
+
+// Remove 移除实例
+func (q *PriorityQueue) Remove(instanceID uint64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item, ok := q.index[instanceID]
+	if !ok {
+		return false
+	}
+	heap.Remove(&q.items, item.index)
+	delete(q.index, instanceID)
+	return true
+}
+
+// Contains 实例是否在队列中
+func (q *PriorityQueue) Contains(instanceID uint64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, ok := q.index[instanceID]
+	return ok
+}
+
+// Len 队列长度
+func (q *PriorityQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.items.Len()
+}
+
+// itemHeap 实例堆(实现heap.Interface)
+type itemHeap struct {
+	items         []*Item
+	agingInterval time.Duration
+}
+
+func (h itemHeap) Len() int { return len(h.items) }
+
+func (h itemHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	ta, tb := a.Instance.TriggerTime, b.Instance.TriggerTime
+	if a.Priority != b.Priority {
+		if h.agingInterval > 0 {
+			// 有效优先级 = Priority + 等待时长/老化间隔, 比较时当前时间可以约去
+			sa := float64(a.Priority) - float64(ta.UnixNano())/float64(h.agingInterval)
+			sb := float64(b.Priority) - float64(tb.UnixNano())/float64(h.agingInterval)
+			if sa != sb {
+				return sa > sb
+			}
+		} else {
+			return a.Priority > b.Priority
+		}
+	}
+	if !ta.Equal(tb) {
+		return ta.Before(tb)
+	}
+	return a.Instance.ID < b.Instance.ID
+}
+
+func (h itemHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *itemHeap) Push(x interface{}) {
+	item := x.(*Item)
+	item.index = len(h.items)
+	h.items = append(h.items, item)
+}
+
+func (h *itemHeap) Pop() interface{} {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items[n-1] = nil
+	item.index = -1
+	h.items = h.items[:n-1]
+	return item
+}
@@ -0,0 +1,81 @@
+package dispatcher
+
+import (
+	"testing"
+	"time"
+
+	"distributed-scheduler/internal/model"
+)
+
+func newQueueItem(id uint64, priority int, triggerTime time.Time) *Item {
+	return &Item{
+		Instance: &model.TaskInstance{ID: id, TriggerTime: triggerTime},
+		Priority: priority,
+	}
+}
+
+// popOrder 依次出队并返回实例ID
+func popOrder(q *PriorityQueue) []uint64 {
+	var ids []uint64
+	for {
+		item, ok := q.Pop()
+		if !ok {
+			return ids
+		}
+		ids = append(ids, item.Instance.ID)
+	}
+}
+
+func TestPriorityQueueOrder(t *testing.T) {
+	q := NewPriorityQueue(0)
+	now := time.Now()
+
+	q.Push(newQueueItem(1, 1, now))
+	q.Push(newQueueItem(2, 5, now.Add(time.Second)))
+	q.Push(newQueueItem(3, 5, now))
+	q.Push(newQueueItem(4, 1, now))
+	if q.Push(newQueueItem(2, 9, now)) {
+		t.Error("pushing an instance already in the queue should return false")
+	}
+	if !q.Remove(4) || q.Contains(4) {
+		t.Error("Remove should drop the instance")
+	}
+
+	got := popOrder(q)
+	want := []uint64{3, 2, 1}
+	if len(got) != len(want) {
+		t.Fatalf("pop order = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("pop order = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestPriorityQueueAging(t *testing.T) {
+	now := time.Now()
+
+	// 低优先级实例等待3个老化间隔, 有效优先级1+3=4, 仍低于刚触发的优先级5
+	q := NewPriorityQueue(time.Minute)
+	q.Push(newQueueItem(1, 1, now.Add(-3*time.Minute)))
+	q.Push(newQueueItem(2, 5, now))
+	if got := popOrder(q); got[0] != 2 {
+		t.Errorf("after 3 intervals: pop order = %v, want high priority first", got)
+	}
+
+	// 等待10个老化间隔后有效优先级11, 排到高优先级实例之前
+	q.Push(newQueueItem(1, 1, now.Add(-10*time.Minute)))
+	q.Push(newQueueItem(2, 5, now))
+	if got := popOrder(q); got[0] != 1 {
+		t.Errorf("after 10 intervals: pop order = %v, want aged low priority first", got)
+	}
+
+	// 不老化时只按优先级
+	q = NewPriorityQueue(0)
+	q.Push(newQueueItem(1, 1, now.Add(-10*time.Minute)))
+	q.Push(newQueueItem(2, 5, now))
+	if got := popOrder(q); got[0] != 2 {
+		t.Errorf("without aging: pop order = %v, want high priority first", got)
+	}
+}
@@ -0,0 +1,102 @@
+package dispatcher
+
+import (
+	"sync"
+	"time"
+
+	"distributed-scheduler/internal/model"
+)
+
+// 分片分布记录的空闲淘汰时间
+const shardIdleTTL = 10 * time.Minute
+
+// shardKey 同一次触发产生的分片实例
+type shardKey struct {
+	taskID      uint64
+	triggerTime int64
+}
+
+// shardPlacement 同一次触发的分片实例所在的主机
+type shardPlacement struct {
+	hosts    map[uint64]string // 实例ID -> 主机
+	lastUsed time.Time
+}
+
+// shardTracker 记录本调度节点为反亲和分片选择的主机
+// 同一批分片在同一轮分发中先后路由, 此时前面的分片尚未下发完成, 数据库中还没有执行器信息
+type shardTracker struct {
+	entries map[shardKey]*shardPlacement
+	mu      sync.Mutex
+}
+
+// newShardTracker 创建分片分布记录
+func newShardTracker() *shardTracker {
+	return &shardTracker{entries: make(map[shardKey]*shardPlacement)}
+}
+
+// newShardKey 获取实例所属的分片批次
+func newShardKey(instance *model.TaskInstance) shardKey {
+	return shardKey{taskID: instance.TaskID, triggerTime: instance.TriggerTime.UnixNano()}
+}
+
+// hostShards 统计同一批次中除instance外的分片在各主机上的数量
+// siblings为数据库中的同批次实例, 已结束的实例不计入, 已下发的实例以数据库中的执行器为准; hostOf为执行器ID到主机的映射
+func (t *shardTracker) hostShards(instance *model.TaskInstance, siblings []*model.TaskInstance, hostOf map[string]string) map[string]int {
+	hosts := make(map[uint64]string)
+	t.mu.Lock()
+	if entry, ok := t.entries[newShardKey(instance)]; ok {
+		for id, host := range entry.hosts {
+			hosts[id] = host
+		}
+	}
+	t.mu.Unlock()
+
+	for _, sibling := range siblings {
+		switch {
+		case sibling.Status != model.InstanceStatusScheduling && sibling.Status != model.InstanceStatusRunning:
+			delete(hosts, sibling.ID)
+		case sibling.ExecutorID != "":
+			if host, ok := hostOf[sibling.ExecutorID]; ok {
+				hosts[sibling.ID] = host
+			}
+		}
+	}
+	delete(hosts, instance.ID)
+
+	counts := make(map[string]int)
+	for _, host := range hosts {
+		counts[host]++
+	}
+	return counts
+}
+
+// record 记录分片实例选择的主机
+func (t *shardTracker) record(instance *model.TaskInstance, host string) {
+	key := newShardKey(instance)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, ok := t.entries[key]
+	if !ok {
+		entry = &shardPlacement{hosts: make(map[uint64]string)}
+		t.entries[key] = entry
+	}
+	entry.hosts[instance.ID] = host
+	entry.lastUsed = time.Now()
+}
+
+// prune 淘汰超过空闲时间未使用的批次, 返回淘汰的数量
+func (t *shardTracker) prune(idle time.Duration) int {
+	expire := time.Now().Add(-idle)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	removed := 0
+	for key, entry := range t.entries {
+		if entry.lastUsed.Before(expire) {
+			delete(t.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
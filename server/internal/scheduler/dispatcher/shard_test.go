@@ -0,0 +1,48 @@
+package dispatcher
+
+import (
+	"testing"
+	"time"
+
+	"distributed-scheduler/internal/model"
+)
+
+func TestShardTrackerHostShards(t *testing.T) {
+	tracker := newShardTracker()
+	triggerTime := time.Now()
+	shard := func(id uint64) *model.TaskInstance {
+		return &model.TaskInstance{ID: id, TaskID: 1, TriggerTime: triggerTime, ShardTotal: 3, Status: model.InstanceStatusScheduling}
+	}
+
+	// 同一轮分发中前面的分片尚未下发, 只有本节点的记录
+	tracker.record(shard(1), "host-a")
+	tracker.record(shard(2), "host-b")
+	counts := tracker.hostShards(shard(3), nil, nil)
+	if counts["host-a"] != 1 || counts["host-b"] != 1 {
+		t.Fatalf("hostShards = %v, want host-a and host-b once", counts)
+	}
+
+	// 数据库中已结束的实例不计入, 已下发的实例以实际执行器为准, 重新分发的实例不计自身
+	finished := shard(1)
+	finished.Status = model.InstanceStatusSuccess
+	running := shard(2)
+	running.Status, running.ExecutorID = model.InstanceStatusRunning, "executor-c"
+	counts = tracker.hostShards(shard(2), []*model.TaskInstance{finished, running}, map[string]string{"executor-c": "host-c"})
+	if len(counts) != 0 {
+		t.Fatalf("hostShards = %v, want empty", counts)
+	}
+	counts = tracker.hostShards(shard(3), []*model.TaskInstance{finished, running}, map[string]string{"executor-c": "host-c"})
+	if len(counts) != 1 || counts["host-c"] != 1 {
+		t.Fatalf("hostShards = %v, want host-c once", counts)
+	}
+
+	other := shard(4)
+	other.TriggerTime = triggerTime.Add(time.Minute)
+	if counts := tracker.hostShards(other, nil, nil); len(counts) != 0 {
+		t.Fatalf("another trigger: hostShards = %v, want empty", counts)
+	}
+
+	if removed := tracker.prune(0); removed != 1 {
+		t.Fatalf("prune(0) removed %d, want 1", removed)
+	}
+}
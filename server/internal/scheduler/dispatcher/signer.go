@@ -0,0 +1,104 @@
+package dispatcher
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"distributed-scheduler/internal/common/utils"
+	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/repository"
+)
+
+// 任务组访问令牌的缓存时间, 令牌轮换后最迟在该时间后使用新令牌签名
+const signerCacheTTL = 10 * time.Second
+
+// Signature 调度中心调用执行器的请求签名, 通过请求头或gRPC metadata携带
+type Signature struct {
+	AppName   string
+	Timestamp string
+	Signature string // 宽限期内同时包含新旧令牌的签名, 以逗号分隔
+}
+
+// Signer 使用执行器所属任务组的访问令牌为请求签名
+type Signer interface {
+	// Sign 计算签名, 任务组未生成访问令牌时返回nil, 请求不签名
+	Sign(ctx context.Context, groupID uint64, target string, body []byte) (*Signature, error)
+}
+
+// groupTokens 缓存的任务组访问令牌
+type groupTokens struct {
+	appName  string
+	tokens   []string
+	expireAt time.Time
+}
+
+// groupSigner 按任务组缓存访问令牌的签名器
+type groupSigner struct {
+	groupRepo   repository.TaskGroupRepository
+	gracePeriod time.Duration
+
+	cache map[uint64]*groupTokens
+	mu    sync.Mutex
+}
+
+// NewSigner 创建签名器
+func NewSigner() Signer {
+	gracePeriod := config.DefaultTokenGracePeriod
+	if cfg := config.GetConfig(); cfg != nil {
+		gracePeriod = cfg.Executor.Auth.GracePeriod()
+	}
+	return &groupSigner{
+		groupRepo:   repository.NewTaskGroupRepository(),
+		gracePeriod: gracePeriod,
+		cache:       make(map[uint64]*groupTokens),
+	}
+}
+
+// Sign 计算签名, 令牌轮换宽限期内同时使用新旧令牌签名, 执行器无论是否已更新令牌都能校验通过
+func (s *groupSigner) Sign(ctx context.Context, groupID uint64, target string, body []byte) (*Signature, error) {
+	entry, err := s.tokens(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entry.tokens) == 0 {
+		return nil, nil
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signatures := make([]string, 0, len(entry.tokens))
+	for _, token := range entry.tokens {
+		signatures = append(signatures, utils.SignSchedulerRequest(token, entry.appName, timestamp, target, body))
+	}
+	return &Signature{
+		AppName:   entry.appName,
+		Timestamp: timestamp,
+		Signature: strings.Join(signatures, ","),
+	}, nil
+}
+
+// tokens 获取任务组当前有效的访问令牌
+func (s *groupSigner) tokens(ctx context.Context, groupID uint64) (*groupTokens, error) {
+	s.mu.Lock()
+	entry, ok := s.cache[groupID]
+	s.mu.Unlock()
+	if ok && time.Now().Before(entry.expireAt) {
+		return entry, nil
+	}
+
+	group, err := s.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	entry = &groupTokens{
+		appName:  group.AppName,
+		tokens:   group.ValidTokens(s.gracePeriod),
+		expireAt: time.Now().Add(signerCacheTTL),
+	}
+	s.mu.Lock()
+	s.cache[groupID] = entry
+	s.mu.Unlock()
+	return entry, nil
+}
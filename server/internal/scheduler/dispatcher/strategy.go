@@ -0,0 +1,82 @@
+package dispatcher
+
+import (
+	"sync"
+	"time"
+
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/scheduler/router"
+)
+
+// 路由策略缓存的空闲淘汰时间
+const strategyIdleTTL = 10 * time.Minute
+
+// strategyKey 路由策略缓存键, 任务修改策略、参数或执行器类型后使用新的策略实例
+type strategyKey struct {
+	taskID       uint64
+	name         string
+	options      string
+	executorType string
+}
+
+// cachedStrategy 缓存的路由策略
+type cachedStrategy struct {
+	strategy router.Strategy
+	lastUsed time.Time
+}
+
+// strategyCache 按任务缓存路由策略实例
+// 轮询、LRU、一致性哈希等策略在实例中保存状态(计数器、使用时间、哈希环), 每次分发重新创建会丢失这些状态
+type strategyCache struct {
+	entries map[strategyKey]*cachedStrategy
+	mu      sync.Mutex
+}
+
+// newStrategyCache 创建路由策略缓存
+func newStrategyCache() *strategyCache {
+	return &strategyCache{entries: make(map[strategyKey]*cachedStrategy)}
+}
+
+// get 获取任务的路由策略, 不存在时创建; 策略参数无效时使用默认参数
+// 探测类策略使用与任务执行器类型一致的协议探测
+func (c *strategyCache) get(task *model.Task) router.Strategy {
+	key := strategyKey{
+		taskID:       task.ID,
+		name:         task.RouteStrategy,
+		options:      task.RouteOptions,
+		executorType: task.ExecutorType,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok {
+		entry.lastUsed = time.Now()
+		return entry.strategy
+	}
+
+	strategy, err := router.New(task.RouteStrategy, task.RouteOptions)
+	if err != nil {
+		strategy = router.NewStrategy(task.RouteStrategy)
+	}
+	if prober, ok := ClientFor(task.ExecutorType).(router.Prober); ok {
+		strategy = router.WithProber(strategy, prober)
+	}
+	c.entries[key] = &cachedStrategy{strategy: strategy, lastUsed: time.Now()}
+	return strategy
+}
+
+// prune 淘汰超过空闲时间未使用的策略(任务已删除或已修改路由配置), 返回淘汰的数量
+func (c *strategyCache) prune(idle time.Duration) int {
+	expire := time.Now().Add(-idle)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := 0
+	for key, entry := range c.entries {
+		if entry.lastUsed.Before(expire) {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
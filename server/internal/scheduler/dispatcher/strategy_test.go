@@ -0,0 +1,63 @@
+package dispatcher
+
+import (
+	"testing"
+	"time"
+
+	"distributed-scheduler/internal/model"
+)
+
+func testNodes(ids ...string) []*model.ExecutorNode {
+	nodes := make([]*model.ExecutorNode, 0, len(ids))
+	for _, id := range ids {
+		nodes = append(nodes, &model.ExecutorNode{ID: id, Status: model.ExecutorStatusOnline, MaxConcurrent: 10})
+	}
+	return nodes
+}
+
+func TestStrategyCacheKeepsState(t *testing.T) {
+	cache := newStrategyCache()
+	task := &model.Task{ID: 1, RouteStrategy: model.RouteStrategyRoundRobin, ExecutorType: model.ExecutorTypeHTTP}
+	nodes := testNodes("a", "b", "c")
+
+	seen := make(map[string]bool)
+	for i := 0; i < len(nodes); i++ {
+		node, err := cache.get(task).Select(nodes, "")
+		if err != nil {
+			t.Fatalf("Select: %v", err)
+		}
+		seen[node.ID] = true
+	}
+	if len(seen) != len(nodes) {
+		t.Fatalf("round robin selected %v, want every node once", seen)
+	}
+
+	lru := &model.Task{ID: 2, RouteStrategy: model.RouteStrategyLeastRecentlyUsed, ExecutorType: model.ExecutorTypeHTTP}
+	first, _ := cache.get(lru).Select(nodes, "")
+	second, _ := cache.get(lru).Select(nodes, "")
+	if first.ID == second.ID {
+		t.Fatalf("LRU selected %s twice in a row", first.ID)
+	}
+}
+
+func TestStrategyCacheKey(t *testing.T) {
+	cache := newStrategyCache()
+	task := &model.Task{ID: 1, RouteStrategy: model.RouteStrategyRoundRobin, ExecutorType: model.ExecutorTypeHTTP}
+	strategy := cache.get(task)
+	if cache.get(task) != strategy {
+		t.Fatal("same task should reuse the cached strategy")
+	}
+
+	changed := *task
+	changed.RouteStrategy = model.RouteStrategyLeastRecentlyUsed
+	if cache.get(&changed) == strategy {
+		t.Fatal("changing the route strategy should create a new strategy")
+	}
+
+	if removed := cache.prune(time.Hour); removed != 0 {
+		t.Fatalf("prune(1h) removed %d, want 0", removed)
+	}
+	if removed := cache.prune(0); removed != 2 {
+		t.Fatalf("prune(0) removed %d, want 2", removed)
+	}
+}
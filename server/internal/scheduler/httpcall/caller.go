@@ -0,0 +1,355 @@
+package httpcall
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/executor/pool"
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/repository"
+	"distributed-scheduler/internal/scheduler/quota"
+	"distributed-scheduler/pkg/logger"
+)
+
+// 默认配置
+const (
+	defaultPoolSize    = 50
+	defaultQueueSize   = 500
+	defaultTimeout     = 30 * time.Second
+	defaultMaxBodySize = 1 << 20
+)
+
+// 孤立请求恢复配置
+const (
+	orphanGrace     = time.Minute // 请求超时后的宽限时间, 覆盖保存日志和更新状态的耗时
+	orphanBatchSize = 500
+)
+
+// 响应体片段长度
+const (
+	resultSnippetSize = 512  // 记录到实例执行结果
+	logSnippetSize    = 4096 // 记录到执行日志
+)
+
+// Caller 由调度中心直接执行HTTP_CALL类型任务
+// 请求在独立的协程池中执行, 不占用分发协程; 执行结果和响应片段记录到实例和执行日志
+type Caller struct {
+	instanceRepo repository.InstanceRepository
+	logRepo      repository.TaskLogRepository
+	quota        *quota.Manager
+	pool         *pool.WorkerPool
+	client       *http.Client
+	timeout      time.Duration
+	maxBodySize  int64
+	allowedHosts []string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+// NewCaller 创建HTTP请求执行器
+func NewCaller(quotaManager *quota.Manager) *Caller {
+	poolSize, queueSize := defaultPoolSize, defaultQueueSize
+	timeout, maxBodySize := defaultTimeout, int64(defaultMaxBodySize)
+	var allowedHosts []string
+	var allowPrivate bool
+
+	if cfg := config.GetConfig(); cfg != nil {
+		callCfg := cfg.Scheduler.HTTPCall
+		if callCfg.PoolSize > 0 {
+			poolSize = callCfg.PoolSize
+		}
+		if callCfg.QueueSize > 0 {
+			queueSize = callCfg.QueueSize
+		}
+		if callCfg.Timeout > 0 {
+			timeout = time.Duration(callCfg.Timeout) * time.Second
+		}
+		if callCfg.MaxBodySize > 0 {
+			maxBodySize = int64(callCfg.MaxBodySize)
+		}
+		allowedHosts = callCfg.AllowedHosts
+		allowPrivate = callCfg.AllowPrivate
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Caller{
+		instanceRepo: repository.NewInstanceRepository(),
+		logRepo:      repository.NewTaskLogRepository(),
+		quota:        quotaManager,
+		pool: pool.NewWorkerPoolWithOptions(pool.Options{
+			Name:       "http_call",
+			MaxWorkers: poolSize,
+			QueueSize:  queueSize,
+		}),
+		client:       newHTTPClient(allowedHosts, allowPrivate),
+		timeout:      timeout,
+		maxBodySize:  maxBodySize,
+		allowedHosts: allowedHosts,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// AllowedHosts 获取允许请求的主机列表
+func AllowedHosts() []string {
+	if cfg := config.GetConfig(); cfg != nil {
+		return cfg.Scheduler.HTTPCall.AllowedHosts
+	}
+	return nil
+}
+
+// Submit 提交实例, 协程池已满时返回ErrCallerBusy
+// 由调用方保证实例已被抢占并占用了并发配额, 请求发出前实例更新为执行中, 结束后释放配额
+func (c *Caller) Submit(instance *model.TaskInstance, task *model.Task, priority int) error {
+	err := c.pool.SubmitWithPriority(func() {
+		c.call(instance, task)
+	}, priority)
+	if errors.Is(err, pool.ErrPoolFull) {
+		return ErrCallerBusy
+	}
+	return err
+}
+
+// Shutdown 停止接收新请求, 取消执行中的请求并等待结束
+func (c *Caller) Shutdown(timeout time.Duration) error {
+	var err error
+	c.once.Do(func() {
+		c.cancel()
+		err = c.pool.Shutdown(timeout)
+	})
+	return err
+}
+
+// RecoverOrphaned 将超过请求超时时间仍处于执行中的HTTP_CALL实例标记为失败并释放配额, 返回处理的数量
+// 调度中心重启或崩溃时正在执行的请求不会再回写结果, 这些实例没有执行器ID, 不会被执行器离线故障转移处理
+func (c *Caller) RecoverOrphaned(ctx context.Context) (int, error) {
+	now := time.Now()
+	instances, err := c.instanceRepo.GetRunningHTTPCalls(ctx, now.Add(-c.timeout-orphanGrace), orphanBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	for _, instance := range instances {
+		timeout := c.timeout
+		if instance.Task != nil {
+			timeout = c.timeoutFor(instance.Task)
+		}
+		if instance.StartTime == nil || now.Sub(*instance.StartTime) < timeout+orphanGrace {
+			continue
+		}
+		ok, err := c.instanceRepo.FinishAttempt(ctx, instance.ID, instance.DispatchToken, model.InstanceStatusFailed,
+			response.CodeError, fmt.Sprintf("请求超过%v未返回结果, 调度中心可能已重启", timeout))
+		if err != nil {
+			return recovered, err
+		}
+		if !ok {
+			continue
+		}
+		if err := c.quota.Release(ctx, instance); err != nil {
+			logger.Errorf("释放并发配额失败, instance_id=%d: %v", instance.ID, err)
+		}
+		recovered++
+	}
+	return recovered, nil
+}
+
+// timeoutFor 获取任务的请求超时时间, 依次使用请求配置、任务配置和全局默认值
+func (c *Caller) timeoutFor(task *model.Task) time.Duration {
+	if task.HTTPCall != nil && task.HTTPCall.Timeout > 0 {
+		return time.Duration(task.HTTPCall.Timeout) * time.Second
+	}
+	if task.Timeout > 0 {
+		return time.Duration(task.Timeout) * time.Second
+	}
+	return c.timeout
+}
+
+// Stats 获取协程池的统计信息
+func (c *Caller) Stats() pool.Stats {
+	return c.pool.GetStats()
+}
+
+// call 执行请求并记录结果
+func (c *Caller) call(instance *model.TaskInstance, task *model.Task) {
+	ctx := context.Background()
+	defer func() {
+		if err := c.quota.Release(ctx, instance); err != nil {
+			logger.Errorf("释放并发配额失败, instance_id=%d: %v", instance.ID, err)
+		}
+	}()
+
+	// 调度中心关闭时队列中尚未发出的请求恢复为待调度, 由其他调度节点执行
+	if c.ctx.Err() != nil {
+		if _, err := c.instanceRepo.ReleaseToPending(ctx, instance.ID, "调度节点关闭, 等待重新调度"); err != nil {
+			logger.Errorf("恢复实例待调度状态失败, instance_id=%d: %v", instance.ID, err)
+		}
+		return
+	}
+
+	logs := newLogBuffer(instance)
+	status, code, msg, ok := c.do(instance, task, logs)
+	if !ok {
+		logger.Warnf("实例已结束或已重新分发, 取消HTTP请求, instance_id=%d", instance.ID)
+		return
+	}
+
+	if len(logs.entries) > 0 {
+		if err := c.logRepo.BatchCreate(ctx, logs.entries); err != nil {
+			logger.Errorf("保存HTTP请求日志失败, instance_id=%d: %v", instance.ID, err)
+		}
+	}
+	if _, err := c.instanceRepo.FinishAttempt(ctx, instance.ID, instance.DispatchToken, status, code, msg); err != nil {
+		logger.Errorf("更新实例状态失败, instance_id=%d: %v", instance.ID, err)
+	}
+}
+
+// do 渲染并发送请求, 校验响应, 返回实例结束状态、结果码和结果信息
+// 实例在发送请求前已被取消或重新分发时不发送请求并返回false
+func (c *Caller) do(instance *model.TaskInstance, task *model.Task, logs *logBuffer) (int8, int, string, bool) {
+	fail := func(msg string) (int8, int, string, bool) {
+		logs.add(model.LogLevelError, msg)
+		return model.InstanceStatusFailed, response.CodeError, msg, true
+	}
+
+	cfg := task.HTTPCall
+	if cfg == nil {
+		return fail("任务未设置HTTP请求配置")
+	}
+	req, err := render(cfg, NewTemplateData(instance))
+	if err != nil {
+		return fail(err.Error())
+	}
+	u, err := checkURL(req.url, c.allowedHosts)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	dispatched, err := c.instanceRepo.UpdateDispatched(context.Background(), instance.ID, instance.DispatchToken, "", u.Host, "")
+	if err != nil {
+		return fail("更新实例状态失败: " + err.Error())
+	}
+	if !dispatched {
+		return 0, 0, "", false
+	}
+
+	timeout := c.timeoutFor(task)
+	ctx, cancel := context.WithTimeout(c.ctx, timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, req.url, strings.NewReader(req.body))
+	if err != nil {
+		return fail("创建请求失败: " + err.Error())
+	}
+	for name, value := range req.headers {
+		httpReq.Header.Set(name, value)
+	}
+	logs.add(model.LogLevelInfo, req.method+" "+req.url)
+
+	start := time.Now()
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fail(fmt.Sprintf("请求超时(%v)", timeout))
+		}
+		return fail("请求失败: " + err.Error())
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.maxBodySize))
+	elapsed := time.Since(start).Milliseconds()
+	if err != nil {
+		return fail(fmt.Sprintf("HTTP %d, 读取响应失败: %v", resp.StatusCode, err))
+	}
+	logs.add(model.LogLevelInfo, fmt.Sprintf("响应状态: %s, 耗时%dms", resp.Status, elapsed))
+	if len(body) > 0 {
+		logs.add(model.LogLevelInfo, "响应内容: "+snippet(body, logSnippetSize))
+	}
+
+	summary := fmt.Sprintf("HTTP %d, 耗时%dms", resp.StatusCode, elapsed)
+	if reason := assert(cfg, resp.StatusCode, body); reason != "" {
+		return fail(summary + ", " + reason + ": " + snippet(body, resultSnippetSize))
+	}
+	if len(body) > 0 {
+		summary += ": " + snippet(body, resultSnippetSize)
+	}
+	return model.InstanceStatusSuccess, response.CodeSuccess, summary, true
+}
+
+// assert 校验响应状态码和响应体, 返回不符合预期的原因
+func assert(cfg *model.HTTPCallConfig, statusCode int, body []byte) string {
+	if len(cfg.ExpectStatus) == 0 {
+		if statusCode < 200 || statusCode > 299 {
+			return "响应状态码不是2xx"
+		}
+	} else {
+		matched := false
+		for _, code := range cfg.ExpectStatus {
+			if code == statusCode {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Sprintf("响应状态码不在期望的%v中", cfg.ExpectStatus)
+		}
+	}
+
+	if cfg.ExpectBody != "" && !strings.Contains(string(body), cfg.ExpectBody) {
+		return "响应内容不包含期望的内容"
+	}
+	if cfg.ExpectBodyRegex != "" {
+		re, err := regexp.Compile(cfg.ExpectBodyRegex)
+		if err != nil {
+			return "期望响应体正则表达式错误: " + err.Error()
+		}
+		if !re.Match(body) {
+			return "响应内容不匹配期望的正则表达式"
+		}
+	}
+	return ""
+}
+
+// snippet 截取响应体片段, 不截断多字节字符
+func snippet(body []byte, size int) string {
+	if len(body) <= size {
+		return strings.ToValidUTF8(string(body), "")
+	}
+	cut := size
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return strings.ToValidUTF8(string(body[:cut]), "") + "...(已截断)"
+}
+
+// logBuffer 暂存一次请求的执行日志, 请求结束后批量写入
+type logBuffer struct {
+	instance *model.TaskInstance
+	entries  []*model.TaskLog
+}
+
+func newLogBuffer(instance *model.TaskInstance) *logBuffer {
+	return &logBuffer{instance: instance}
+}
+
+func (b *logBuffer) add(level, content string) {
+	b.entries = append(b.entries, &model.TaskLog{
+		InstanceID: b.instance.ID,
+		TaskID:     b.instance.TaskID,
+		LogTime:    time.Now(),
+		LogLevel:   level,
+		LogContent: content,
+	})
+}
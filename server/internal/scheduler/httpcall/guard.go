@@ -0,0 +1,94 @@
+package httpcall
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrAddressNotAllowed = errors.New("不允许请求回环、链路本地或内网地址")
+	ErrTooManyRedirects  = errors.New("重定向次数过多")
+)
+
+// 请求限制
+const (
+	maxRedirects   = 10
+	dialTimeout    = 10 * time.Second
+	dialKeepAlive  = 30 * time.Second
+	idleConnection = 90 * time.Second
+)
+
+// deniedPrefixes 默认禁止请求的地址段(IsLoopback/IsPrivate等未覆盖的部分)
+var deniedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"), // 运营商级NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF协议分配
+	netip.MustParsePrefix("198.18.0.0/15"), // 基准测试
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, 可映射到内网IPv4地址
+}
+
+// addressDenied 地址是否为回环、链路本地、内网或其他非公网地址
+func addressDenied(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+	for _, prefix := range deniedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// newHTTPClient 创建发起HTTP_CALL请求的客户端
+// 建立连接时按解析后的IP校验地址(防止域名解析到内网地址), 每次重定向重新校验请求地址;
+// 不使用环境变量中的代理, 否则地址校验只作用于代理
+func newHTTPClient(allowedHosts []string, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: dialKeepAlive,
+	}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if addressDenied(addr) {
+				return fmt.Errorf("%w: %s", ErrAddressNotAllowed, addr)
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       idleConnection,
+			TLSHandshakeTimeout:   dialTimeout,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return ErrTooManyRedirects
+			}
+			_, err := checkURL(req.URL.String(), allowedHosts)
+			return err
+		},
+	}
+}
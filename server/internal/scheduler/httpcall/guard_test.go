@@ -0,0 +1,75 @@
+package httpcall
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestAddressDenied(t *testing.T) {
+	tests := []struct {
+		addr   string
+		denied bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"64:ff9b::a00:1", true},
+		{"8.8.8.8", false},
+		{"2001:4860:4860::8888", false},
+	}
+	for _, tt := range tests {
+		if got := addressDenied(netip.MustParseAddr(tt.addr)); got != tt.denied {
+			t.Errorf("addressDenied(%s) = %v, want %v", tt.addr, got, tt.denied)
+		}
+	}
+}
+
+func TestClientDeniesPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := newHTTPClient(nil, false).Get(server.URL)
+	if !errors.Is(err, ErrAddressNotAllowed) {
+		t.Fatalf("request to loopback: err = %v, want ErrAddressNotAllowed", err)
+	}
+
+	resp, err := newHTTPClient(nil, true).Get(server.URL)
+	if err != nil {
+		t.Fatalf("request with allow_private: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestClientChecksRedirect(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+	targetURL, _ := url.Parse(target.URL)
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 允许列表中只有127.0.0.1, 重定向到localhost需要重新校验
+		http.Redirect(w, r, "http://localhost:"+targetURL.Port(), http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	_, err := newHTTPClient([]string{"127.0.0.1"}, true).Get(redirect.URL)
+	if !errors.Is(err, ErrHostNotAllowed) {
+		t.Fatalf("redirect to disallowed host: err = %v, want ErrHostNotAllowed", err)
+	}
+
+	resp, err := newHTTPClient([]string{"127.0.0.1", "localhost"}, true).Get(redirect.URL)
+	if err != nil {
+		t.Fatalf("redirect to allowed host: %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,193 @@
+package httpcall
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"distributed-scheduler/internal/model"
+)
+
+var (
+	ErrInvalidConfig  = errors.New("无效的HTTP请求配置")
+	ErrHostNotAllowed = errors.New("请求的主机不在允许列表中")
+	ErrCallerBusy     = errors.New("HTTP请求协程池已满")
+)
+
+// 支持的请求方法
+var methods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+}
+
+// TemplateData 渲染URL、请求头和请求体模板时可引用的变量
+type TemplateData struct {
+	TaskID      uint64
+	InstanceID  uint64
+	Param       string
+	ShardIndex  uint
+	ShardTotal  uint
+	TriggerType string
+	TriggerTime time.Time
+}
+
+// NewTemplateData 根据实例生成模板变量
+func NewTemplateData(instance *model.TaskInstance) *TemplateData {
+	return &TemplateData{
+		TaskID:      instance.TaskID,
+		InstanceID:  instance.ID,
+		Param:       instance.ExecutorParam,
+		ShardIndex:  instance.ShardIndex,
+		ShardTotal:  instance.ShardTotal,
+		TriggerType: instance.TriggerType,
+		TriggerTime: instance.TriggerTime,
+	}
+}
+
+// Validate 校验请求配置, 设置默认请求方法; allowedHosts为空时不限制主机
+// 模板使用空变量试渲染以发现引用不存在的变量; 不含模板的URL在此校验协议和主机, 含模板的URL在渲染后校验
+func Validate(cfg *model.HTTPCallConfig, allowedHosts []string) error {
+	if cfg == nil {
+		return fmt.Errorf("%w: HTTP_CALL类型任务必须设置请求配置", ErrInvalidConfig)
+	}
+	cfg.Method = strings.ToUpper(strings.TrimSpace(cfg.Method))
+	if cfg.Method == "" {
+		cfg.Method = http.MethodGet
+	}
+	if !methods[cfg.Method] {
+		return fmt.Errorf("%w: 不支持的请求方法 %s", ErrInvalidConfig, cfg.Method)
+	}
+
+	cfg.URL = strings.TrimSpace(cfg.URL)
+	if cfg.URL == "" {
+		return fmt.Errorf("%w: 请求地址不能为空", ErrInvalidConfig)
+	}
+	if _, err := execute("url", cfg.URL, &TemplateData{}); err != nil {
+		return fmt.Errorf("%w: 请求地址模板错误: %v", ErrInvalidConfig, err)
+	}
+	if !strings.Contains(cfg.URL, "{{") {
+		if _, err := checkURL(cfg.URL, allowedHosts); err != nil {
+			return err
+		}
+	}
+
+	for name, value := range cfg.Headers {
+		if name == "" || strings.ContainsAny(name, " :\r\n") {
+			return fmt.Errorf("%w: 无效的请求头 %q", ErrInvalidConfig, name)
+		}
+		if _, err := execute(name, value, &TemplateData{}); err != nil {
+			return fmt.Errorf("%w: 请求头%s模板错误: %v", ErrInvalidConfig, name, err)
+		}
+	}
+	if _, err := execute("body", cfg.Body, &TemplateData{}); err != nil {
+		return fmt.Errorf("%w: 请求体模板错误: %v", ErrInvalidConfig, err)
+	}
+
+	for _, code := range cfg.ExpectStatus {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("%w: 无效的期望状态码 %d", ErrInvalidConfig, code)
+		}
+	}
+	if cfg.ExpectBodyRegex != "" {
+		if _, err := regexp.Compile(cfg.ExpectBodyRegex); err != nil {
+			return fmt.Errorf("%w: 期望响应体正则表达式错误: %v", ErrInvalidConfig, err)
+		}
+	}
+	return nil
+}
+
+// request 渲染后的请求
+type request struct {
+	method  string
+	url     string
+	headers map[string]string
+	body    string
+}
+
+// render 使用实例变量渲染请求配置
+func render(cfg *model.HTTPCallConfig, data *TemplateData) (*request, error) {
+	req := &request{method: cfg.Method, headers: make(map[string]string, len(cfg.Headers))}
+	if req.method == "" {
+		req.method = http.MethodGet
+	}
+
+	var err error
+	if req.url, err = execute("url", cfg.URL, data); err != nil {
+		return nil, fmt.Errorf("渲染请求地址失败: %w", err)
+	}
+	for name, value := range cfg.Headers {
+		if req.headers[name], err = execute(name, value, data); err != nil {
+			return nil, fmt.Errorf("渲染请求头%s失败: %w", name, err)
+		}
+	}
+	if req.body, err = execute("body", cfg.Body, data); err != nil {
+		return nil, fmt.Errorf("渲染请求体失败: %w", err)
+	}
+	return req, nil
+}
+
+// checkURL 校验请求地址的协议和主机
+func checkURL(rawURL string, allowedHosts []string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 请求地址错误: %v", ErrInvalidConfig, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: 请求地址只支持http和https协议", ErrInvalidConfig)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("%w: 请求地址缺少主机", ErrInvalidConfig)
+	}
+	if !hostAllowed(u.Hostname(), allowedHosts) {
+		return nil, fmt.Errorf("%w: %w: %s", ErrInvalidConfig, ErrHostNotAllowed, u.Hostname())
+	}
+	return u, nil
+}
+
+// hostAllowed 主机是否在允许列表中, 列表项支持"*.example.com"形式的通配
+func hostAllowed(host string, allowedHosts []string) bool {
+	if len(allowedHosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// execute 渲染模板, 不含模板语法时直接返回原文
+func execute(name, text string, data *TemplateData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,109 @@
+package httpcall
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"distributed-scheduler/internal/model"
+)
+
+func TestHostAllowed(t *testing.T) {
+	allowed := []string{"api.example.com", " *.internal.example.com "}
+	tests := []struct {
+		host    string
+		allowed []string
+		want    bool
+	}{
+		{"anything.com", nil, true},
+		{"api.example.com", allowed, true},
+		{"API.Example.com", allowed, true},
+		{"svc.internal.example.com", allowed, true},
+		{"a.b.internal.example.com", allowed, true},
+		{"example.com", allowed, false},
+		{"evil-api.example.com", allowed, false},
+		{"internal.example.com.evil.com", allowed, false},
+	}
+	for _, tt := range tests {
+		if got := hostAllowed(tt.host, tt.allowed); got != tt.want {
+			t.Errorf("hostAllowed(%q, %v) = %v, want %v", tt.host, tt.allowed, got, tt.want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	allowed := []string{"api.example.com"}
+	if _, err := checkURL("https://api.example.com:8443/run?x=1", allowed); err != nil {
+		t.Errorf("allowed url: %v", err)
+	}
+	for _, rawURL := range []string{"ftp://api.example.com/", "file:///etc/passwd", "http:///path", "://bad"} {
+		if _, err := checkURL(rawURL, allowed); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("checkURL(%q): err = %v, want ErrInvalidConfig", rawURL, err)
+		}
+	}
+	_, err := checkURL("http://other.example.com/", allowed)
+	if !errors.Is(err, ErrHostNotAllowed) || !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("disallowed host: err = %v, want ErrHostNotAllowed", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := &model.HTTPCallConfig{Method: " post ", URL: " https://api.example.com/{{.TaskID}} "}
+	if err := Validate(cfg, []string{"other.com"}); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if cfg.Method != http.MethodPost || cfg.URL != "https://api.example.com/{{.TaskID}}" {
+		t.Errorf("method and url should be normalized, got %q %q", cfg.Method, cfg.URL)
+	}
+
+	invalid := []*model.HTTPCallConfig{
+		nil,
+		{URL: ""},
+		{Method: "TRACE", URL: "http://a.com"},
+		{URL: "http://a.com/{{.Missing}}"},
+		{URL: "http://a.com/{{"},
+		{URL: "http://a.com", Headers: map[string]string{"Bad Name": "x"}},
+		{URL: "http://a.com", Body: "{{.Nope}}"},
+		{URL: "http://a.com", ExpectStatus: []int{99}},
+		{URL: "http://a.com", ExpectBodyRegex: "("},
+	}
+	for i, c := range invalid {
+		if err := Validate(c, nil); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("invalid config %d: err = %v, want ErrInvalidConfig", i, err)
+		}
+	}
+	if err := Validate(&model.HTTPCallConfig{URL: "http://a.com"}, []string{"b.com"}); !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("static url with disallowed host: err = %v, want ErrHostNotAllowed", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	triggerTime := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	data := NewTemplateData(&model.TaskInstance{
+		ID: 7, TaskID: 3, ExecutorParam: "p", ShardIndex: 1, ShardTotal: 4,
+		TriggerType: model.TriggerTypeRetry, TriggerTime: triggerTime,
+	})
+	cfg := &model.HTTPCallConfig{
+		URL:     "https://api.example.com/tasks/{{.TaskID}}/run?shard={{.ShardIndex}}/{{.ShardTotal}}",
+		Headers: map[string]string{"X-Instance": "{{.InstanceID}}", "X-Static": "v"},
+		Body:    `{"param":"{{.Param}}","trigger":"{{.TriggerType}}","at":{{.TriggerTime.Unix}}}`,
+	}
+
+	req, err := render(cfg, data)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if req.method != http.MethodGet {
+		t.Errorf("method = %q, want GET by default", req.method)
+	}
+	if want := "https://api.example.com/tasks/3/run?shard=1/4"; req.url != want {
+		t.Errorf("url = %q, want %q", req.url, want)
+	}
+	if req.headers["X-Instance"] != "7" || req.headers["X-Static"] != "v" {
+		t.Errorf("headers = %v", req.headers)
+	}
+	if want := `{"param":"p","trigger":"` + model.TriggerTypeRetry + `","at":1714550400}`; req.body != want {
+		t.Errorf("body = %q, want %q", req.body, want)
+	}
+}
@@ -0,0 +1,177 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/repository"
+	"distributed-scheduler/internal/service"
+	"distributed-scheduler/pkg/logger"
+)
+
+// 默认配置
+const (
+	defaultInterval         = 10 * time.Second
+	defaultHeartbeatTimeout = 90 * time.Second
+	defaultPruneAfter       = 24 * time.Hour
+	failoverBatchSize       = 500
+)
+
+// ExecutorMonitor 执行器监控
+// 定期将心跳超时的执行器标记为离线并触发告警, 对其上执行中的实例进行故障转移, 并清理离线时间过长的执行器记录
+type ExecutorMonitor struct {
+	executorService  service.ExecutorService
+	instanceService  service.InstanceService
+	alarmService     service.AlarmService
+	configRepo       repository.SysConfigRepository
+	interval         time.Duration
+	heartbeatTimeout time.Duration
+	pruneAfter       time.Duration
+
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewExecutorMonitor 创建执行器监控
+func NewExecutorMonitor() *ExecutorMonitor {
+	m := &ExecutorMonitor{
+		executorService:  service.NewExecutorService(),
+		instanceService:  service.NewInstanceService(),
+		alarmService:     service.NewAlarmService(),
+		configRepo:       repository.NewSysConfigRepository(),
+		interval:         defaultInterval,
+		heartbeatTimeout: defaultHeartbeatTimeout,
+		pruneAfter:       defaultPruneAfter,
+		stopCh:           make(chan struct{}),
+	}
+
+	if cfg := config.GetConfig(); cfg != nil {
+		monitorCfg := cfg.Scheduler.Monitor
+		if monitorCfg.Interval > 0 {
+			m.interval = time.Duration(monitorCfg.Interval) * time.Second
+		}
+		if monitorCfg.HeartbeatTimeout > 0 {
+			m.heartbeatTimeout = time.Duration(monitorCfg.HeartbeatTimeout) * time.Second
+		}
+		if monitorCfg.PruneAfter > 0 {
+			m.pruneAfter = time.Duration(monitorCfg.PruneAfter) * time.Second
+		}
+	}
+	return m
+}
+
+// Start 启动执行器监控
+func (m *ExecutorMonitor) Start() {
+	m.wg.Add(1)
+	go m.run()
+	logger.Infof("执行器监控启动成功, 心跳超时: %v, 离线清理: %v", m.heartbeatTimeout, m.pruneAfter)
+}
+
+// Stop 停止执行器监控
+func (m *ExecutorMonitor) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stopCh)
+		m.wg.Wait()
+		logger.Info("执行器监控已停止")
+	})
+}
+
+// run 监控主循环
+func (m *ExecutorMonitor) run() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.check(context.Background())
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+// check 标记心跳超时的执行器, 处理离线执行器上的实例并清理长期离线的执行器
+func (m *ExecutorMonitor) check(ctx context.Context) {
+	timeout := m.deadTimeout(ctx)
+	offline, err := m.executorService.CheckOfflineExecutors(ctx, timeout)
+	if err != nil {
+		logger.Errorf("检查离线执行器失败: %v", err)
+	} else if len(offline) > 0 {
+		logger.Warnf("%d 个执行器心跳超时, 已标记为离线", len(offline))
+	}
+
+	// 每轮都处理孤立实例, 上一轮处理中断或其他调度节点标记离线的执行器也能被接管
+	results, err := m.instanceService.FailoverOrphaned(ctx, failoverBatchSize)
+	if err != nil {
+		logger.Errorf("离线执行器实例故障转移失败: %v", err)
+	}
+	failed := make(map[string]int)
+	rerouted := make(map[string]int)
+	for _, r := range results {
+		if r.Rerouted {
+			rerouted[r.ExecutorID]++
+		} else {
+			failed[r.ExecutorID]++
+		}
+	}
+	if len(results) > 0 {
+		logger.Warnf("离线执行器上的 %d 个执行中实例已故障转移", len(results))
+	}
+
+	for _, node := range offline {
+		// 摘流完成的执行器属于计划内停止, 不触发告警
+		if node.DrainStatus == model.DrainStatusDrained {
+			logger.Infof("摘流完成的执行器已停止: %s %s", node.AppName, node.Address())
+			continue
+		}
+		m.raiseOffline(ctx, node, timeout, failed[node.ID], rerouted[node.ID])
+	}
+
+	pruned, err := m.executorService.PruneOfflineExecutors(ctx, m.pruneAfter)
+	if err != nil {
+		logger.Errorf("清理离线执行器失败: %v", err)
+	} else if pruned > 0 {
+		logger.Infof("已清理 %d 个长期离线的执行器", pruned)
+	}
+}
+
+// raiseOffline 触发执行器离线告警
+func (m *ExecutorMonitor) raiseOffline(ctx context.Context, node *model.ExecutorNode, timeout time.Duration, failed, rerouted int) {
+	address := node.Address()
+	alarm := &service.Alarm{
+		Type:    model.AlarmRuleTypeExecutorOffline,
+		GroupID: node.GroupID,
+		Title:   fmt.Sprintf("执行器离线: %s %s", node.AppName, address),
+		Content: fmt.Sprintf("执行器 %s(%s) 超过 %v 未上报心跳, 已标记为离线, 最后心跳时间: %s; 执行中实例 %d 个标记为失败, %d 个重新调度",
+			address, node.ID, timeout, node.LastHeartbeat.Format(time.DateTime), failed, rerouted),
+	}
+	created, err := m.alarmService.Raise(ctx, alarm)
+	if err != nil {
+		logger.Errorf("触发执行器离线告警失败, executor_id=%s: %v", node.ID, err)
+	} else if created == 0 {
+		logger.Warnf("执行器离线未匹配到告警规则: %s", alarm.Content)
+	}
+}
+
+// deadTimeout 获取执行器离线判定时间, 优先使用系统配置executor.dead_timeout(秒)
+func (m *ExecutorMonitor) deadTimeout(ctx context.Context) time.Duration {
+	cfg, err := m.configRepo.GetByKey(ctx, model.ConfigKeyExecutorDeadTimeout)
+	if err != nil {
+		return m.heartbeatTimeout
+	}
+	seconds, err := strconv.Atoi(cfg.ConfigValue)
+	if err != nil || seconds <= 0 {
+		logger.Warnf("系统配置%s的值无效: %q", model.ConfigKeyExecutorDeadTimeout, cfg.ConfigValue)
+		return m.heartbeatTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
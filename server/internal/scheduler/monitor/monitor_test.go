@@ -0,0 +1,154 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/repository"
+	"distributed-scheduler/internal/service"
+	"distributed-scheduler/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	logger.Sugar = logger.Logger.Sugar()
+	os.Exit(m.Run())
+}
+
+// fakeExecutorService 返回固定的离线执行器, 记录检查使用的心跳超时
+type fakeExecutorService struct {
+	service.ExecutorService
+	offline []*model.ExecutorNode
+	timeout time.Duration
+}
+
+func (s *fakeExecutorService) CheckOfflineExecutors(ctx context.Context, timeout time.Duration) ([]*model.ExecutorNode, error) {
+	s.timeout = timeout
+	return s.offline, nil
+}
+
+func (s *fakeExecutorService) PruneOfflineExecutors(ctx context.Context, retention time.Duration) (int64, error) {
+	return 0, nil
+}
+
+// fakeInstanceService 返回固定的故障转移结果
+type fakeInstanceService struct {
+	service.InstanceService
+	results []*service.FailoverResult
+	calls   int
+}
+
+func (s *fakeInstanceService) FailoverOrphaned(ctx context.Context, limit int) ([]*service.FailoverResult, error) {
+	s.calls++
+	return s.results, nil
+}
+
+// fakeAlarmService 记录触发的告警
+type fakeAlarmService struct {
+	service.AlarmService
+	alarms []*service.Alarm
+}
+
+func (s *fakeAlarmService) Raise(ctx context.Context, alarm *service.Alarm) (int, error) {
+	s.alarms = append(s.alarms, alarm)
+	return 1, nil
+}
+
+// fakeConfigRepo 返回executor.dead_timeout配置, value为空时表示未配置
+type fakeConfigRepo struct {
+	repository.SysConfigRepository
+	value string
+}
+
+func (r *fakeConfigRepo) GetByKey(ctx context.Context, key string) (*model.SysConfig, error) {
+	if r.value == "" || key != model.ConfigKeyExecutorDeadTimeout {
+		return nil, errors.New("配置不存在")
+	}
+	return &model.SysConfig{ConfigKey: key, ConfigValue: r.value}, nil
+}
+
+func newTestMonitor(executors *fakeExecutorService, instances *fakeInstanceService, alarms *fakeAlarmService, deadTimeout string) *ExecutorMonitor {
+	return &ExecutorMonitor{
+		executorService:  executors,
+		instanceService:  instances,
+		alarmService:     alarms,
+		configRepo:       &fakeConfigRepo{value: deadTimeout},
+		heartbeatTimeout: defaultHeartbeatTimeout,
+		pruneAfter:       defaultPruneAfter,
+	}
+}
+
+func TestCheckRaisesOfflineAlarm(t *testing.T) {
+	executors := &fakeExecutorService{offline: []*model.ExecutorNode{
+		{ID: "e1", GroupID: 1, AppName: "demo", Host: "10.0.0.1", Port: 9999},
+		{ID: "e2", GroupID: 1, AppName: "demo", Host: "10.0.0.2", Port: 9999, DrainStatus: model.DrainStatusDrained},
+	}}
+	instances := &fakeInstanceService{results: []*service.FailoverResult{
+		{InstanceID: 1, ExecutorID: "e1"},
+		{InstanceID: 2, ExecutorID: "e1", Rerouted: true},
+		{InstanceID: 3, ExecutorID: "e1", Rerouted: true},
+	}}
+	alarms := &fakeAlarmService{}
+	m := newTestMonitor(executors, instances, alarms, "")
+
+	m.check(context.Background())
+
+	if executors.timeout != defaultHeartbeatTimeout {
+		t.Errorf("心跳超时 = %v, want %v", executors.timeout, defaultHeartbeatTimeout)
+	}
+	if instances.calls != 1 {
+		t.Fatalf("FailoverOrphaned调用%d次, want 1", instances.calls)
+	}
+	// 摘流完成的执行器属于计划内停止, 只对e1告警
+	if len(alarms.alarms) != 1 {
+		t.Fatalf("告警%d条, want 1", len(alarms.alarms))
+	}
+	alarm := alarms.alarms[0]
+	if alarm.Type != model.AlarmRuleTypeExecutorOffline || alarm.GroupID != 1 {
+		t.Errorf("alarm = %+v", alarm)
+	}
+	if !strings.Contains(alarm.Content, "1 个标记为失败, 2 个重新调度") {
+		t.Errorf("告警内容未包含故障转移结果: %s", alarm.Content)
+	}
+}
+
+func TestCheckFailsOverWithoutNewOfflineExecutors(t *testing.T) {
+	instances := &fakeInstanceService{results: []*service.FailoverResult{{InstanceID: 1, ExecutorID: "e1"}}}
+	alarms := &fakeAlarmService{}
+	m := newTestMonitor(&fakeExecutorService{}, instances, alarms, "")
+
+	m.check(context.Background())
+
+	// 其他调度节点标记离线的执行器上的实例也由本节点接管, 但不重复告警
+	if instances.calls != 1 {
+		t.Fatalf("FailoverOrphaned调用%d次, want 1", instances.calls)
+	}
+	if len(alarms.alarms) != 0 {
+		t.Fatalf("告警%d条, want 0", len(alarms.alarms))
+	}
+}
+
+func TestDeadTimeout(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", defaultHeartbeatTimeout},
+		{"30", 30 * time.Second},
+		{"0", defaultHeartbeatTimeout},
+		{"abc", defaultHeartbeatTimeout},
+	}
+	for _, tt := range tests {
+		m := newTestMonitor(nil, nil, nil, tt.value)
+		if got := m.deadTimeout(context.Background()); got != tt.want {
+			t.Errorf("deadTimeout(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
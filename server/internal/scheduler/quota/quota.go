@@ -0,0 +1,182 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+
+	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/model"
+	pkgRedis "distributed-scheduler/pkg/redis"
+)
+
+var (
+	ErrQuotaExceeded       = errors.New("并发配额已满")
+	ErrGlobalQuotaExceeded = fmt.Errorf("%w: 全局", ErrQuotaExceeded)
+	ErrGroupQuotaExceeded  = fmt.Errorf("%w: 任务组", ErrQuotaExceeded)
+	ErrTaskQuotaExceeded   = fmt.Errorf("%w: 任务", ErrQuotaExceeded)
+)
+
+// Redis键, 所有配额键使用相同的hash tag, Redis Cluster中位于同一个槽, Lua脚本可以同时访问
+const (
+	keyPrefix = "quota:{quota}:"
+	keyIndex  = keyPrefix + "keys" // 所有配额键的索引, 用于对账
+	keyGlobal = keyPrefix + "global"
+)
+
+// acquireScript 原子地检查并占用所有维度的配额
+// KEYS[1]: 配额键索引; KEYS[2..]: 各维度的集合键; ARGV[1]: 实例ID; ARGV[i]: KEYS[i]的上限(0表示不限制)
+// 返回0表示成功, 否则返回超限维度的序号(从1开始)
+var acquireScript = redis.NewScript(`
+for i = 2, #KEYS do
+	if redis.call("SISMEMBER", KEYS[i], ARGV[1]) == 0 then
+		local limit = tonumber(ARGV[i])
+		if limit > 0 and redis.call("SCARD", KEYS[i]) >= limit then
+			return i - 1
+		end
+	end
+end
+for i = 2, #KEYS do
+	redis.call("SADD", KEYS[i], ARGV[1])
+	redis.call("SADD", KEYS[1], KEYS[i])
+end
+return 0
+`)
+
+// Manager 并发配额管理器
+// 每个维度(全局/任务组/任务)在Redis中对应一个集合, 成员为占用配额的实例ID,
+// 集合大小即当前并发数; 多个调度节点通过Lua脚本原子地检查并占用配额
+type Manager struct {
+	globalLimit uint
+}
+
+// NewManager 创建配额管理器
+func NewManager() *Manager {
+	m := &Manager{}
+	if cfg := config.GetConfig(); cfg != nil {
+		m.globalLimit = cfg.Scheduler.Quota.GlobalMaxConcurrent
+	}
+	return m
+}
+
+// enabled Redis未初始化时不限制并发
+func (m *Manager) enabled() bool {
+	return pkgRedis.GetClient() != nil
+}
+
+// Acquire 为实例占用配额, 超限时返回ErrGlobalQuotaExceeded/ErrGroupQuotaExceeded/ErrTaskQuotaExceeded
+// 重复占用同一实例的配额是幂等的; 实例的Task及Task.Group需已加载
+func (m *Manager) Acquire(ctx context.Context, instance *model.TaskInstance) error {
+	if !m.enabled() {
+		return nil
+	}
+
+	var groupLimit, taskLimit uint
+	if instance.Task != nil {
+		taskLimit = instance.Task.MaxConcurrent
+		if instance.Task.Group != nil {
+			groupLimit = instance.Task.Group.MaxConcurrent
+		}
+	}
+
+	keys := scopeKeys(instance)
+	limits := []uint{m.globalLimit, groupLimit, taskLimit}
+	args := []interface{}{instance.ID}
+	for _, limit := range limits {
+		args = append(args, limit)
+	}
+
+	result, err := pkgRedis.RunScript(ctx, acquireScript, append([]string{keyIndex}, keys...), args...)
+	if err != nil {
+		return err
+	}
+
+	switch result.(int64) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("%w并发数已达上限(%d)", ErrGlobalQuotaExceeded, limits[0])
+	case 2:
+		return fmt.Errorf("%w并发数已达上限(%d)", ErrGroupQuotaExceeded, limits[1])
+	default:
+		return fmt.Errorf("%w并发数已达上限(%d)", ErrTaskQuotaExceeded, limits[2])
+	}
+}
+
+// Release 释放实例占用的配额, 未占用时无影响
+func (m *Manager) Release(ctx context.Context, instance *model.TaskInstance) error {
+	if !m.enabled() {
+		return nil
+	}
+	for _, key := range scopeKeys(instance) {
+		if err := pkgRedis.SRem(ctx, key, instance.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Usage 获取当前全局并发数
+func (m *Manager) Usage(ctx context.Context) (int64, error) {
+	if !m.enabled() {
+		return 0, nil
+	}
+	return pkgRedis.SCard(ctx, keyGlobal)
+}
+
+// Reconcile 对账: 移除已不在调度中/执行中的实例占用的配额
+// 调度节点异常退出时未释放的配额由此回收; activeIDs需在读取集合成员之后查询,
+// 保证对账期间新占用配额的实例不会被误删
+func (m *Manager) Reconcile(ctx context.Context, activeIDs func(ctx context.Context) ([]uint64, error)) (int, error) {
+	if !m.enabled() {
+		return 0, nil
+	}
+
+	keys, err := pkgRedis.SMembers(ctx, keyIndex)
+	if err != nil {
+		return 0, err
+	}
+	members := make(map[string][]string, len(keys))
+	for _, key := range keys {
+		ids, err := pkgRedis.SMembers(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		members[key] = ids
+	}
+
+	ids, err := activeIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	active := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		active[strconv.FormatUint(id, 10)] = true
+	}
+
+	removed := 0
+	for key, ids := range members {
+		for _, id := range ids {
+			if active[id] {
+				continue
+			}
+			if err := pkgRedis.SRem(ctx, key, id); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// scopeKeys 实例所属的配额键: 全局、任务组、任务
+func scopeKeys(instance *model.TaskInstance) []string {
+	return []string{
+		keyGlobal,
+		keyPrefix + "group:" + strconv.FormatUint(instance.GroupID, 10),
+		keyPrefix + "task:" + strconv.FormatUint(instance.TaskID, 10),
+	}
+}
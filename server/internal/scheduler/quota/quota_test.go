@@ -0,0 +1,139 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"distributed-scheduler/internal/model"
+	pkgRedis "distributed-scheduler/pkg/redis"
+)
+
+// setupRedis 使用miniredis替代Redis
+func setupRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	pkgRedis.Client = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		pkgRedis.Client.Close()
+		pkgRedis.Client = nil
+	})
+	return mr
+}
+
+// newInstance 创建任务组上限为groupLimit、任务上限为taskLimit的实例
+func newInstance(id, groupID, taskID uint64, groupLimit, taskLimit uint) *model.TaskInstance {
+	return &model.TaskInstance{
+		ID:      id,
+		GroupID: groupID,
+		TaskID:  taskID,
+		Task: &model.Task{
+			ID:            taskID,
+			MaxConcurrent: taskLimit,
+			Group:         &model.TaskGroup{ID: groupID, MaxConcurrent: groupLimit},
+		},
+	}
+}
+
+func TestAcquireAndRelease(t *testing.T) {
+	setupRedis(t)
+	ctx := context.Background()
+	m := &Manager{globalLimit: 3}
+
+	if err := m.Acquire(ctx, newInstance(1, 1, 1, 2, 1)); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	// 重复占用是幂等的
+	if err := m.Acquire(ctx, newInstance(1, 1, 1, 2, 1)); err != nil {
+		t.Fatalf("重复占用同一实例的配额应成功, 实际: %v", err)
+	}
+	if err := m.Acquire(ctx, newInstance(2, 1, 1, 2, 1)); !errors.Is(err, ErrTaskQuotaExceeded) {
+		t.Fatalf("任务并发已满时应返回ErrTaskQuotaExceeded, 实际: %v", err)
+	}
+	if err := m.Acquire(ctx, newInstance(3, 1, 2, 2, 0)); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if err := m.Acquire(ctx, newInstance(4, 1, 3, 2, 0)); !errors.Is(err, ErrGroupQuotaExceeded) {
+		t.Fatalf("任务组并发已满时应返回ErrGroupQuotaExceeded, 实际: %v", err)
+	}
+	if err := m.Acquire(ctx, newInstance(5, 2, 4, 0, 0)); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	err := m.Acquire(ctx, newInstance(6, 3, 5, 0, 0))
+	if !errors.Is(err, ErrGlobalQuotaExceeded) || !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("全局并发已满时应返回ErrGlobalQuotaExceeded, 实际: %v", err)
+	}
+	// 超限时不占用任何维度的配额
+	if usage, _ := m.Usage(ctx); usage != 3 {
+		t.Errorf("全局并发数应为3, 实际: %d", usage)
+	}
+
+	if err := m.Release(ctx, newInstance(1, 1, 1, 2, 1)); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err := m.Release(ctx, newInstance(1, 1, 1, 2, 1)); err != nil {
+		t.Fatalf("释放未占用的配额应无影响, 实际: %v", err)
+	}
+	if err := m.Acquire(ctx, newInstance(2, 1, 1, 2, 1)); err != nil {
+		t.Errorf("释放后应可以重新占用配额, 实际: %v", err)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	mr := setupRedis(t)
+	ctx := context.Background()
+	m := &Manager{}
+
+	for _, id := range []uint64{1, 2, 3} {
+		if err := m.Acquire(ctx, newInstance(id, 1, id, 0, 0)); err != nil {
+			t.Fatalf("Acquire: %v", err)
+		}
+	}
+
+	// 实例2已结束但未释放配额
+	removed, err := m.Reconcile(ctx, func(ctx context.Context) ([]uint64, error) {
+		return []uint64{1, 3}, nil
+	})
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("应从全局、任务组和任务三个维度移除实例2, 实际移除: %d", removed)
+	}
+	if members, _ := mr.SMembers(keyGlobal); len(members) != 2 {
+		t.Errorf("全局配额应只保留执行中的实例, 实际: %v", members)
+	}
+	if members, _ := mr.SMembers(keyPrefix + "task:2"); len(members) != 0 {
+		t.Errorf("任务2的配额应已回收, 实际: %v", members)
+	}
+
+	wantErr := errors.New("查询失败")
+	if _, err := m.Reconcile(ctx, func(ctx context.Context) ([]uint64, error) { return nil, wantErr }); !errors.Is(err, wantErr) {
+		t.Errorf("查询执行中实例失败时应返回错误, 实际: %v", err)
+	}
+}
+
+func TestDisabledWithoutRedis(t *testing.T) {
+	m := &Manager{globalLimit: 1}
+	ctx := context.Background()
+	for _, id := range []uint64{1, 2} {
+		if err := m.Acquire(ctx, newInstance(id, 1, 1, 1, 1)); err != nil {
+			t.Errorf("Redis未初始化时不应限制并发, 实际: %v", err)
+		}
+	}
+}
+
+func TestKeysShareHashTag(t *testing.T) {
+	// Lua脚本访问的所有键需位于Redis Cluster的同一个槽
+	keys := append([]string{keyIndex}, scopeKeys(newInstance(1, 2, 3, 0, 0))...)
+	for _, key := range keys {
+		start, end := strings.Index(key, "{"), strings.Index(key, "}")
+		if start < 0 || end <= start+1 || key[start:end+1] != "{quota}" {
+			t.Errorf("配额键%s应使用hash tag {quota}", key)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package router
+
+import (
+	"hash/crc32"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+
+	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/model"
+)
+
+// DefaultReplicas 默认每个节点的虚拟节点数
+const DefaultReplicas = 160
+
+// HashFunc 哈希函数
+type HashFunc func(data []byte) uint64
+
+// HashXXHash xxhash哈希(默认)
+func HashXXHash(data []byte) uint64 {
+	return xxhash.Sum64(data)
+}
+
+// HashCRC32 CRC32哈希(兼容旧版本)
+func HashCRC32(data []byte) uint64 {
+	return uint64(crc32.ChecksumIEEE(data))
+}
+
+// hashFuncs 内置哈希函数
+var hashFuncs = map[string]HashFunc{
+	"xxhash": HashXXHash,
+	"crc32":  HashCRC32,
+}
+
+// HashFuncByName 根据名称获取哈希函数, 未知名称返回xxhash
+func HashFuncByName(name string) HashFunc {
+	if fn, ok := hashFuncs[strings.ToLower(name)]; ok {
+		return fn
+	}
+	return HashXXHash
+}
+
+// ConsistentHashStrategy 一致性哈希策略
+// 哈希环只在执行器成员变化时重建; LoadFactor大于1时启用有界负载一致性哈希,
+// 单个节点的负载不超过 ceil(平均负载 * LoadFactor)
+type ConsistentHashStrategy struct {
+	Replicas   int      // 每个节点的虚拟节点数, 为0时使用DefaultReplicas
+	Hash       HashFunc // 哈希函数, 为nil时使用xxhash
+	LoadFactor float64  // 有界负载系数, 小于等于1时不限制负载
+
+	mu   sync.RWMutex
+	ring *ConsistentHashRing
+}
+
+// NewConsistentHashStrategy 创建一致性哈希策略
+func NewConsistentHashStrategy(replicas int, hash HashFunc, loadFactor float64) *ConsistentHashStrategy {
+	return &ConsistentHashStrategy{
+		Replicas:   replicas,
+		Hash:       hash,
+		LoadFactor: loadFactor,
+	}
+}
+
+// newConsistentHashFromConfig 根据全局配置创建一致性哈希策略
+func newConsistentHashFromConfig() *ConsistentHashStrategy {
+	cfg := config.GetConfig()
+	if cfg == nil {
+		return &ConsistentHashStrategy{}
+	}
+	chCfg := cfg.Scheduler.ConsistentHash
+	return NewConsistentHashStrategy(chCfg.Replicas, HashFuncByName(chCfg.HashFunc), chCfg.LoadFactor)
+}
+
+func (s *ConsistentHashStrategy) Select(executors []*model.ExecutorNode, param string) (*model.ExecutorNode, error) {
+	if len(executors) == 0 {
+		return nil, ErrNoAvailableExecutor
+	}
+
+	available := filterAvailable(executors)
+	if len(available) == 0 {
+		return nil, ErrNoAvailableExecutor
+	}
+
+	nodes := make(map[string]*model.ExecutorNode, len(available))
+	for _, node := range available {
+		nodes[node.ID] = node
+	}
+
+	ring := s.getRing(available)
+
+	if s.LoadFactor <= 1 {
+		return nodes[ring.Get(param)], nil
+	}
+
+	// 有界负载: 沿哈希环顺时针查找第一个未超过负载上限的节点
+	var totalLoad uint
+	for _, node := range available {
+		totalLoad += node.CurrentLoad
+	}
+	capacity := uint(math.Ceil(float64(totalLoad+1) / float64(len(available)) * s.LoadFactor))
+
+	var selected *model.ExecutorNode
+	ring.Walk(param, func(nodeID string) bool {
+		node := nodes[nodeID]
+		if selected == nil {
+			selected = node
+		}
+		if node.CurrentLoad+1 <= capacity {
+			selected = node
+			return false
+		}
+		return true
+	})
+
+	return selected, nil
+}
+
+// Ring 获取当前哈希环
+func (s *ConsistentHashStrategy) Ring() *ConsistentHashRing {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ring
+}
+
+// getRing 获取哈希环, 成员变化时重建
+func (s *ConsistentHashStrategy) getRing(executors []*model.ExecutorNode) *ConsistentHashRing {
+	ids := make([]string, 0, len(executors))
+	for _, node := range executors {
+		ids = append(ids, node.ID)
+	}
+	sort.Strings(ids)
+	members := strings.Join(ids, ",")
+
+	s.mu.RLock()
+	ring := s.ring
+	s.mu.RUnlock()
+	if ring != nil && ring.members == members {
+		return ring
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ring != nil && s.ring.members == members {
+		return s.ring
+	}
+	s.ring = NewConsistentHashRing(s.Replicas, s.Hash, ids)
+	return s.ring
+}
+
+// ConsistentHashRing 一致性哈希环(创建后只读)
+type ConsistentHashRing struct {
+	replicas    int
+	hash        HashFunc
+	members     string
+	hashedNodes []uint64
+	circle      map[uint64]string
+	nodeCount   int
+}
+
+// NewConsistentHashRing 创建一致性哈希环
+func NewConsistentHashRing(replicas int, hash HashFunc, nodeIDs []string) *ConsistentHashRing {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	if hash == nil {
+		hash = HashXXHash
+	}
+
+	ids := make([]string, len(nodeIDs))
+	copy(ids, nodeIDs)
+	sort.Strings(ids)
+
+	ring := &ConsistentHashRing{
+		replicas:    replicas,
+		hash:        hash,
+		members:     strings.Join(ids, ","),
+		hashedNodes: make([]uint64, 0, len(ids)*replicas),
+		circle:      make(map[uint64]string, len(ids)*replicas),
+		nodeCount:   len(ids),
+	}
+
+	for _, id := range ids {
+		for i := 0; i < replicas; i++ {
+			h := hash([]byte(id + "#" + strconv.Itoa(i)))
+			if _, exists := ring.circle[h]; exists {
+				continue
+			}
+			ring.circle[h] = id
+			ring.hashedNodes = append(ring.hashedNodes, h)
+		}
+	}
+	sort.Slice(ring.hashedNodes, func(i, j int) bool {
+		return ring.hashedNodes[i] < ring.hashedNodes[j]
+	})
+
+	return ring
+}
+
+// Get 获取key对应的节点ID
+func (r *ConsistentHashRing) Get(key string) string {
+	if len(r.hashedNodes) == 0 {
+		return ""
+	}
+	return r.circle[r.hashedNodes[r.search(key)]]
+}
+
+// Walk 从key所在位置顺时针遍历不重复的节点, fn返回false时停止
+func (r *ConsistentHashRing) Walk(key string, fn func(nodeID string) bool) {
+	if len(r.hashedNodes) == 0 {
+		return
+	}
+
+	visited := make(map[string]struct{}, r.nodeCount)
+	start := r.search(key)
+	for i := 0; i < len(r.hashedNodes) && len(visited) < r.nodeCount; i++ {
+		id := r.circle[r.hashedNodes[(start+i)%len(r.hashedNodes)]]
+		if _, ok := visited[id]; ok {
+			continue
+		}
+		visited[id] = struct{}{}
+		if !fn(id) {
+			return
+		}
+	}
+}
+
+// search 查找key在环上的位置
+func (r *ConsistentHashRing) search(key string) int {
+	h := r.hash([]byte(key))
+	idx := sort.Search(len(r.hashedNodes), func(i int) bool {
+		return r.hashedNodes[i] >= h
+	})
+	if idx >= len(r.hashedNodes) {
+		idx = 0
+	}
+	return idx
+}
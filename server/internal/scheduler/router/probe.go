@@ -0,0 +1,221 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/model"
+)
+
+var (
+	ErrExecutorBusy        = errors.New("执行器忙碌")
+	ErrExecutorUnreachable = errors.New("执行器不可达")
+)
+
+// 默认探测超时时间
+const DefaultProbeTimeout = 3 * time.Second
+
+// 探测结果
+const (
+	ProbeResultOK          = "OK"
+	ProbeResultBusy        = "BUSY"
+	ProbeResultUnreachable = "UNREACHABLE"
+)
+
+// Prober 执行器探测器
+type Prober interface {
+	// Beat 健康检查, 执行器不可达时返回ErrExecutorUnreachable
+	Beat(ctx context.Context, node *model.ExecutorNode) error
+	// IdleBeat 空闲检查, 执行器忙碌时返回ErrExecutorBusy
+	IdleBeat(ctx context.Context, node *model.ExecutorNode) error
+}
+
+// HTTPProber 基于HTTP协议的执行器探测器
+type HTTPProber struct {
+	client *http.Client
+}
+
+// NewHTTPProber 创建HTTP探测器
+func NewHTTPProber(timeout time.Duration) *HTTPProber {
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+	return &HTTPProber{
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Beat 健康检查
+func (p *HTTPProber) Beat(ctx context.Context, node *model.ExecutorNode) error {
+	return p.call(ctx, node, model.ExecutorPathBeat, ErrExecutorUnreachable)
+}
+
+// IdleBeat 空闲检查
+func (p *HTTPProber) IdleBeat(ctx context.Context, node *model.ExecutorNode) error {
+	return p.call(ctx, node, model.ExecutorPathIdleBeat, ErrExecutorBusy)
+}
+
+// call 调用执行器探测接口, 业务码非0时返回rejectErr
+func (p *HTTPProber) call(ctx context.Context, node *model.ExecutorNode, path string, rejectErr error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+node.Address()+path, bytes.NewReader([]byte("{}")))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrExecutorUnreachable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: HTTP %d", ErrExecutorUnreachable, resp.StatusCode)
+	}
+
+	var result response.Response
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("%w: 响应解析失败: %v", ErrExecutorUnreachable, err)
+	}
+	if result.Code != response.CodeSuccess {
+		return fmt.Errorf("%w: %s", rejectErr, result.Message)
+	}
+	return nil
+}
+
+// ProbeRecord 单次探测记录
+type ProbeRecord struct {
+	ExecutorID string        `json:"executor_id"`
+	Address    string        `json:"address"`
+	Result     string        `json:"result"`
+	Message    string        `json:"message,omitempty"`
+	Cost       time.Duration `json:"cost"`
+}
+
+// ProbeTrace 探测链路, 调用方应将其写入任务实例的ResultMsg便于排查
+type ProbeTrace []ProbeRecord
+
+// String 输出探测链路, 如: 探测链路: 10.0.0.1:9090[BUSY 执行器忙碌] -> 10.0.0.2:9090[OK]
+func (t ProbeTrace) String() string {
+	if len(t) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(t))
+	for _, record := range t {
+		part := fmt.Sprintf("%s[%s %dms", record.Address, record.Result, record.Cost.Milliseconds())
+		if record.Message != "" {
+			part += " " + record.Message
+		}
+		parts = append(parts, part+"]")
+	}
+	return "探测链路: " + strings.Join(parts, " -> ")
+}
+
+// TracingStrategy 探测执行器并记录探测链路的路由策略
+type TracingStrategy interface {
+	Strategy
+	SelectWithTrace(ctx context.Context, executors []*model.ExecutorNode, param string) (*model.ExecutorNode, ProbeTrace, error)
+}
+
+// probeInOrder 按顺序探测执行器, 返回第一个探测成功的执行器
+func probeInOrder(ctx context.Context, candidates []*model.ExecutorNode, timeout time.Duration,
+	probe func(ctx context.Context, node *model.ExecutorNode) error) (*model.ExecutorNode, ProbeTrace, error) {
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+
+	trace := make(ProbeTrace, 0, len(candidates))
+	for _, node := range candidates {
+		start := time.Now()
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := probe(probeCtx, node)
+		cancel()
+
+		record := ProbeRecord{
+			ExecutorID: node.ID,
+			Address:    node.Address(),
+			Result:     ProbeResultOK,
+			Cost:       time.Since(start),
+		}
+		if err != nil {
+			record.Result = ProbeResultUnreachable
+			if errors.Is(err, ErrExecutorBusy) {
+				record.Result = ProbeResultBusy
+			}
+			record.Message = err.Error()
+		}
+		trace = append(trace, record)
+
+		if err == nil {
+			return node, trace, nil
+		}
+		if ctx.Err() != nil {
+			return nil, trace, ctx.Err()
+		}
+	}
+
+	return nil, trace, ErrNoAvailableExecutor
+}
+
+// probeCandidates 获取待探测的执行器: 数据库中在线且未摘流的节点, 未过载的优先
+// 数据库中的负载可能滞后一个心跳周期, 因此过载节点也参与探测
+func probeCandidates(executors []*model.ExecutorNode) []*model.ExecutorNode {
+	candidates := make([]*model.ExecutorNode, 0, len(executors))
+	for _, node := range executors {
+		if node.IsSchedulable() {
+			candidates = append(candidates, node)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return !candidates[i].IsOverload() && candidates[j].IsOverload()
+	})
+	return candidates
+}
+
+// BusyoverStrategy 忙碌转移策略
+// 按顺序对执行器进行空闲探测, 忙碌或不可达时顺延到下一个节点
+type BusyoverStrategy struct {
+	Prober  Prober
+	Timeout time.Duration // 单个节点的探测超时时间
+}
+
+func (s *BusyoverStrategy) Select(executors []*model.ExecutorNode, param string) (*model.ExecutorNode, error) {
+	node, _, err := s.SelectWithTrace(context.Background(), executors, param)
+	return node, err
+}
+
+// SelectWithTrace 选择执行器并返回探测链路
+func (s *BusyoverStrategy) SelectWithTrace(ctx context.Context, executors []*model.ExecutorNode, param string) (*model.ExecutorNode, ProbeTrace, error) {
+	candidates := probeCandidates(executors)
+	if len(candidates) == 0 {
+		return nil, nil, ErrNoAvailableExecutor
+	}
+
+	prober := s.Prober
+	if prober == nil {
+		prober = defaultProber
+	}
+	return probeInOrder(ctx, candidates, s.Timeout, prober.IdleBeat)
+}
+
+// WithProber 替换探测类策略(FAILOVER/BUSYOVER)的探测器, 用于非HTTP协议的执行器, 其他策略原样返回
+func WithProber(strategy Strategy, prober Prober) Strategy {
+	switch s := strategy.(type) {
+	case *FailoverStrategy:
+		s.Prober = prober
+	case *BusyoverStrategy:
+		s.Prober = prober
+	}
+	return strategy
+}
+
+// defaultProber 默认探测器
+var defaultProber Prober = NewHTTPProber(DefaultProbeTimeout)
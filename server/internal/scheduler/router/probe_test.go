@@ -0,0 +1,110 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"distributed-scheduler/internal/common/response"
+	"distributed-scheduler/internal/model"
+)
+
+// fakeProber 按执行器ID返回预设的探测结果
+type fakeProber struct {
+	errs   map[string]error
+	probed []string
+}
+
+func (p *fakeProber) Beat(ctx context.Context, node *model.ExecutorNode) error {
+	return p.IdleBeat(ctx, node)
+}
+
+func (p *fakeProber) IdleBeat(ctx context.Context, node *model.ExecutorNode) error {
+	p.probed = append(p.probed, node.ID)
+	return p.errs[node.ID]
+}
+
+func TestBusyoverProbeTrace(t *testing.T) {
+	nodes := newTestNodes(5)
+	nodes[0].CurrentLoad = nodes[0].MaxConcurrent // 过载节点最后探测
+	nodes[1].DrainStatus = model.DrainStatusDraining
+	prober := &fakeProber{errs: map[string]error{
+		"executor-2": errors.Join(ErrExecutorBusy, errors.New("running 100")),
+		"executor-3": ErrExecutorUnreachable,
+	}}
+	s := &BusyoverStrategy{Prober: prober}
+
+	node, trace, err := s.SelectWithTrace(context.Background(), nodes, "")
+	if err != nil {
+		t.Fatalf("SelectWithTrace: %v", err)
+	}
+	if node.ID != "executor-4" {
+		t.Fatalf("selected %s, want executor-4", node.ID)
+	}
+	if got := strings.Join(prober.probed, ","); got != "executor-2,executor-3,executor-4" {
+		t.Fatalf("probe order = %s, want draining skipped and overloaded last", got)
+	}
+
+	wantResults := []string{ProbeResultBusy, ProbeResultUnreachable, ProbeResultOK}
+	if len(trace) != len(wantResults) {
+		t.Fatalf("trace = %+v, want %d records", trace, len(wantResults))
+	}
+	for i, want := range wantResults {
+		if trace[i].Result != want {
+			t.Errorf("trace[%d].Result = %s, want %s", i, trace[i].Result, want)
+		}
+	}
+	str := trace.String()
+	if !strings.HasPrefix(str, "探测链路: 10.0.0.2:9090[BUSY ") || !strings.Contains(str, " -> 10.0.0.4:9090[OK ") {
+		t.Errorf("trace string = %q", str)
+	}
+
+	// 全部忙碌时返回完整链路
+	prober = &fakeProber{errs: map[string]error{
+		"executor-0": ErrExecutorBusy, "executor-2": ErrExecutorBusy,
+		"executor-3": ErrExecutorBusy, "executor-4": ErrExecutorBusy,
+	}}
+	s.Prober = prober
+	if _, trace, err := s.SelectWithTrace(context.Background(), nodes, ""); !errors.Is(err, ErrNoAvailableExecutor) || len(trace) != 4 {
+		t.Errorf("all busy: err = %v, trace = %d records, want ErrNoAvailableExecutor with 4 records", err, len(trace))
+	}
+}
+
+func TestHTTPProberIdleBeat(t *testing.T) {
+	busy := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != model.ExecutorPathIdleBeat {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		resp := response.Response{Code: response.CodeSuccess}
+		if busy {
+			resp = response.Response{Code: response.CodeError, Message: "执行器忙碌"}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+	node := &model.ExecutorNode{ID: "e", Host: host, Port: uint(portNum)}
+	prober := NewHTTPProber(0)
+
+	if err := prober.IdleBeat(context.Background(), node); err != nil {
+		t.Fatalf("idle executor: %v", err)
+	}
+	busy = true
+	if err := prober.IdleBeat(context.Background(), node); !errors.Is(err, ErrExecutorBusy) {
+		t.Errorf("busy executor: err = %v, want ErrExecutorBusy", err)
+	}
+	// 健康检查路径返回404, 视为不可达
+	if err := prober.Beat(context.Background(), node); !errors.Is(err, ErrExecutorUnreachable) {
+		t.Errorf("beat: err = %v, want ErrExecutorUnreachable", err)
+	}
+}
@@ -0,0 +1,162 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"distributed-scheduler/internal/model"
+)
+
+var (
+	ErrUnknownStrategy = errors.New("未注册的路由策略")
+	ErrInvalidOptions  = errors.New("无效的路由策略参数")
+)
+
+// Factory 路由策略工厂
+// options为任务上配置的策略参数(JSON), 未配置时为空
+type Factory func(options json.RawMessage) (Strategy, error)
+
+var (
+	registryMu sync.RWMutex
+	factories  = make(map[string]Factory)
+)
+
+// Register 注册路由策略, 同名策略会被覆盖
+// 嵌入方可在启动时注册自定义策略, 例如:
+//
+//	router.Register("COST_AWARE", func(options json.RawMessage) (router.Strategy, error) {
+//		return newCostAwareStrategy(options)
+//	})
+func Register(name string, factory Factory) {
+	if name == "" || factory == nil {
+		panic("router: 注册的策略名称和工厂不能为空")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	factories[name] = factory
+}
+
+// IsRegistered 判断路由策略是否已注册
+func IsRegistered(name string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, ok := factories[name]
+	return ok
+}
+
+// Names 获取所有已注册的路由策略名称
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New 根据策略名称和参数创建路由策略
+func New(name, options string) (Strategy, error) {
+	registryMu.RLock()
+	factory, ok := factories[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, name)
+	}
+
+	strategy, err := factory(json.RawMessage(strings.TrimSpace(options)))
+	if err != nil {
+		if errors.Is(err, ErrInvalidOptions) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidOptions, name, err)
+	}
+	return strategy, nil
+}
+
+// DecodeOptions 解析策略参数, 参数为空时保持v不变; 不允许出现未知字段
+func DecodeOptions(options json.RawMessage, v interface{}) error {
+	if len(options) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(options))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOptions, err)
+	}
+	return nil
+}
+
+// ConsistentHashOptions 一致性哈希策略参数, 未设置的字段使用配置文件中的值
+type ConsistentHashOptions struct {
+	Replicas   int      `json:"replicas"`
+	HashFunc   string   `json:"hash_func"`
+	LoadFactor *float64 `json:"load_factor"`
+}
+
+// ProbeOptions 探测类策略(FAILOVER/BUSYOVER)参数
+type ProbeOptions struct {
+	TimeoutMs int `json:"timeout_ms"` // 单个节点的探测超时时间(毫秒)
+}
+
+// simpleFactory 不需要参数的策略工厂
+func simpleFactory(create func() Strategy) Factory {
+	return func(options json.RawMessage) (Strategy, error) {
+		return create(), nil
+	}
+}
+
+func init() {
+	Register(model.RouteStrategyRoundRobin, simpleFactory(func() Strategy { return &RoundRobinStrategy{} }))
+	Register(model.RouteStrategyRandom, simpleFactory(func() Strategy { return &RandomStrategy{} }))
+	Register(model.RouteStrategyLeastFrequentlyUsed, simpleFactory(func() Strategy { return &LFUStrategy{} }))
+	Register(model.RouteStrategyLeastRecentlyUsed, simpleFactory(func() Strategy { return &LRUStrategy{} }))
+	// 分片广播由调用方按分片逐个选择执行器, 单个分片使用轮询
+	Register(model.RouteStrategyShardingBroadcast, simpleFactory(func() Strategy { return &RoundRobinStrategy{} }))
+
+	Register(model.RouteStrategyConsistentHash, func(options json.RawMessage) (Strategy, error) {
+		var opts ConsistentHashOptions
+		if err := DecodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		s := newConsistentHashFromConfig()
+		if opts.Replicas > 0 {
+			s.Replicas = opts.Replicas
+		}
+		if opts.HashFunc != "" {
+			if _, ok := hashFuncs[strings.ToLower(opts.HashFunc)]; !ok {
+				return nil, fmt.Errorf("%w: 未知的哈希函数 %s", ErrInvalidOptions, opts.HashFunc)
+			}
+			s.Hash = HashFuncByName(opts.HashFunc)
+		}
+		if opts.LoadFactor != nil {
+			s.LoadFactor = *opts.LoadFactor
+		}
+		return s, nil
+	})
+
+	Register(model.RouteStrategyFailover, func(options json.RawMessage) (Strategy, error) {
+		var opts ProbeOptions
+		if err := DecodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return &FailoverStrategy{Prober: defaultProber, Timeout: time.Duration(opts.TimeoutMs) * time.Millisecond}, nil
+	})
+
+	Register(model.RouteStrategyBusyover, func(options json.RawMessage) (Strategy, error) {
+		var opts ProbeOptions
+		if err := DecodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return &BusyoverStrategy{Prober: defaultProber, Timeout: time.Duration(opts.TimeoutMs) * time.Millisecond}, nil
+	})
+}
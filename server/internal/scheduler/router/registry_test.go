@@ -0,0 +1,112 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"distributed-scheduler/internal/model"
+)
+
+// pinnedStrategy 总是选择第一个执行器的自定义策略
+type pinnedStrategy struct{}
+
+func (pinnedStrategy) Select(executors []*model.ExecutorNode, param string) (*model.ExecutorNode, error) {
+	return executors[0], nil
+}
+
+func TestRegistry(t *testing.T) {
+	for _, name := range []string{
+		model.RouteStrategyRoundRobin, model.RouteStrategyRandom, model.RouteStrategyConsistentHash,
+		model.RouteStrategyLeastFrequentlyUsed, model.RouteStrategyLeastRecentlyUsed,
+		model.RouteStrategyFailover, model.RouteStrategyBusyover, model.RouteStrategyShardingBroadcast,
+	} {
+		if !IsRegistered(name) {
+			t.Errorf("built-in strategy %s is not registered", name)
+		}
+	}
+
+	if _, err := New("NOT_EXIST", ""); !errors.Is(err, ErrUnknownStrategy) {
+		t.Errorf("unknown strategy: err = %v, want ErrUnknownStrategy", err)
+	}
+	if _, ok := NewStrategy("NOT_EXIST").(*RoundRobinStrategy); !ok {
+		t.Error("NewStrategy should fall back to round robin for unknown strategies")
+	}
+
+	Register("TEST_PINNED", func(options json.RawMessage) (Strategy, error) {
+		return pinnedStrategy{}, nil
+	})
+	found := false
+	for _, name := range Names() {
+		found = found || name == "TEST_PINNED"
+	}
+	if !found {
+		t.Error("Names should include custom strategies")
+	}
+	s, err := New("TEST_PINNED", "")
+	if err != nil {
+		t.Fatalf("New custom strategy: %v", err)
+	}
+	if node, _ := s.Select(newTestNodes(3), ""); node.ID != "executor-0" {
+		t.Errorf("custom strategy selected %s", node.ID)
+	}
+}
+
+func TestStrategyOptions(t *testing.T) {
+	s, err := New(model.RouteStrategyFailover, ` {"timeout_ms": 250} `)
+	if err != nil {
+		t.Fatalf("New failover: %v", err)
+	}
+	if failover := s.(*FailoverStrategy); failover.Timeout != 250*time.Millisecond || failover.Prober == nil {
+		t.Errorf("failover options not applied: %+v", failover)
+	}
+
+	s, err = New(model.RouteStrategyConsistentHash, `{"replicas": 50, "hash_func": "CRC32", "load_factor": 1.5}`)
+	if err != nil {
+		t.Fatalf("New consistent hash: %v", err)
+	}
+	if hash := s.(*ConsistentHashStrategy); hash.Replicas != 50 || hash.LoadFactor != 1.5 {
+		t.Errorf("consistent hash options not applied: replicas=%d load_factor=%v", hash.Replicas, hash.LoadFactor)
+	}
+
+	for _, c := range []struct{ name, options string }{
+		{model.RouteStrategyFailover, `{"timeout": 1}`},
+		{model.RouteStrategyBusyover, `not json`},
+		{model.RouteStrategyConsistentHash, `{"hash_func": "md5x"}`},
+	} {
+		if _, err := New(c.name, c.options); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("New(%s, %s): err = %v, want ErrInvalidOptions", c.name, c.options, err)
+		}
+	}
+}
+
+func TestRandomStrategyConcurrent(t *testing.T) {
+	s, _ := New(model.RouteStrategyRandom, "")
+	nodes := newTestNodes(4)
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				node, err := s.Select(nodes, "")
+				if err != nil {
+					t.Errorf("Select: %v", err)
+					return
+				}
+				mu.Lock()
+				seen[node.ID] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(seen) != len(nodes) {
+		t.Errorf("random strategy selected %v, want every node", seen)
+	}
+}
@@ -1,9 +1,9 @@
 package router
 
 import (
+	"context"
 	"errors"
-	"hash/crc32"
-	"math/rand"
+	"math/rand/v2"
 	"sort"
 	"sync"
 	"sync/atomic"
@@ -21,22 +21,13 @@ type Strategy interface {
 	Select(executors []*model.ExecutorNode, param string) (*model.ExecutorNode, error)
 }
 
-// NewStrategy 根据策略名称创建路由策略
+// NewStrategy 根据策略名称创建路由策略(使用默认参数), 未注册的策略退化为轮询
 func NewStrategy(strategy string) Strategy {
-	switch strategy {
-	case model.RouteStrategyRandom:
-		return &RandomStrategy{}
-	case model.RouteStrategyConsistentHash:
-		return &ConsistentHashStrategy{}
-	case model.RouteStrategyLeastFrequentlyUsed:
-		return &LFUStrategy{}
-	case model.RouteStrategyLeastRecentlyUsed:
-		return &LRUStrategy{}
-	case model.RouteStrategyFailover:
-		return &FailoverStrategy{}
-	default:
+	s, err := New(strategy, "")
+	if err != nil {
 		return &RoundRobinStrategy{}
 	}
+	return s
 }
 
 // RoundRobinStrategy 轮询策略
@@ -60,9 +51,8 @@ func (s *RoundRobinStrategy) Select(executors []*model.ExecutorNode, param strin
 }
 
 // RandomStrategy 随机策略
-type RandomStrategy struct {
-	r *rand.Rand
-}
+// 使用并发安全的全局随机数生成器, 同一任务的策略实例会被多个分发协程同时使用
+type RandomStrategy struct{}
 
 func (s *RandomStrategy) Select(executors []*model.ExecutorNode, param string) (*model.ExecutorNode, error) {
 	if len(executors) == 0 {
@@ -74,69 +64,7 @@ func (s *RandomStrategy) Select(executors []*model.ExecutorNode, param string) (
 		return nil, ErrNoAvailableExecutor
 	}
 
-	if s.r == nil {
-		s.r = rand.New(rand.NewSource(time.Now().UnixNano()))
-	}
-
-	return available[s.r.Intn(len(available))], nil
-}
-
-// ConsistentHashStrategy 一致性哈希策略
-type ConsistentHashStrategy struct {
-	mu       sync.RWMutex
-	ring     *ConsistentHashRing
-	replicas int
-}
-
-type ConsistentHashRing struct {
-	hashSortedNodes []uint32
-	circle          map[uint32]*model.ExecutorNode
-}
-
-func (s *ConsistentHashStrategy) Select(executors []*model.ExecutorNode, param string) (*model.ExecutorNode, error) {
-	if len(executors) == 0 {
-		return nil, ErrNoAvailableExecutor
-	}
-
-	available := filterAvailable(executors)
-	if len(available) == 0 {
-		return nil, ErrNoAvailableExecutor
-	}
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// 重建哈希环
-	s.replicas = 100
-	s.ring = &ConsistentHashRing{
-		circle: make(map[uint32]*model.ExecutorNode),
-	}
-
-	for _, node := range available {
-		for i := 0; i < s.replicas; i++ {
-			hash := hashKey(node.ID + string(rune(i)))
-			s.ring.circle[hash] = node
-			s.ring.hashSortedNodes = append(s.ring.hashSortedNodes, hash)
-		}
-	}
-	sort.Slice(s.ring.hashSortedNodes, func(i, j int) bool {
-		return s.ring.hashSortedNodes[i] < s.ring.hashSortedNodes[j]
-	})
-
-	// 查找节点
-	hash := hashKey(param)
-	idx := sort.Search(len(s.ring.hashSortedNodes), func(i int) bool {
-		return s.ring.hashSortedNodes[i] >= hash
-	})
-	if idx >= len(s.ring.hashSortedNodes) {
-		idx = 0
-	}
-
-	return s.ring.circle[s.ring.hashSortedNodes[idx]], nil
-}
-
-func hashKey(key string) uint32 {
-	return crc32.ChecksumIEEE([]byte(key))
+	return available[rand.IntN(len(available))], nil
 }
 
 // LFUStrategy 最不经常使用策略
@@ -213,37 +141,54 @@ func (s *LRUStrategy) Select(executors []*model.ExecutorNode, param string) (*mo
 }
 
 // FailoverStrategy 故障转移策略
-type FailoverStrategy struct{}
+// 按权重从高到低依次进行心跳探测, 选择第一个存活的执行器; Prober为nil时仅依据数据库中的状态选择
+type FailoverStrategy struct {
+	Prober  Prober
+	Timeout time.Duration // 单个节点的探测超时时间
+}
 
 func (s *FailoverStrategy) Select(executors []*model.ExecutorNode, param string) (*model.ExecutorNode, error) {
+	node, _, err := s.SelectWithTrace(context.Background(), executors, param)
+	return node, err
+}
+
+// SelectWithTrace 选择执行器并返回探测链路
+func (s *FailoverStrategy) SelectWithTrace(ctx context.Context, executors []*model.ExecutorNode, param string) (*model.ExecutorNode, ProbeTrace, error) {
 	if len(executors) == 0 {
-		return nil, ErrNoAvailableExecutor
+		return nil, nil, ErrNoAvailableExecutor
 	}
 
 	// 按权重排序，选择第一个可用的
 	sorted := make([]*model.ExecutorNode, len(executors))
 	copy(sorted, executors)
-	sort.Slice(sorted, func(i, j int) bool {
+	sort.SliceStable(sorted, func(i, j int) bool {
 		return sorted[i].Weight > sorted[j].Weight
 	})
 
+	if s.Prober != nil {
+		candidates := probeCandidates(sorted)
+		if len(candidates) == 0 {
+			return nil, nil, ErrNoAvailableExecutor
+		}
+		return probeInOrder(ctx, candidates, s.Timeout, s.Prober.Beat)
+	}
+
 	for _, node := range sorted {
-		if node.IsOnline() && !node.IsOverload() {
-			return node, nil
+		if node.IsSchedulable() && !node.IsOverload() {
+			return node, nil, nil
 		}
 	}
 
-	return nil, ErrNoAvailableExecutor
+	return nil, nil, ErrNoAvailableExecutor
 }
 
 // filterAvailable 过滤可用的执行器
 func filterAvailable(executors []*model.ExecutorNode) []*model.ExecutorNode {
 	available := make([]*model.ExecutorNode, 0, len(executors))
 	for _, node := range executors {
-		if node.IsOnline() && !node.IsOverload() {
+		if node.IsSchedulable() && !node.IsOverload() {
 			available = append(available, node)
 		}
 	}
 	return available
 }
-
@@ -0,0 +1,177 @@
+package router
+
+import (
+	"fmt"
+	"testing"
+
+	"distributed-scheduler/internal/model"
+)
+
+func newTestNodes(n int) []*model.ExecutorNode {
+	nodes := make([]*model.ExecutorNode, 0, n)
+	for i := 0; i < n; i++ {
+		nodes = append(nodes, &model.ExecutorNode{
+			ID:            fmt.Sprintf("executor-%d", i),
+			Host:          fmt.Sprintf("10.0.0.%d", i),
+			Port:          9090,
+			MaxConcurrent: 100,
+			Status:        model.ExecutorStatusOnline,
+		})
+	}
+	return nodes
+}
+
+func assignKeys(t *testing.T, s Strategy, nodes []*model.ExecutorNode, keys int) map[string]string {
+	t.Helper()
+	assignment := make(map[string]string, keys)
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("task-%d", i)
+		node, err := s.Select(nodes, key)
+		if err != nil {
+			t.Fatalf("select %s: %v", key, err)
+		}
+		assignment[key] = node.ID
+	}
+	return assignment
+}
+
+func TestConsistentHashKeyMovementOnJoin(t *testing.T) {
+	const keys = 20000
+	nodes := newTestNodes(11)
+	s := &ConsistentHashStrategy{}
+
+	before := assignKeys(t, s, nodes[:10], keys)
+	after := assignKeys(t, s, nodes, keys)
+
+	moved := 0
+	for key, id := range before {
+		if after[key] == id {
+			continue
+		}
+		moved++
+		if after[key] != nodes[10].ID {
+			t.Fatalf("key %s moved from %s to %s, expected only moves to the joining node", key, id, after[key])
+		}
+	}
+
+	// 理想情况下移动 1/11 的key
+	ratio := float64(moved) / keys
+	t.Logf("join: moved %d/%d keys (%.2f%%)", moved, keys, ratio*100)
+	if ratio > 0.15 || ratio < 0.04 {
+		t.Fatalf("unexpected key movement ratio on join: %.4f", ratio)
+	}
+}
+
+func TestConsistentHashKeyMovementOnLeave(t *testing.T) {
+	const keys = 20000
+	nodes := newTestNodes(10)
+	s := &ConsistentHashStrategy{}
+
+	before := assignKeys(t, s, nodes, keys)
+	after := assignKeys(t, s, nodes[1:], keys)
+
+	moved := 0
+	for key, id := range before {
+		if after[key] == id {
+			continue
+		}
+		moved++
+		if id != nodes[0].ID {
+			t.Fatalf("key %s moved from %s although that node stayed", key, id)
+		}
+	}
+
+	ratio := float64(moved) / keys
+	t.Logf("leave: moved %d/%d keys (%.2f%%)", moved, keys, ratio*100)
+	if ratio > 0.15 || ratio < 0.05 {
+		t.Fatalf("unexpected key movement ratio on leave: %.4f", ratio)
+	}
+}
+
+func TestConsistentHashRingRebuiltOnlyOnMembershipChange(t *testing.T) {
+	nodes := newTestNodes(5)
+	s := &ConsistentHashStrategy{}
+
+	if _, err := s.Select(nodes, "a"); err != nil {
+		t.Fatal(err)
+	}
+	ring := s.Ring()
+
+	// 负载变化、顺序变化不触发重建
+	nodes[0].CurrentLoad = 10
+	reordered := []*model.ExecutorNode{nodes[4], nodes[3], nodes[2], nodes[1], nodes[0]}
+	if _, err := s.Select(reordered, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Ring() != ring {
+		t.Fatal("ring rebuilt without membership change")
+	}
+
+	// 节点离线触发重建
+	nodes[2].Status = model.ExecutorStatusOffline
+	if _, err := s.Select(nodes, "c"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Ring() == ring {
+		t.Fatal("ring not rebuilt after membership change")
+	}
+}
+
+func TestConsistentHashBoundedLoad(t *testing.T) {
+	nodes := newTestNodes(4)
+	s := NewConsistentHashStrategy(0, nil, 1.25)
+
+	unbounded := &ConsistentHashStrategy{}
+	hot, err := unbounded.Select(nodes, "hot-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 热点节点负载远超平均值时, 应顺延到下一个节点
+	hot.CurrentLoad = 50
+	selected, err := s.Select(nodes, "hot-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selected.ID == hot.ID {
+		t.Fatalf("bounded load did not skip overloaded node %s", hot.ID)
+	}
+
+	// 负载恢复后回到原节点
+	hot.CurrentLoad = 0
+	selected, err = s.Select(nodes, "hot-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selected.ID != hot.ID {
+		t.Fatalf("expected %s after load dropped, got %s", hot.ID, selected.ID)
+	}
+}
+
+func TestConsistentHashCustomHash(t *testing.T) {
+	nodes := newTestNodes(3)
+	s := NewConsistentHashStrategy(50, HashCRC32, 0)
+
+	first, err := s.Select(nodes, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		node, err := s.Select(nodes, "key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.ID != first.ID {
+			t.Fatalf("unstable selection: %s != %s", node.ID, first.ID)
+		}
+	}
+}
+
+func BenchmarkConsistentHashSelect(b *testing.B) {
+	nodes := newTestNodes(50)
+	s := &ConsistentHashStrategy{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = s.Select(nodes, fmt.Sprintf("task-%d", i))
+	}
+}
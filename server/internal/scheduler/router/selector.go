@@ -0,0 +1,368 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"distributed-scheduler/internal/model"
+)
+
+var (
+	ErrInvalidSelector = errors.New("无效的标签选择器")
+)
+
+// 选择器操作符
+const (
+	OperatorEquals       = "="
+	OperatorNotEquals    = "!="
+	OperatorIn           = "in"
+	OperatorNotIn        = "notin"
+	OperatorExists       = "exists"
+	OperatorDoesNotExist = "!exists"
+)
+
+// Requirement 标签匹配条件
+type Requirement struct {
+	Key      string
+	Operator string
+	Values   []string
+}
+
+// Matches 判断标签是否满足条件
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, exists := labels[r.Key]
+	switch r.Operator {
+	case OperatorExists:
+		return exists
+	case OperatorDoesNotExist:
+		return !exists
+	case OperatorEquals, OperatorIn:
+		return exists && containsString(r.Values, value)
+	case OperatorNotEquals, OperatorNotIn:
+		return !exists || !containsString(r.Values, value)
+	default:
+		return false
+	}
+}
+
+// String 输出条件表达式
+func (r Requirement) String() string {
+	switch r.Operator {
+	case OperatorExists:
+		return r.Key
+	case OperatorDoesNotExist:
+		return "!" + r.Key
+	case OperatorIn, OperatorNotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	default:
+		return r.Key + r.Operator + r.Values[0]
+	}
+}
+
+// Selector 标签选择器, 所有条件均满足才匹配
+// 表达式格式(逗号分隔): zone=sh, disk!=hdd, version in (1.2,1.3), region notin (us), gpu, !spot
+type Selector []Requirement
+
+// ParseSelector 解析标签选择器表达式, 空表达式匹配所有节点
+func ParseSelector(expr string) (Selector, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+
+	parts, err := splitSelector(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := make(Selector, 0, len(parts))
+	for _, part := range parts {
+		req, err := parseRequirement(part)
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, req)
+	}
+	return selector, nil
+}
+
+// Matches 判断标签是否满足选择器
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, req := range s {
+		if !req.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty 是否为空选择器
+func (s Selector) Empty() bool {
+	return len(s) == 0
+}
+
+// String 输出选择器表达式
+func (s Selector) String() string {
+	parts := make([]string, 0, len(s))
+	for _, req := range s {
+		parts = append(parts, req.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// splitSelector 按逗号拆分表达式(忽略括号内的逗号)
+func splitSelector(expr string) ([]string, error) {
+	var parts []string
+	depth, start := 0, 0
+	for i, ch := range expr {
+		switch ch {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, fmt.Errorf("%w: 括号嵌套 %q", ErrInvalidSelector, expr)
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("%w: 括号不匹配 %q", ErrInvalidSelector, expr)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(expr[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("%w: 括号不匹配 %q", ErrInvalidSelector, expr)
+	}
+	parts = append(parts, strings.TrimSpace(expr[start:]))
+	return parts, nil
+}
+
+// parseRequirement 解析单个条件
+func parseRequirement(part string) (Requirement, error) {
+	if part == "" {
+		return Requirement{}, fmt.Errorf("%w: 存在空条件", ErrInvalidSelector)
+	}
+
+	// 集合操作: key in (a,b) / key notin (a,b)
+	if idx := strings.Index(part, "("); idx > 0 {
+		if !strings.HasSuffix(part, ")") {
+			return Requirement{}, fmt.Errorf("%w: %q", ErrInvalidSelector, part)
+		}
+		fields := strings.Fields(part[:idx])
+		if len(fields) != 2 || (fields[1] != OperatorIn && fields[1] != OperatorNotIn) {
+			return Requirement{}, fmt.Errorf("%w: %q", ErrInvalidSelector, part)
+		}
+		values := make([]string, 0)
+		for _, v := range strings.Split(part[idx+1:len(part)-1], ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		if len(values) == 0 {
+			return Requirement{}, fmt.Errorf("%w: %q 缺少取值", ErrInvalidSelector, part)
+		}
+		return newRequirement(fields[0], fields[1], values)
+	}
+
+	if idx := strings.Index(part, "!="); idx >= 0 {
+		return newRequirement(part[:idx], OperatorNotEquals, []string{part[idx+2:]})
+	}
+	if idx := strings.Index(part, "="); idx >= 0 {
+		value := strings.TrimPrefix(part[idx+1:], "=")
+		return newRequirement(part[:idx], OperatorEquals, []string{value})
+	}
+	if strings.HasPrefix(part, "!") {
+		return newRequirement(part[1:], OperatorDoesNotExist, nil)
+	}
+	return newRequirement(part, OperatorExists, nil)
+}
+
+// newRequirement 创建并校验条件
+func newRequirement(key, operator string, values []string) (Requirement, error) {
+	key = strings.TrimSpace(key)
+	if key == "" || strings.ContainsAny(key, " =!(),") {
+		return Requirement{}, fmt.Errorf("%w: 无效的标签键 %q", ErrInvalidSelector, key)
+	}
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+		if (operator == OperatorEquals || operator == OperatorNotEquals) && values[i] == "" {
+			return Requirement{}, fmt.Errorf("%w: 标签 %q 缺少取值", ErrInvalidSelector, key)
+		}
+	}
+	return Requirement{Key: key, Operator: operator, Values: values}, nil
+}
+
+// containsString 判断切片是否包含字符串
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Affinity 任务的节点亲和性约束
+type Affinity struct {
+	Required     Selector // 必须满足的标签选择器
+	Preferred    Selector // 优先满足的标签选择器
+	AntiAffinity bool     // 同一任务的分片尽量分散到不同主机
+}
+
+// NewAffinity 根据任务定义创建亲和性约束
+func NewAffinity(task *model.Task) (*Affinity, error) {
+	required, err := ParseSelector(task.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	preferred, err := ParseSelector(task.PreferredSelector)
+	if err != nil {
+		return nil, err
+	}
+	return &Affinity{
+		Required:     required,
+		Preferred:    preferred,
+		AntiAffinity: task.AntiAffinity,
+	}, nil
+}
+
+// Filter 按标签选择器过滤候选执行器
+// 先过滤掉不满足Required的节点; 若存在满足Preferred的节点则只保留这些节点
+func (a *Affinity) Filter(executors []*model.ExecutorNode) []*model.ExecutorNode {
+	if a == nil {
+		return executors
+	}
+
+	matched := make([]*model.ExecutorNode, 0, len(executors))
+	for _, node := range executors {
+		if a.Required.Matches(node.Labels) {
+			matched = append(matched, node)
+		}
+	}
+
+	if a.Preferred.Empty() {
+		return matched
+	}
+
+	preferred := make([]*model.ExecutorNode, 0, len(matched))
+	for _, node := range matched {
+		if a.Preferred.Matches(node.Labels) {
+			preferred = append(preferred, node)
+		}
+	}
+	// 优先节点均不可用时退化为只满足Required的节点
+	if len(filterAvailable(preferred)) > 0 {
+		return preferred
+	}
+	return matched
+}
+
+// filterSchedulable 过滤可以接收新任务的执行器, 摘流中的执行器不参与路由
+func filterSchedulable(executors []*model.ExecutorNode) []*model.ExecutorNode {
+	schedulable := make([]*model.ExecutorNode, 0, len(executors))
+	for _, node := range executors {
+		if node.IsSchedulable() {
+			schedulable = append(schedulable, node)
+		}
+	}
+	return schedulable
+}
+
+// Route 按亲和性过滤后使用路由策略选择执行器
+func Route(strategy Strategy, executors []*model.ExecutorNode, param string, affinity *Affinity) (*model.ExecutorNode, error) {
+	candidates := affinity.Filter(filterSchedulable(executors))
+	if len(candidates) == 0 {
+		return nil, ErrNoAvailableExecutor
+	}
+	return strategy.Select(candidates, param)
+}
+
+// HasCandidates 是否有满足亲和性且可以接收新任务的执行器
+func HasCandidates(executors []*model.ExecutorNode, affinity *Affinity) bool {
+	return len(affinity.Filter(filterSchedulable(executors))) > 0
+}
+
+// RouteWithTrace 按亲和性过滤后选择执行器, 探测类策略同时返回探测链路
+func RouteWithTrace(ctx context.Context, strategy Strategy, executors []*model.ExecutorNode, param string, affinity *Affinity) (*model.ExecutorNode, ProbeTrace, error) {
+	candidates := affinity.Filter(filterSchedulable(executors))
+	if len(candidates) == 0 {
+		return nil, nil, ErrNoAvailableExecutor
+	}
+	return selectWithTrace(ctx, strategy, candidates, param)
+}
+
+// RouteShards 为分片任务的每个分片选择执行器
+// 开启反亲和时优先选择尚未承载该任务分片的主机, 主机数不足时按已分配数量均摊
+func RouteShards(strategy Strategy, executors []*model.ExecutorNode, param string, shardTotal int, affinity *Affinity) ([]*model.ExecutorNode, error) {
+	hostShards := make(map[string]int)
+	result := make([]*model.ExecutorNode, 0, shardTotal)
+
+	for i := 0; i < shardTotal; i++ {
+		node, _, err := RouteShard(context.Background(), strategy, executors, ShardParam(param, i), hostShards, affinity)
+		if err != nil {
+			return nil, err
+		}
+		hostShards[node.Host]++
+		result = append(result, node)
+	}
+
+	return result, nil
+}
+
+// RouteShard 按亲和性过滤后为一个分片选择执行器, 探测类策略同时返回探测链路
+// hostShards为同一次触发的其他分片在各主机上的数量, 开启反亲和时优先选择分片最少的主机, 这些主机均不可用时退化为不限制主机
+func RouteShard(ctx context.Context, strategy Strategy, executors []*model.ExecutorNode, param string, hostShards map[string]int, affinity *Affinity) (*model.ExecutorNode, ProbeTrace, error) {
+	candidates := affinity.Filter(filterSchedulable(executors))
+	if len(candidates) == 0 {
+		return nil, nil, ErrNoAvailableExecutor
+	}
+	if affinity == nil || !affinity.AntiAffinity {
+		return selectWithTrace(ctx, strategy, candidates, param)
+	}
+
+	pool := leastUsedHosts(candidates, hostShards)
+	node, trace, err := selectWithTrace(ctx, strategy, pool, param)
+	if err != nil && len(pool) < len(candidates) {
+		var fallback ProbeTrace
+		node, fallback, err = selectWithTrace(ctx, strategy, candidates, param)
+		trace = append(trace, fallback...)
+	}
+	return node, trace, err
+}
+
+// ShardParam 分片的路由参数, 使哈希类策略将同一任务的不同分片映射到不同节点
+func ShardParam(param string, shardIndex int) string {
+	return fmt.Sprintf("%s#%d", param, shardIndex)
+}
+
+// selectWithTrace 使用路由策略选择执行器, 探测类策略同时返回探测链路
+func selectWithTrace(ctx context.Context, strategy Strategy, candidates []*model.ExecutorNode, param string) (*model.ExecutorNode, ProbeTrace, error) {
+	if tracing, ok := strategy.(TracingStrategy); ok {
+		return tracing.SelectWithTrace(ctx, candidates, param)
+	}
+	node, err := strategy.Select(candidates, param)
+	return node, nil, err
+}
+
+// leastUsedHosts 返回已分配分片数最少的主机上的执行器
+func leastUsedHosts(executors []*model.ExecutorNode, hostShards map[string]int) []*model.ExecutorNode {
+	minCount := -1
+	for _, node := range executors {
+		if count := hostShards[node.Host]; minCount < 0 || count < minCount {
+			minCount = count
+		}
+	}
+
+	result := make([]*model.ExecutorNode, 0, len(executors))
+	for _, node := range executors {
+		if hostShards[node.Host] == minCount {
+			result = append(result, node)
+		}
+	}
+	return result
+}
@@ -0,0 +1,173 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"distributed-scheduler/internal/model"
+)
+
+func TestParseSelector(t *testing.T) {
+	labels := map[string]string{"zone": "sh", "disk": "ssd", "gpu": "false", "version": "1.2"}
+
+	cases := []struct {
+		expr  string
+		match bool
+	}{
+		{"", true},
+		{"zone=sh", true},
+		{"zone==sh", true},
+		{"zone=bj", false},
+		{"disk!=hdd", true},
+		{"version in (1.1, 1.2)", true},
+		{"version notin (1.2)", false},
+		{"gpu", true},
+		{"!spot", true},
+		{"!gpu", false},
+		{"zone=sh, disk=ssd, version in (1.2,1.3)", true},
+		{"zone=sh, disk=hdd", false},
+	}
+
+	for _, tc := range cases {
+		selector, err := ParseSelector(tc.expr)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.expr, err)
+		}
+		if got := selector.Matches(labels); got != tc.match {
+			t.Errorf("%q matches = %v, want %v", tc.expr, got, tc.match)
+		}
+	}
+
+	for _, expr := range []string{"zone=", "=sh", "zone in ()", "zone in (a", "zone, ,disk", "zone like (a)"} {
+		if _, err := ParseSelector(expr); !errors.Is(err, ErrInvalidSelector) {
+			t.Errorf("expected ErrInvalidSelector for %q, got %v", expr, err)
+		}
+	}
+}
+
+func TestAffinityFilter(t *testing.T) {
+	nodes := newTestNodes(4)
+	nodes[0].Labels = model.Labels{"zone": "sh", "disk": "ssd"}
+	nodes[1].Labels = model.Labels{"zone": "sh", "disk": "hdd"}
+	nodes[2].Labels = model.Labels{"zone": "bj", "disk": "ssd"}
+
+	affinity, err := NewAffinity(&model.Task{LabelSelector: "zone=sh", PreferredSelector: "disk=ssd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filtered := affinity.Filter(nodes)
+	if len(filtered) != 1 || filtered[0].ID != nodes[0].ID {
+		t.Fatalf("expected only preferred node %s, got %v", nodes[0].ID, filtered)
+	}
+
+	// 优先节点过载时退化为满足Required的节点
+	nodes[0].CurrentLoad = nodes[0].MaxConcurrent
+	node, err := Route(&RoundRobinStrategy{}, nodes, "", affinity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.ID != nodes[1].ID {
+		t.Fatalf("expected fallback to %s, got %s", nodes[1].ID, node.ID)
+	}
+}
+
+func TestRouteShardsAntiAffinity(t *testing.T) {
+	nodes := newTestNodes(6)
+	// 两个执行器共用一台主机
+	for i, node := range nodes {
+		node.Host = []string{"host-a", "host-a", "host-b", "host-b", "host-c", "host-c"}[i]
+	}
+
+	shards, err := RouteShards(&RoundRobinStrategy{}, nodes, "task-1", 3, &Affinity{AntiAffinity: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := make(map[string]bool)
+	for _, node := range shards {
+		if hosts[node.Host] {
+			t.Fatalf("shards share host %s: %v", node.Host, shards)
+		}
+		hosts[node.Host] = true
+	}
+
+	// 分片数超过主机数时均摊
+	shards, err = RouteShards(&RoundRobinStrategy{}, nodes, "task-1", 6, &Affinity{AntiAffinity: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	perHost := make(map[string]int)
+	for _, node := range shards {
+		perHost[node.Host]++
+	}
+	for host, count := range perHost {
+		if count != 2 {
+			t.Fatalf("host %s got %d shards, want 2", host, count)
+		}
+	}
+}
+
+func TestRouteShardSkipsDrainingAndUsedHosts(t *testing.T) {
+	nodes := newTestNodes(3)
+	for i, node := range nodes {
+		node.Host = []string{"host-a", "host-b", "host-c"}[i]
+	}
+	nodes[2].DrainStatus = model.DrainStatusDraining
+
+	// host-a已承载同批次的分片, host-c摘流中
+	hostShards := map[string]int{"host-a": 1}
+	for i := 0; i < 5; i++ {
+		node, _, err := RouteShard(context.Background(), &RoundRobinStrategy{}, nodes, ShardParam("task-1", i), hostShards, &Affinity{AntiAffinity: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Host != "host-b" {
+			t.Fatalf("shard routed to %s, want host-b", node.Host)
+		}
+	}
+
+	if _, err := RouteShards(&RoundRobinStrategy{}, nodes[2:], "task-1", 1, &Affinity{AntiAffinity: true}); !errors.Is(err, ErrNoAvailableExecutor) {
+		t.Fatalf("RouteShards on draining executors: err = %v, want ErrNoAvailableExecutor", err)
+	}
+}
+
+func TestLabelsScan(t *testing.T) {
+	var labels model.Labels
+	if err := labels.Scan([]byte(`{"zone":"sh"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if labels["zone"] != "sh" {
+		t.Fatalf("unexpected labels: %v", labels)
+	}
+	if err := labels.Scan(nil); err != nil || len(labels) != 0 {
+		t.Fatalf("scan nil: %v %v", labels, err)
+	}
+}
+
+func TestRouteSkipsDrainingExecutors(t *testing.T) {
+	nodes := newTestNodes(3)
+	nodes[0].DrainStatus = model.DrainStatusDraining
+	nodes[1].DrainStatus = model.DrainStatusDrained
+
+	for i := 0; i < 5; i++ {
+		node, err := Route(&RoundRobinStrategy{}, nodes, "task-1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node != nodes[2] {
+			t.Fatalf("routed to %s, want %s", node.ID, nodes[2].ID)
+		}
+		node, _, err = RouteWithTrace(context.Background(), &RoundRobinStrategy{}, nodes, "task-1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node != nodes[2] {
+			t.Fatalf("RouteWithTrace routed to %s, want %s", node.ID, nodes[2].ID)
+		}
+	}
+
+	if _, err := Route(&RoundRobinStrategy{}, nodes[:2], "task-1", nil); !errors.Is(err, ErrNoAvailableExecutor) {
+		t.Fatalf("Route on draining executors: err = %v, want ErrNoAvailableExecutor", err)
+	}
+}
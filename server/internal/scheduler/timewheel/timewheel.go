@@ -2,133 +2,480 @@ package timewheel
 
 import (
 	"container/list"
+	"context"
+	"fmt"
+	"runtime/debug"
 	"sync"
 	"time"
 
+	"github.com/robfig/cron/v3"
+
+	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/executor/pool"
 	"distributed-scheduler/pkg/logger"
 )
 
+// Callback 定时任务回调, ctx在时间轮停止时取消
+type Callback func(ctx context.Context) error
+
+// ResultHandler 回调执行结果处理函数, err为回调返回的错误、panic(*pool.PanicError)或提交执行器失败的错误
+type ResultHandler func(key string, err error)
+
+// Executor 回调执行器, 如pool.WorkerPool
+type Executor interface {
+	Submit(task pool.Task) error
+}
+
 // Task 定时任务
 type Task struct {
-	delay    time.Duration // 延迟时间
-	circle   int           // 圈数
-	key      string        // 唯一标识
-	callback func()        // 回调函数
+	delay      time.Duration // 延迟时间
+	expiration int64         // 到期的绝对刻度(第0层刻度)
+	key        string        // 唯一标识
+	callback   Callback      // 回调函数
+	schedule   cron.Schedule // Cron调度, 非空时每次执行后自动按下次触发时间重新添加
+	fireAt     time.Time     // Cron任务的触发时间(墙上时钟)
 }
 
-// TimeWheel 时间轮
+// Timer 未执行的定时任务, 由Stop返回用于持久化
+type Timer struct {
+	Key      string
+	ExpireAt time.Time // 预计到期时间
+	Callback Callback
+	Schedule cron.Schedule // Cron任务的调度, 普通任务为空
+}
+
+// level 时间轮的一层
+type level struct {
+	span  int64        // 每个槽代表的第0层刻度数
+	slots []*list.List // 时间槽
+}
+
+// TimeWheel 分层时间轮
+// 第0层每个槽代表一个interval, 第i+1层每个槽代表第i层转一圈的时长(如 秒/分/时/天);
+// 任务按剩余时长放入能容纳它的最低层, 上层槽到期时整体降级到下层(溢出晋升的逆过程),
+// 每次滴答只处理到期槽中的任务, 长延时任务不会在槽中被反复扫描
+// 超出最高层范围的任务会自动扩展出新的一层(槽数与最高层相同)
 type TimeWheel struct {
-	interval    time.Duration // 时间间隔
-	slots       []*list.List  // 时间槽
-	slotNum     int           // 槽数量
-	currentPos  int           // 当前位置
-	ticker      *time.Ticker  // 定时器
+	interval    time.Duration           // 第0层刻度间隔, 支持亚秒级
+	levels      []*level                // 各层时间轮, 由低到高
+	currentTick int64                   // 已处理的刻度
+	startTime   time.Time               // 启动时间(含单调时钟读数), 用于校正滴答延迟
+	wall        func() time.Time        // 墙上时钟, 用于换算Cron任务的触发时间
+	clockOffset time.Duration           // 墙上时钟相对单调时钟的偏移, 变化超过一个刻度时重新锚定Cron任务
 	taskMap     map[string]*TaskElement // 任务映射
-	addTaskCh   chan *Task    // 添加任务通道
-	removeTaskCh chan string  // 移除任务通道
-	stopCh      chan struct{} // 停止信号
+	ticker      *time.Ticker            // 定时器
+	stopCh      chan struct{}           // 停止信号
+	stopped     bool                    // 是否已停止
+	executor    Executor                // 回调执行器, 为空时每个回调启动一个协程
+	onResult    ResultHandler           // 回调结果处理函数
+	ctx         context.Context         // 回调上下文, 停止时取消
+	cancel      context.CancelFunc
 	mu          sync.RWMutex
 }
 
 // TaskElement 任务元素(用于快速删除)
 type TaskElement struct {
-	task *Task
-	pos  int
-	elem *list.Element
+	task  *Task
+	level int
+	pos   int
+	elem  *list.Element
+}
+
+// 调度器时间轮的默认配置: 第0层刻度1秒, 秒/分/时三层
+var (
+	defaultInterval = time.Second
+	defaultLevels   = []int{60, 60, 24}
+)
+
+// 调度器时间轮回调执行器的默认配置
+const (
+	defaultPoolSize  = 50
+	defaultQueueSize = 1000
+)
+
+// NewSchedulerTimeWheel 按配置scheduler.time_wheel创建调度器时间轮
+// levels为各层槽数(由低到高), 为空时使用slot_num创建每层槽数相同的时间轮, 都未配置时使用默认配置
+func NewSchedulerTimeWheel() *TimeWheel {
+	interval, levels := defaultInterval, defaultLevels
+	if cfg := config.GetConfig(); cfg != nil {
+		twCfg := cfg.Scheduler.TimeWheel
+		if twCfg.Interval > 0 {
+			interval = time.Duration(twCfg.Interval) * time.Millisecond
+		}
+		if len(twCfg.Levels) > 0 {
+			levels = twCfg.Levels
+		} else if twCfg.SlotNum > 0 {
+			levels = []int{twCfg.SlotNum}
+		}
+	}
+
+	tw := NewHierarchicalTimeWheel(interval, levels...)
+	if tw == nil {
+		logger.Warnf("时间轮配置无效(interval=%v, levels=%v), 使用默认配置", interval, levels)
+		tw = NewHierarchicalTimeWheel(defaultInterval, defaultLevels...)
+	}
+	return tw
+}
+
+// NewSchedulerPool 按配置scheduler.time_wheel创建调度器时间轮的回调执行器, 限制同时执行的回调数量
+func NewSchedulerPool() *pool.WorkerPool {
+	poolSize, queueSize := defaultPoolSize, defaultQueueSize
+	if cfg := config.GetConfig(); cfg != nil {
+		twCfg := cfg.Scheduler.TimeWheel
+		if twCfg.PoolSize > 0 {
+			poolSize = twCfg.PoolSize
+		}
+		if twCfg.QueueSize > 0 {
+			queueSize = twCfg.QueueSize
+		}
+	}
+	return pool.NewWorkerPoolWithOptions(pool.Options{
+		Name:       "timewheel",
+		MaxWorkers: poolSize,
+		QueueSize:  queueSize,
+	})
 }
 
 // NewTimeWheel 创建时间轮
-// interval: 时间间隔(如1秒)
-// slotNum: 槽数量(如3600，则可支持1小时内的定时任务)
+// interval: 第0层刻度间隔(如1秒)
+// slotNum: 每层槽数量(如60, 则各层依次覆盖1分钟、1小时、60小时...)
 func NewTimeWheel(interval time.Duration, slotNum int) *TimeWheel {
-	if interval <= 0 || slotNum <= 0 {
+	if slotNum <= 0 {
 		return nil
 	}
+	return NewHierarchicalTimeWheel(interval, slotNum)
+}
 
+// NewHierarchicalTimeWheel 按指定的各层槽数创建分层时间轮
+// 如 NewHierarchicalTimeWheel(100*time.Millisecond, 10, 60, 60, 24) 依次为 秒/分/时/天 四层,
+// 超过一天的任务会自动扩展出第五层
+func NewHierarchicalTimeWheel(interval time.Duration, slotNums ...int) *TimeWheel {
+	if interval <= 0 || len(slotNums) == 0 {
+		return nil
+	}
+	for _, n := range slotNums {
+		if n <= 0 {
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 	tw := &TimeWheel{
-		interval:     interval,
-		slots:        make([]*list.List, slotNum),
-		slotNum:      slotNum,
-		currentPos:   0,
-		taskMap:      make(map[string]*TaskElement),
-		addTaskCh:    make(chan *Task, 1000),
-		removeTaskCh: make(chan string, 1000),
-		stopCh:       make(chan struct{}),
+		interval: interval,
+		taskMap:  make(map[string]*TaskElement),
+		stopCh:   make(chan struct{}),
+		wall:     time.Now,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	for _, n := range slotNums {
+		tw.addLevel(n)
 	}
+	return tw
+}
 
-	// 初始化槽
-	for i := 0; i < slotNum; i++ {
-		tw.slots[i] = list.New()
+// addLevel 添加一层时间轮
+func (tw *TimeWheel) addLevel(slotNum int) {
+	span := int64(1)
+	if n := len(tw.levels); n > 0 {
+		top := tw.levels[n-1]
+		span = top.span * int64(len(top.slots))
 	}
 
-	return tw
+	l := &level{span: span, slots: make([]*list.List, slotNum)}
+	for i := range l.slots {
+		l.slots[i] = list.New()
+	}
+	tw.levels = append(tw.levels, l)
+}
+
+// SetExecutor 设置回调执行器, 用于限制并发执行的回调数量, 需在Start之前调用
+func (tw *TimeWheel) SetExecutor(executor Executor) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.executor = executor
+}
+
+// SetResultHandler 设置回调结果处理函数, 需在Start之前调用
+func (tw *TimeWheel) SetResultHandler(handler ResultHandler) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.onResult = handler
 }
 
 // Start 启动时间轮
 func (tw *TimeWheel) Start() {
+	// 起始时间对齐到墙上时钟的刻度边界并保留单调时钟读数, 刻度按单调时钟推进,
+	// 普通定时任务不受墙上时钟跳变(NTP校准、手动改时间)影响; Cron任务在时钟跳变时按墙上时钟重新锚定
+	now := time.Now()
+	aligned := now.Add(-now.Sub(now.Truncate(tw.interval)))
+
+	tw.mu.Lock()
+	tw.startTime = aligned.Add(-time.Duration(tw.currentTick) * tw.interval)
+	tw.clockOffset = tw.offset()
+	tw.mu.Unlock()
+
 	tw.ticker = time.NewTicker(tw.interval)
-	go tw.run()
+	go tw.run(aligned.Add(tw.interval).Sub(now))
 	logger.Info("时间轮启动成功")
 }
 
-// Stop 停止时间轮
-func (tw *TimeWheel) Stop() {
+// Stop 停止时间轮, 取消正在执行的回调的上下文, 并返回所有未执行的定时任务(按到期时间无序)
+func (tw *TimeWheel) Stop() []Timer {
+	tw.mu.Lock()
+	if tw.stopped {
+		tw.mu.Unlock()
+		return nil
+	}
+	tw.stopped = true
 	close(tw.stopCh)
 	if tw.ticker != nil {
 		tw.ticker.Stop()
 	}
-	logger.Info("时间轮已停止")
+	tw.cancel()
+
+	now := tw.wallNow()
+	timers := make([]Timer, 0, len(tw.taskMap))
+	for key, taskElem := range tw.taskMap {
+		remaining := time.Duration(taskElem.task.expiration-tw.currentTick) * tw.interval
+		timer := Timer{
+			Key:      key,
+			ExpireAt: now.Add(remaining),
+			Callback: taskElem.task.callback,
+			Schedule: taskElem.task.schedule,
+		}
+		if taskElem.task.schedule != nil {
+			timer.ExpireAt = taskElem.task.fireAt
+		}
+		timers = append(timers, timer)
+		tw.levels[taskElem.level].slots[taskElem.pos].Remove(taskElem.elem)
+	}
+	tw.taskMap = make(map[string]*TaskElement)
+	tw.mu.Unlock()
+
+	logger.Infof("时间轮已停止, 未执行的定时任务: %d", len(timers))
+	return timers
 }
 
-// run 运行时间轮
-func (tw *TimeWheel) run() {
+// run 运行时间轮, 等待到下一个刻度边界后开始滴答
+func (tw *TimeWheel) run(align time.Duration) {
+	select {
+	case <-time.After(align):
+		tw.ticker.Reset(tw.interval)
+		tw.advanceTo(tw.elapsedTicks())
+	case <-tw.stopCh:
+		return
+	}
+
 	for {
 		select {
 		case <-tw.ticker.C:
-			tw.tick()
-		case task := <-tw.addTaskCh:
-			tw.addTask(task)
-		case key := <-tw.removeTaskCh:
-			tw.removeTask(key)
+			// 按单调时钟实际经过的时间推进, 避免滴答延迟导致的累计误差
+			tw.checkClock()
+			tw.advanceTo(tw.elapsedTicks())
 		case <-tw.stopCh:
 			return
 		}
 	}
 }
 
-// tick 时间轮滴答
-func (tw *TimeWheel) tick() {
+// elapsedTicks 启动以来按单调时钟经过的刻度数
+func (tw *TimeWheel) elapsedTicks() int64 {
+	return int64(time.Since(tw.startTime) / tw.interval)
+}
+
+// wallNow 当前墙上时钟时间(不含单调时钟读数)
+func (tw *TimeWheel) wallNow() time.Time {
+	return tw.wall().Round(0)
+}
+
+// offset 墙上时钟相对启动时间按单调时钟推算的时间的偏移, 墙上时钟跳变时随之变化
+func (tw *TimeWheel) offset() time.Duration {
+	expected := tw.startTime.Round(0).Add(time.Since(tw.startTime))
+	return tw.wallNow().Sub(expected)
+}
+
+// checkClock 墙上时钟跳变超过一个刻度时按新的墙上时钟重新计算所有Cron任务的到期刻度
+// 时钟向前跳变时已错过的触发在下一个刻度执行, 向后跳变时推迟到墙上时钟到达触发时间
+func (tw *TimeWheel) checkClock() {
 	tw.mu.Lock()
 	defer tw.mu.Unlock()
+	if tw.stopped {
+		return
+	}
 
-	// 获取当前槽
-	slot := tw.slots[tw.currentPos]
+	offset := tw.offset()
+	jump := offset - tw.clockOffset
+	if jump > -tw.interval && jump < tw.interval {
+		return
+	}
+	tw.clockOffset = offset
 
-	// 遍历当前槽的所有任务
-	for e := slot.Front(); e != nil; {
-		task := e.Value.(*Task)
-		if task.circle > 0 {
-			task.circle--
-			e = e.Next()
+	now := tw.wallNow()
+	var cronTasks []*Task
+	for key, taskElem := range tw.taskMap {
+		if taskElem.task.schedule == nil {
+			continue
+		}
+		cronTasks = append(cronTasks, taskElem.task)
+		tw.removeTask(key)
+	}
+	for _, task := range cronTasks {
+		task.expiration = tw.expirationOf(now, task.fireAt)
+		tw.place(task)
+	}
+	logger.Warnf("墙上时钟跳变%v, 已重新锚定%d个Cron任务", jump, len(cronTasks))
+}
+
+// advanceTo 推进到指定刻度, 并执行到期任务
+func (tw *TimeWheel) advanceTo(target int64) {
+	tw.mu.Lock()
+	if tw.stopped {
+		tw.mu.Unlock()
+		return
+	}
+	var expired []*Task
+	for tw.currentTick < target {
+		expired = tw.tick(expired)
+	}
+	executor := tw.executor
+	tw.mu.Unlock()
+
+	for _, task := range expired {
+		task := task
+		if executor == nil {
+			go tw.execute(task)
 			continue
 		}
+		if err := executor.Submit(func() { tw.execute(task) }); err != nil {
+			logger.Errorf("定时任务提交执行器失败, key=%s: %v", task.key, err)
+			tw.report(task.key, fmt.Errorf("提交执行器失败: %w", err))
+		}
+	}
+}
+
+// execute 执行回调, 捕获panic并上报结果
+func (tw *TimeWheel) execute(task *Task) {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr := &pool.PanicError{Value: r, Stack: debug.Stack()}
+			logger.Errorf("定时任务回调panic, key=%s: %v\n%s", task.key, r, panicErr.Stack)
+			err = panicErr
+		}
+		tw.report(task.key, err)
+	}()
+	err = task.callback(tw.ctx)
+}
+
+// report 上报回调结果
+func (tw *TimeWheel) report(key string, err error) {
+	tw.mu.RLock()
+	onResult := tw.onResult
+	tw.mu.RUnlock()
 
-		// 执行任务
-		go task.callback()
+	if onResult != nil {
+		onResult(key, err)
+	} else if err != nil {
+		logger.Errorf("定时任务执行失败, key=%s: %v", key, err)
+	}
+}
 
-		// 移除任务
+// tick 时间轮滴答, 将到期任务追加到expired后返回
+func (tw *TimeWheel) tick(expired []*Task) []*Task {
+	tw.currentTick++
+
+	// 由高到低将到期的上层槽降级, 保证高层降下来的任务能继续降到第0层
+	for i := len(tw.levels) - 1; i > 0; i-- {
+		l := tw.levels[i]
+		if tw.currentTick%l.span != 0 {
+			continue
+		}
+		slot := l.slots[(tw.currentTick/l.span)%int64(len(l.slots))]
+		for e := slot.Front(); e != nil; {
+			next := e.Next()
+			task := slot.Remove(e).(*Task)
+			tw.place(task)
+			e = next
+		}
+	}
+
+	// 执行第0层当前槽的任务
+	slot := tw.levels[0].slots[tw.currentTick%int64(len(tw.levels[0].slots))]
+	for e := slot.Front(); e != nil; {
 		next := e.Next()
-		slot.Remove(e)
+		task := slot.Remove(e).(*Task)
 		delete(tw.taskMap, task.key)
 		e = next
+
+		if task.schedule == nil {
+			expired = append(expired, task)
+			continue
+		}
+
+		// 墙上时钟比时间轮慢(如时钟被向回校准)时, 按剩余时间重新放入, 不提前触发
+		now := tw.wallNow()
+		if task.fireAt.Sub(now) >= tw.interval {
+			task.expiration = tw.expirationOf(now, task.fireAt)
+			tw.place(task)
+			continue
+		}
+		expired = append(expired, task)
+		tw.rearm(task, now)
 	}
+	return expired
+}
 
-	// 移动指针
-	tw.currentPos = (tw.currentPos + 1) % tw.slotNum
+// rearm 按Cron调度添加下一次触发, 错过的触发(如进程长时间停顿)直接跳过
+func (tw *TimeWheel) rearm(task *Task, now time.Time) {
+	next := task.schedule.Next(task.fireAt)
+	if next.Before(now) {
+		next = task.schedule.Next(now)
+	}
+	if next.IsZero() {
+		return
+	}
+
+	tw.place(&Task{
+		expiration: tw.expirationOf(now, next),
+		key:        task.key,
+		callback:   task.callback,
+		schedule:   task.schedule,
+		fireAt:     next,
+	})
 }
 
-// AddTask 添加任务
+// expirationOf 计算墙上时钟时间at对应的到期刻度, now为当前墙上时钟时间
+// 已启动时按单调时钟经过的时间加上墙上时钟的剩余时间换算为绝对刻度, 不受当前刻度因滴答延迟落后的影响
+func (tw *TimeWheel) expirationOf(now, at time.Time) int64 {
+	d := at.Sub(now)
+	if d < 0 {
+		d = 0
+	}
+
+	var expiration int64
+	if tw.startTime.IsZero() {
+		expiration = tw.currentTick + int64((d+tw.interval-1)/tw.interval)
+	} else {
+		elapsed := time.Since(tw.startTime) + d
+		expiration = int64((elapsed + tw.interval - 1) / tw.interval)
+	}
+	if expiration <= tw.currentTick {
+		expiration = tw.currentTick + 1
+	}
+	return expiration
+}
+
+// AddTask 添加任务, 相同key的任务会被替换
 func (tw *TimeWheel) AddTask(delay time.Duration, key string, callback func()) {
+	tw.AddTaskCtx(delay, key, func(ctx context.Context) error {
+		callback()
+		return nil
+	})
+}
+
+// AddTaskCtx 添加带上下文和返回结果的任务, 相同key的任务会被替换; 时间轮停止后添加的任务会被忽略
+func (tw *TimeWheel) AddTaskCtx(delay time.Duration, key string, callback Callback) {
 	if delay <= 0 {
 		return
 	}
@@ -139,14 +486,12 @@ func (tw *TimeWheel) AddTask(delay time.Duration, key string, callback func()) {
 		callback: callback,
 	}
 
-	select {
-	case tw.addTaskCh <- task:
-	default:
-		// 通道满了，直接添加
-		tw.mu.Lock()
-		tw.addTask(task)
-		tw.mu.Unlock()
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.stopped {
+		return
 	}
+	tw.addTask(task)
 }
 
 // addTask 内部添加任务
@@ -156,28 +501,75 @@ func (tw *TimeWheel) addTask(task *Task) {
 		tw.removeTask(task.key)
 	}
 
-	// 计算位置和圈数
-	pos, circle := tw.getPositionAndCircle(task.delay)
-	task.circle = circle
+	// 向上取整, 保证不早于delay执行
+	ticks := int64((task.delay + tw.interval - 1) / tw.interval)
+	task.expiration = tw.currentTick + ticks
+	tw.place(task)
+}
+
+// place 将任务放入能容纳其剩余时长的最低层
+func (tw *TimeWheel) place(task *Task) {
+	remaining := task.expiration - tw.currentTick
 
-	// 添加到对应槽
-	elem := tw.slots[pos].PushBack(task)
+	idx := 0
+	for {
+		l := tw.levels[idx]
+		if remaining < l.span*int64(len(l.slots)) {
+			break
+		}
+		idx++
+		if idx == len(tw.levels) {
+			// 溢出: 扩展一层
+			tw.addLevel(len(l.slots))
+		}
+	}
+
+	// 降级时恰好到期的任务落在第0层当前槽, 在本次滴答中执行
+	l := tw.levels[idx]
+	pos := int((task.expiration / l.span) % int64(len(l.slots)))
+	elem := l.slots[pos].PushBack(task)
 	tw.taskMap[task.key] = &TaskElement{
-		task: task,
-		pos:  pos,
-		elem: elem,
+		task:  task,
+		level: idx,
+		pos:   pos,
+		elem:  elem,
 	}
 }
 
-// RemoveTask 移除任务
-func (tw *TimeWheel) RemoveTask(key string) {
-	select {
-	case tw.removeTaskCh <- key:
-	default:
-		tw.mu.Lock()
+// AddCronTask 添加按Cron调度重复执行的任务, 相同key的任务会被替换
+// 每次触发后按schedule自动添加下一次触发, 直到RemoveTask; 返回首次触发时间,
+// schedule没有后续触发时间时返回零值且不添加任务
+// schedule可通过utils.CronParser.Parse获取
+func (tw *TimeWheel) AddCronTask(key string, schedule cron.Schedule, callback Callback) time.Time {
+	now := tw.wallNow()
+	next := schedule.Next(now)
+	if next.IsZero() {
+		return next
+	}
+
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.stopped {
+		return time.Time{}
+	}
+	if _, ok := tw.taskMap[key]; ok {
 		tw.removeTask(key)
-		tw.mu.Unlock()
 	}
+	tw.place(&Task{
+		expiration: tw.expirationOf(now, next),
+		key:        key,
+		callback:   callback,
+		schedule:   schedule,
+		fireAt:     next,
+	})
+	return next
+}
+
+// RemoveTask 移除任务
+func (tw *TimeWheel) RemoveTask(key string) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.removeTask(key)
 }
 
 // removeTask 内部移除任务
@@ -187,18 +579,10 @@ func (tw *TimeWheel) removeTask(key string) {
 		return
 	}
 
-	tw.slots[taskElem.pos].Remove(taskElem.elem)
+	tw.levels[taskElem.level].slots[taskElem.pos].Remove(taskElem.elem)
 	delete(tw.taskMap, key)
 }
 
-// getPositionAndCircle 计算位置和圈数
-func (tw *TimeWheel) getPositionAndCircle(delay time.Duration) (int, int) {
-	delaySeconds := int(delay / tw.interval)
-	circle := delaySeconds / tw.slotNum
-	pos := (tw.currentPos + delaySeconds) % tw.slotNum
-	return pos, circle
-}
-
 // HasTask 检查任务是否存在
 func (tw *TimeWheel) HasTask(key string) bool {
 	tw.mu.RLock()
@@ -214,3 +598,9 @@ func (tw *TimeWheel) TaskCount() int {
 	return len(tw.taskMap)
 }
 
+// Levels 获取时间轮层数
+func (tw *TimeWheel) Levels() int {
+	tw.mu.RLock()
+	defer tw.mu.RUnlock()
+	return len(tw.levels)
+}
@@ -0,0 +1,498 @@
+package timewheel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/executor/pool"
+	"distributed-scheduler/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	logger.Sugar = logger.Logger.Sugar()
+	os.Exit(m.Run())
+}
+
+// newTestWheel 秒/分/时/天 四层, 第0层刻度100ms
+func newTestWheel() *TimeWheel {
+	return NewHierarchicalTimeWheel(100*time.Millisecond, 10, 60, 60, 24)
+}
+
+func TestTimeWheelCascade(t *testing.T) {
+	tw := newTestWheel()
+
+	delays := []time.Duration{
+		100 * time.Millisecond,
+		900 * time.Millisecond,
+		time.Second,
+		61 * time.Second,
+		time.Hour + 500*time.Millisecond,
+		25 * time.Hour,
+	}
+	fired := make([]int32, len(delays))
+	for i, delay := range delays {
+		i := i
+		tw.AddTask(delay, strconv.Itoa(i), func() {
+			atomic.AddInt32(&fired[i], 1)
+		})
+	}
+	if tw.Levels() != 5 {
+		t.Fatalf("超过一天的任务应扩展出第五层, 实际层数: %d", tw.Levels())
+	}
+
+	// delays按升序排列, 逐个推进到到期刻度的前一刻和到期刻度
+	for i, delay := range delays {
+		want := int64((delay + tw.interval - 1) / tw.interval)
+
+		tw.advanceTo(want - 1)
+		time.Sleep(10 * time.Millisecond)
+		if atomic.LoadInt32(&fired[i]) != 0 {
+			t.Errorf("延迟%v的任务在第%d个刻度之前执行", delay, want)
+		}
+
+		tw.advanceTo(want)
+		time.Sleep(10 * time.Millisecond)
+		if got := atomic.LoadInt32(&fired[i]); got != 1 {
+			t.Errorf("延迟%v的任务应在第%d个刻度执行一次, 实际执行次数: %d", delay, want, got)
+		}
+	}
+	if tw.TaskCount() != 0 {
+		t.Errorf("到期任务应全部移除, 剩余: %d", tw.TaskCount())
+	}
+}
+
+func TestTimeWheelRemoveAndReplace(t *testing.T) {
+	tw := newTestWheel()
+
+	var count int32
+	tw.AddTask(time.Hour, "a", func() { atomic.AddInt32(&count, 100) })
+	tw.AddTask(time.Minute, "a", func() { atomic.AddInt32(&count, 1) })
+	tw.AddTask(time.Minute, "b", func() { atomic.AddInt32(&count, 100) })
+	tw.RemoveTask("b")
+
+	if !tw.HasTask("a") || tw.HasTask("b") {
+		t.Fatal("任务替换或移除结果不正确")
+	}
+
+	tw.advanceTo(int64(2 * time.Hour / tw.interval))
+	time.Sleep(50 * time.Millisecond)
+
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Errorf("只有替换后的任务应执行一次, 实际: %d", got)
+	}
+}
+
+func TestNewSchedulerTimeWheel(t *testing.T) {
+	old := config.GlobalConfig
+	t.Cleanup(func() { config.GlobalConfig = old })
+
+	cases := []struct {
+		name         string
+		cfg          config.TimeWheelConfig
+		wantInterval time.Duration
+		wantSlots    []int
+	}{
+		{"默认配置", config.TimeWheelConfig{}, time.Second, []int{60, 60, 24}},
+		{"分层配置", config.TimeWheelConfig{SlotNum: 3600, Interval: 100, Levels: []int{10, 60}}, 100 * time.Millisecond, []int{10, 60}},
+		{"单层槽数", config.TimeWheelConfig{SlotNum: 3600, Interval: 500}, 500 * time.Millisecond, []int{3600}},
+		{"无效槽数", config.TimeWheelConfig{Levels: []int{60, 0}}, time.Second, []int{60, 60, 24}},
+	}
+	for _, c := range cases {
+		config.GlobalConfig = &config.Config{Scheduler: config.SchedulerConfig{TimeWheel: c.cfg}}
+		tw := NewSchedulerTimeWheel()
+		if tw.interval != c.wantInterval {
+			t.Errorf("%s: 刻度应为%v, 实际: %v", c.name, c.wantInterval, tw.interval)
+		}
+		slots := make([]int, len(tw.levels))
+		for i, l := range tw.levels {
+			slots[i] = len(l.slots)
+		}
+		if fmt.Sprint(slots) != fmt.Sprint(c.wantSlots) {
+			t.Errorf("%s: 各层槽数应为%v, 实际: %v", c.name, c.wantSlots, slots)
+		}
+	}
+}
+
+// fakeExecutor 记录提交次数的回调执行器, err非空时拒绝提交
+type fakeExecutor struct {
+	submitted int32
+	err       error
+}
+
+func (e *fakeExecutor) Submit(task pool.Task) error {
+	if e.err != nil {
+		return e.err
+	}
+	atomic.AddInt32(&e.submitted, 1)
+	go task()
+	return nil
+}
+
+// resultRecorder 记录回调结果
+type resultRecorder struct {
+	mu      sync.Mutex
+	results map[string]error
+	done    chan string
+}
+
+func newResultRecorder() *resultRecorder {
+	return &resultRecorder{results: make(map[string]error), done: make(chan string, 10)}
+}
+
+func (r *resultRecorder) handle(key string, err error) {
+	r.mu.Lock()
+	r.results[key] = err
+	r.mu.Unlock()
+	r.done <- key
+}
+
+// wait 等待n个回调结果
+func (r *resultRecorder) wait(t *testing.T, n int) map[string]error {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.done:
+		case <-time.After(time.Second):
+			t.Fatalf("等待第%d个回调结果超时", i+1)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.results
+}
+
+func TestCallbacksRunThroughExecutor(t *testing.T) {
+	tw := newTestWheel()
+	executor := &fakeExecutor{}
+	results := newResultRecorder()
+	tw.SetExecutor(executor)
+	tw.SetResultHandler(results.handle)
+
+	callbackErr := errors.New("回调失败")
+	tw.AddTaskCtx(100*time.Millisecond, "ok", func(ctx context.Context) error { return nil })
+	tw.AddTaskCtx(100*time.Millisecond, "fail", func(ctx context.Context) error { return callbackErr })
+	tw.advanceTo(1)
+
+	got := results.wait(t, 2)
+	if n := atomic.LoadInt32(&executor.submitted); n != 2 {
+		t.Fatalf("到期回调应提交到执行器, 实际提交次数: %d", n)
+	}
+	if got["ok"] != nil || !errors.Is(got["fail"], callbackErr) {
+		t.Fatalf("回调结果不正确: %v", got)
+	}
+
+	// 执行器拒绝时以提交失败上报, 回调不执行
+	executor.err = pool.ErrPoolFull
+	var ran int32
+	tw.AddTaskCtx(100*time.Millisecond, "rejected", func(ctx context.Context) error {
+		atomic.AddInt32(&ran, 1)
+		return nil
+	})
+	tw.advanceTo(2)
+	got = results.wait(t, 1)
+	if !errors.Is(got["rejected"], pool.ErrPoolFull) || atomic.LoadInt32(&ran) != 0 {
+		t.Fatalf("执行器拒绝时应上报提交失败且不执行回调, 结果: %v, 执行次数: %d", got["rejected"], ran)
+	}
+}
+
+func TestCallbackPanicRecovered(t *testing.T) {
+	tw := newTestWheel()
+	results := newResultRecorder()
+	tw.SetExecutor(pool.NewWorkerPool(1, 10))
+	tw.SetResultHandler(results.handle)
+
+	tw.AddTaskCtx(100*time.Millisecond, "panic", func(ctx context.Context) error { panic("boom") })
+	tw.AddTaskCtx(200*time.Millisecond, "next", func(ctx context.Context) error { return nil })
+	tw.advanceTo(1)
+	results.wait(t, 1)
+	tw.advanceTo(2)
+	got := results.wait(t, 1)
+
+	var panicErr *pool.PanicError
+	if !errors.As(got["panic"], &panicErr) || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Fatalf("panic应以附带调用栈的*pool.PanicError上报, 实际: %v", got["panic"])
+	}
+	if err, ok := got["next"]; !ok || err != nil {
+		t.Fatalf("回调panic后时间轮应继续执行后续回调, 实际: %v, %v", err, ok)
+	}
+}
+
+func TestStopCancelsCallbackContext(t *testing.T) {
+	tw := newTestWheel()
+	results := newResultRecorder()
+	tw.SetResultHandler(results.handle)
+
+	started := make(chan struct{})
+	tw.AddTaskCtx(100*time.Millisecond, "long", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	tw.advanceTo(1)
+	<-started
+
+	tw.Stop()
+	got := results.wait(t, 1)
+	if !errors.Is(got["long"], context.Canceled) {
+		t.Fatalf("Stop应取消执行中回调的上下文, 实际: %v", got["long"])
+	}
+}
+
+func TestStopReturnsUnfiredTimers(t *testing.T) {
+	tw := newTestWheel()
+	tw.wall = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	var fired int32
+	tw.AddTask(time.Second, "fired", func() { atomic.AddInt32(&fired, 1) })
+	tw.AddTask(time.Minute, "minute", func() {})
+	tw.AddTask(2*time.Hour, "hours", func() {})
+	fireAt := tw.AddCronTask("cron", everySchedule(time.Hour), func(ctx context.Context) error { return nil })
+	tw.advanceTo(10)
+	if !waitFired(&fired, 1, time.Second) {
+		t.Fatal("到期任务应执行")
+	}
+
+	timers := tw.Stop()
+	sort.Slice(timers, func(i, j int) bool { return timers[i].Key < timers[j].Key })
+	now := tw.wallNow()
+	want := []struct {
+		key      string
+		expireAt time.Time
+		cron     bool
+	}{
+		{"cron", fireAt, true},
+		{"hours", now.Add(2*time.Hour - time.Second), false},
+		{"minute", now.Add(time.Minute - time.Second), false},
+	}
+	if len(timers) != len(want) {
+		t.Fatalf("Stop应返回%d个未执行的定时任务, 实际: %d", len(want), len(timers))
+	}
+	for i, w := range want {
+		timer := timers[i]
+		if timer.Key != w.key || !timer.ExpireAt.Equal(w.expireAt) || (timer.Schedule != nil) != w.cron || timer.Callback == nil {
+			t.Errorf("未执行的定时任务 = {%s %v cron=%v}, want {%s %v cron=%v}",
+				timer.Key, timer.ExpireAt, timer.Schedule != nil, w.key, w.expireAt, w.cron)
+		}
+	}
+
+	if tw.TaskCount() != 0 || tw.Stop() != nil {
+		t.Error("Stop后时间轮应清空, 再次Stop不应返回定时任务")
+	}
+	tw.AddTask(time.Second, "late", func() {})
+	if tw.HasTask("late") {
+		t.Error("Stop后添加的任务应被忽略")
+	}
+}
+
+func TestNewSchedulerPool(t *testing.T) {
+	old := config.GlobalConfig
+	t.Cleanup(func() { config.GlobalConfig = old })
+
+	config.GlobalConfig = &config.Config{}
+	p := NewSchedulerPool()
+	if stats := p.GetStats(); stats.MaxWorkers != defaultPoolSize || stats.QueueCap != defaultQueueSize {
+		t.Errorf("默认执行器应为%d个协程、队列%d, 实际: %d, %d", defaultPoolSize, defaultQueueSize, stats.MaxWorkers, stats.QueueCap)
+	}
+	p.Close()
+
+	config.GlobalConfig = &config.Config{Scheduler: config.SchedulerConfig{TimeWheel: config.TimeWheelConfig{PoolSize: 4, QueueSize: 16}}}
+	p = NewSchedulerPool()
+	if stats := p.GetStats(); stats.MaxWorkers != 4 || stats.QueueCap != 16 {
+		t.Errorf("执行器应为4个协程、队列16, 实际: %d, %d", stats.MaxWorkers, stats.QueueCap)
+	}
+	p.Close()
+}
+
+// everySchedule 固定间隔的Cron调度, 支持亚秒级间隔
+type everySchedule time.Duration
+
+func (e everySchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// neverSchedule 没有后续触发时间的调度
+type neverSchedule struct{}
+
+func (neverSchedule) Next(time.Time) time.Time {
+	return time.Time{}
+}
+
+// fakeClock 可跳变的墙上时钟
+type fakeClock struct {
+	offset atomic.Int64
+}
+
+func (c *fakeClock) now() time.Time {
+	return time.Now().Add(time.Duration(c.offset.Load()))
+}
+
+func (c *fakeClock) jump(d time.Duration) {
+	c.offset.Add(int64(d))
+}
+
+// startTestWheel 启动刻度为10ms、使用可跳变墙上时钟的时间轮
+func startTestWheel(t *testing.T) (*TimeWheel, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{}
+	tw := NewHierarchicalTimeWheel(10*time.Millisecond, 100, 60, 60)
+	tw.wall = clock.now
+	tw.Start()
+	t.Cleanup(func() { tw.Stop() })
+	return tw, clock
+}
+
+// waitFired 等待计数达到want, 超时返回false
+func waitFired(count *int32, want int32, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if atomic.LoadInt32(count) >= want {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return atomic.LoadInt32(count) >= want
+}
+
+func TestAddCronTask(t *testing.T) {
+	tw, _ := startTestWheel(t)
+
+	var count int32
+	before := time.Now()
+	first := tw.AddCronTask("cron", everySchedule(50*time.Millisecond), func(ctx context.Context) error {
+		atomic.AddInt32(&count, 1)
+		return nil
+	})
+	if first.Before(before.Add(50*time.Millisecond).Round(0)) || first.After(time.Now().Add(50*time.Millisecond).Round(0)) {
+		t.Errorf("首次触发时间应为50ms后, 实际: %v", first.Sub(before))
+	}
+	if !waitFired(&count, 3, 2*time.Second) {
+		t.Fatalf("Cron任务应按调度重复触发, 实际触发次数: %d", atomic.LoadInt32(&count))
+	}
+	if !tw.HasTask("cron") {
+		t.Fatal("触发后应自动添加下一次触发")
+	}
+
+	tw.RemoveTask("cron")
+	fired := atomic.LoadInt32(&count)
+	time.Sleep(150 * time.Millisecond)
+	if got := atomic.LoadInt32(&count); got > fired+1 {
+		t.Errorf("移除后不应继续触发, 移除时%d次, 之后%d次", fired, got)
+	}
+
+	// 没有后续触发时间的调度不添加任务
+	if next := tw.AddCronTask("never", neverSchedule{}, nil); !next.IsZero() || tw.HasTask("never") {
+		t.Error("没有后续触发时间时不应添加任务")
+	}
+}
+
+func TestCronTaskReanchoredOnClockJumpForward(t *testing.T) {
+	tw, clock := startTestWheel(t)
+
+	var count int32
+	tw.AddCronTask("hourly", everySchedule(time.Hour), func(ctx context.Context) error {
+		atomic.AddInt32(&count, 1)
+		return nil
+	})
+
+	// 墙上时钟向前跳过触发时间后, Cron任务应在下一个刻度触发而不是再等一小时的单调时间
+	clock.jump(time.Hour + time.Second)
+	if !waitFired(&count, 1, time.Second) {
+		t.Fatal("墙上时钟向前跳变后应立即触发已错过的Cron任务")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Errorf("错过的触发只应执行一次, 实际: %d", got)
+	}
+}
+
+func TestClockJumpBackward(t *testing.T) {
+	tw, clock := startTestWheel(t)
+
+	var cronCount, timerCount int32
+	tw.AddCronTask("cron", everySchedule(100*time.Millisecond), func(ctx context.Context) error {
+		atomic.AddInt32(&cronCount, 1)
+		return nil
+	})
+	tw.AddTask(100*time.Millisecond, "timer", func() {
+		atomic.AddInt32(&timerCount, 1)
+	})
+
+	// 墙上时钟向回跳变: Cron任务推迟到墙上时钟到达触发时间, 普通定时任务按单调时钟照常到期
+	clock.jump(-time.Hour)
+	if !waitFired(&timerCount, 1, time.Second) {
+		t.Fatal("墙上时钟跳变不应影响普通定时任务")
+	}
+	time.Sleep(200 * time.Millisecond)
+	if got := atomic.LoadInt32(&cronCount); got != 0 {
+		t.Errorf("墙上时钟向回跳变后Cron任务不应提前触发, 实际触发次数: %d", got)
+	}
+}
+
+func TestTimerIgnoresClockJumpForward(t *testing.T) {
+	tw, clock := startTestWheel(t)
+
+	var count int32
+	start := time.Now()
+	tw.AddTask(200*time.Millisecond, "timer", func() {
+		atomic.AddInt32(&count, 1)
+	})
+	clock.jump(time.Hour)
+	if !waitFired(&count, 1, time.Second) {
+		t.Fatal("普通定时任务应到期执行")
+	}
+	// 到期刻度按当前刻度计算, 当前刻度最多落后一个刻度间隔
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond-tw.interval {
+		t.Errorf("墙上时钟向前跳变不应使普通定时任务提前执行, 实际等待: %v", elapsed)
+	}
+}
+
+// fillWheel 添加n个延迟分布在一天内的任务
+func fillWheel(tw *TimeWheel, n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+		tw.AddTask(time.Duration(i%86400+1)*time.Second, keys[i], func() {})
+	}
+	return keys
+}
+
+func BenchmarkAddTask1M(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		tw := newTestWheel()
+		fillWheel(tw, 1000000)
+	}
+}
+
+func BenchmarkAddRemove1M(b *testing.B) {
+	tw := newTestWheel()
+	keys := fillWheel(tw, 1000000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := keys[i%len(keys)]
+		tw.RemoveTask(key)
+		tw.AddTask(time.Duration(i%86400+1)*time.Second, key, func() {})
+	}
+}
+
+func BenchmarkTick1M(b *testing.B) {
+	tw := newTestWheel()
+	fillWheel(tw, 1000000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tw.advanceTo(tw.currentTick + 1)
+	}
+}
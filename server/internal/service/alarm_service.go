@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/repository"
+)
+
+// Alarm 待触发的告警
+type Alarm struct {
+	Type       string // 告警规则类型
+	GroupID    uint64
+	TaskID     uint64
+	InstanceID uint64
+	Title      string
+	Content    string
+}
+
+// AlarmService 告警服务接口
+type AlarmService interface {
+	Raise(ctx context.Context, alarm *Alarm) (int, error)
+}
+
+// alarmService 告警服务实现
+type alarmService struct {
+	alarmRepo repository.AlarmRepository
+}
+
+// NewAlarmService 创建告警服务
+func NewAlarmService() AlarmService {
+	return &alarmService{
+		alarmRepo: repository.NewAlarmRepository(),
+	}
+}
+
+// Raise 按匹配的启用规则生成待发送的告警记录, 返回生成的记录数
+func (s *alarmService) Raise(ctx context.Context, alarm *Alarm) (int, error) {
+	rules, err := s.alarmRepo.GetEnabledRules(ctx, alarm.Type, alarm.GroupID, alarm.TaskID)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, rule := range rules {
+		record := &model.AlarmRecord{
+			RuleID:       rule.ID,
+			TaskID:       alarm.TaskID,
+			InstanceID:   alarm.InstanceID,
+			AlarmType:    alarm.Type,
+			AlarmLevel:   rule.AlarmLevel,
+			AlarmTitle:   alarm.Title,
+			AlarmContent: alarm.Content,
+			NotifyStatus: model.NotifyStatusPending,
+		}
+		if err := s.alarmRepo.CreateRecord(ctx, record); err != nil {
+			return created, err
+		}
+		created++
+	}
+	return created, nil
+}
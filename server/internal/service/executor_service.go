@@ -4,35 +4,60 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"distributed-scheduler/internal/common/utils"
+	"distributed-scheduler/internal/config"
 	"distributed-scheduler/internal/model"
 	"distributed-scheduler/internal/repository"
+	pkgRedis "distributed-scheduler/pkg/redis"
 )
 
 var (
-	ErrExecutorNotFound = errors.New("执行器不存在")
-	ErrNoAvailableNode  = errors.New("没有可用的执行节点")
+	ErrExecutorNotFound     = errors.New("执行器不存在")
+	ErrNoAvailableNode      = errors.New("没有可用的执行节点")
+	ErrExecutorUnauthorized = errors.New("执行器认证失败")
+	ErrExecutorForbidden    = errors.New("无权操作其他任务组的执行器")
 )
 
 // ExecutorService 执行器服务接口
 type ExecutorService interface {
-	Register(ctx context.Context, appName, host string, port uint, maxConcurrent uint) (*model.ExecutorNode, error)
-	Unregister(ctx context.Context, id string) error
-	Heartbeat(ctx context.Context, heartbeat *model.ExecutorHeartbeat) error
-	GetByID(ctx context.Context, id string) (*model.ExecutorNode, error)
-	GetOnlineByGroupID(ctx context.Context, groupID uint64) ([]*model.ExecutorNode, error)
-	List(ctx context.Context, page, pageSize int, groupID uint64, status int8) ([]*model.ExecutorNode, int64, error)
-	CheckOfflineExecutors(ctx context.Context, timeout time.Duration) (int64, error)
+	Register(ctx context.Context, appName, instanceID, host string, port uint, maxConcurrent uint, labels model.Labels) (*model.ExecutorNode, error)
+	Authenticate(ctx context.Context, credentials *ExecutorCredentials, method, path string, body []byte) (*model.TaskGroup, error)
+	Unregister(ctx context.Context, groupID uint64, id string) error
+	Heartbeat(ctx context.Context, groupID uint64, heartbeat *model.ExecutorHeartbeat) (*model.ExecutorHeartbeatAck, error)
+	Drain(ctx context.Context, groupID uint64, id string) (*model.ExecutorNode, error)
+	CancelDrain(ctx context.Context, scope *model.GroupScope, id string) (*model.ExecutorNode, error)
+	GetByID(ctx context.Context, scope *model.GroupScope, id string) (*model.ExecutorNode, error)
+	GetOnlineByGroupID(ctx context.Context, scope *model.GroupScope, groupID uint64) ([]*model.ExecutorNode, error)
+	List(ctx context.Context, scope *model.GroupScope, page, pageSize int, groupID uint64, status, drainStatus int8) ([]*model.ExecutorNode, int64, error)
+	CheckOfflineExecutors(ctx context.Context, timeout time.Duration) ([]*model.ExecutorNode, error)
+	PruneOfflineExecutors(ctx context.Context, retention time.Duration) (int64, error)
 }
 
+// ExecutorCredentials 执行器请求的签名信息(请求头或gRPC metadata)
+type ExecutorCredentials struct {
+	AppName   string
+	Timestamp string
+	Nonce     string
+	Signature string
+}
+
+// 已使用的执行器请求随机串, 保留到请求时间戳超出允许范围
+const keyExecutorNonce = "executor:nonce:"
+
+// 执行器请求随机串的最大长度
+const maxNonceLength = 64
+
 // executorService 执行器服务实现
 type executorService struct {
 	executorRepo repository.ExecutorRepository
 	groupRepo    repository.TaskGroupRepository
+	instanceRepo repository.InstanceRepository
 }
 
 // NewExecutorService 创建执行器服务
@@ -40,11 +65,13 @@ func NewExecutorService() ExecutorService {
 	return &executorService{
 		executorRepo: repository.NewExecutorRepository(),
 		groupRepo:    repository.NewTaskGroupRepository(),
+		instanceRepo: repository.NewInstanceRepository(),
 	}
 }
 
 // Register 注册执行器
-func (s *executorService) Register(ctx context.Context, appName, host string, port uint, maxConcurrent uint) (*model.ExecutorNode, error) {
+// 执行器ID由应用名称和实例标识(为空时使用host:port)确定, 重启后重新注册会更新原有记录
+func (s *executorService) Register(ctx context.Context, appName, instanceID, host string, port uint, maxConcurrent uint, labels model.Labels) (*model.ExecutorNode, error) {
 	// 获取任务组
 	group, err := s.groupRepo.GetByAppName(ctx, appName)
 	if err != nil {
@@ -54,8 +81,7 @@ func (s *executorService) Register(ctx context.Context, appName, host string, po
 		return nil, err
 	}
 
-	// 生成执行器ID
-	nodeID := uuid.New().String()
+	nodeID := executorNodeID(appName, instanceID, host, port)
 
 	node := &model.ExecutorNode{
 		ID:            nodeID,
@@ -66,6 +92,7 @@ func (s *executorService) Register(ctx context.Context, appName, host string, po
 		Weight:        100,
 		MaxConcurrent: maxConcurrent,
 		CurrentLoad:   0,
+		Labels:        labels,
 		Status:        model.ExecutorStatusOnline,
 		LastHeartbeat: time.Now(),
 		RegisteredAt:  time.Now(),
@@ -78,18 +105,157 @@ func (s *executorService) Register(ctx context.Context, appName, host string, po
 	return node, nil
 }
 
-// Unregister 注销执行器
-func (s *executorService) Unregister(ctx context.Context, id string) error {
+// Authenticate 校验执行器请求签名, 返回签名对应的任务组
+// 签名使用任务组的访问令牌计算, 令牌轮换后的宽限期内旧令牌的签名仍然有效;
+// 签名绑定请求方法和路径, 随机串在时间戳允许范围内只能使用一次, 截获的请求不能重放或用于其他接口
+func (s *executorService) Authenticate(ctx context.Context, credentials *ExecutorCredentials, method, path string, body []byte) (*model.TaskGroup, error) {
+	maxClockSkew, gracePeriod := config.DefaultMaxClockSkew, config.DefaultTokenGracePeriod
+	if cfg := config.GetConfig(); cfg != nil {
+		maxClockSkew, gracePeriod = cfg.Executor.Auth.ClockSkew(), cfg.Executor.Auth.GracePeriod()
+	}
+
+	ts, err := strconv.ParseInt(credentials.Timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 无效的时间戳", ErrExecutorUnauthorized)
+	}
+	// 限制时间戳范围, 随机串只需保留到时间戳超出范围
+	expireAt := time.Unix(ts, 0).Add(maxClockSkew)
+	if skew := time.Since(time.Unix(ts, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return nil, fmt.Errorf("%w: 时间戳超出允许范围", ErrExecutorUnauthorized)
+	}
+	if credentials.Nonce == "" || len(credentials.Nonce) > maxNonceLength {
+		return nil, fmt.Errorf("%w: 无效的随机串", ErrExecutorUnauthorized)
+	}
+
+	group, err := s.groupRepo.GetByAppName(ctx, credentials.AppName)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: 任务组不存在: %s", ErrExecutorUnauthorized, credentials.AppName)
+		}
+		return nil, err
+	}
+	tokens := group.ValidTokens(gracePeriod)
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: 任务组未生成访问令牌", ErrExecutorUnauthorized)
+	}
+	for _, token := range tokens {
+		if utils.VerifyExecutorSignature(token, credentials.AppName, credentials.Timestamp, credentials.Nonce,
+			method, path, body, credentials.Signature) {
+			// 签名校验通过后再记录随机串, 伪造的请求不占用Redis
+			if err := s.useNonce(ctx, credentials.AppName, credentials.Nonce, time.Until(expireAt)); err != nil {
+				return nil, err
+			}
+			return group, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: 签名错误", ErrExecutorUnauthorized)
+}
+
+// useNonce 记录已使用的随机串, 随机串已被使用(请求被重放)时返回ErrExecutorUnauthorized
+// 多个调度节点通过Redis共享记录, Redis不可用时拒绝请求
+func (s *executorService) useNonce(ctx context.Context, appName, nonce string, ttl time.Duration) error {
+	if pkgRedis.GetClient() == nil {
+		return ErrRedisNotInitialized
+	}
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	ok, err := pkgRedis.SetNX(ctx, keyExecutorNonce+appName+":"+nonce, 1, ttl)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: 请求已被使用", ErrExecutorUnauthorized)
+	}
+	return nil
+}
+
+// Unregister 注销执行器, groupID不为0时只能注销该任务组的执行器
+func (s *executorService) Unregister(ctx context.Context, groupID uint64, id string) error {
+	if err := s.checkGroup(ctx, groupID, id); err != nil {
+		return err
+	}
 	return s.executorRepo.Unregister(ctx, id)
 }
 
-// Heartbeat 心跳
-func (s *executorService) Heartbeat(ctx context.Context, heartbeat *model.ExecutorHeartbeat) error {
-	return s.executorRepo.UpdateHeartbeat(ctx, heartbeat)
+// Heartbeat 心跳, groupID不为0时只能更新该任务组的执行器, 返回执行器当前的摘流状态
+// 执行器不存在(如已被清理)时返回ErrExecutorNotFound, 执行器需重新注册
+// 摘流中的执行器上报ReadyToStop且已没有执行中的实例时标记为摘流完成
+func (s *executorService) Heartbeat(ctx context.Context, groupID uint64, heartbeat *model.ExecutorHeartbeat) (*model.ExecutorHeartbeatAck, error) {
+	if heartbeat.ExecutorID == "" {
+		heartbeat.ExecutorID = executorNodeID(heartbeat.AppName, heartbeat.InstanceID, heartbeat.Host, heartbeat.Port)
+	}
+	if err := s.checkGroup(ctx, groupID, heartbeat.ExecutorID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.executorRepo.UpdateHeartbeat(ctx, heartbeat); err != nil {
+		return nil, err
+	}
+	node, err := s.GetByID(ctx, nil, heartbeat.ExecutorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if heartbeat.ReadyToStop && node.DrainStatus == model.DrainStatusDraining {
+		// 下发中的实例可能尚未被执行器接收, 以数据库中的实例状态为准
+		active, err := s.instanceRepo.CountActiveByExecutorID(ctx, node.ID)
+		if err != nil {
+			return nil, err
+		}
+		if active == 0 {
+			if _, err := s.executorRepo.FinishDrain(ctx, node.ID); err != nil {
+				return nil, err
+			}
+			node.DrainStatus = model.DrainStatusDrained
+		}
+	}
+
+	return &model.ExecutorHeartbeatAck{
+		ExecutorID:  node.ID,
+		DrainStatus: node.DrainStatus,
+	}, nil
 }
 
-// GetByID 根据ID获取执行器
-func (s *executorService) GetByID(ctx context.Context, id string) (*model.ExecutorNode, error) {
+// Drain 执行器开始摘流, 不再接收新任务, groupID不为0时只能操作该任务组的执行器
+func (s *executorService) Drain(ctx context.Context, groupID uint64, id string) (*model.ExecutorNode, error) {
+	if err := s.checkGroup(ctx, groupID, id); err != nil {
+		return nil, err
+	}
+	if err := s.executorRepo.StartDrain(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, nil, id)
+}
+
+// CancelDrain 取消摘流, 执行器恢复接收新任务
+func (s *executorService) CancelDrain(ctx context.Context, scope *model.GroupScope, id string) (*model.ExecutorNode, error) {
+	if _, err := s.GetByID(ctx, scope, id); err != nil {
+		return nil, err
+	}
+	if err := s.executorRepo.CancelDrain(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, nil, id)
+}
+
+// checkGroup 检查执行器是否属于指定任务组, groupID为0时不检查
+func (s *executorService) checkGroup(ctx context.Context, groupID uint64, id string) error {
+	if groupID == 0 {
+		return nil
+	}
+	node, err := s.GetByID(ctx, nil, id)
+	if err != nil {
+		return err
+	}
+	if node.GroupID != groupID {
+		return ErrExecutorForbidden
+	}
+	return nil
+}
+
+// GetByID 根据ID获取scope内任务组的执行器
+func (s *executorService) GetByID(ctx context.Context, scope *model.GroupScope, id string) (*model.ExecutorNode, error) {
 	node, err := s.executorRepo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -97,21 +263,60 @@ func (s *executorService) GetByID(ctx context.Context, id string) (*model.Execut
 		}
 		return nil, err
 	}
+	if !scope.Allows(node.GroupID) {
+		return nil, ErrGroupForbidden
+	}
 	return node, nil
 }
 
 // GetOnlineByGroupID 获取任务组的在线执行器
-func (s *executorService) GetOnlineByGroupID(ctx context.Context, groupID uint64) ([]*model.ExecutorNode, error) {
+func (s *executorService) GetOnlineByGroupID(ctx context.Context, scope *model.GroupScope, groupID uint64) ([]*model.ExecutorNode, error) {
+	if !scope.Allows(groupID) {
+		return nil, ErrGroupForbidden
+	}
 	return s.executorRepo.GetOnlineByGroupID(ctx, groupID)
 }
 
-// List 获取执行器列表
-func (s *executorService) List(ctx context.Context, page, pageSize int, groupID uint64, status int8) ([]*model.ExecutorNode, int64, error) {
-	return s.executorRepo.List(ctx, page, pageSize, groupID, status)
+// List 获取scope内任务组的执行器列表
+func (s *executorService) List(ctx context.Context, scope *model.GroupScope, page, pageSize int, groupID uint64, status, drainStatus int8) ([]*model.ExecutorNode, int64, error) {
+	return s.executorRepo.List(ctx, scope, page, pageSize, groupID, status, drainStatus)
+}
+
+// CheckOfflineExecutors 检查离线执行器, 将心跳超时的执行器设置为离线并返回本次设置的执行器
+func (s *executorService) CheckOfflineExecutors(ctx context.Context, timeout time.Duration) ([]*model.ExecutorNode, error) {
+	deadline := time.Now().Add(-timeout)
+	nodes, err := s.executorRepo.GetHeartbeatTimeout(ctx, deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	offline := make([]*model.ExecutorNode, 0, len(nodes))
+	for _, node := range nodes {
+		ok, err := s.executorRepo.SetOfflineIfTimeout(ctx, node.ID, deadline)
+		if err != nil {
+			return offline, err
+		}
+		if ok {
+			node.Status = model.ExecutorStatusOffline
+			offline = append(offline, node)
+		}
+	}
+	return offline, nil
 }
 
-// CheckOfflineExecutors 检查离线执行器
-func (s *executorService) CheckOfflineExecutors(ctx context.Context, timeout time.Duration) (int64, error) {
-	return s.executorRepo.SetOfflineByTimeout(ctx, timeout)
+// PruneOfflineExecutors 清理离线超过retention的执行器
+func (s *executorService) PruneOfflineExecutors(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.executorRepo.DeleteOfflineBefore(ctx, time.Now().Add(-retention))
 }
 
+// executorNamespace 执行器ID的命名空间
+var executorNamespace = uuid.NewSHA1(uuid.NameSpaceOID, []byte("distributed-scheduler/executor"))
+
+// executorNodeID 根据稳定标识生成执行器ID: 应用名称+实例标识, 实例标识为空时使用应用名称+host:port
+func executorNodeID(appName, instanceID, host string, port uint) string {
+	key := instanceID
+	if key == "" {
+		key = host + ":" + strconv.FormatUint(uint64(port), 10)
+	}
+	return uuid.NewSHA1(executorNamespace, []byte(appName+"/"+key)).String()
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+
+	"distributed-scheduler/internal/common/utils"
+	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/repository"
+	pkgRedis "distributed-scheduler/pkg/redis"
+)
+
+// memExecutorRepo 内存中的执行器仓库, 按ID注册或更新
+type memExecutorRepo struct {
+	repository.ExecutorRepository
+	nodes map[string]*model.ExecutorNode
+}
+
+func newMemExecutorRepo() *memExecutorRepo {
+	return &memExecutorRepo{nodes: make(map[string]*model.ExecutorNode)}
+}
+
+func (r *memExecutorRepo) Register(ctx context.Context, node *model.ExecutorNode) error {
+	copied := *node
+	r.nodes[node.ID] = &copied
+	return nil
+}
+
+func (r *memExecutorRepo) GetByID(ctx context.Context, id string) (*model.ExecutorNode, error) {
+	node, ok := r.nodes[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *node
+	return &copied, nil
+}
+
+func (r *memExecutorRepo) UpdateHeartbeat(ctx context.Context, heartbeat *model.ExecutorHeartbeat) (int64, error) {
+	node, ok := r.nodes[heartbeat.ExecutorID]
+	if !ok {
+		return 0, nil
+	}
+	node.CurrentLoad = heartbeat.CurrentLoad
+	return 1, nil
+}
+
+// fakeGroupRepo 按应用名称查询任务组
+type fakeGroupRepo struct {
+	repository.TaskGroupRepository
+	group *model.TaskGroup
+}
+
+func (r *fakeGroupRepo) GetByAppName(ctx context.Context, appName string) (*model.TaskGroup, error) {
+	if r.group == nil || r.group.AppName != appName {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.group, nil
+}
+
+func TestExecutorNodeID(t *testing.T) {
+	// 同一实例重启或更换地址后ID不变
+	id := executorNodeID("app", "pod-1", "10.0.0.1", 9090)
+	if id != executorNodeID("app", "pod-1", "10.0.0.2", 9191) {
+		t.Error("实例标识相同时执行器ID应保持不变")
+	}
+	if id == executorNodeID("app", "pod-2", "10.0.0.1", 9090) || id == executorNodeID("other", "pod-1", "10.0.0.1", 9090) {
+		t.Error("不同实例或应用的执行器ID应不同")
+	}
+
+	// 未设置实例标识时按地址区分
+	byAddr := executorNodeID("app", "", "10.0.0.1", 9090)
+	if byAddr != executorNodeID("app", "", "10.0.0.1", 9090) {
+		t.Error("相同地址的执行器ID应保持不变")
+	}
+	if byAddr == executorNodeID("app", "", "10.0.0.1", 9091) {
+		t.Error("不同端口的执行器ID应不同")
+	}
+	if len(id) != 36 {
+		t.Errorf("执行器ID应为UUID格式, 实际: %q", id)
+	}
+}
+
+func TestReRegisterKeepsIdentity(t *testing.T) {
+	executorRepo := newMemExecutorRepo()
+	s := &executorService{
+		executorRepo: executorRepo,
+		groupRepo:    &fakeGroupRepo{group: &model.TaskGroup{ID: 1, AppName: "app"}},
+	}
+	ctx := context.Background()
+
+	first, err := s.Register(ctx, "app", "pod-1", "10.0.0.1", 9090, 10, nil)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	// 重启后地址变化, 重新注册更新原有记录
+	second, err := s.Register(ctx, "app", "pod-1", "10.0.0.2", 9191, 20, nil)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if first.ID != second.ID || len(executorRepo.nodes) != 1 {
+		t.Fatalf("重新注册应更新原有执行器, 实际ID: %s %s, 执行器数: %d", first.ID, second.ID, len(executorRepo.nodes))
+	}
+	if node := executorRepo.nodes[first.ID]; node.Host != "10.0.0.2" || node.Port != 9191 || node.MaxConcurrent != 20 {
+		t.Errorf("重新注册应更新地址和并发上限, 实际: %+v", node)
+	}
+
+	// 心跳未携带执行器ID时按应用名称和实例标识推导
+	ack, err := s.Heartbeat(ctx, 1, &model.ExecutorHeartbeat{AppName: "app", InstanceID: "pod-1", CurrentLoad: 3})
+	if err != nil {
+		t.Fatalf("Heartbeat: %v", err)
+	}
+	if ack.ExecutorID != first.ID || executorRepo.nodes[first.ID].CurrentLoad != 3 {
+		t.Errorf("心跳应更新推导出的执行器, 实际: %s", ack.ExecutorID)
+	}
+
+	// 已被清理的执行器心跳返回ErrExecutorNotFound, 执行器需重新注册
+	if _, err := s.Heartbeat(ctx, 1, &model.ExecutorHeartbeat{AppName: "app", InstanceID: "pod-2"}); !errors.Is(err, ErrExecutorNotFound) {
+		t.Errorf("未注册的执行器心跳应返回ErrExecutorNotFound, 实际: %v", err)
+	}
+
+	if _, err := s.Register(ctx, "missing", "pod-1", "10.0.0.1", 9090, 10, nil); err == nil {
+		t.Error("任务组不存在时注册应失败")
+	}
+}
+
+func TestAuthenticateRejectsReplay(t *testing.T) {
+	mr := miniredis.RunT(t)
+	pkgRedis.Client = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		pkgRedis.Client.Close()
+		pkgRedis.Client = nil
+	})
+
+	s := &executorService{groupRepo: &fakeGroupRepo{group: &model.TaskGroup{ID: 1, AppName: "app", AccessToken: "token"}}}
+	ctx := context.Background()
+	body := []byte(`{"executor_id":"e1"}`)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	credentials := func(nonce, method, path string) *ExecutorCredentials {
+		return &ExecutorCredentials{
+			AppName:   "app",
+			Timestamp: timestamp,
+			Nonce:     nonce,
+			Signature: utils.SignExecutorRequest("token", "app", timestamp, nonce, method, path, body),
+		}
+	}
+
+	heartbeat := credentials("n1", "POST", "/api/v1/executor/heartbeat")
+	if group, err := s.Authenticate(ctx, heartbeat, "POST", "/api/v1/executor/heartbeat", body); err != nil || group.ID != 1 {
+		t.Fatalf("正确签名的请求应认证通过, 实际: %v", err)
+	}
+	// 截获的心跳原样重放
+	if _, err := s.Authenticate(ctx, heartbeat, "POST", "/api/v1/executor/heartbeat", body); !errors.Is(err, ErrExecutorUnauthorized) {
+		t.Errorf("重放的请求应被拒绝, 实际: %v", err)
+	}
+	// 截获的心跳换用新随机串或改发到其他接口
+	replayed := credentials("n2", "POST", "/api/v1/executor/heartbeat")
+	if _, err := s.Authenticate(ctx, replayed, "POST", "/api/v1/executor/unregister", body); !errors.Is(err, ErrExecutorUnauthorized) {
+		t.Errorf("签名不能用于其他接口, 实际: %v", err)
+	}
+	forged := *heartbeat
+	forged.Nonce = "n3"
+	if _, err := s.Authenticate(ctx, &forged, "POST", "/api/v1/executor/heartbeat", body); !errors.Is(err, ErrExecutorUnauthorized) {
+		t.Errorf("随机串不能替换, 实际: %v", err)
+	}
+	if _, err := s.Authenticate(ctx, credentials("", "POST", "/api/v1/executor/drain"), "POST", "/api/v1/executor/drain", body); !errors.Is(err, ErrExecutorUnauthorized) {
+		t.Errorf("缺少随机串的请求应被拒绝, 实际: %v", err)
+	}
+
+	// 随机串保留到时间戳超出允许范围, 伪造的请求不记录随机串
+	ttl := mr.TTL(keyExecutorNonce + "app:n1")
+	if ttl <= 0 || ttl > config.DefaultMaxClockSkew+time.Second {
+		t.Errorf("随机串的保留时间应为时间戳的剩余有效期, 实际: %v", ttl)
+	}
+	if mr.Exists(keyExecutorNonce+"app:n2") || mr.Exists(keyExecutorNonce+"app:n3") {
+		t.Error("签名校验失败的请求不应记录随机串")
+	}
+}
+
+func (r *memExecutorRepo) FinishDrain(ctx context.Context, id string) (bool, error) {
+	node, ok := r.nodes[id]
+	if !ok || node.DrainStatus != model.DrainStatusDraining {
+		return false, nil
+	}
+	node.DrainStatus = model.DrainStatusDrained
+	return true, nil
+}
+
+// activeInstanceRepo 返回固定的执行中实例数
+type activeInstanceRepo struct {
+	repository.InstanceRepository
+	active int64
+}
+
+func (r *activeInstanceRepo) CountActiveByExecutorID(ctx context.Context, executorID string) (int64, error) {
+	return r.active, nil
+}
+
+func TestHeartbeatFinishesDrain(t *testing.T) {
+	tests := []struct {
+		name        string
+		drainStatus int8
+		readyToStop bool
+		active      int64
+		want        int8
+	}{
+		{"draining and ready", model.DrainStatusDraining, true, 0, model.DrainStatusDrained},
+		// 已分配但尚未被执行器接收的实例仍在数据库中处于执行中
+		{"instances still active", model.DrainStatusDraining, true, 1, model.DrainStatusDraining},
+		{"draining not ready", model.DrainStatusDraining, false, 0, model.DrainStatusDraining},
+		{"not draining", model.DrainStatusNone, true, 0, model.DrainStatusNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemExecutorRepo()
+			repo.nodes["e1"] = &model.ExecutorNode{ID: "e1", GroupID: 1, DrainStatus: tt.drainStatus}
+			s := &executorService{executorRepo: repo, instanceRepo: &activeInstanceRepo{active: tt.active}}
+
+			ack, err := s.Heartbeat(context.Background(), 0, &model.ExecutorHeartbeat{ExecutorID: "e1", ReadyToStop: tt.readyToStop})
+			if err != nil {
+				t.Fatalf("Heartbeat: %v", err)
+			}
+			if ack.ExecutorID != "e1" || ack.DrainStatus != tt.want {
+				t.Fatalf("ack = %+v, want drain status %d", ack, tt.want)
+			}
+			if repo.nodes["e1"].DrainStatus != tt.want {
+				t.Fatalf("stored drain status = %d, want %d", repo.nodes["e1"].DrainStatus, tt.want)
+			}
+		})
+	}
+}
@@ -3,27 +3,47 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
 
 	"distributed-scheduler/internal/model"
 	"distributed-scheduler/internal/repository"
+	"distributed-scheduler/internal/scheduler/dispatcher"
+	"distributed-scheduler/internal/scheduler/quota"
+	"distributed-scheduler/pkg/logger"
 )
 
 var (
-	ErrInstanceNotFound = errors.New("任务实例不存在")
+	ErrInstanceNotFound  = errors.New("任务实例不存在")
+	ErrInstanceForbidden = errors.New("无权上报其他任务组的任务实例")
+	ErrInstanceFinished  = errors.New("任务实例已结束")
+	ErrInstanceChanged   = errors.New("任务实例状态正在变化, 请稍后重试")
+	// ErrStaleDispatch 上报的执行结果属于已被取代的分发
+	ErrStaleDispatch = errors.New("执行结果已过期")
 )
 
 // InstanceService 任务实例服务接口
 type InstanceService interface {
-	GetByID(ctx context.Context, id uint64) (*model.TaskInstance, error)
-	List(ctx context.Context, page, pageSize int, taskID uint64, status int8, startTime, endTime *time.Time) ([]*model.TaskInstance, int64, error)
-	Cancel(ctx context.Context, id uint64) error
-	Retry(ctx context.Context, id uint64) (*model.TaskInstance, error)
-	GetLogs(ctx context.Context, instanceID uint64, page, pageSize int) ([]*model.TaskLog, int64, error)
-	GetStatistics(ctx context.Context, taskID uint64, startTime, endTime time.Time) (*InstanceStatistics, error)
-	GetRecentInstances(ctx context.Context, limit int) ([]*model.TaskInstance, error)
+	GetByID(ctx context.Context, scope *model.GroupScope, id uint64) (*model.TaskInstance, error)
+	List(ctx context.Context, scope *model.GroupScope, page, pageSize int, taskID uint64, status int8, startTime, endTime *time.Time) ([]*model.TaskInstance, int64, error)
+	Cancel(ctx context.Context, scope *model.GroupScope, id uint64) error
+	Retry(ctx context.Context, scope *model.GroupScope, id uint64) (*model.TaskInstance, error)
+	GetLogs(ctx context.Context, scope *model.GroupScope, instanceID uint64, page, pageSize int) ([]*model.TaskLog, int64, error)
+	GetStatistics(ctx context.Context, scope *model.GroupScope, taskID uint64, startTime, endTime time.Time) (*InstanceStatistics, error)
+	GetRecentInstances(ctx context.Context, scope *model.GroupScope, limit int) ([]*model.TaskInstance, error)
+	Callback(ctx context.Context, groupID uint64, result *model.ExecutorResult) error
+	FailoverOrphaned(ctx context.Context, limit int) ([]*FailoverResult, error)
+	SaveLogs(ctx context.Context, groupID uint64, logs []*model.TaskLog) error
+}
+
+// FailoverResult 执行器离线后执行中实例的处理结果
+type FailoverResult struct {
+	InstanceID uint64
+	TaskID     uint64
+	ExecutorID string
+	Rerouted   bool // true表示已恢复为待调度, false表示已标记为失败
 }
 
 // InstanceStatistics 实例统计
@@ -42,6 +62,9 @@ type instanceService struct {
 	instanceRepo repository.InstanceRepository
 	logRepo      repository.TaskLogRepository
 	taskRepo     repository.TaskRepository
+	executorRepo repository.ExecutorRepository
+	quota        *quota.Manager
+	clientFor    func(executorType string) dispatcher.ExecutorClient // 按执行器类型获取执行器客户端, 用于终止执行中的实例
 }
 
 // NewInstanceService 创建任务实例服务
@@ -50,11 +73,14 @@ func NewInstanceService() InstanceService {
 		instanceRepo: repository.NewInstanceRepository(),
 		logRepo:      repository.NewTaskLogRepository(),
 		taskRepo:     repository.NewTaskRepository(),
+		executorRepo: repository.NewExecutorRepository(),
+		quota:        quota.NewManager(),
+		clientFor:    dispatcher.ClientFor,
 	}
 }
 
-// GetByID 根据ID获取任务实例
-func (s *instanceService) GetByID(ctx context.Context, id uint64) (*model.TaskInstance, error) {
+// GetByID 根据ID获取scope内任务组的任务实例
+func (s *instanceService) GetByID(ctx context.Context, scope *model.GroupScope, id uint64) (*model.TaskInstance, error) {
 	instance, err := s.instanceRepo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -62,32 +88,97 @@ func (s *instanceService) GetByID(ctx context.Context, id uint64) (*model.TaskIn
 		}
 		return nil, err
 	}
+	if !scope.Allows(instance.GroupID) {
+		return nil, ErrGroupForbidden
+	}
 	return instance, nil
 }
 
-// List 获取任务实例列表
-func (s *instanceService) List(ctx context.Context, page, pageSize int, taskID uint64, status int8, startTime, endTime *time.Time) ([]*model.TaskInstance, int64, error) {
-	return s.instanceRepo.List(ctx, page, pageSize, taskID, status, startTime, endTime)
+// List 获取scope内任务组的任务实例列表
+func (s *instanceService) List(ctx context.Context, scope *model.GroupScope, page, pageSize int, taskID uint64, status int8, startTime, endTime *time.Time) ([]*model.TaskInstance, int64, error) {
+	return s.instanceRepo.List(ctx, scope, page, pageSize, taskID, status, startTime, endTime)
 }
 
-// Cancel 取消任务实例
-func (s *instanceService) Cancel(ctx context.Context, id uint64) error {
-	instance, err := s.instanceRepo.GetByID(ctx, id)
+// 取消期间实例状态持续变化(如被反复重新分发)时的最大尝试次数
+const cancelAttempts = 3
+
+// Cancel 取消任务实例, 已下发的实例同时通知执行器终止执行
+// 按查询到的状态和派发令牌条件更新, 与分发、回调和故障转移并发时以先完成的为准
+func (s *instanceService) Cancel(ctx context.Context, scope *model.GroupScope, id uint64) error {
+	instance, err := s.GetByID(ctx, scope, id)
 	if err != nil {
 		return err
 	}
 
-	// 只能取消待调度或调度中的任务
-	if instance.Status != model.InstanceStatusPending && instance.Status != model.InstanceStatusScheduling {
-		return errors.New("只能取消待调度或调度中的任务")
+	for attempt := 0; attempt < cancelAttempts; attempt++ {
+		var ok bool
+		switch instance.Status {
+		case model.InstanceStatusPending:
+			ok, err = s.instanceRepo.FinishIfStatus(ctx, id, model.InstanceStatusPending, model.InstanceStatusCancelled, 0, "用户取消")
+		case model.InstanceStatusScheduling, model.InstanceStatusRunning:
+			// 执行器之后上报的结果因实例已结束被忽略
+			ok, err = s.instanceRepo.FinishAttempt(ctx, id, instance.DispatchToken, model.InstanceStatusCancelled, 0, "用户取消")
+		default:
+			return ErrInstanceFinished
+		}
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// 实例已被分发、结束或重新分发, 按最新状态重试
+			if instance, err = s.GetByID(ctx, scope, id); err != nil {
+				return err
+			}
+			continue
+		}
+
+		s.releaseQuota(ctx, instance)
+		if instance.Status == model.InstanceStatusPending {
+			return nil
+		}
+		// 调度中的实例可能在取消前已被分发器更新为执行中, 重新查询执行器;
+		// 尚未更新时分发器更新失败后会自行终止本次下发
+		if instance.Status == model.InstanceStatusScheduling {
+			if current, err := s.instanceRepo.GetByID(ctx, id); err == nil {
+				instance = current
+			}
+		}
+		s.kill(ctx, instance)
+		return nil
+	}
+	return ErrInstanceChanged
+}
+
+// kill 通知执行器终止实例本次派发令牌的执行, 失败时只记录日志
+// 执行器忽略令牌不一致的终止请求, 不会误杀实例重新分发后的执行
+// HTTP_CALL实例由调度中心直接执行, 没有执行器, 请求在超时后自然结束
+func (s *instanceService) kill(ctx context.Context, instance *model.TaskInstance) {
+	if instance.ExecutorID == "" {
+		return
+	}
+	node, err := s.executorRepo.GetByID(ctx, instance.ExecutorID)
+	if err != nil {
+		logger.Warnf("执行器不存在, 无法终止实例, instance_id=%d, executor_id=%s: %v", instance.ID, instance.ExecutorID, err)
+		return
 	}
 
-	return s.instanceRepo.UpdateStatus(ctx, id, model.InstanceStatusCancelled, 0, "用户取消")
+	executorType := ""
+	if instance.Task != nil {
+		executorType = instance.Task.ExecutorType
+	}
+	killed, err := s.clientFor(executorType).Kill(ctx, node, instance.ID, instance.DispatchToken)
+	if err != nil {
+		logger.Warnf("通知执行器终止实例失败, instance_id=%d, executor=%s: %v", instance.ID, node.Address(), err)
+		return
+	}
+	if killed {
+		logger.Infof("已终止执行器上的实例, instance_id=%d, dispatch_token=%d, executor=%s", instance.ID, instance.DispatchToken, node.Address())
+	}
 }
 
 // Retry 重试任务实例
-func (s *instanceService) Retry(ctx context.Context, id uint64) (*model.TaskInstance, error) {
-	instance, err := s.instanceRepo.GetByID(ctx, id)
+func (s *instanceService) Retry(ctx context.Context, scope *model.GroupScope, id uint64) (*model.TaskInstance, error) {
+	instance, err := s.GetByID(ctx, scope, id)
 	if err != nil {
 		return nil, err
 	}
@@ -118,13 +209,16 @@ func (s *instanceService) Retry(ctx context.Context, id uint64) (*model.TaskInst
 }
 
 // GetLogs 获取任务实例日志
-func (s *instanceService) GetLogs(ctx context.Context, instanceID uint64, page, pageSize int) ([]*model.TaskLog, int64, error) {
+func (s *instanceService) GetLogs(ctx context.Context, scope *model.GroupScope, instanceID uint64, page, pageSize int) ([]*model.TaskLog, int64, error) {
+	if _, err := s.GetByID(ctx, scope, instanceID); err != nil {
+		return nil, 0, err
+	}
 	return s.logRepo.GetByInstanceID(ctx, instanceID, page, pageSize)
 }
 
-// GetStatistics 获取实例统计
-func (s *instanceService) GetStatistics(ctx context.Context, taskID uint64, startTime, endTime time.Time) (*InstanceStatistics, error) {
-	countMap, err := s.instanceRepo.CountByStatus(ctx, taskID, startTime, endTime)
+// GetStatistics 获取scope内任务组的实例统计
+func (s *instanceService) GetStatistics(ctx context.Context, scope *model.GroupScope, taskID uint64, startTime, endTime time.Time) (*InstanceStatistics, error) {
+	countMap, err := s.instanceRepo.CountByStatus(ctx, scope, taskID, startTime, endTime)
 	if err != nil {
 		return nil, err
 	}
@@ -145,8 +239,135 @@ func (s *instanceService) GetStatistics(ctx context.Context, taskID uint64, star
 	return stats, nil
 }
 
-// GetRecentInstances 获取最近的实例
-func (s *instanceService) GetRecentInstances(ctx context.Context, limit int) ([]*model.TaskInstance, error) {
-	return s.instanceRepo.GetRecentInstances(ctx, limit)
+// GetRecentInstances 获取scope内任务组最近的实例
+func (s *instanceService) GetRecentInstances(ctx context.Context, scope *model.GroupScope, limit int) ([]*model.TaskInstance, error) {
+	return s.instanceRepo.GetRecentInstances(ctx, scope, limit)
+}
+
+// Callback 处理执行器上报的执行结果, groupID不为0时只接受该任务组的实例
+func (s *instanceService) Callback(ctx context.Context, groupID uint64, result *model.ExecutorResult) error {
+	instance, err := s.instanceRepo.GetByID(ctx, result.InstanceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInstanceNotFound
+		}
+		return err
+	}
+	if groupID != 0 && instance.GroupID != groupID {
+		return ErrInstanceForbidden
+	}
+
+	// 已被重新分发的旧执行结果不再接受; 实例已分配令牌时未携带令牌的结果同样拒绝
+	if instance.DispatchToken > 0 && result.DispatchToken != instance.DispatchToken {
+		return fmt.Errorf("%w: 实例当前令牌为%d, 上报令牌为%d", ErrStaleDispatch, instance.DispatchToken, result.DispatchToken)
+	}
+	// 已结束的实例忽略重复上报
+	if instance.Status != model.InstanceStatusScheduling && instance.Status != model.InstanceStatusRunning {
+		return nil
+	}
+
+	status := int8(model.InstanceStatusSuccess)
+	if result.Code != 0 {
+		status = model.InstanceStatusFailed
+	}
+	finished, err := s.instanceRepo.FinishAttempt(ctx, result.InstanceID, instance.DispatchToken, status, result.Code, result.Message)
+	if err != nil {
+		return err
+	}
+	// 查询后实例被取消、结束或重新分发
+	if !finished {
+		return ErrStaleDispatch
+	}
+	s.releaseQuota(ctx, instance)
+
+	// panic调用栈写入执行日志
+	if result.Stack != "" {
+		if err := s.logRepo.Create(ctx, &model.TaskLog{
+			InstanceID: instance.ID,
+			TaskID:     instance.TaskID,
+			LogTime:    time.Now(),
+			LogLevel:   model.LogLevelError,
+			LogContent: result.Message + "\n" + result.Stack,
+		}); err != nil {
+			logger.Errorf("保存panic调用栈失败, instance_id=%d: %v", instance.ID, err)
+		}
+	}
+	return nil
+}
+
+// SaveLogs 保存执行器上报的执行日志, groupID不为0时只接受该任务组的实例
+func (s *instanceService) SaveLogs(ctx context.Context, groupID uint64, logs []*model.TaskLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	instances := make(map[uint64]*model.TaskInstance)
+	for _, log := range logs {
+		instance, ok := instances[log.InstanceID]
+		if !ok {
+			var err error
+			instance, err = s.instanceRepo.GetByID(ctx, log.InstanceID)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrInstanceNotFound
+				}
+				return err
+			}
+			if groupID != 0 && instance.GroupID != groupID {
+				return ErrInstanceForbidden
+			}
+			instances[log.InstanceID] = instance
+		}
+		log.TaskID = instance.TaskID
+	}
+	return s.logRepo.BatchCreate(ctx, logs)
 }
 
+// FailoverOrphaned 处理执行器已离线或已删除的执行中实例
+// 任务故障转移策略为REROUTE时恢复为待调度状态由调度器重新路由, 否则标记为失败
+func (s *instanceService) FailoverOrphaned(ctx context.Context, limit int) ([]*FailoverResult, error) {
+	instances, err := s.instanceRepo.GetOrphanedInstances(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*FailoverResult, 0, len(instances))
+	for _, instance := range instances {
+		reroute := instance.Task != nil && instance.Task.FailoverStrategy == model.FailoverStrategyReroute
+
+		var ok bool
+		if reroute {
+			ok, err = s.instanceRepo.RequeueIfStatus(ctx, instance.ID, model.InstanceStatusRunning,
+				"执行器离线, 等待重新调度: "+instance.ExecutorAddress)
+		} else {
+			ok, err = s.instanceRepo.FinishIfStatus(ctx, instance.ID, model.InstanceStatusRunning,
+				model.InstanceStatusFailed, -1, "执行器离线: "+instance.ExecutorAddress)
+		}
+		if err != nil {
+			return results, err
+		}
+		// 实例已被回调或其他调度节点处理
+		if !ok {
+			continue
+		}
+		s.releaseQuota(ctx, instance)
+		// 心跳超时的执行器可能只是网络分区仍在执行, 按原派发令牌尽力通知终止, 避免与重新调度的执行重复;
+		// 重新调度到同一执行器时令牌不一致, 终止请求被忽略; 不可达的执行器会等待到请求超时, 不阻塞故障转移
+		go s.kill(context.Background(), instance)
+
+		results = append(results, &FailoverResult{
+			InstanceID: instance.ID,
+			TaskID:     instance.TaskID,
+			ExecutorID: instance.ExecutorID,
+			Rerouted:   reroute,
+		})
+	}
+	return results, nil
+}
+
+// releaseQuota 释放实例占用的并发配额, 失败时由调度器对账回收
+func (s *instanceService) releaseQuota(ctx context.Context, instance *model.TaskInstance) {
+	if err := s.quota.Release(ctx, instance); err != nil {
+		logger.Errorf("释放并发配额失败, instance_id=%d: %v", instance.ID, err)
+	}
+}
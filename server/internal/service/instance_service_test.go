@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/repository"
+	"distributed-scheduler/internal/scheduler/dispatcher"
+	"distributed-scheduler/internal/scheduler/quota"
+)
+
+// fakeInstanceRepo 内存中的实例仓库, 只实现回调用到的方法
+type fakeInstanceRepo struct {
+	repository.InstanceRepository
+	instance *model.TaskInstance
+	stale    *model.TaskInstance // 非空时第一次查询返回该实例, 模拟查询后实例被并发修改
+}
+
+func (r *fakeInstanceRepo) GetByID(ctx context.Context, id uint64) (*model.TaskInstance, error) {
+	if r.stale != nil {
+		stale := r.stale
+		r.stale = nil
+		return stale, nil
+	}
+	copied := *r.instance
+	return &copied, nil
+}
+
+func (r *fakeInstanceRepo) FinishAttempt(ctx context.Context, id, token uint64, status int8, resultCode int, resultMsg string) (bool, error) {
+	if r.instance.DispatchToken != token || r.instance.Status > model.InstanceStatusRunning {
+		return false, nil
+	}
+	r.instance.Status = status
+	r.instance.ResultCode = resultCode
+	return true, nil
+}
+
+func (r *fakeInstanceRepo) FinishIfStatus(ctx context.Context, id uint64, from, status int8, resultCode int, resultMsg string) (bool, error) {
+	if r.instance.Status != from {
+		return false, nil
+	}
+	r.instance.Status = status
+	return true, nil
+}
+
+// fakeExecutorRepo 只实现按ID查询执行器
+type fakeExecutorRepo struct {
+	repository.ExecutorRepository
+	node *model.ExecutorNode
+}
+
+func (r *fakeExecutorRepo) GetByID(ctx context.Context, id string) (*model.ExecutorNode, error) {
+	if r.node == nil || r.node.ID != id {
+		return nil, errors.New("执行器不存在")
+	}
+	return r.node, nil
+}
+
+// killRequest 终止请求
+type killRequest struct {
+	instanceID uint64
+	token      uint64
+}
+
+// fakeExecutorClient 记录终止请求的执行器客户端
+type fakeExecutorClient struct {
+	dispatcher.ExecutorClient
+	killed []killRequest
+}
+
+func (c *fakeExecutorClient) Kill(ctx context.Context, node *model.ExecutorNode, instanceID, dispatchToken uint64) (bool, error) {
+	c.killed = append(c.killed, killRequest{instanceID, dispatchToken})
+	return true, nil
+}
+
+func TestCancelKillsRunningInstance(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int8
+		executorID string
+		wantErr    error
+		wantKilled int
+	}{
+		{"pending", model.InstanceStatusPending, "", nil, 0},
+		// 尚未分发完成, 由分发器更新为执行中失败后终止
+		{"scheduling", model.InstanceStatusScheduling, "", nil, 0},
+		{"scheduling already dispatched", model.InstanceStatusScheduling, "e1", nil, 1},
+		{"running on executor", model.InstanceStatusRunning, "e1", nil, 1},
+		{"running http call", model.InstanceStatusRunning, "", nil, 0},
+		{"finished", model.InstanceStatusSuccess, "e1", ErrInstanceFinished, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeInstanceRepo{instance: &model.TaskInstance{
+				ID: 1, GroupID: 1, Status: tt.status, ExecutorID: tt.executorID, DispatchToken: 5,
+				Task: &model.Task{ExecutorType: model.ExecutorTypeGRPC},
+			}}
+			client := &fakeExecutorClient{}
+			var clientType string
+			s := &instanceService{
+				instanceRepo: repo,
+				executorRepo: &fakeExecutorRepo{node: &model.ExecutorNode{ID: "e1"}},
+				quota:        &quota.Manager{},
+				clientFor: func(executorType string) dispatcher.ExecutorClient {
+					clientType = executorType
+					return client
+				},
+			}
+
+			err := s.Cancel(context.Background(), nil, 1)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Cancel err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && repo.instance.Status != model.InstanceStatusCancelled {
+				t.Errorf("status = %d, want cancelled", repo.instance.Status)
+			}
+			if len(client.killed) != tt.wantKilled {
+				t.Fatalf("kill calls = %v, want %d", client.killed, tt.wantKilled)
+			}
+			if tt.wantKilled > 0 && clientType != model.ExecutorTypeGRPC {
+				t.Errorf("kill should use the task's executor client, got %q", clientType)
+			}
+			if tt.wantKilled > 0 && client.killed[0] != (killRequest{1, 5}) {
+				t.Errorf("kill request = %v, want the instance's dispatch token", client.killed[0])
+			}
+		})
+	}
+}
+
+func TestCancelRetriesOnConcurrentChange(t *testing.T) {
+	current := &model.TaskInstance{ID: 1, GroupID: 1, Status: model.InstanceStatusRunning, ExecutorID: "e1", DispatchToken: 2}
+	// 查询时实例仍在等待调度, 取消前已被分发为新的执行
+	repo := &fakeInstanceRepo{instance: current, stale: &model.TaskInstance{ID: 1, GroupID: 1, Status: model.InstanceStatusPending, DispatchToken: 1}}
+	client := &fakeExecutorClient{}
+	s := &instanceService{
+		instanceRepo: repo,
+		executorRepo: &fakeExecutorRepo{node: &model.ExecutorNode{ID: "e1"}},
+		quota:        &quota.Manager{},
+		clientFor:    func(string) dispatcher.ExecutorClient { return client },
+	}
+
+	if err := s.Cancel(context.Background(), nil, 1); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if current.Status != model.InstanceStatusCancelled {
+		t.Fatalf("status = %d, want cancelled", current.Status)
+	}
+	if len(client.killed) != 1 || client.killed[0] != (killRequest{1, 2}) {
+		t.Fatalf("kill requests = %v, want the new dispatch token", client.killed)
+	}
+}
+
+func TestCallbackDispatchToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		instanceToken uint64
+		resultToken   uint64
+		wantErr       error
+		wantStatus    int8
+	}{
+		{"matching token", 3, 3, nil, model.InstanceStatusSuccess},
+		{"stale token", 3, 2, ErrStaleDispatch, model.InstanceStatusRunning},
+		{"missing token", 3, 0, ErrStaleDispatch, model.InstanceStatusRunning},
+		{"instance without token", 0, 0, nil, model.InstanceStatusSuccess},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeInstanceRepo{instance: &model.TaskInstance{
+				ID: 1, GroupID: 1, Status: model.InstanceStatusRunning, DispatchToken: tt.instanceToken,
+			}}
+			s := &instanceService{instanceRepo: repo, quota: &quota.Manager{}}
+
+			err := s.Callback(context.Background(), 1, &model.ExecutorResult{InstanceID: 1, DispatchToken: tt.resultToken})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Callback err = %v, want %v", err, tt.wantErr)
+			}
+			if repo.instance.Status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", repo.instance.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestGetByIDRespectsGroupScope(t *testing.T) {
+	s := &instanceService{instanceRepo: &fakeInstanceRepo{instance: &model.TaskInstance{ID: 1, GroupID: 2}}}
+	ctx := context.Background()
+
+	if _, err := s.GetByID(ctx, &model.GroupScope{GroupIDs: []uint64{2}}, 1); err != nil {
+		t.Errorf("成员所在任务组的实例应可访问, 实际: %v", err)
+	}
+	if _, err := s.GetByID(ctx, &model.GroupScope{GroupIDs: []uint64{1, 3}}, 1); !errors.Is(err, ErrGroupForbidden) {
+		t.Errorf("其他任务组的实例应返回ErrGroupForbidden, 实际: %v", err)
+	}
+	if _, err := s.GetByID(ctx, &model.GroupScope{All: true}, 1); err != nil {
+		t.Errorf("超级管理员应可访问所有任务组的实例, 实际: %v", err)
+	}
+}
+
+// orphanInstanceRepo 返回执行器离线的执行中实例, 记录恢复为待调度的实例
+type orphanInstanceRepo struct {
+	fakeInstanceRepo
+}
+
+func (r *orphanInstanceRepo) GetOrphanedInstances(ctx context.Context, limit int) ([]*model.TaskInstance, error) {
+	copied := *r.instance
+	return []*model.TaskInstance{&copied}, nil
+}
+
+func (r *orphanInstanceRepo) RequeueIfStatus(ctx context.Context, id uint64, from int8, reason string) (bool, error) {
+	if r.instance.Status != from {
+		return false, nil
+	}
+	r.instance.Status = model.InstanceStatusPending
+	return true, nil
+}
+
+// asyncKillClient 通过通道上报终止请求的执行器客户端
+type asyncKillClient struct {
+	dispatcher.ExecutorClient
+	killed chan killRequest
+}
+
+func (c *asyncKillClient) Kill(ctx context.Context, node *model.ExecutorNode, instanceID, dispatchToken uint64) (bool, error) {
+	c.killed <- killRequest{instanceID, dispatchToken}
+	return false, nil
+}
+
+func TestFailoverKillsWithOldToken(t *testing.T) {
+	repo := &orphanInstanceRepo{fakeInstanceRepo{instance: &model.TaskInstance{
+		ID: 1, GroupID: 1, Status: model.InstanceStatusRunning, ExecutorID: "e1", DispatchToken: 4,
+		Task: &model.Task{FailoverStrategy: model.FailoverStrategyReroute},
+	}}}
+	client := &asyncKillClient{killed: make(chan killRequest, 1)}
+	s := &instanceService{
+		instanceRepo: repo,
+		executorRepo: &fakeExecutorRepo{node: &model.ExecutorNode{ID: "e1"}},
+		quota:        &quota.Manager{},
+		clientFor:    func(string) dispatcher.ExecutorClient { return client },
+	}
+
+	results, err := s.FailoverOrphaned(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("FailoverOrphaned: %v", err)
+	}
+	if len(results) != 1 || !results[0].Rerouted {
+		t.Fatalf("results = %+v, want one rerouted instance", results)
+	}
+	// 重新调度的执行使用新令牌, 终止请求携带原令牌, 执行器据此只终止旧的执行
+	select {
+	case req := <-client.killed:
+		if req != (killRequest{1, 4}) {
+			t.Fatalf("kill request = %v, want the old dispatch token", req)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("orphaned execution was not killed")
+	}
+}
+
+func TestFailoverOrphanedStrategy(t *testing.T) {
+	tests := []struct {
+		name         string
+		strategy     string
+		status       int8
+		wantStatus   int8
+		wantResults  int
+		wantRerouted bool
+	}{
+		{"fail", model.FailoverStrategyFail, model.InstanceStatusRunning, model.InstanceStatusFailed, 1, false},
+		{"reroute", model.FailoverStrategyReroute, model.InstanceStatusRunning, model.InstanceStatusPending, 1, true},
+		// 查询后实例已回调完成, 不再处理
+		{"already finished", model.FailoverStrategyFail, model.InstanceStatusSuccess, model.InstanceStatusSuccess, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &orphanInstanceRepo{fakeInstanceRepo{instance: &model.TaskInstance{
+				ID: 1, TaskID: 2, GroupID: 1, Status: tt.status, ExecutorID: "e1", DispatchToken: 4,
+				Task: &model.Task{FailoverStrategy: tt.strategy},
+			}}}
+			client := &asyncKillClient{killed: make(chan killRequest, 1)}
+			s := &instanceService{
+				instanceRepo: repo,
+				executorRepo: &fakeExecutorRepo{node: &model.ExecutorNode{ID: "e1"}},
+				quota:        &quota.Manager{},
+				clientFor:    func(string) dispatcher.ExecutorClient { return client },
+			}
+
+			results, err := s.FailoverOrphaned(context.Background(), 10)
+			if err != nil {
+				t.Fatalf("FailoverOrphaned: %v", err)
+			}
+			if repo.instance.Status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", repo.instance.Status, tt.wantStatus)
+			}
+			if len(results) != tt.wantResults {
+				t.Fatalf("results = %+v, want %d", results, tt.wantResults)
+			}
+			if tt.wantResults > 0 {
+				want := FailoverResult{InstanceID: 1, TaskID: 2, ExecutorID: "e1", Rerouted: tt.wantRerouted}
+				if *results[0] != want {
+					t.Errorf("result = %+v, want %+v", *results[0], want)
+				}
+				<-client.killed
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/repository"
+)
+
+// 用户权限缓存时间, 角色或权限变更后最多经过该时间生效
+const permissionCacheTTL = 30 * time.Second
+
+// UserPermissions 用户的有效权限
+type UserPermissions struct {
+	Roles       []string `json:"roles"`       // 角色编码
+	Permissions []string `json:"permissions"` // 权限编码(资源:操作)
+	SuperAdmin  bool     `json:"super_admin"` // 超级管理员, 拥有所有权限
+	// 任务组授权(任务组ID -> 组内角色), 非超级管理员只能访问已授权的任务组
+	Groups map[uint64]string `json:"groups"`
+}
+
+// Has 是否拥有权限
+func (p *UserPermissions) Has(code string) bool {
+	if p.SuperAdmin {
+		return true
+	}
+	i := sort.SearchStrings(p.Permissions, code)
+	return i < len(p.Permissions) && p.Permissions[i] == code
+}
+
+// GroupScope 获取组内角色不低于role的任务组范围, 超级管理员不限制
+func (p *UserPermissions) GroupScope(role string) *model.GroupScope {
+	if p.SuperAdmin {
+		return &model.GroupScope{All: true}
+	}
+	scope := &model.GroupScope{GroupIDs: []uint64{}}
+	level := model.GroupRoleLevel(role)
+	for groupID, groupRole := range p.Groups {
+		if model.GroupRoleLevel(groupRole) >= level {
+			scope.GroupIDs = append(scope.GroupIDs, groupID)
+		}
+	}
+	sort.Slice(scope.GroupIDs, func(i, j int) bool { return scope.GroupIDs[i] < scope.GroupIDs[j] })
+	return scope
+}
+
+// PermissionService 权限服务接口
+type PermissionService interface {
+	GetUserPermissions(ctx context.Context, userID uint64) (*UserPermissions, error)
+}
+
+// permissionService 权限服务实现
+type permissionService struct {
+	userRepo       repository.UserRepository
+	permissionRepo repository.PermissionRepository
+	memberRepo     repository.TaskGroupMemberRepository
+}
+
+// NewPermissionService 创建权限服务
+func NewPermissionService() PermissionService {
+	return &permissionService{
+		userRepo:       repository.NewUserRepository(),
+		permissionRepo: repository.NewPermissionRepository(),
+		memberRepo:     repository.NewTaskGroupMemberRepository(),
+	}
+}
+
+// cachedPermissions 缓存的用户权限
+type cachedPermissions struct {
+	permissions *UserPermissions
+	expireAt    time.Time
+}
+
+// 用户权限缓存, 所有权限服务实例共享
+var permissionCache = struct {
+	sync.Mutex
+	entries map[uint64]*cachedPermissions
+}{entries: make(map[uint64]*cachedPermissions)}
+
+// invalidatePermissions 清除用户的权限缓存, 任务组成员变更后立即生效
+func invalidatePermissions(userID uint64) {
+	permissionCache.Lock()
+	delete(permissionCache.entries, userID)
+	permissionCache.Unlock()
+}
+
+// GetUserPermissions 获取用户的有效权限(所有已启用角色的权限并集), 超级管理员拥有所有权限
+func (s *permissionService) GetUserPermissions(ctx context.Context, userID uint64) (*UserPermissions, error) {
+	now := time.Now()
+	permissionCache.Lock()
+	if entry, ok := permissionCache.entries[userID]; ok && now.Before(entry.expireAt) {
+		permissionCache.Unlock()
+		return entry.permissions, nil
+	}
+	permissionCache.Unlock()
+
+	permissions, err := s.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissionCache.Lock()
+	defer permissionCache.Unlock()
+	for id, entry := range permissionCache.entries {
+		if !now.Before(entry.expireAt) {
+			delete(permissionCache.entries, id)
+		}
+	}
+	permissionCache.entries[userID] = &cachedPermissions{
+		permissions: permissions,
+		expireAt:    now.Add(permissionCacheTTL),
+	}
+	return permissions, nil
+}
+
+// load 从数据库加载用户的有效权限, 已删除或禁用的用户没有任何权限
+func (s *permissionService) load(ctx context.Context, userID uint64) (*UserPermissions, error) {
+	result := &UserPermissions{Roles: []string{}, Permissions: []string{}, Groups: map[uint64]string{}}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return result, nil
+		}
+		return nil, err
+	}
+	if user.Status != model.UserStatusEnabled {
+		return result, nil
+	}
+
+	roles, err := s.userRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if role.Status != 1 {
+			continue
+		}
+		result.Roles = append(result.Roles, role.Code)
+		if role.Code == model.RoleSuperAdmin {
+			result.SuperAdmin = true
+		}
+	}
+
+	var permissions []*model.SysPermission
+	if result.SuperAdmin {
+		permissions, err = s.permissionRepo.List(ctx)
+	} else {
+		permissions, err = s.permissionRepo.GetByUserID(ctx, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, permission := range permissions {
+		result.Permissions = append(result.Permissions, permission.Code())
+	}
+	sort.Strings(result.Permissions)
+
+	members, err := s.memberRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		result.Groups[member.GroupID] = member.Role
+	}
+	return result, nil
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/repository"
+)
+
+// permUserRepo 内存中的用户和用户角色
+type permUserRepo struct {
+	repository.UserRepository
+	users map[uint64]*model.SysUser
+	roles map[uint64][]model.SysRole
+}
+
+func (r *permUserRepo) GetByID(ctx context.Context, id uint64) (*model.SysUser, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (r *permUserRepo) GetUserRoles(ctx context.Context, userID uint64) ([]model.SysRole, error) {
+	return r.roles[userID], nil
+}
+
+// fakePermissionRepo 所有权限和按用户授予的权限
+type fakePermissionRepo struct {
+	repository.PermissionRepository
+	all    []*model.SysPermission
+	byUser map[uint64][]*model.SysPermission
+}
+
+func (r *fakePermissionRepo) List(ctx context.Context) ([]*model.SysPermission, error) {
+	return r.all, nil
+}
+
+func (r *fakePermissionRepo) GetByUserID(ctx context.Context, userID uint64) ([]*model.SysPermission, error) {
+	return r.byUser[userID], nil
+}
+
+// fakeMemberRepo 用户加入的任务组
+type fakeMemberRepo struct {
+	repository.TaskGroupMemberRepository
+	members map[uint64][]*model.TaskGroupMember
+}
+
+func (r *fakeMemberRepo) ListByUserID(ctx context.Context, userID uint64) ([]*model.TaskGroupMember, error) {
+	return r.members[userID], nil
+}
+
+// permission 根据权限编码(资源:操作)创建权限
+func permission(code string) *model.SysPermission {
+	resource, action, _ := strings.Cut(code, ":")
+	return &model.SysPermission{Resource: resource, Action: action}
+}
+
+// newTestPermissionService 用户1为超级管理员, 用户2为普通用户, 用户3已禁用, 用户4的角色已停用
+func newTestPermissionService() *permissionService {
+	enabled := func(id uint64) *model.SysUser {
+		return &model.SysUser{ID: id, Status: model.UserStatusEnabled}
+	}
+	disabled := enabled(3)
+	disabled.Status = 0
+	all := []*model.SysPermission{permission(model.PermUserCreate), permission(model.PermTaskView), permission(model.PermTaskCreate)}
+	return &permissionService{
+		userRepo: &permUserRepo{
+			users: map[uint64]*model.SysUser{1: enabled(1), 2: enabled(2), 3: disabled, 4: enabled(4)},
+			roles: map[uint64][]model.SysRole{
+				1: {{Code: model.RoleSuperAdmin, Status: 1}},
+				2: {{Code: "DEVELOPER", Status: 1}},
+				3: {{Code: "DEVELOPER", Status: 1}},
+				4: {{Code: model.RoleSuperAdmin, Status: 0}},
+			},
+		},
+		permissionRepo: &fakePermissionRepo{
+			all: all,
+			byUser: map[uint64][]*model.SysPermission{
+				2: {permission(model.PermTaskView), permission(model.PermTaskCreate)},
+				3: {permission(model.PermTaskView)},
+			},
+		},
+		memberRepo: &fakeMemberRepo{},
+	}
+}
+
+func TestLoadUserPermissions(t *testing.T) {
+	s := newTestPermissionService()
+	ctx := context.Background()
+
+	admin, err := s.load(ctx, 1)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !admin.SuperAdmin || !admin.Has(model.PermUserCreate) || !admin.Has("unknown:action") {
+		t.Errorf("超级管理员应拥有所有权限, 实际: %+v", admin)
+	}
+
+	user, err := s.load(ctx, 2)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if user.SuperAdmin || !user.Has(model.PermTaskView) || !user.Has(model.PermTaskCreate) || user.Has(model.PermUserCreate) {
+		t.Errorf("普通用户只拥有角色授予的权限, 实际: %v", user.Permissions)
+	}
+
+	for _, id := range []uint64{3, 4, 99} {
+		p, err := s.load(ctx, id)
+		if err != nil {
+			t.Fatalf("load: %v", err)
+		}
+		if p.SuperAdmin || p.Has(model.PermTaskView) {
+			t.Errorf("用户%d已禁用、角色已停用或不存在, 不应拥有任何权限, 实际: %+v", id, p)
+		}
+	}
+}
+
+func TestGroupScopeByRole(t *testing.T) {
+	s := newTestPermissionService()
+	s.memberRepo = &fakeMemberRepo{members: map[uint64][]*model.TaskGroupMember{
+		2: {
+			{GroupID: 3, Role: model.GroupRoleViewer},
+			{GroupID: 1, Role: model.GroupRoleOwner},
+			{GroupID: 2, Role: model.GroupRoleOperator},
+		},
+	}}
+	ctx := context.Background()
+
+	user, err := s.load(ctx, 2)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	cases := map[string][]uint64{
+		model.GroupRoleViewer:   {1, 2, 3},
+		model.GroupRoleOperator: {1, 2},
+		model.GroupRoleOwner:    {1},
+	}
+	for role, want := range cases {
+		scope := user.GroupScope(role)
+		if scope.All || fmt.Sprint(scope.GroupIDs) != fmt.Sprint(want) {
+			t.Errorf("组内角色不低于%s的任务组应为%v, 实际: %+v", role, want, scope)
+		}
+	}
+
+	admin, _ := s.load(ctx, 1)
+	if scope := admin.GroupScope(model.GroupRoleOwner); !scope.All {
+		t.Errorf("超级管理员不应限制任务组, 实际: %+v", scope)
+	}
+	// 没有加入任何任务组的用户不能访问任务组
+	none, _ := s.load(ctx, 4)
+	if scope := none.GroupScope(model.GroupRoleViewer); scope.Allows(1) {
+		t.Errorf("未加入任务组的用户不应访问任务组, 实际: %+v", scope)
+	}
+}
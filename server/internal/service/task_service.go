@@ -3,6 +3,9 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -10,25 +13,53 @@ import (
 	"distributed-scheduler/internal/common/utils"
 	"distributed-scheduler/internal/model"
 	"distributed-scheduler/internal/repository"
+	"distributed-scheduler/internal/scheduler/httpcall"
+	"distributed-scheduler/internal/scheduler/router"
 )
 
 var (
 	ErrTaskNotFound  = errors.New("任务不存在")
 	ErrGroupNotFound = errors.New("任务组不存在")
-	ErrInvalidCron   = errors.New("无效的Cron表达式")
+	// ErrGroupForbidden 用户未被授权访问该任务组
+	ErrGroupForbidden      = errors.New("无权访问该任务组")
+	ErrInvalidGroupRole    = errors.New("无效的任务组角色")
+	ErrGroupMemberNotFound = errors.New("用户不是该任务组的成员")
+	ErrInvalidCron         = errors.New("无效的Cron表达式")
+	// ErrInvalidSelector 标签选择器错误(错误信息包含具体原因)
+	ErrInvalidSelector = router.ErrInvalidSelector
+	// ErrInvalidRoute 路由策略或参数错误(错误信息包含具体原因)
+	ErrInvalidRoute = errors.New("无效的路由策略")
+	// ErrInvalidScript 脚本错误(错误信息包含具体原因)
+	ErrInvalidScript  = errors.New("无效的脚本")
+	ErrScriptNotFound = errors.New("脚本版本不存在")
+	// ErrInvalidHTTPCall HTTP请求配置错误(错误信息包含具体原因)
+	ErrInvalidHTTPCall = httpcall.ErrInvalidConfig
+	ErrHandlerRequired = errors.New("执行器Handler不能为空")
+)
+
+// 脚本内容上限
+const maxScriptSize = 1 << 20
+
+var (
+	scriptTypePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9._-]{0,31}$`)
+	envNamePattern    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
 )
 
 // TaskService 任务服务接口
 type TaskService interface {
-	Create(ctx context.Context, task *model.Task) error
-	Update(ctx context.Context, task *model.Task) error
-	Delete(ctx context.Context, id uint64) error
-	GetByID(ctx context.Context, id uint64) (*model.Task, error)
-	List(ctx context.Context, page, pageSize int, groupID uint64, keyword string, status int8) ([]*model.Task, int64, error)
-	Start(ctx context.Context, id uint64) error
-	Stop(ctx context.Context, id uint64) error
-	Trigger(ctx context.Context, id uint64, param string) (*model.TaskInstance, error)
+	Create(ctx context.Context, scope *model.GroupScope, task *model.Task) error
+	Update(ctx context.Context, scope *model.GroupScope, task *model.Task) error
+	Delete(ctx context.Context, scope *model.GroupScope, id uint64) error
+	GetByID(ctx context.Context, scope *model.GroupScope, id uint64) (*model.Task, error)
+	List(ctx context.Context, scope *model.GroupScope, page, pageSize int, groupID uint64, keyword string, status int8) ([]*model.Task, int64, error)
+	Start(ctx context.Context, scope *model.GroupScope, id uint64) error
+	Stop(ctx context.Context, scope *model.GroupScope, id uint64) error
+	Trigger(ctx context.Context, scope *model.GroupScope, id uint64, param string) (*model.TaskInstance, error)
 	GetNextTriggerTimes(ctx context.Context, cron string, count int) ([]time.Time, error)
+	GetRouteStrategies(ctx context.Context) []string
+	ListScripts(ctx context.Context, scope *model.GroupScope, taskID uint64) ([]*model.TaskScript, error)
+	GetScript(ctx context.Context, scope *model.GroupScope, taskID uint64, version uint) (*model.TaskScript, error)
+	RollbackScript(ctx context.Context, scope *model.GroupScope, taskID uint64, version uint, userID uint64) (*model.TaskScript, error)
 }
 
 // taskService 任务服务实现
@@ -36,6 +67,7 @@ type taskService struct {
 	taskRepo     repository.TaskRepository
 	groupRepo    repository.TaskGroupRepository
 	instanceRepo repository.InstanceRepository
+	scriptRepo   repository.TaskScriptRepository
 }
 
 // NewTaskService 创建任务服务
@@ -44,16 +76,32 @@ func NewTaskService() TaskService {
 		taskRepo:     repository.NewTaskRepository(),
 		groupRepo:    repository.NewTaskGroupRepository(),
 		instanceRepo: repository.NewInstanceRepository(),
+		scriptRepo:   repository.NewTaskScriptRepository(),
 	}
 }
 
-// Create 创建任务
-func (s *taskService) Create(ctx context.Context, task *model.Task) error {
+// Create 创建任务, 只能创建到scope内的任务组
+func (s *taskService) Create(ctx context.Context, scope *model.GroupScope, task *model.Task) error {
+	if !scope.Allows(task.GroupID) {
+		return ErrGroupForbidden
+	}
+
 	// 验证Cron表达式
 	if err := utils.ValidateCron(task.Cron); err != nil {
 		return ErrInvalidCron
 	}
 
+	// 验证路由策略和标签选择器
+	if err := validateRoute(task); err != nil {
+		return err
+	}
+	if err := validateAffinity(task); err != nil {
+		return err
+	}
+	if err := validateExecutor(task); err != nil {
+		return err
+	}
+
 	// 验证任务组是否存在
 	_, err := s.groupRepo.GetByID(ctx, task.GroupID)
 	if err != nil {
@@ -70,16 +118,38 @@ func (s *taskService) Create(ctx context.Context, task *model.Task) error {
 	}
 	task.NextTriggerTime = &nextTime
 
-	return s.taskRepo.Create(ctx, task)
+	if err := s.taskRepo.Create(ctx, task); err != nil {
+		return err
+	}
+	if task.Script != nil {
+		return s.publishScript(ctx, task)
+	}
+	return nil
 }
 
-// Update 更新任务
-func (s *taskService) Update(ctx context.Context, task *model.Task) error {
+// Update 更新任务, 任务只能移动到scope内的任务组
+// 原任务组的访问权限由调用方通过GetByID获取任务时校验
+func (s *taskService) Update(ctx context.Context, scope *model.GroupScope, task *model.Task) error {
+	if !scope.Allows(task.GroupID) {
+		return ErrGroupForbidden
+	}
+
 	// 验证Cron表达式
 	if err := utils.ValidateCron(task.Cron); err != nil {
 		return ErrInvalidCron
 	}
 
+	// 验证路由策略和标签选择器
+	if err := validateRoute(task); err != nil {
+		return err
+	}
+	if err := validateAffinity(task); err != nil {
+		return err
+	}
+	if err := validateExecutor(task); err != nil {
+		return err
+	}
+
 	// 重新计算下次触发时间
 	nextTime, err := utils.GetNextTriggerTime(task.Cron, time.Now())
 	if err != nil {
@@ -90,16 +160,47 @@ func (s *taskService) Update(ctx context.Context, task *model.Task) error {
 	// 乐观锁更新
 	task.Version++
 
-	return s.taskRepo.Update(ctx, task)
+	if err := s.taskRepo.Update(ctx, task); err != nil {
+		return err
+	}
+	if task.Script == nil {
+		return nil
+	}
+
+	// 脚本未修改时不生成新版本
+	if task.ScriptVersion > 0 {
+		current, err := s.scriptRepo.GetByVersion(ctx, task.ID, task.ScriptVersion)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if task.Script.SameAs(current) {
+			task.Script = current
+			return nil
+		}
+	}
+	return s.publishScript(ctx, task)
+}
+
+// publishScript 发布任务的脚本为新版本
+func (s *taskService) publishScript(ctx context.Context, task *model.Task) error {
+	task.Script.TaskID = task.ID
+	if err := s.scriptRepo.Publish(ctx, task.Script); err != nil {
+		return err
+	}
+	task.ScriptVersion = task.Script.Version
+	return nil
 }
 
 // Delete 删除任务
-func (s *taskService) Delete(ctx context.Context, id uint64) error {
+func (s *taskService) Delete(ctx context.Context, scope *model.GroupScope, id uint64) error {
+	if _, err := s.getTask(ctx, scope, id); err != nil {
+		return err
+	}
 	return s.taskRepo.Delete(ctx, id)
 }
 
-// GetByID 根据ID获取任务
-func (s *taskService) GetByID(ctx context.Context, id uint64) (*model.Task, error) {
+// getTask 获取scope内任务组的任务
+func (s *taskService) getTask(ctx context.Context, scope *model.GroupScope, id uint64) (*model.Task, error) {
 	task, err := s.taskRepo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -107,6 +208,18 @@ func (s *taskService) GetByID(ctx context.Context, id uint64) (*model.Task, erro
 		}
 		return nil, err
 	}
+	if !scope.Allows(task.GroupID) {
+		return nil, ErrGroupForbidden
+	}
+	return task, nil
+}
+
+// GetByID 根据ID获取任务
+func (s *taskService) GetByID(ctx context.Context, scope *model.GroupScope, id uint64) (*model.Task, error) {
+	task, err := s.getTask(ctx, scope, id)
+	if err != nil {
+		return nil, err
+	}
 
 	// 获取依赖任务
 	deps, err := s.taskRepo.GetDependencies(ctx, id)
@@ -115,17 +228,26 @@ func (s *taskService) GetByID(ctx context.Context, id uint64) (*model.Task, erro
 	}
 	task.Dependencies = deps
 
+	// 获取当前版本的脚本
+	if task.ScriptVersion > 0 {
+		script, err := s.scriptRepo.GetByVersion(ctx, id, task.ScriptVersion)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		task.Script = script
+	}
+
 	return task, nil
 }
 
-// List 获取任务列表
-func (s *taskService) List(ctx context.Context, page, pageSize int, groupID uint64, keyword string, status int8) ([]*model.Task, int64, error) {
-	return s.taskRepo.List(ctx, page, pageSize, groupID, keyword, status)
+// List 获取scope内任务组的任务列表
+func (s *taskService) List(ctx context.Context, scope *model.GroupScope, page, pageSize int, groupID uint64, keyword string, status int8) ([]*model.Task, int64, error) {
+	return s.taskRepo.List(ctx, scope, page, pageSize, groupID, keyword, status)
 }
 
 // Start 启动任务
-func (s *taskService) Start(ctx context.Context, id uint64) error {
-	task, err := s.taskRepo.GetByID(ctx, id)
+func (s *taskService) Start(ctx context.Context, scope *model.GroupScope, id uint64) error {
+	task, err := s.getTask(ctx, scope, id)
 	if err != nil {
 		return err
 	}
@@ -143,17 +265,17 @@ func (s *taskService) Start(ctx context.Context, id uint64) error {
 }
 
 // Stop 停止任务
-func (s *taskService) Stop(ctx context.Context, id uint64) error {
+func (s *taskService) Stop(ctx context.Context, scope *model.GroupScope, id uint64) error {
+	if _, err := s.getTask(ctx, scope, id); err != nil {
+		return err
+	}
 	return s.taskRepo.UpdateStatus(ctx, id, model.TaskStatusDisabled)
 }
 
 // Trigger 手动触发任务
-func (s *taskService) Trigger(ctx context.Context, id uint64, param string) (*model.TaskInstance, error) {
-	task, err := s.taskRepo.GetByID(ctx, id)
+func (s *taskService) Trigger(ctx context.Context, scope *model.GroupScope, id uint64, param string) (*model.TaskInstance, error) {
+	task, err := s.getTask(ctx, scope, id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrTaskNotFound
-		}
 		return nil, err
 	}
 
@@ -186,55 +308,318 @@ func (s *taskService) GetNextTriggerTimes(ctx context.Context, cron string, coun
 	return utils.GetNextNTriggerTimes(cron, time.Now(), count)
 }
 
+// GetRouteStrategies 获取已注册的路由策略
+func (s *taskService) GetRouteStrategies(ctx context.Context) []string {
+	return router.Names()
+}
+
+// ListScripts 获取任务的脚本版本列表
+func (s *taskService) ListScripts(ctx context.Context, scope *model.GroupScope, taskID uint64) ([]*model.TaskScript, error) {
+	if _, err := s.getTask(ctx, scope, taskID); err != nil {
+		return nil, err
+	}
+	return s.scriptRepo.ListByTaskID(ctx, taskID)
+}
+
+// GetScript 获取任务指定版本的脚本
+func (s *taskService) GetScript(ctx context.Context, scope *model.GroupScope, taskID uint64, version uint) (*model.TaskScript, error) {
+	if _, err := s.getTask(ctx, scope, taskID); err != nil {
+		return nil, err
+	}
+	return s.getScript(ctx, taskID, version)
+}
+
+// getScript 获取任务指定版本的脚本, 不校验任务组范围
+func (s *taskService) getScript(ctx context.Context, taskID uint64, version uint) (*model.TaskScript, error) {
+	script, err := s.scriptRepo.GetByVersion(ctx, taskID, version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScriptNotFound
+		}
+		return nil, err
+	}
+	return script, nil
+}
+
+// RollbackScript 将任务的脚本回滚到指定版本, 以该版本的内容发布新版本, 历史版本保持不变
+func (s *taskService) RollbackScript(ctx context.Context, scope *model.GroupScope, taskID uint64, version uint, userID uint64) (*model.TaskScript, error) {
+	task, err := s.getTask(ctx, scope, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.ExecutorType != model.ExecutorTypeSCRIPT {
+		return nil, fmt.Errorf("%w: 任务不是SCRIPT类型", ErrInvalidScript)
+	}
+
+	target, err := s.getScript(ctx, taskID, version)
+	if err != nil {
+		return nil, err
+	}
+	if version == task.ScriptVersion {
+		return target, nil
+	}
+
+	script := &model.TaskScript{
+		TaskID:     taskID,
+		ScriptType: target.ScriptType,
+		Content:    target.Content,
+		Env:        target.Env,
+		Remark:     fmt.Sprintf("回滚到版本%d", version),
+		CreatedBy:  userID,
+	}
+	if err := s.scriptRepo.Publish(ctx, script); err != nil {
+		return nil, err
+	}
+	return script, nil
+}
+
+// validateExecutor 按执行器类型校验任务的Handler、脚本和HTTP请求配置
+func validateExecutor(task *model.Task) error {
+	switch task.ExecutorType {
+	case model.ExecutorTypeHTTP, model.ExecutorTypeGRPC:
+		if strings.TrimSpace(task.ExecutorHandler) == "" {
+			return ErrHandlerRequired
+		}
+	}
+	if err := validateScript(task); err != nil {
+		return err
+	}
+	return validateHTTPCall(task)
+}
+
+// validateHTTPCall 校验HTTP_CALL类型任务的请求配置, 其他类型的任务忽略请求配置
+func validateHTTPCall(task *model.Task) error {
+	if task.ExecutorType != model.ExecutorTypeHTTPCall {
+		task.HTTPCall = nil
+		return nil
+	}
+	return httpcall.Validate(task.HTTPCall, httpcall.AllowedHosts())
+}
+
+// validateScript 校验SCRIPT类型任务的脚本, 其他类型的任务忽略脚本
+// 更新时脚本为空表示沿用当前版本
+func validateScript(task *model.Task) error {
+	if task.ExecutorType != model.ExecutorTypeSCRIPT {
+		task.Script = nil
+		return nil
+	}
+
+	script := task.Script
+	if script == nil {
+		if task.ScriptVersion == 0 {
+			return fmt.Errorf("%w: SCRIPT类型任务必须设置脚本", ErrInvalidScript)
+		}
+		return nil
+	}
+	if !scriptTypePattern.MatchString(script.ScriptType) {
+		return fmt.Errorf("%w: 脚本类型只能包含字母、数字和._-", ErrInvalidScript)
+	}
+	if strings.TrimSpace(script.Content) == "" {
+		return fmt.Errorf("%w: 脚本内容不能为空", ErrInvalidScript)
+	}
+	if len(script.Content) > maxScriptSize {
+		return fmt.Errorf("%w: 脚本内容不能超过1MB", ErrInvalidScript)
+	}
+	for name := range script.Env {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("%w: 无效的环境变量名 %s", ErrInvalidScript, name)
+		}
+	}
+	return nil
+}
+
+// validateRoute 校验任务的路由策略及其参数, 未设置策略时使用轮询
+func validateRoute(task *model.Task) error {
+	if task.RouteStrategy == "" {
+		if task.RouteOptions != "" {
+			return fmt.Errorf("%w: 未指定路由策略时不能设置参数", ErrInvalidRoute)
+		}
+		return nil
+	}
+	if _, err := router.New(task.RouteStrategy, task.RouteOptions); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRoute, err)
+	}
+	return nil
+}
+
+// validateAffinity 校验任务的标签选择器
+func validateAffinity(task *model.Task) error {
+	_, err := router.NewAffinity(task)
+	return err
+}
+
 // TaskGroupService 任务组服务接口
 type TaskGroupService interface {
-	Create(ctx context.Context, group *model.TaskGroup) error
-	Update(ctx context.Context, group *model.TaskGroup) error
-	Delete(ctx context.Context, id uint64) error
-	GetByID(ctx context.Context, id uint64) (*model.TaskGroup, error)
-	List(ctx context.Context, page, pageSize int, keyword string) ([]*model.TaskGroup, int64, error)
-	GetAll(ctx context.Context) ([]*model.TaskGroup, error)
+	Create(ctx context.Context, group *model.TaskGroup, ownerID uint64) error
+	Update(ctx context.Context, scope *model.GroupScope, group *model.TaskGroup) error
+	Delete(ctx context.Context, scope *model.GroupScope, id uint64) error
+	GetByID(ctx context.Context, scope *model.GroupScope, id uint64) (*model.TaskGroup, error)
+	List(ctx context.Context, scope *model.GroupScope, page, pageSize int, keyword string) ([]*model.TaskGroup, int64, error)
+	GetAll(ctx context.Context, scope *model.GroupScope) ([]*model.TaskGroup, error)
+	RotateToken(ctx context.Context, scope *model.GroupScope, id uint64) (*model.TaskGroup, error)
+	ListMembers(ctx context.Context, scope *model.GroupScope, id uint64) ([]*model.TaskGroupMember, error)
+	SaveMember(ctx context.Context, scope *model.GroupScope, member *model.TaskGroupMember) error
+	RemoveMember(ctx context.Context, scope *model.GroupScope, id, userID uint64) error
 }
 
 // taskGroupService 任务组服务实现
 type taskGroupService struct {
-	groupRepo repository.TaskGroupRepository
+	groupRepo  repository.TaskGroupRepository
+	memberRepo repository.TaskGroupMemberRepository
+	userRepo   repository.UserRepository
 }
 
 // NewTaskGroupService 创建任务组服务
 func NewTaskGroupService() TaskGroupService {
 	return &taskGroupService{
-		groupRepo: repository.NewTaskGroupRepository(),
+		groupRepo:  repository.NewTaskGroupRepository(),
+		memberRepo: repository.NewTaskGroupMemberRepository(),
+		userRepo:   repository.NewUserRepository(),
 	}
 }
 
-// Create 创建任务组
-func (s *taskGroupService) Create(ctx context.Context, group *model.TaskGroup) error {
-	return s.groupRepo.Create(ctx, group)
+// Create 创建任务组, 同时生成执行器访问令牌, 创建者成为任务组的所有者
+func (s *taskGroupService) Create(ctx context.Context, group *model.TaskGroup, ownerID uint64) error {
+	if group.AccessToken == "" {
+		token, err := utils.GenerateAccessToken()
+		if err != nil {
+			return err
+		}
+		group.AccessToken = token
+	}
+	if err := s.groupRepo.Create(ctx, group); err != nil {
+		return err
+	}
+	if ownerID == 0 {
+		return nil
+	}
+	return s.saveMember(ctx, &model.TaskGroupMember{
+		GroupID:   group.ID,
+		UserID:    ownerID,
+		Role:      model.GroupRoleOwner,
+		CreatedBy: ownerID,
+	})
 }
 
 // Update 更新任务组
-func (s *taskGroupService) Update(ctx context.Context, group *model.TaskGroup) error {
+func (s *taskGroupService) Update(ctx context.Context, scope *model.GroupScope, group *model.TaskGroup) error {
+	if !scope.Allows(group.ID) {
+		return ErrGroupForbidden
+	}
 	return s.groupRepo.Update(ctx, group)
 }
 
-// Delete 删除任务组
-func (s *taskGroupService) Delete(ctx context.Context, id uint64) error {
-	return s.groupRepo.Delete(ctx, id)
+// Delete 删除任务组及其成员
+func (s *taskGroupService) Delete(ctx context.Context, scope *model.GroupScope, id uint64) error {
+	members, err := s.ListMembers(ctx, scope, id)
+	if err != nil {
+		return err
+	}
+	if err := s.groupRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := s.memberRepo.DeleteByGroupID(ctx, id); err != nil {
+		return err
+	}
+	for _, member := range members {
+		invalidatePermissions(member.UserID)
+	}
+	return nil
+}
+
+// GetByID 根据ID获取scope内的任务组
+func (s *taskGroupService) GetByID(ctx context.Context, scope *model.GroupScope, id uint64) (*model.TaskGroup, error) {
+	if !scope.Allows(id) {
+		return nil, ErrGroupForbidden
+	}
+	group, err := s.groupRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, err
+	}
+	return group, nil
 }
 
-// GetByID 根据ID获取任务组
-func (s *taskGroupService) GetByID(ctx context.Context, id uint64) (*model.TaskGroup, error) {
-	return s.groupRepo.GetByID(ctx, id)
+// List 获取scope内的任务组列表
+func (s *taskGroupService) List(ctx context.Context, scope *model.GroupScope, page, pageSize int, keyword string) ([]*model.TaskGroup, int64, error) {
+	return s.groupRepo.List(ctx, scope, page, pageSize, keyword)
 }
 
-// List 获取任务组列表
-func (s *taskGroupService) List(ctx context.Context, page, pageSize int, keyword string) ([]*model.TaskGroup, int64, error) {
-	return s.groupRepo.List(ctx, page, pageSize, keyword)
+// RotateToken 轮换任务组的执行器访问令牌, 旧令牌在宽限期内仍然有效
+func (s *taskGroupService) RotateToken(ctx context.Context, scope *model.GroupScope, id uint64) (*model.TaskGroup, error) {
+	group, err := s.GetByID(ctx, scope, id)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	group.PrevToken = group.AccessToken
+	group.AccessToken = token
+	group.TokenRotateAt = &now
+	if err := s.groupRepo.Update(ctx, group); err != nil {
+		return nil, err
+	}
+	return group, nil
 }
 
-// GetAll 获取所有任务组
-func (s *taskGroupService) GetAll(ctx context.Context) ([]*model.TaskGroup, error) {
-	return s.groupRepo.GetAll(ctx)
+// GetAll 获取scope内所有启用的任务组
+func (s *taskGroupService) GetAll(ctx context.Context, scope *model.GroupScope) ([]*model.TaskGroup, error) {
+	return s.groupRepo.GetAll(ctx, scope)
 }
 
+// ListMembers 获取任务组的成员
+func (s *taskGroupService) ListMembers(ctx context.Context, scope *model.GroupScope, id uint64) ([]*model.TaskGroupMember, error) {
+	if _, err := s.GetByID(ctx, scope, id); err != nil {
+		return nil, err
+	}
+	return s.memberRepo.ListByGroupID(ctx, id)
+}
+
+// SaveMember 授权用户访问任务组, 已是成员时更新其组内角色
+func (s *taskGroupService) SaveMember(ctx context.Context, scope *model.GroupScope, member *model.TaskGroupMember) error {
+	if model.GroupRoleLevel(member.Role) == 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidGroupRole, member.Role)
+	}
+	if _, err := s.GetByID(ctx, scope, member.GroupID); err != nil {
+		return err
+	}
+	if _, err := s.userRepo.GetByID(ctx, member.UserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return s.saveMember(ctx, member)
+}
+
+// saveMember 保存成员并使其权限缓存失效
+func (s *taskGroupService) saveMember(ctx context.Context, member *model.TaskGroupMember) error {
+	if err := s.memberRepo.Save(ctx, member); err != nil {
+		return err
+	}
+	invalidatePermissions(member.UserID)
+	return nil
+}
+
+// RemoveMember 取消用户对任务组的授权
+func (s *taskGroupService) RemoveMember(ctx context.Context, scope *model.GroupScope, id, userID uint64) error {
+	if _, err := s.GetByID(ctx, scope, id); err != nil {
+		return err
+	}
+	removed, err := s.memberRepo.Delete(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrGroupMemberNotFound
+	}
+	invalidatePermissions(userID)
+	return nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+
+	"distributed-scheduler/internal/common/utils"
+	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/repository"
+	"distributed-scheduler/pkg/logger"
+	pkgRedis "distributed-scheduler/pkg/redis"
+)
+
+var (
+	// ErrTokenRevoked Token已退出登录、已轮换或用户已退出所有会话
+	ErrTokenRevoked        = errors.New("token已失效")
+	ErrRedisNotInitialized = errors.New("Redis未初始化")
+)
+
+// Token吊销相关的Redis键
+const (
+	keyTokenDenylist   = "auth:denylist:"   // 已吊销Token的jti, 保留到Token过期
+	keyTokenGeneration = "auth:generation:" // 用户的Token代数
+	keyTokenRotated    = "auth:rotated:"    // 刷新Token轮换得到的Token对, 宽限期内重复使用时返回
+)
+
+// 刷新Token轮换后的默认宽限期
+const defaultRefreshGrace = 30 * time.Second
+
+// tokenValidateScript 检查Token是否被吊销: jti在黑名单中或代数不是用户的当前代数
+// KEYS[1] 黑名单键, KEYS[2] 代数键; ARGV[1] Token的代数
+// 返回1表示有效, 0表示已吊销
+var tokenValidateScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+local generation = tonumber(redis.call('GET', KEYS[2]) or '0')
+if generation ~= tonumber(ARGV[1]) then
+	return 0
+end
+return 1
+`)
+
+// tokenConsumeScript 使用刷新Token: 代数有效时将jti加入黑名单并保存轮换得到的Token对, 每个刷新Token只能使用一次
+// 宽限期内再次使用返回首次轮换得到的Token对(多个标签页共用刷新Token时同时刷新)
+// KEYS[1] 黑名单键, KEYS[2] 代数键, KEYS[3] 轮换结果键; ARGV[1] Token的代数, ARGV[2] 黑名单保留时间(毫秒),
+// ARGV[3] 新的Token对, ARGV[4] 宽限期(毫秒)
+// 返回1表示成功, 字符串为宽限期内已轮换的Token对, 0表示已被使用(疑似泄露), -1表示已吊销
+var tokenConsumeScript = redis.NewScript(`
+local generation = tonumber(redis.call('GET', KEYS[2]) or '0')
+if generation ~= tonumber(ARGV[1]) then
+	return -1
+end
+if redis.call('SET', KEYS[1], '1', 'NX', 'PX', ARGV[2]) then
+	redis.call('SET', KEYS[3], ARGV[3], 'PX', ARGV[4])
+	return 1
+end
+local rotated = redis.call('GET', KEYS[3])
+if rotated then
+	return rotated
+end
+return 0
+`)
+
+// TokenService Token签发与吊销服务接口
+type TokenService interface {
+	Issue(ctx context.Context, userID uint64, username, roleCode string) (*utils.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*utils.TokenPair, error)
+	Validate(ctx context.Context, claims *utils.Claims) error
+	Revoke(ctx context.Context, claims *utils.Claims) error
+	RevokeAll(ctx context.Context, userID uint64) error
+}
+
+// tokenService Token服务实现, 吊销状态保存在Redis
+type tokenService struct {
+	userRepo repository.UserRepository
+}
+
+// NewTokenService 创建Token服务
+func NewTokenService() TokenService {
+	return &tokenService{
+		userRepo: repository.NewUserRepository(),
+	}
+}
+
+// Issue 按用户的当前代数签发访问Token和刷新Token
+func (s *tokenService) Issue(ctx context.Context, userID uint64, username, roleCode string) (*utils.TokenPair, error) {
+	generation, err := s.generation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return utils.GenerateToken(userID, username, roleCode, generation)
+}
+
+// Refresh 使用刷新Token签发新的Token对, 原刷新Token随即失效
+// 宽限期(jwt.refresh_grace)内再次使用返回同一个Token对; 超过宽限期后再次使用视为泄露, 吊销该用户的所有会话
+func (s *tokenService) Refresh(ctx context.Context, refreshToken string) (*utils.TokenPair, error) {
+	pair, claims, err := utils.RefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.Status != model.UserStatusEnabled {
+		return nil, ErrUserDisabled
+	}
+
+	if pkgRedis.GetClient() == nil {
+		return nil, ErrRedisNotInitialized
+	}
+	data, err := json.Marshal(pair)
+	if err != nil {
+		return nil, err
+	}
+	result, err := pkgRedis.RunScript(ctx, tokenConsumeScript,
+		[]string{keyTokenDenylist + claims.ID, generationKey(claims.UserID), keyTokenRotated + claims.ID},
+		claims.Generation, tokenTTL(claims).Milliseconds(), string(data), refreshGrace().Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	if rotated, ok := result.(string); ok {
+		var previous utils.TokenPair
+		if err := json.Unmarshal([]byte(rotated), &previous); err != nil {
+			return nil, err
+		}
+		return &previous, nil
+	}
+	switch result.(int64) {
+	case 1:
+		return pair, nil
+	case 0:
+		logger.Warnf("刷新Token被重复使用, 吊销用户的所有会话, user_id=%d, jti=%s", claims.UserID, claims.ID)
+		if err := s.RevokeAll(ctx, claims.UserID); err != nil {
+			return nil, err
+		}
+	}
+	return nil, ErrTokenRevoked
+}
+
+// Validate 检查Token是否已被吊销, 已吊销时返回ErrTokenRevoked
+func (s *tokenService) Validate(ctx context.Context, claims *utils.Claims) error {
+	if pkgRedis.GetClient() == nil {
+		return ErrRedisNotInitialized
+	}
+	result, err := pkgRedis.RunScript(ctx, tokenValidateScript,
+		[]string{keyTokenDenylist + claims.ID, generationKey(claims.UserID)},
+		claims.Generation)
+	if err != nil {
+		return err
+	}
+	if result.(int64) != 1 {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// Revoke 吊销单个Token, 黑名单记录保留到Token过期
+func (s *tokenService) Revoke(ctx context.Context, claims *utils.Claims) error {
+	if pkgRedis.GetClient() == nil {
+		return ErrRedisNotInitialized
+	}
+	return pkgRedis.Set(ctx, keyTokenDenylist+claims.ID, 1, tokenTTL(claims))
+}
+
+// RevokeAll 吊销用户已签发的所有Token(退出所有会话)
+func (s *tokenService) RevokeAll(ctx context.Context, userID uint64) error {
+	if pkgRedis.GetClient() == nil {
+		return ErrRedisNotInitialized
+	}
+	_, err := pkgRedis.Incr(ctx, generationKey(userID))
+	return err
+}
+
+// generation 获取用户的当前Token代数
+func (s *tokenService) generation(ctx context.Context, userID uint64) (int64, error) {
+	if pkgRedis.GetClient() == nil {
+		return 0, ErrRedisNotInitialized
+	}
+	value, err := pkgRedis.Get(ctx, generationKey(userID))
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// generationKey 用户Token代数的键, 不设置过期时间, 保证代数只增不减
+func generationKey(userID uint64) string {
+	return keyTokenGeneration + strconv.FormatUint(userID, 10)
+}
+
+// refreshGrace 刷新Token轮换后的宽限期
+func refreshGrace() time.Duration {
+	if cfg := config.GetConfig(); cfg != nil && cfg.JWT.RefreshGrace > 0 {
+		return time.Duration(cfg.JWT.RefreshGrace) * time.Second
+	}
+	return defaultRefreshGrace
+}
+
+// tokenTTL Token的剩余有效期, 至少1秒
+func tokenTTL(claims *utils.Claims) time.Duration {
+	ttl := time.Second
+	if claims.ExpiresAt != nil {
+		if d := time.Until(claims.ExpiresAt.Time); d > ttl {
+			ttl = d
+		}
+	}
+	return ttl
+}
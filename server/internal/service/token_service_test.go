@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"distributed-scheduler/internal/common/utils"
+	"distributed-scheduler/internal/config"
+	"distributed-scheduler/internal/model"
+	"distributed-scheduler/internal/repository"
+	"distributed-scheduler/pkg/logger"
+	pkgRedis "distributed-scheduler/pkg/redis"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	logger.Sugar = logger.Logger.Sugar()
+	os.Exit(m.Run())
+}
+
+// setupTokenService 使用miniredis和测试JWT配置创建Token服务
+func setupTokenService(t *testing.T) (*tokenService, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	pkgRedis.Client = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	prev := config.GlobalConfig
+	config.GlobalConfig = &config.Config{JWT: config.JWTConfig{
+		Secret: "test-secret", Expire: 900, RefreshExpire: 3600, RefreshGrace: 30, Issuer: "test",
+	}}
+	t.Cleanup(func() {
+		pkgRedis.Client.Close()
+		pkgRedis.Client = nil
+		config.GlobalConfig = prev
+	})
+	return &tokenService{userRepo: &fakeUserRepo{}}, mr
+}
+
+// fakeUserRepo 返回启用状态的用户
+type fakeUserRepo struct {
+	repository.UserRepository
+}
+
+func (r *fakeUserRepo) GetByID(ctx context.Context, id uint64) (*model.SysUser, error) {
+	return &model.SysUser{ID: id, Username: "admin", Status: model.UserStatusEnabled}, nil
+}
+
+func TestRefreshRotation(t *testing.T) {
+	s, _ := setupTokenService(t)
+	ctx := context.Background()
+
+	pair, err := s.Issue(ctx, 1, "admin", "SUPER_ADMIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := s.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("first refresh: %v", err)
+	}
+	if rotated.RefreshToken == pair.RefreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+
+	// 宽限期内(如另一个标签页同时刷新)再次使用返回同一个Token对, 会话不受影响
+	again, err := s.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh within grace period: %v", err)
+	}
+	if again.AccessToken != rotated.AccessToken || again.RefreshToken != rotated.RefreshToken {
+		t.Fatal("refresh within grace period returned a different pair")
+	}
+	claims, err := utils.ParseToken(rotated.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Validate(ctx, claims); err != nil {
+		t.Fatalf("rotated token invalid after concurrent refresh: %v", err)
+	}
+	if _, err := s.Refresh(ctx, rotated.RefreshToken); err != nil {
+		t.Fatalf("refresh with rotated token: %v", err)
+	}
+}
+
+func TestRefreshReuseAfterGraceRevokesAll(t *testing.T) {
+	s, mr := setupTokenService(t)
+	ctx := context.Background()
+
+	pair, err := s.Issue(ctx, 1, "admin", "SUPER_ADMIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := s.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 超过宽限期后重复使用视为泄露, 吊销所有会话
+	mr.FastForward(31 * time.Second)
+	if _, err := s.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("reuse after grace period: err = %v, want ErrTokenRevoked", err)
+	}
+	claims, err := utils.ParseToken(rotated.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Validate(ctx, claims); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("session after reuse: err = %v, want ErrTokenRevoked", err)
+	}
+}
@@ -20,7 +20,7 @@ var (
 
 // UserService 用户服务接口
 type UserService interface {
-	Login(ctx context.Context, username, password, ip string) (string, *model.SysUser, error)
+	Login(ctx context.Context, username, password, ip string) (*utils.TokenPair, *model.SysUser, error)
 	Create(ctx context.Context, user *model.SysUser, password string) error
 	Update(ctx context.Context, user *model.SysUser) error
 	Delete(ctx context.Context, id uint64) error
@@ -33,41 +33,43 @@ type UserService interface {
 
 // userService 用户服务实现
 type userService struct {
-	userRepo repository.UserRepository
+	userRepo     repository.UserRepository
+	tokenService TokenService
 }
 
 // NewUserService 创建用户服务
 func NewUserService() UserService {
 	return &userService{
-		userRepo: repository.NewUserRepository(),
+		userRepo:     repository.NewUserRepository(),
+		tokenService: NewTokenService(),
 	}
 }
 
-// Login 用户登录
-func (s *userService) Login(ctx context.Context, username, password, ip string) (string, *model.SysUser, error) {
+// Login 用户登录, 签发访问Token和刷新Token
+func (s *userService) Login(ctx context.Context, username, password, ip string) (*utils.TokenPair, *model.SysUser, error) {
 	// 查询用户
 	user, err := s.userRepo.GetByUsername(ctx, username)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", nil, ErrUserNotFound
+			return nil, nil, ErrUserNotFound
 		}
-		return "", nil, err
+		return nil, nil, err
 	}
 
 	// 验证密码
 	if !utils.CheckPassword(password, user.Password) {
-		return "", nil, ErrPasswordInvalid
+		return nil, nil, ErrPasswordInvalid
 	}
 
 	// 检查状态
 	if user.Status != model.UserStatusEnabled {
-		return "", nil, ErrUserDisabled
+		return nil, nil, ErrUserDisabled
 	}
 
 	// 获取角色
 	roles, err := s.userRepo.GetUserRoles(ctx, user.ID)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 
 	roleCode := ""
@@ -77,9 +79,9 @@ func (s *userService) Login(ctx context.Context, username, password, ip string)
 	}
 
 	// 生成Token
-	token, err := utils.GenerateToken(user.ID, user.Username, roleCode)
+	token, err := s.tokenService.Issue(ctx, user.ID, user.Username, roleCode)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 
 	// 更新登录信息
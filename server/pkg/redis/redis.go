@@ -167,3 +167,17 @@ func ZRem(ctx context.Context, key string, members ...interface{}) error {
 	return Client.ZRem(ctx, key, members...).Err()
 }
 
+// SetNX 键不存在时设置值, 返回是否设置成功
+func SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return Client.SetNX(ctx, key, value, expiration).Result()
+}
+
+// SCard 获取集合成员数量
+func SCard(ctx context.Context, key string) (int64, error) {
+	return Client.SCard(ctx, key).Result()
+}
+
+// RunScript 执行Lua脚本(优先使用EVALSHA)
+func RunScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	return script.Run(ctx, Client, keys, args...).Result()
+}
@@ -57,6 +57,27 @@ CREATE TABLE IF NOT EXISTS `sys_user_role` (
     INDEX `idx_role_id` (`role_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户角色关联表';
 
+-- 权限表(资源×操作)
+CREATE TABLE IF NOT EXISTS `sys_permission` (
+    `id` BIGINT UNSIGNED PRIMARY KEY AUTO_INCREMENT COMMENT '权限ID',
+    `resource` VARCHAR(64) NOT NULL COMMENT '资源 user/group/task/instance/executor',
+    `action` VARCHAR(32) NOT NULL COMMENT '操作 view/create/update/delete/execute/manage等',
+    `name` VARCHAR(64) NOT NULL COMMENT '权限名称',
+    `description` VARCHAR(256) DEFAULT '' COMMENT '权限描述',
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    UNIQUE KEY `uk_resource_action` (`resource`, `action`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='系统权限表';
+
+-- 角色权限关联表
+CREATE TABLE IF NOT EXISTS `sys_role_permission` (
+    `id` BIGINT UNSIGNED PRIMARY KEY AUTO_INCREMENT COMMENT 'ID',
+    `role_id` BIGINT UNSIGNED NOT NULL COMMENT '角色ID',
+    `permission_id` BIGINT UNSIGNED NOT NULL COMMENT '权限ID',
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    UNIQUE KEY `uk_role_permission` (`role_id`, `permission_id`),
+    INDEX `idx_permission_id` (`permission_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='角色权限关联表';
+
 -- ============================================
 -- 任务组相关表
 -- ============================================
@@ -67,6 +88,10 @@ CREATE TABLE IF NOT EXISTS `task_group` (
     `name` VARCHAR(128) NOT NULL COMMENT '任务组名称',
     `description` VARCHAR(512) DEFAULT '' COMMENT '描述',
     `app_name` VARCHAR(64) NOT NULL COMMENT '应用名称(用于执行器注册)',
+    `max_concurrent` INT UNSIGNED DEFAULT 0 COMMENT '最大并发实例数 0-不限制',
+    `access_token` VARCHAR(64) DEFAULT '' COMMENT '执行器访问令牌',
+    `prev_token` VARCHAR(64) DEFAULT '' COMMENT '轮换前的令牌(宽限期内有效)',
+    `token_rotate_at` DATETIME DEFAULT NULL COMMENT '令牌轮换时间',
     `status` TINYINT DEFAULT 1 COMMENT '状态 0-禁用 1-启用',
     `created_by` BIGINT UNSIGNED DEFAULT 0 COMMENT '创建人ID',
     `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
@@ -77,6 +102,19 @@ CREATE TABLE IF NOT EXISTS `task_group` (
     INDEX `idx_deleted_at` (`deleted_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='任务组表';
 
+-- 任务组成员表(非超级管理员只能访问已授权的任务组)
+CREATE TABLE IF NOT EXISTS `task_group_member` (
+    `id` BIGINT UNSIGNED PRIMARY KEY AUTO_INCREMENT COMMENT 'ID',
+    `group_id` BIGINT UNSIGNED NOT NULL COMMENT '任务组ID',
+    `user_id` BIGINT UNSIGNED NOT NULL COMMENT '用户ID',
+    `role` VARCHAR(16) NOT NULL COMMENT '组内角色 OWNER-所有者 OPERATOR-运维 VIEWER-只读',
+    `created_by` BIGINT UNSIGNED DEFAULT 0 COMMENT '授权人ID',
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at` DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    UNIQUE KEY `uk_group_user` (`group_id`, `user_id`),
+    INDEX `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='任务组成员表';
+
 -- ============================================
 -- 任务相关表
 -- ============================================
@@ -88,17 +126,25 @@ CREATE TABLE IF NOT EXISTS `task` (
     `name` VARCHAR(128) NOT NULL COMMENT '任务名称',
     `description` VARCHAR(512) DEFAULT '' COMMENT '任务描述',
     `cron` VARCHAR(64) NOT NULL COMMENT 'Cron表达式',
-    `executor_type` VARCHAR(32) NOT NULL DEFAULT 'HTTP' COMMENT '执行器类型 HTTP/GRPC/SCRIPT',
+    `executor_type` VARCHAR(32) NOT NULL DEFAULT 'HTTP' COMMENT '执行器类型 HTTP/GRPC/SCRIPT/HTTP_CALL',
     `executor_handler` VARCHAR(256) NOT NULL COMMENT '执行器Handler',
     `executor_param` TEXT COMMENT '执行参数(JSON格式)',
-    `route_strategy` VARCHAR(32) DEFAULT 'ROUND_ROBIN' COMMENT '路由策略 ROUND_ROBIN/RANDOM/CONSISTENT_HASH/LEAST_FREQUENTLY_USED/LEAST_RECENTLY_USED/FAILOVER/SHARDING_BROADCAST',
+    `route_strategy` VARCHAR(32) DEFAULT 'ROUND_ROBIN' COMMENT '路由策略 ROUND_ROBIN/RANDOM/CONSISTENT_HASH/LEAST_FREQUENTLY_USED/LEAST_RECENTLY_USED/FAILOVER/BUSYOVER/SHARDING_BROADCAST及自定义注册的策略',
+    `route_options` TEXT COMMENT '路由策略参数(JSON)',
+    `label_selector` VARCHAR(512) DEFAULT '' COMMENT '执行器标签选择器(必须满足) 如 zone=sh,disk in (ssd,nvme)',
+    `preferred_selector` VARCHAR(512) DEFAULT '' COMMENT '执行器标签选择器(优先满足)',
+    `anti_affinity` TINYINT(1) DEFAULT 0 COMMENT '分片反亲和 0-否 1-同一任务的分片尽量分散到不同主机',
     `block_strategy` VARCHAR(32) DEFAULT 'SERIAL_EXECUTION' COMMENT '阻塞策略 SERIAL_EXECUTION/DISCARD_LATER/COVER_EARLY',
+    `failover_strategy` VARCHAR(32) DEFAULT 'FAIL' COMMENT '执行器离线时的处理策略 FAIL-标记失败 REROUTE-重新调度',
     `shard_num` INT UNSIGNED DEFAULT 1 COMMENT '分片数量',
     `retry_count` INT UNSIGNED DEFAULT 0 COMMENT '失败重试次数',
     `retry_interval` INT UNSIGNED DEFAULT 0 COMMENT '重试间隔(秒)',
     `timeout` INT UNSIGNED DEFAULT 0 COMMENT '任务超时时间(秒) 0-无限制',
     `alarm_email` VARCHAR(512) DEFAULT '' COMMENT '告警邮箱(多个用逗号分隔)',
     `priority` INT DEFAULT 0 COMMENT '优先级 数值越大优先级越高',
+    `max_concurrent` INT UNSIGNED DEFAULT 0 COMMENT '最大并发实例数 0-不限制',
+    `script_version` INT UNSIGNED DEFAULT 0 COMMENT '当前脚本版本(SCRIPT类型任务)',
+    `http_call` TEXT COMMENT 'HTTP请求配置(HTTP_CALL类型任务, JSON)',
     `status` TINYINT DEFAULT 1 COMMENT '状态 0-禁用 1-启用',
     `version` INT UNSIGNED DEFAULT 0 COMMENT '版本号(乐观锁)',
     `next_trigger_time` DATETIME DEFAULT NULL COMMENT '下次触发时间',
@@ -123,6 +169,20 @@ CREATE TABLE IF NOT EXISTS `task_dependency` (
     INDEX `idx_depend_task_id` (`depend_task_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='任务依赖关系表';
 
+-- 任务脚本版本表(SCRIPT类型任务)
+CREATE TABLE IF NOT EXISTS `task_script` (
+    `id` BIGINT UNSIGNED PRIMARY KEY AUTO_INCREMENT COMMENT 'ID',
+    `task_id` BIGINT UNSIGNED NOT NULL COMMENT '任务ID',
+    `version` INT UNSIGNED NOT NULL COMMENT '脚本版本(任务内递增)',
+    `script_type` VARCHAR(32) NOT NULL COMMENT '脚本类型 bash/sh/python及执行器配置的其他解释器',
+    `content` MEDIUMTEXT COMMENT '脚本内容',
+    `env` TEXT COMMENT '环境变量(JSON)',
+    `remark` VARCHAR(256) DEFAULT '' COMMENT '版本说明',
+    `created_by` BIGINT UNSIGNED DEFAULT 0 COMMENT '创建人ID',
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    UNIQUE KEY `uk_task_version` (`task_id`, `version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='任务脚本版本表';
+
 -- ============================================
 -- 任务执行相关表
 -- ============================================
@@ -146,6 +206,8 @@ CREATE TABLE IF NOT EXISTS `task_instance` (
     `status` TINYINT DEFAULT 0 COMMENT '状态 0-待调度 1-调度中 2-执行中 3-执行成功 4-执行失败 5-已取消',
     `result_code` INT DEFAULT 0 COMMENT '结果码 0-成功 其他-失败',
     `result_msg` TEXT COMMENT '执行结果消息',
+    `pending_reason` VARCHAR(256) DEFAULT '' COMMENT '待调度原因(如配额已满、无可用执行器)',
+    `dispatch_token` BIGINT UNSIGNED DEFAULT 0 COMMENT '派发令牌, 每次分发递增, 旧令牌的执行结果被拒绝',
     `retry_count` INT UNSIGNED DEFAULT 0 COMMENT '已重试次数',
     `alarm_status` TINYINT DEFAULT 0 COMMENT '告警状态 0-默认 1-已告警',
     `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
@@ -187,7 +249,10 @@ CREATE TABLE IF NOT EXISTS `executor_node` (
     `current_load` INT UNSIGNED DEFAULT 0 COMMENT '当前负载(执行中任务数)',
     `cpu_usage` DECIMAL(5,2) DEFAULT 0 COMMENT 'CPU使用率',
     `memory_usage` DECIMAL(5,2) DEFAULT 0 COMMENT '内存使用率',
+    `labels` TEXT COMMENT '节点标签(JSON格式) 如 {"zone":"sh","disk":"ssd"}',
     `status` TINYINT DEFAULT 1 COMMENT '状态 0-离线 1-在线',
+    `drain_status` TINYINT DEFAULT 0 COMMENT '摘流状态 0-正常 1-摘流中 2-摘流完成',
+    `drain_at` DATETIME DEFAULT NULL COMMENT '开始摘流时间',
     `last_heartbeat` DATETIME DEFAULT NULL COMMENT '最后心跳时间',
     `registered_at` DATETIME DEFAULT CURRENT_TIMESTAMP COMMENT '注册时间',
     `updated_at` DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
@@ -303,6 +368,34 @@ SELECT u.id, r.id FROM `sys_user` u, `sys_role` r
 WHERE u.username = 'admin' AND r.code = 'SUPER_ADMIN'
 ON DUPLICATE KEY UPDATE `user_id` = `user_id`;
 
+-- 插入权限
+INSERT INTO `sys_permission` (`resource`, `action`, `name`, `description`) VALUES
+('user', 'view', '查看用户', '查看用户列表'),
+('user', 'create', '创建用户', '创建用户'),
+('group', 'view', '查看任务组', '查看任务组列表和详情'),
+('group', 'create', '创建任务组', '创建任务组'),
+('group', 'update', '更新任务组', '更新任务组'),
+('group', 'delete', '删除任务组', '删除任务组'),
+('group', 'token', '管理访问令牌', '查看和轮换执行器访问令牌'),
+('task', 'view', '查看任务', '查看任务列表、详情和脚本版本'),
+('task', 'create', '创建任务', '创建任务'),
+('task', 'update', '更新任务', '更新任务和回滚脚本'),
+('task', 'delete', '删除任务', '删除任务'),
+('task', 'execute', '执行任务', '启动、停止和手动触发任务'),
+('instance', 'view', '查看执行记录', '查看执行记录、统计和执行日志'),
+('instance', 'manage', '管理任务实例', '取消和重试任务实例'),
+('executor', 'view', '查看执行器', '查看执行器列表和详情'),
+('executor', 'manage', '管理执行器', '执行器摘流和取消摘流')
+ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), `description` = VALUES(`description`);
+
+-- 关联角色权限(超级管理员拥有所有权限)
+INSERT INTO `sys_role_permission` (`role_id`, `permission_id`)
+SELECT r.id, p.id FROM `sys_role` r, `sys_permission` p
+WHERE r.code = 'SUPER_ADMIN'
+   OR (r.code = 'ADMIN' AND (p.resource IN ('group', 'task', 'instance', 'executor') OR (p.resource = 'user' AND p.action = 'view')))
+   OR (r.code = 'USER' AND ((p.action = 'view' AND p.resource IN ('group', 'task', 'instance', 'executor')) OR (p.resource = 'task' AND p.action = 'execute')))
+ON DUPLICATE KEY UPDATE `role_id` = `role_id`;
+
 -- 插入系统配置
 INSERT INTO `sys_config` (`config_key`, `config_value`, `config_type`, `description`) VALUES
 ('scheduler.thread_pool_size', '20', 'NUMBER', '调度器线程池大小'),
@@ -318,6 +411,10 @@ INSERT INTO `task_group` (`name`, `description`, `app_name`, `status`) VALUES
 ('默认执行器组', '系统默认的执行器组', 'default-executor', 1)
 ON DUPLICATE KEY UPDATE `name` = VALUES(`name`);
 
+-- 为没有访问令牌的任务组(包括升级前创建的任务组)生成执行器访问令牌
+UPDATE `task_group` SET `access_token` = LOWER(HEX(RANDOM_BYTES(32)))
+WHERE `access_token` IS NULL OR `access_token` = '';
+
 -- ============================================
 -- 完成
 -- ============================================
@@ -1,4 +1,4 @@
-import { get } from '@/utils/request'
+import { get, post } from '@/utils/request'
 import type { ApiResponse, PageResult } from '@/utils/request'
 import type { ExecutorNode, ExecutorListParams } from './types'
 
@@ -12,6 +12,16 @@ export function getExecutorDetail(id: string): Promise<ApiResponse<ExecutorNode>
   return get(`/executor/${id}`)
 }
 
+// 执行器摘流
+export function drainExecutor(id: string): Promise<ApiResponse<ExecutorNode>> {
+  return post(`/executor/${id}/drain`)
+}
+
+// 取消执行器摘流
+export function undrainExecutor(id: string): Promise<ApiResponse<ExecutorNode>> {
+  return post(`/executor/${id}/undrain`)
+}
+
 // 获取在线执行器
 export function getOnlineExecutors(groupId: number): Promise<ApiResponse<ExecutorNode[]>> {
   return get('/executor/online', { group_id: groupId })
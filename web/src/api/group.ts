@@ -1,6 +1,6 @@
 import { get, post, put, del } from '@/utils/request'
 import type { ApiResponse, PageResult } from '@/utils/request'
-import type { TaskGroup, PageParams, CreateGroupRequest } from './types'
+import type { TaskGroup, PageParams, CreateGroupRequest, GroupToken, TaskGroupMember, SaveGroupMemberRequest } from './types'
 
 // 获取任务组列表
 export function getGroupList(params: PageParams & { keyword?: string }): Promise<ApiResponse<PageResult<TaskGroup>>> {
@@ -32,3 +32,27 @@ export function deleteGroup(id: number): Promise<ApiResponse<null>> {
   return del(`/group/${id}`)
 }
 
+// 获取任务组执行器访问令牌
+export function getGroupToken(id: number): Promise<ApiResponse<GroupToken>> {
+  return get(`/group/${id}/token`)
+}
+
+// 轮换任务组执行器访问令牌
+export function rotateGroupToken(id: number): Promise<ApiResponse<GroupToken>> {
+  return post(`/group/${id}/token/rotate`)
+}
+
+// 获取任务组成员
+export function getGroupMembers(id: number): Promise<ApiResponse<TaskGroupMember[]>> {
+  return get(`/group/${id}/members`)
+}
+
+// 授权用户访问任务组(已是成员时更新组内角色)
+export function saveGroupMember(id: number, data: SaveGroupMemberRequest): Promise<ApiResponse<TaskGroupMember>> {
+  return put(`/group/${id}/members`, data)
+}
+
+// 取消用户对任务组的授权
+export function removeGroupMember(id: number, userId: number): Promise<ApiResponse<null>> {
+  return del(`/group/${id}/members/${userId}`)
+}
@@ -1,6 +1,6 @@
 import { get, post, put, del } from '@/utils/request'
 import type { ApiResponse, PageResult } from '@/utils/request'
-import type { Task, TaskListParams, CreateTaskRequest } from './types'
+import type { Task, TaskListParams, CreateTaskRequest, TaskScript } from './types'
 
 // 获取任务列表
 export function getTaskList(params: TaskListParams): Promise<ApiResponse<PageResult<Task>>> {
@@ -47,3 +47,13 @@ export function getNextTriggerTimes(cron: string, count?: number): Promise<ApiRe
   return get('/task/next-trigger-times', { cron, count: count || 5 })
 }
 
+
+// 获取任务的脚本版本列表
+export function getTaskScripts(id: number): Promise<ApiResponse<TaskScript[]>> {
+  return get(`/task/${id}/scripts`)
+}
+
+// 回滚任务脚本到指定版本
+export function rollbackTaskScript(id: number, version: number): Promise<ApiResponse<TaskScript>> {
+  return post(`/task/${id}/scripts/${version}/rollback`)
+}
@@ -20,8 +20,23 @@ export interface Role {
   description: string
 }
 
-export interface LoginResult {
+// 当前用户的有效权限
+export interface UserPermissions {
+  roles: string[]
+  permissions: string[] // 权限编码(资源:操作), 如 task:create
+  super_admin: boolean
+  groups: Record<number, GroupRole> // 已授权的任务组(任务组ID -> 组内角色)
+}
+
+// 访问Token和刷新Token, 刷新Token每次使用后轮换
+export interface TokenPair {
   token: string
+  refresh_token: string
+  expires_at: string
+  refresh_expires_at: string
+}
+
+export interface LoginResult extends TokenPair {
   user: UserInfo
 }
 
@@ -31,12 +46,40 @@ export interface TaskGroup {
   name: string
   description: string
   app_name: string
+  max_concurrent: number
+  token_rotate_at?: string
   status: number
   created_by: number
   created_at: string
   updated_at: string
 }
 
+// 任务组执行器访问令牌
+export interface GroupToken {
+  app_name: string
+  access_token: string
+  token_rotate_at?: string
+}
+
+// 任务组内角色: 所有者/运维/只读
+export type GroupRole = 'OWNER' | 'OPERATOR' | 'VIEWER'
+
+export interface TaskGroupMember {
+  id: number
+  group_id: number
+  user_id: number
+  role: GroupRole
+  created_by: number
+  created_at: string
+  updated_at: string
+  user?: UserInfo
+}
+
+export interface SaveGroupMemberRequest {
+  user_id: number
+  role: GroupRole
+}
+
 // 任务相关类型
 export interface Task {
   id: number
@@ -48,13 +91,21 @@ export interface Task {
   executor_handler: string
   executor_param: string
   route_strategy: string
+  route_options: string
+  label_selector: string
+  preferred_selector: string
+  anti_affinity: boolean
   block_strategy: string
+  failover_strategy: string
   shard_num: number
   retry_count: number
   retry_interval: number
   timeout: number
   alarm_email: string
   priority: number
+  max_concurrent: number
+  script_version: number
+  http_call?: HTTPCallConfig
   status: number
   version: number
   next_trigger_time: string
@@ -63,6 +114,32 @@ export interface Task {
   created_at: string
   updated_at: string
   group?: TaskGroup
+  script?: TaskScript
+}
+
+// 任务脚本版本
+export interface TaskScript {
+  id: number
+  task_id: number
+  version: number
+  script_type: string
+  content: string
+  env: Record<string, string>
+  remark: string
+  created_by: number
+  created_at: string
+}
+
+// HTTP_CALL类型任务的请求配置, URL、请求头的值和请求体支持模板变量
+export interface HTTPCallConfig {
+  method: string
+  url: string
+  headers?: Record<string, string>
+  body?: string
+  timeout?: number
+  expect_status?: number[]
+  expect_body?: string
+  expect_body_regex?: string
 }
 
 // 任务实例相关类型
@@ -84,6 +161,8 @@ export interface TaskInstance {
   status: number
   result_code: number
   result_msg: string
+  pending_reason: string
+  dispatch_token: number
   retry_count: number
   alarm_status: number
   created_at: string
@@ -114,7 +193,10 @@ export interface ExecutorNode {
   current_load: number
   cpu_usage: number
   memory_usage: number
+  labels: Record<string, string>
   status: number
+  drain_status: number
+  drain_at?: string
   last_heartbeat: string
   registered_at: string
   updated_at: string
@@ -156,6 +238,7 @@ export interface InstanceListParams extends PageParams {
 export interface ExecutorListParams extends PageParams {
   group_id?: number
   status?: number
+  drain_status?: number
 }
 
 // 创建任务请求
@@ -168,14 +251,30 @@ export interface CreateTaskRequest {
   executor_handler: string
   executor_param?: string
   route_strategy?: string
+  route_options?: string
+  label_selector?: string
+  preferred_selector?: string
+  anti_affinity?: boolean
   block_strategy?: string
+  failover_strategy?: string
   shard_num?: number
   retry_count?: number
   retry_interval?: number
   timeout?: number
   alarm_email?: string
   priority?: number
+  max_concurrent?: number
   dependency_ids?: number[]
+  script?: TaskScriptRequest
+  http_call?: HTTPCallConfig
+}
+
+// 任务脚本请求, 更新任务时为空表示不修改脚本
+export interface TaskScriptRequest {
+  script_type: string
+  content: string
+  env?: Record<string, string>
+  remark?: string
 }
 
 // 创建任务组请求
@@ -183,5 +282,6 @@ export interface CreateGroupRequest {
   name: string
   description?: string
   app_name: string
+  max_concurrent?: number
 }
 
@@ -1,15 +1,25 @@
 import { post, get } from '@/utils/request'
 import type { ApiResponse } from '@/utils/request'
-import type { LoginResult, UserInfo } from './types'
+import type { LoginResult, TokenPair, UserInfo, UserPermissions } from './types'
 
 // 登录
 export function login(username: string, password: string): Promise<ApiResponse<LoginResult>> {
   return post('/auth/login', { username, password })
 }
 
-// 退出登录
-export function logout(): Promise<ApiResponse<null>> {
-  return post('/auth/logout')
+// 刷新Token
+export function refreshToken(refreshToken: string): Promise<ApiResponse<TokenPair>> {
+  return post('/auth/refresh', { refresh_token: refreshToken })
+}
+
+// 退出登录(同时吊销刷新Token)
+export function logout(refreshToken?: string): Promise<ApiResponse<null>> {
+  return post('/auth/logout', { refresh_token: refreshToken })
+}
+
+// 退出所有会话
+export function logoutAll(): Promise<ApiResponse<null>> {
+  return post('/auth/logout-all')
 }
 
 // 获取当前用户信息
@@ -17,6 +27,11 @@ export function getCurrentUser(): Promise<ApiResponse<UserInfo>> {
   return get('/user/current')
 }
 
+// 获取当前用户的有效权限
+export function getPermissions(): Promise<ApiResponse<UserPermissions>> {
+  return get('/user/permissions')
+}
+
 // 修改密码
 export function changePassword(oldPassword: string, newPassword: string): Promise<ApiResponse<null>> {
   return post('/user/password', { old_password: oldPassword, new_password: newPassword })
@@ -1,28 +1,84 @@
 import { defineStore } from 'pinia'
 import { ref, computed } from 'vue'
-import { login as loginApi, getCurrentUser, logout as logoutApi } from '@/api/user'
-import type { UserInfo } from '@/api/types'
+import {
+  login as loginApi, getCurrentUser, getPermissions, logout as logoutApi,
+  logoutAll as logoutAllApi, refreshToken as refreshTokenApi
+} from '@/api/user'
+import type { UserInfo, UserPermissions, GroupRole, TokenPair } from '@/api/types'
+
+// 组内角色等级, 高等级角色拥有低等级角色的所有能力
+const groupRoleLevel: Record<GroupRole, number> = { VIEWER: 1, OPERATOR: 2, OWNER: 3 }
 
 export const useUserStore = defineStore('user', () => {
   const token = ref<string>(localStorage.getItem('token') || '')
+  const refreshToken = ref<string>(localStorage.getItem('refresh_token') || '')
   const userInfo = ref<UserInfo | null>(null)
+  const permissions = ref<UserPermissions | null>(null)
 
   const isLoggedIn = computed(() => !!token.value)
 
+  // 保存Token
+  function setTokens(pair: TokenPair) {
+    token.value = pair.token
+    refreshToken.value = pair.refresh_token
+    localStorage.setItem('token', pair.token)
+    localStorage.setItem('refresh_token', pair.refresh_token)
+  }
+
+  // 清除登录状态
+  function clear() {
+    token.value = ''
+    refreshToken.value = ''
+    userInfo.value = null
+    permissions.value = null
+    localStorage.removeItem('token')
+    localStorage.removeItem('refresh_token')
+  }
+
   // 登录
   async function login(username: string, password: string) {
     const res = await loginApi(username, password)
-    token.value = res.data.token
+    setTokens(res.data)
     userInfo.value = res.data.user
-    localStorage.setItem('token', res.data.token)
+    await fetchPermissions()
     return res
   }
 
+  // 其他标签页刷新或退出登录后同步Token, 避免继续使用已轮换的刷新Token
+  window.addEventListener('storage', (e) => {
+    if (e.key === 'token') {
+      token.value = e.newValue || ''
+    } else if (e.key === 'refresh_token') {
+      refreshToken.value = e.newValue || ''
+    }
+  })
+
+  // 刷新Token, 同一标签页的并发请求共用同一次刷新(刷新Token只能使用一次);
+  // 多个标签页同时刷新时, 服务端在宽限期内返回同一个Token对
+  let refreshing: Promise<string> | null = null
+  function refresh() {
+    if (!refreshToken.value) {
+      return Promise.reject(new Error('登录已过期'))
+    }
+    if (!refreshing) {
+      refreshing = refreshTokenApi(refreshToken.value)
+        .then((res) => {
+          setTokens(res.data)
+          return res.data.token
+        })
+        .finally(() => {
+          refreshing = null
+        })
+    }
+    return refreshing
+  }
+
   // 获取用户信息
   async function fetchUserInfo() {
     try {
       const res = await getCurrentUser()
       userInfo.value = res.data
+      await fetchPermissions()
       return res.data
     } catch (error) {
       logout()
@@ -30,24 +86,59 @@ export const useUserStore = defineStore('user', () => {
     }
   }
 
+  // 获取有效权限
+  async function fetchPermissions() {
+    const res = await getPermissions()
+    permissions.value = res.data
+    return res.data
+  }
+
+  // 是否拥有权限(资源:操作)
+  function hasPermission(code: string) {
+    if (!permissions.value) return false
+    return permissions.value.super_admin || permissions.value.permissions.includes(code)
+  }
+
+  // 在任务组内的角色是否不低于role, 超级管理员不受任务组限制
+  function hasGroupRole(groupId: number, role: GroupRole) {
+    if (!permissions.value) return false
+    if (permissions.value.super_admin) return true
+    const current = permissions.value.groups?.[groupId]
+    return !!current && groupRoleLevel[current] >= groupRoleLevel[role]
+  }
+
   // 退出登录
   async function logout() {
     try {
-      await logoutApi()
+      await logoutApi(refreshToken.value || undefined)
+    } finally {
+      clear()
+    }
+  }
+
+  // 退出所有会话
+  async function logoutAll() {
+    try {
+      await logoutAllApi()
     } finally {
-      token.value = ''
-      userInfo.value = null
-      localStorage.removeItem('token')
+      clear()
     }
   }
 
   return {
     token,
+    refreshToken,
     userInfo,
+    permissions,
     isLoggedIn,
     login,
+    refresh,
     fetchUserInfo,
-    logout
+    fetchPermissions,
+    hasPermission,
+    hasGroupRole,
+    logout,
+    logoutAll
   }
 })
 
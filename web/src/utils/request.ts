@@ -43,9 +43,13 @@ service.interceptors.request.use(
   }
 )
 
+// 访问Token过期后使用刷新Token重新签发并重试一次, 认证相关接口不重试
+const canRefresh = (config: InternalAxiosRequestConfig & { _retried?: boolean }) =>
+  !config._retried && !config.url?.startsWith('/auth/')
+
 // 响应拦截器
 service.interceptors.response.use(
-  (response: AxiosResponse<ApiResponse>) => {
+  async (response: AxiosResponse<ApiResponse>) => {
     const res = response.data
     
     // 业务成功
@@ -53,6 +57,19 @@ service.interceptors.response.use(
       return res
     }
     
+    // 访问Token过期, 刷新后重试
+    const config = response.config as InternalAxiosRequestConfig & { _retried?: boolean }
+    if (res.code === 10005 && canRefresh(config)) {
+      const userStore = useUserStore()
+      if (userStore.refreshToken) {
+        config._retried = true
+        // 刷新失败时由刷新请求提示重新登录
+        const token = await userStore.refresh()
+        config.headers.Authorization = `Bearer ${token}`
+        return service(config)
+      }
+    }
+    
     // Token过期或无效
     if (res.code === 401 || res.code === 10005 || res.code === 10006) {
       ElMessageBox.confirm('登录已过期，请重新登录', '提示', {
@@ -89,6 +106,9 @@ service.interceptors.response.use(
         case 404:
           message = '请求地址不存在'
           break
+        case 429:
+          message = '请求过于频繁，请稍后再试'
+          break
         case 500:
           message = '服务器内部错误'
           break